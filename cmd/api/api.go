@@ -4,13 +4,36 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/config"
+	"github.com/nandaiqbalh/go-backend-ecom/service/cart"
+	"github.com/nandaiqbalh/go-backend-ecom/service/category"
+	"github.com/nandaiqbalh/go-backend-ecom/service/coupon"
+	"github.com/nandaiqbalh/go-backend-ecom/service/currency"
+	"github.com/nandaiqbalh/go-backend-ecom/service/giftcard"
+	"github.com/nandaiqbalh/go-backend-ecom/service/inventory"
+	"github.com/nandaiqbalh/go-backend-ecom/service/invoice"
+	"github.com/nandaiqbalh/go-backend-ecom/service/notify"
+	"github.com/nandaiqbalh/go-backend-ecom/service/order"
+	"github.com/nandaiqbalh/go-backend-ecom/service/payment"
 	"github.com/nandaiqbalh/go-backend-ecom/service/product"
+	"github.com/nandaiqbalh/go-backend-ecom/service/returns"
+	"github.com/nandaiqbalh/go-backend-ecom/service/review"
+	"github.com/nandaiqbalh/go-backend-ecom/service/shipment"
+	"github.com/nandaiqbalh/go-backend-ecom/service/shipping"
+	"github.com/nandaiqbalh/go-backend-ecom/service/tax"
+	"github.com/nandaiqbalh/go-backend-ecom/service/thumbnail"
 	"github.com/nandaiqbalh/go-backend-ecom/service/user"
+	"github.com/nandaiqbalh/go-backend-ecom/service/wishlist"
+	"github.com/nandaiqbalh/go-backend-ecom/storage"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
 )
 
 // APIServer holds the address to listen on and a reference to the database
@@ -50,11 +73,134 @@ func (s *APIServer) Run() error {
     userHandler := user.NewHandler(userStore)
     userHandler.RegisterRoutes(subroute)
 
-	// product related
+	// uploaded files are kept on the local filesystem and served outside
+	// the versioned API prefix
+	blobStore, err := storage.NewLocalStore(config.Envs.UploadDir, config.Envs.UploadURLPrefix)
+	if err != nil {
+		return err
+	}
+	router.PathPrefix(blobStore.Prefix()).Handler(http.StripPrefix(blobStore.Prefix(), blobStore)).Methods("GET", "HEAD")
+
+	// exchange rates used to show prices in the buyer's currency
+	rateStore := currency.NewStore(s.db)
+	rateHandler := currency.NewHandler(rateStore, userStore)
+	rateHandler.RegisterRoutes(subroute)
+
+	// product related; resized image variants are generated by a
+	// background worker fed by the upload handler
 	productStore:= product.NewStore(s.db)
-	productHandler := product.NewHandler(productStore)
+	thumbnailWorker := thumbnail.NewWorker(productStore, blobStore, config.Envs.MaxImagePixels, 100)
+	go thumbnailWorker.Run(context.Background())
+
+	productHandler := product.NewHandler(productStore, userStore, rateStore, blobStore, thumbnailWorker)
 	productHandler.RegisterRoutes(subroute)
 
+	// category tree and admin category management
+	categoryStore := category.NewStore(s.db)
+	categoryHandler := category.NewHandler(categoryStore, productStore, userStore)
+	categoryHandler.RegisterRoutes(subroute)
+
+	// orders and checkout; checkout reserves stock and the sweeper
+	// cancels orders left unpaid past their reservation
+	orderStore := order.NewStore(s.db)
+	orderHandler := order.NewHandler(orderStore, userStore)
+	orderHandler.RegisterRoutes(subroute)
+
+	clock := inventory.SystemClock{}
+	inventoryStore := inventory.NewStore(s.db)
+	sweeper := inventory.NewSweeper(inventoryStore, orderStore, clock,
+		time.Duration(config.Envs.ReservationSweepSeconds)*time.Second)
+	go sweeper.Run(context.Background())
+
+	// stock locations, movement ledger and reconciliation for admins, and
+	// back-in-stock subscriptions for customers
+	inventoryHandler := inventory.NewHandler(inventoryStore, inventoryStore, inventoryStore, productStore, userStore)
+	inventoryHandler.RegisterRoutes(subroute)
+
+	// low-stock and back-in-stock alerts are delivered in the background
+	var notifier types.Notifier = notify.Log{}
+	if config.Envs.NotifyWebhookURL != "" {
+		notifier = notify.NewWebhook(config.Envs.NotifyWebhookURL, config.Envs.NotifyWebhookSecret, nil)
+	}
+	dispatcher := inventory.NewDispatcher(inventoryStore, productStore, notifier,
+		time.Duration(config.Envs.AlertDispatchSeconds)*time.Second)
+	go dispatcher.Run(context.Background())
+
+	// coupons managed by admins and applied at checkout
+	couponStore := coupon.NewStore(s.db)
+	couponHandler := coupon.NewHandler(couponStore, productStore, categoryStore, userStore)
+	couponHandler.RegisterRoutes(subroute)
+
+	// tax rates by country, region and tax class, applied at checkout
+	taxStore := tax.NewStore(s.db)
+	taxHandler := tax.NewHandler(taxStore, userStore)
+	taxHandler.RegisterRoutes(subroute)
+
+	// gift cards issued by admins, which pay for orders at checkout and
+	// take refunds back
+	giftCardStore := giftcard.NewStore(s.db)
+	giftCardHandler := giftcard.NewHandler(giftCardStore, userStore, clock)
+	giftCardHandler.RegisterRoutes(subroute)
+
+	// shipping zones and methods managed by admins, quoted and charged at
+	// checkout
+	shippingStore := shipping.NewStore(s.db)
+	shippingHandler := shipping.NewHandler(shippingStore, userStore)
+	shippingHandler.RegisterRoutes(subroute)
+
+	// the server-side cart, filled from wishlists, shipping quotes and
+	// checkout
+	cartStore := cart.NewStore(s.db)
+	cartHandler := cart.NewHandler(cartStore, orderStore, productStore, couponStore, giftCardStore, rateStore, tax.NewCalculator(taxStore), shippingStore, userStore, clock)
+	cartHandler.RegisterRoutes(subroute)
+
+	// payments of orders through the configured provider, confirmed by the
+	// client and settled by provider events
+	var provider types.PaymentProvider
+	switch config.Envs.PaymentProvider {
+	case "fake":
+		provider = payment.NewFake(config.Envs.PaymentWebhookSecret,
+			config.Envs.PublicHost+":"+config.Envs.Port+"/api/v1/payments/fake/intents")
+	default:
+		log.Fatalf("unknown payment provider %q", config.Envs.PaymentProvider)
+	}
+	paymentStore := payment.NewStore(s.db)
+	paymentHandler := payment.NewHandler(paymentStore, orderStore, provider)
+	paymentHandler.RegisterRoutes(subroute)
+
+	// return requests approved, restocked and refunded by support, and
+	// refunds of order lines
+	returnStore := returns.NewStore(s.db)
+	returnHandler := returns.NewHandler(returnStore, returnStore, orderStore, paymentStore, inventoryStore,
+		payment.NewProcessor(paymentStore, orderStore, provider), userStore)
+	returnHandler.RegisterRoutes(subroute)
+
+	// invoices issued when orders are paid and credit notes issued for
+	// refunds, rendered as HTML or PDF
+	invoiceStore := invoice.NewStore(s.db)
+	invoiceHandler := invoice.NewHandler(invoiceStore, orderStore, types.InvoiceParty{
+		Name:    config.Envs.InvoiceSellerName,
+		Address: strings.ReplaceAll(config.Envs.InvoiceSellerAddress, `\n`, "\n"),
+		TaxID:   config.Envs.InvoiceSellerTaxID,
+	})
+	invoiceHandler.RegisterRoutes(subroute)
+
+	// shipments of paid orders with their tracking, which move orders to
+	// shipped and delivered
+	shipmentStore := shipment.NewStore(s.db)
+	shipmentHandler := shipment.NewHandler(shipmentStore, orderStore, userStore, clock)
+	shipmentHandler.RegisterRoutes(subroute)
+
+	// wishlists with read-only share links
+	wishlistStore := wishlist.NewStore(s.db)
+	wishlistHandler := wishlist.NewHandler(wishlistStore, productStore)
+	wishlistHandler.RegisterRoutes(subroute)
+
+	// product reviews, shown once approved by an admin
+	reviewStore := review.NewStore(s.db)
+	reviewHandler := review.NewHandler(reviewStore, productStore, userStore)
+	reviewHandler.RegisterRoutes(subroute)
+
     log.Println("Listening on", s.addr)
 
     return http.ListenAndServe(s.addr, router)
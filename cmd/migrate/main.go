@@ -27,6 +27,8 @@ func main() {
         DBName:               config.Envs.DBName,
         AllowNativePasswords: true,
         ParseTime:            true,
+        // migrations may contain several statements per file
+        MultiStatements:      true,
     })
 
     if err != nil {
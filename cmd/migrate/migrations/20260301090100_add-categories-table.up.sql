@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS categories (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `parentId` INT UNSIGNED NULL,
+    `name` VARCHAR(255) NOT NULL,
+    `slug` VARCHAR(255) NOT NULL,
+    `description` TEXT,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY (`slug`),
+    FOREIGN KEY (`parentId`) REFERENCES categories(`id`) ON DELETE RESTRICT
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS product_variant_option_values;
+DROP TABLE IF EXISTS product_variants;
+DROP TABLE IF EXISTS product_option_values;
+DROP TABLE IF EXISTS product_options;
@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS product_options (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(64) NOT NULL,
+    `position` INT UNSIGNED NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY (`productId`, `name`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS product_option_values (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `optionId` INT UNSIGNED NOT NULL,
+    `value` VARCHAR(64) NOT NULL,
+    `position` INT UNSIGNED NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY (`optionId`, `value`),
+    FOREIGN KEY (`optionId`) REFERENCES product_options(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS product_variants (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `sku` VARCHAR(64) NOT NULL,
+    `price` DECIMAL(10, 2) NULL,
+    `quantity` INT UNSIGNED NOT NULL DEFAULT 0,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY (`sku`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS product_variant_option_values (
+    `variantId` INT UNSIGNED NOT NULL,
+    `optionValueId` INT UNSIGNED NOT NULL,
+
+    PRIMARY KEY (`variantId`, `optionValueId`),
+    FOREIGN KEY (`variantId`) REFERENCES product_variants(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`optionValueId`) REFERENCES product_option_values(`id`) ON DELETE CASCADE
+);
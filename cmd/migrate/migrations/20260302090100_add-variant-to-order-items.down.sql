@@ -0,0 +1,3 @@
+ALTER TABLE order_items
+    DROP FOREIGN KEY `fk_order_items_variant`,
+    DROP COLUMN `variantId`;
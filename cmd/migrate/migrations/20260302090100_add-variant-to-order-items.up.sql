@@ -0,0 +1,3 @@
+ALTER TABLE order_items
+    ADD COLUMN `variantId` INT UNSIGNED NULL AFTER `productId`,
+    ADD CONSTRAINT `fk_order_items_variant` FOREIGN KEY (`variantId`) REFERENCES product_variants(`id`) ON DELETE SET NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS product_images (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `storageKey` VARCHAR(255) NOT NULL,
+    `contentType` VARCHAR(64) NOT NULL,
+    `size` INT UNSIGNED NOT NULL,
+    `position` INT UNSIGNED NOT NULL DEFAULT 0,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY (`storageKey`),
+    KEY (`productId`, `position`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS product_image_sizes;
\ No newline at end of file
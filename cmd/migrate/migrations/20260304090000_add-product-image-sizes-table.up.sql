@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS product_image_sizes (
+    `imageId` INT UNSIGNED NOT NULL,
+    `size` VARCHAR(32) NOT NULL,
+    `storageKey` VARCHAR(255) NOT NULL,
+    `width` INT UNSIGNED NOT NULL,
+    `height` INT UNSIGNED NOT NULL,
+
+    PRIMARY KEY (`imageId`, `size`),
+    FOREIGN KEY (`imageId`) REFERENCES product_images(`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+ALTER TABLE order_items
+    DROP FOREIGN KEY `fk_order_items_product`,
+    ADD CONSTRAINT `order_items_ibfk_2` FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE;
+
+ALTER TABLE products
+    DROP KEY `idx_products_deleted_at`,
+    DROP COLUMN `deletedAt`;
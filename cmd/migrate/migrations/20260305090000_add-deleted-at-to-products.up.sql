@@ -0,0 +1,9 @@
+ALTER TABLE products
+    ADD COLUMN `deletedAt` TIMESTAMP NULL DEFAULT NULL,
+    ADD KEY `idx_products_deleted_at` (`deletedAt`);
+
+-- products are no longer hard deleted, and order history must never be
+-- removed together with a product
+ALTER TABLE order_items
+    DROP FOREIGN KEY `order_items_ibfk_2`,
+    ADD CONSTRAINT `fk_order_items_product` FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE RESTRICT;
@@ -0,0 +1,2 @@
+ALTER TABLE products
+    ADD COLUMN `version` INT UNSIGNED NOT NULL DEFAULT 1;
@@ -0,0 +1 @@
+ALTER TABLE products DROP INDEX `products_sku_unique`, DROP COLUMN `sku`;
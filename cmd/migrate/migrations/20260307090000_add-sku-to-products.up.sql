@@ -0,0 +1,3 @@
+ALTER TABLE products
+    ADD COLUMN `sku` VARCHAR(64) NULL,
+    ADD UNIQUE KEY `products_sku_unique` (`sku`);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS inventory_reservations;
+
+UPDATE orders SET `status` = 'completed' WHERE `status` = 'paid';
+ALTER TABLE orders
+    MODIFY COLUMN `status` ENUM('pending', 'completed', 'cancelled') NOT NULL DEFAULT 'pending';
@@ -0,0 +1,20 @@
+ALTER TABLE orders
+    MODIFY COLUMN `status` ENUM('pending', 'paid', 'completed', 'cancelled') NOT NULL DEFAULT 'pending';
+
+CREATE TABLE IF NOT EXISTS inventory_reservations (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `productId` INT UNSIGNED NOT NULL,
+    `variantId` INT UNSIGNED NULL,
+    `quantity` INT UNSIGNED NOT NULL,
+    `status` ENUM('active', 'committed', 'released') NOT NULL DEFAULT 'active',
+    `expiresAt` DATETIME NOT NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `inventory_reservations_product_status` (`productId`, `status`),
+    KEY `inventory_reservations_status_expires` (`status`, `expiresAt`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`variantId`) REFERENCES product_variants(`id`) ON DELETE CASCADE
+);
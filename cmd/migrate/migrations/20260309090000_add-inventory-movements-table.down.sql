@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS inventory_movements_no_update;
+DROP TRIGGER IF EXISTS inventory_movements_no_delete;
+DROP TABLE IF EXISTS inventory_movements;
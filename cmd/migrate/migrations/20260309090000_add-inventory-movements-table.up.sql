@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS inventory_movements (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `variantId` INT UNSIGNED NULL,
+    `delta` INT NOT NULL,
+    `reason` ENUM('sale', 'restock', 'adjustment', 'return', 'cancellation') NOT NULL,
+    `referenceId` INT UNSIGNED NULL,
+    `actorId` INT UNSIGNED NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `inventory_movements_product` (`productId`, `id`),
+    KEY `inventory_movements_variant` (`variantId`),
+    -- variants may be deleted later; their movements are kept as history
+    FOREIGN KEY (`productId`) REFERENCES products(`id`),
+    FOREIGN KEY (`actorId`) REFERENCES users(`id`) ON DELETE SET NULL
+);
+
+-- opening balances so the ledger sums match the stock on hand
+INSERT INTO inventory_movements (productId, variantId, delta, reason)
+SELECT productId, id, quantity, 'adjustment' FROM product_variants WHERE quantity <> 0;
+
+INSERT INTO inventory_movements (productId, variantId, delta, reason)
+SELECT p.id, NULL, p.quantity - COALESCE(SUM(v.quantity), 0), 'adjustment'
+  FROM products p
+  LEFT JOIN product_variants v ON v.productId = p.id
+ GROUP BY p.id, p.quantity
+HAVING p.quantity - COALESCE(SUM(v.quantity), 0) <> 0;
+
+-- the ledger is append-only
+CREATE TRIGGER inventory_movements_no_update BEFORE UPDATE ON inventory_movements
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'inventory_movements is append-only';
+
+CREATE TRIGGER inventory_movements_no_delete BEFORE DELETE ON inventory_movements
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'inventory_movements is append-only';
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS order_item_allocations;
+
+ALTER TABLE orders
+    DROP COLUMN `latitude`,
+    DROP COLUMN `longitude`;
+
+ALTER TABLE inventory_reservations
+    DROP FOREIGN KEY `inventory_reservations_location`,
+    DROP COLUMN `locationId`;
+
+ALTER TABLE inventory_movements
+    DROP FOREIGN KEY `inventory_movements_location`,
+    DROP COLUMN `locationId`;
+
+DROP TABLE IF EXISTS stock_levels;
+DROP TABLE IF EXISTS stock_locations;
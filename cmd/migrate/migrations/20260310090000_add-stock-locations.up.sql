@@ -0,0 +1,72 @@
+CREATE TABLE IF NOT EXISTS stock_locations (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `code` VARCHAR(32) NOT NULL,
+    `name` VARCHAR(255) NOT NULL,
+    `latitude` DECIMAL(9, 6) NULL,
+    `longitude` DECIMAL(9, 6) NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `stock_locations_code` (`code`)
+);
+
+-- the stock on hand so far lives in the default location
+INSERT INTO stock_locations (id, code, name) VALUES (1, 'MAIN', 'Main warehouse');
+
+CREATE TABLE IF NOT EXISTS stock_levels (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `locationId` INT UNSIGNED NOT NULL,
+    `productId` INT UNSIGNED NOT NULL,
+    `variantId` INT UNSIGNED NULL,
+    -- unique keys treat NULLs as distinct, so rows without a variant are
+    -- keyed by 0
+    `variantKey` INT UNSIGNED AS (COALESCE(`variantId`, 0)) STORED,
+    `quantity` INT UNSIGNED NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `stock_levels_unit_location` (`productId`, `variantKey`, `locationId`),
+    FOREIGN KEY (`locationId`) REFERENCES stock_locations(`id`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`),
+    FOREIGN KEY (`variantId`) REFERENCES product_variants(`id`)
+);
+
+INSERT INTO stock_levels (locationId, productId, variantId, quantity)
+SELECT 1, productId, id, quantity FROM product_variants WHERE quantity <> 0;
+
+INSERT INTO stock_levels (locationId, productId, variantId, quantity)
+SELECT 1, p.id, NULL, p.quantity - COALESCE(SUM(v.quantity), 0)
+  FROM products p
+  LEFT JOIN product_variants v ON v.productId = p.id
+ GROUP BY p.id, p.quantity
+HAVING p.quantity - COALESCE(SUM(v.quantity), 0) <> 0;
+
+-- adding a column does not fire the append-only triggers
+ALTER TABLE inventory_movements
+    ADD COLUMN `locationId` INT UNSIGNED NOT NULL DEFAULT 1 AFTER `variantId`,
+    ADD CONSTRAINT `inventory_movements_location` FOREIGN KEY (`locationId`) REFERENCES stock_locations(`id`);
+ALTER TABLE inventory_movements ALTER COLUMN `locationId` DROP DEFAULT;
+
+ALTER TABLE inventory_reservations
+    ADD COLUMN `locationId` INT UNSIGNED NOT NULL DEFAULT 1 AFTER `variantId`,
+    ADD CONSTRAINT `inventory_reservations_location` FOREIGN KEY (`locationId`) REFERENCES stock_locations(`id`);
+ALTER TABLE inventory_reservations ALTER COLUMN `locationId` DROP DEFAULT;
+
+-- the delivery point used to pick the closest location at checkout
+ALTER TABLE orders
+    ADD COLUMN `latitude` DECIMAL(9, 6) NULL AFTER `address`,
+    ADD COLUMN `longitude` DECIMAL(9, 6) NULL AFTER `latitude`;
+
+CREATE TABLE IF NOT EXISTS order_item_allocations (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderItemId` INT UNSIGNED NOT NULL,
+    `locationId` INT UNSIGNED NOT NULL,
+    `quantity` INT UNSIGNED NOT NULL,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `order_item_allocations_item_location` (`orderItemId`, `locationId`),
+    FOREIGN KEY (`orderItemId`) REFERENCES order_items(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`locationId`) REFERENCES stock_locations(`id`)
+);
+
+INSERT INTO order_item_allocations (orderItemId, locationId, quantity)
+SELECT id, 1, quantity FROM order_items;
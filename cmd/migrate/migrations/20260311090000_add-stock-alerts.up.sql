@@ -0,0 +1,34 @@
+-- NULL disables low-stock alerts for the product
+ALTER TABLE products
+    ADD COLUMN `lowStockThreshold` INT UNSIGNED NULL AFTER `quantity`;
+
+-- outbox of stock alerts, written in the transaction that changed the
+-- stock and delivered afterwards
+CREATE TABLE IF NOT EXISTS stock_alerts (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `kind` ENUM('low_stock', 'back_in_stock') NOT NULL,
+    `quantity` INT NOT NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    `sentAt` TIMESTAMP NULL,
+
+    PRIMARY KEY (`id`),
+    KEY `stock_alerts_sent` (`sentAt`, `id`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS stock_subscriptions (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `userId` INT UNSIGNED NOT NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    `notifiedAt` TIMESTAMP NULL,
+    -- 1 while waiting, NULL once notified, so a user has at most one
+    -- waiting subscription per product but may subscribe again later
+    `waiting` TINYINT AS (IF(`notifiedAt` IS NULL, 1, NULL)) STORED,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `stock_subscriptions_waiting` (`productId`, `userId`, `waiting`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`userId`) REFERENCES users(`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+ALTER TABLE products
+    DROP COLUMN `ratingAverage`,
+    DROP COLUMN `ratingCount`;
+
+DROP TABLE IF EXISTS product_reviews;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS product_reviews (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `userId` INT UNSIGNED NOT NULL,
+    `rating` TINYINT UNSIGNED NOT NULL,
+    `body` TEXT NOT NULL,
+    -- set when the review was posted after a completed order of the product
+    `verifiedPurchase` BOOLEAN NOT NULL DEFAULT FALSE,
+    `status` ENUM('pending', 'approved', 'hidden') NOT NULL DEFAULT 'pending',
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    -- one review per customer and product
+    UNIQUE KEY `product_reviews_product_user` (`productId`, `userId`),
+    KEY `product_reviews_product_status` (`productId`, `status`, `createdAt`),
+    KEY `product_reviews_status` (`status`, `createdAt`),
+    CONSTRAINT `product_reviews_rating` CHECK (`rating` BETWEEN 1 AND 5),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`userId`) REFERENCES users(`id`) ON DELETE CASCADE
+);
+
+-- rating of the approved reviews, recomputed whenever a review is
+-- moderated; ratingAverage is NULL until a review is approved
+ALTER TABLE products
+    ADD COLUMN `ratingAverage` DECIMAL(3, 2) NULL AFTER `lowStockThreshold`,
+    ADD COLUMN `ratingCount` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `ratingAverage`;
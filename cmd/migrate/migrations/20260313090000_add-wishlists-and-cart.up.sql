@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS wishlists (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `userId` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(100) NOT NULL,
+    -- set while the wishlist is shared; anyone with the token can read it
+    `shareToken` CHAR(32) NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `wishlists_user_name` (`userId`, `name`),
+    UNIQUE KEY `wishlists_share_token` (`shareToken`),
+    FOREIGN KEY (`userId`) REFERENCES users(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS wishlist_items (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `wishlistId` INT UNSIGNED NOT NULL,
+    `productId` INT UNSIGNED NOT NULL,
+    `variantId` INT UNSIGNED NULL,
+    -- unique keys treat NULLs as distinct, so items without a variant are
+    -- keyed by 0
+    `variantKey` INT UNSIGNED AS (COALESCE(`variantId`, 0)) STORED,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `wishlist_items_unit` (`wishlistId`, `productId`, `variantKey`),
+    FOREIGN KEY (`wishlistId`) REFERENCES wishlists(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`variantId`) REFERENCES product_variants(`id`) ON DELETE CASCADE
+);
+
+-- the server-side cart; a product or variant appears once per user
+CREATE TABLE IF NOT EXISTS cart_items (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `userId` INT UNSIGNED NOT NULL,
+    `productId` INT UNSIGNED NOT NULL,
+    `variantId` INT UNSIGNED NULL,
+    `variantKey` INT UNSIGNED AS (COALESCE(`variantId`, 0)) STORED,
+    `quantity` INT UNSIGNED NOT NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `cart_items_unit` (`userId`, `productId`, `variantKey`),
+    FOREIGN KEY (`userId`) REFERENCES users(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`variantId`) REFERENCES product_variants(`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+ALTER TABLE orders
+    DROP COLUMN `subtotal`,
+    DROP COLUMN `discount`,
+    DROP COLUMN `freeShipping`;
+
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TABLE IF EXISTS coupon_categories;
+DROP TABLE IF EXISTS coupon_products;
+DROP TABLE IF EXISTS coupons;
@@ -0,0 +1,69 @@
+CREATE TABLE IF NOT EXISTS coupons (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `code` VARCHAR(32) NOT NULL,
+    `kind` ENUM('percentage', 'fixed_amount', 'free_shipping', 'buy_x_get_y') NOT NULL,
+    -- percent off for percentage coupons, amount off for fixed-amount ones
+    `value` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    -- buy buyQuantity, get getQuantity of the cheapest ones free
+    `buyQuantity` INT UNSIGNED NOT NULL DEFAULT 0,
+    `getQuantity` INT UNSIGNED NOT NULL DEFAULT 0,
+    `minSubtotal` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `startsAt` TIMESTAMP NULL,
+    `endsAt` TIMESTAMP NULL,
+    -- NULL means unlimited
+    `usageLimit` INT UNSIGNED NULL,
+    `perUserLimit` INT UNSIGNED NULL,
+    `active` BOOLEAN NOT NULL DEFAULT TRUE,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `coupons_code` (`code`)
+);
+
+-- a coupon restricted to products or categories only discounts the items
+-- linked to at least one of them; without rows it applies to every item
+CREATE TABLE IF NOT EXISTS coupon_products (
+    `couponId` INT UNSIGNED NOT NULL,
+    `productId` INT UNSIGNED NOT NULL,
+
+    PRIMARY KEY (`couponId`, `productId`),
+    FOREIGN KEY (`couponId`) REFERENCES coupons(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS coupon_categories (
+    `couponId` INT UNSIGNED NOT NULL,
+    `categoryId` INT UNSIGNED NOT NULL,
+
+    PRIMARY KEY (`couponId`, `categoryId`),
+    FOREIGN KEY (`couponId`) REFERENCES coupons(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`categoryId`) REFERENCES categories(`id`) ON DELETE CASCADE
+);
+
+-- one row per order placed with a coupon; releasedAt is set when the order
+-- is cancelled so the redemption no longer counts towards the limits
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `couponId` INT UNSIGNED NOT NULL,
+    `orderId` INT UNSIGNED NOT NULL,
+    `userId` INT UNSIGNED NOT NULL,
+    `discount` DECIMAL(10, 2) NOT NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    `releasedAt` TIMESTAMP NULL,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `coupon_redemptions_order` (`orderId`),
+    KEY `coupon_redemptions_coupon_user` (`couponId`, `userId`),
+    FOREIGN KEY (`couponId`) REFERENCES coupons(`id`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`userId`) REFERENCES users(`id`) ON DELETE CASCADE
+);
+
+-- total is what the customer pays: the subtotal of the items less the
+-- discount
+ALTER TABLE orders
+    ADD COLUMN `subtotal` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `userId`,
+    ADD COLUMN `discount` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `subtotal`,
+    ADD COLUMN `freeShipping` BOOLEAN NOT NULL DEFAULT FALSE AFTER `total`;
+
+UPDATE orders SET subtotal = total;
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS order_exchange_rates;
+
+ALTER TABLE orders
+    DROP COLUMN `currency`;
+
+ALTER TABLE users
+    DROP COLUMN `currency`;
+
+ALTER TABLE products
+    DROP COLUMN `currency`;
+
+DROP TABLE IF EXISTS exchange_rates;
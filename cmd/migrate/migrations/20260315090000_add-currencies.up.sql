@@ -0,0 +1,32 @@
+-- the number of units of each currency one unit of the default currency
+-- (USD) buys; the default currency itself is implicit with a rate of 1
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    `currency` CHAR(3) NOT NULL,
+    `rate` DECIMAL(18, 8) NOT NULL,
+    `updatedAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`currency`)
+);
+
+-- variant prices are in the currency of their product
+ALTER TABLE products
+    ADD COLUMN `currency` CHAR(3) NOT NULL DEFAULT 'USD' AFTER `price`;
+
+-- NULL means the user did not choose a currency
+ALTER TABLE users
+    ADD COLUMN `currency` CHAR(3) NULL;
+
+-- the amounts of an order and of its items are in the order currency
+ALTER TABLE orders
+    ADD COLUMN `currency` CHAR(3) NOT NULL DEFAULT 'USD' AFTER `total`;
+
+-- the exchange rates table as it was at checkout, for the order currency
+-- and the currencies its items were priced in
+CREATE TABLE IF NOT EXISTS order_exchange_rates (
+    `orderId` INT UNSIGNED NOT NULL,
+    `currency` CHAR(3) NOT NULL,
+    `rate` DECIMAL(18, 8) NOT NULL,
+
+    PRIMARY KEY (`orderId`, `currency`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS order_taxes;
+
+DROP TABLE IF EXISTS order_item_taxes;
+
+ALTER TABLE order_items
+    DROP COLUMN `taxClass`;
+
+ALTER TABLE orders
+    DROP COLUMN `region`,
+    DROP COLUMN `country`,
+    DROP COLUMN `tax`;
+
+ALTER TABLE products
+    DROP COLUMN `taxClass`;
+
+DROP TABLE IF EXISTS tax_rates;
@@ -0,0 +1,56 @@
+-- rate is a percentage; an empty region applies to the whole country and
+-- inclusive rates are already part of the catalog prices
+CREATE TABLE IF NOT EXISTS tax_rates (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `country` CHAR(2) NOT NULL,
+    `region` VARCHAR(64) NOT NULL DEFAULT '',
+    `taxClass` VARCHAR(32) NOT NULL DEFAULT 'standard',
+    `name` VARCHAR(64) NOT NULL,
+    `rate` DECIMAL(7, 4) NOT NULL,
+    `inclusive` BOOLEAN NOT NULL DEFAULT FALSE,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `tax_rates_name` (`country`, `region`, `taxClass`, `name`)
+);
+
+ALTER TABLE products
+    ADD COLUMN `taxClass` VARCHAR(32) NOT NULL DEFAULT 'standard' AFTER `currency`;
+
+-- tax sums the tax lines of the order, included in the prices or not;
+-- country and region are the destination they were worked out for
+ALTER TABLE orders
+    ADD COLUMN `tax` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `discount`,
+    ADD COLUMN `country` CHAR(2) NULL,
+    ADD COLUMN `region` VARCHAR(64) NULL;
+
+ALTER TABLE order_items
+    ADD COLUMN `taxClass` VARCHAR(32) NOT NULL DEFAULT 'standard';
+
+-- the taxes charged at each rate, per order item and summed per order;
+-- a name may appear twice when rates of the country and the region share it
+CREATE TABLE IF NOT EXISTS order_item_taxes (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderItemId` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(64) NOT NULL,
+    `rate` DECIMAL(7, 4) NOT NULL,
+    `inclusive` BOOLEAN NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+
+    PRIMARY KEY (`id`),
+    KEY `order_item_taxes_orderItem` (`orderItemId`),
+    FOREIGN KEY (`orderItemId`) REFERENCES order_items(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS order_taxes (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(64) NOT NULL,
+    `rate` DECIMAL(7, 4) NOT NULL,
+    `inclusive` BOOLEAN NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+
+    PRIMARY KEY (`id`),
+    KEY `order_taxes_order` (`orderId`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`) ON DELETE CASCADE
+);
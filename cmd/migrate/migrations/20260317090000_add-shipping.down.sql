@@ -0,0 +1,17 @@
+ALTER TABLE orders
+    DROP FOREIGN KEY `fk_orders_shipping_method`,
+    DROP COLUMN `shippingMethod`,
+    DROP COLUMN `shippingMethodId`,
+    DROP COLUMN `shipping`;
+
+ALTER TABLE products
+    DROP COLUMN `height`,
+    DROP COLUMN `width`,
+    DROP COLUMN `length`,
+    DROP COLUMN `weight`;
+
+DROP TABLE IF EXISTS shipping_methods;
+
+DROP TABLE IF EXISTS shipping_zone_countries;
+
+DROP TABLE IF EXISTS shipping_zones;
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `name` VARCHAR(64) NOT NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`)
+);
+
+-- a country belongs to one zone at most; a zone without rows covers the
+-- countries no other zone lists
+CREATE TABLE IF NOT EXISTS shipping_zone_countries (
+    `zoneId` INT UNSIGNED NOT NULL,
+    `country` CHAR(2) NOT NULL,
+
+    PRIMARY KEY (`country`),
+    FOREIGN KEY (`zoneId`) REFERENCES shipping_zones(`id`) ON DELETE CASCADE
+);
+
+-- amounts are in the default currency; perKg only applies to
+-- weight-based methods and freeOver to free-over ones
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `zoneId` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(64) NOT NULL,
+    `kind` ENUM('flat_rate', 'weight_based', 'free_over') NOT NULL,
+    `rate` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `perKg` DECIMAL(10, 2) NULL,
+    `freeOver` DECIMAL(10, 2) NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`zoneId`) REFERENCES shipping_zones(`id`) ON DELETE CASCADE
+);
+
+-- weight in grams, dimensions in millimetres
+ALTER TABLE products
+    ADD COLUMN `weight` INT UNSIGNED NULL AFTER `taxClass`,
+    ADD COLUMN `length` INT UNSIGNED NULL AFTER `weight`,
+    ADD COLUMN `width` INT UNSIGNED NULL AFTER `length`,
+    ADD COLUMN `height` INT UNSIGNED NULL AFTER `width`;
+
+-- shippingMethod keeps the name of the method for when it is deleted
+ALTER TABLE orders
+    ADD COLUMN `shipping` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `tax`,
+    ADD COLUMN `shippingMethodId` INT UNSIGNED NULL AFTER `shipping`,
+    ADD COLUMN `shippingMethod` VARCHAR(64) NULL AFTER `shippingMethodId`,
+    ADD CONSTRAINT `fk_orders_shipping_method` FOREIGN KEY (`shippingMethodId`) REFERENCES shipping_methods(`id`) ON DELETE SET NULL;
@@ -0,0 +1,37 @@
+-- one row per attempt to pay an order; intentId is the payment as known
+-- to the provider and amounts are in the order currency
+CREATE TABLE IF NOT EXISTS payments (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `provider` VARCHAR(32) NOT NULL,
+    `intentId` VARCHAR(64) NOT NULL,
+    `status` ENUM('pending', 'requires_action', 'authorized', 'captured', 'failed', 'refunded') NOT NULL DEFAULT 'pending',
+    `amount` DECIMAL(10, 2) NOT NULL,
+    `refunded` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `currency` CHAR(3) NOT NULL,
+    `nextActionUrl` VARCHAR(255) NULL,
+    `failureReason` VARCHAR(255) NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    `updatedAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `payments_intent` (`provider`, `intentId`),
+    KEY `payments_order` (`orderId`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`) ON DELETE CASCADE
+);
+
+-- the events that moved each payment, whether returned by a provider call
+-- or delivered to the webhook; the unique key drops redelivered events
+CREATE TABLE IF NOT EXISTS payment_events (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `paymentId` INT UNSIGNED NOT NULL,
+    `provider` VARCHAR(32) NOT NULL,
+    `eventId` VARCHAR(64) NOT NULL,
+    `type` VARCHAR(32) NOT NULL,
+    `amount` DECIMAL(10, 2) NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `payment_events_event` (`provider`, `eventId`),
+    FOREIGN KEY (`paymentId`) REFERENCES payments(`id`) ON DELETE CASCADE
+);
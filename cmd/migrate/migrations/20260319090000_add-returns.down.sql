@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS refund_items;
+
+DROP TABLE IF EXISTS refunds;
+
+DROP TABLE IF EXISTS return_items;
+
+DROP TABLE IF EXISTS returns;
+
+ALTER TABLE order_items
+    DROP COLUMN `refunded`,
+    DROP COLUMN `total`;
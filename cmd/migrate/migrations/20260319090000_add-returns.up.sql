@@ -0,0 +1,81 @@
+-- total is what the customer paid for the line once the discount is taken
+-- off and the taxes not included in the price are added; refunded is the
+-- part of it given back. Lines of older orders get their share of the
+-- order total less shipping.
+ALTER TABLE order_items
+    ADD COLUMN `total` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN `refunded` DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+UPDATE order_items i
+    JOIN orders o ON o.id = i.orderId
+    SET i.total = ROUND(i.price * i.quantity * (o.total - o.shipping) / o.subtotal, 2)
+    WHERE o.subtotal > 0;
+
+-- a customer's request to send items of an order back; resolution is the
+-- note support leaves when approving or rejecting it and receivedAt is
+-- when the items came back
+CREATE TABLE IF NOT EXISTS returns (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `userId` INT UNSIGNED NOT NULL,
+    `status` ENUM('requested', 'approved', 'rejected', 'received', 'refunded') NOT NULL DEFAULT 'requested',
+    `note` TEXT NULL,
+    `resolution` TEXT NULL,
+    `receivedAt` TIMESTAMP NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    `updatedAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `returns_order` (`orderId`),
+    KEY `returns_status` (`status`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`userId`) REFERENCES users(`id`) ON DELETE CASCADE
+);
+
+-- restocked is set once the item came back and was put back in stock
+CREATE TABLE IF NOT EXISTS return_items (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `returnId` INT UNSIGNED NOT NULL,
+    `orderItemId` INT UNSIGNED NOT NULL,
+    `quantity` INT UNSIGNED NOT NULL,
+    `reason` ENUM('damaged', 'wrong_item', 'not_as_described', 'no_longer_needed', 'other') NOT NULL,
+    `restocked` BOOLEAN NOT NULL DEFAULT FALSE,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `return_items_line` (`returnId`, `orderItemId`),
+    FOREIGN KEY (`returnId`) REFERENCES returns(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`orderItemId`) REFERENCES order_items(`id`) ON DELETE CASCADE
+);
+
+-- money given back on a payment, for a return or on its own; amounts are
+-- in the order currency
+CREATE TABLE IF NOT EXISTS refunds (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `returnId` INT UNSIGNED NULL,
+    `paymentId` INT UNSIGNED NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+    `currency` CHAR(3) NOT NULL,
+    `reason` VARCHAR(255) NOT NULL,
+    `actorId` INT UNSIGNED NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `refunds_order` (`orderId`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`returnId`) REFERENCES returns(`id`) ON DELETE SET NULL,
+    FOREIGN KEY (`paymentId`) REFERENCES payments(`id`),
+    FOREIGN KEY (`actorId`) REFERENCES users(`id`) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS refund_items (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `refundId` INT UNSIGNED NOT NULL,
+    `orderItemId` INT UNSIGNED NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `refund_items_line` (`refundId`, `orderItemId`),
+    FOREIGN KEY (`refundId`) REFERENCES refunds(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`orderItemId`) REFERENCES order_items(`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,74 @@
+-- the next number of each kind of document; numbers are taken in the
+-- transaction that issues the document, so a rollback gives the number
+-- back and the sequence has no gaps
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    `kind` VARCHAR(16) NOT NULL,
+    `next` INT UNSIGNED NOT NULL DEFAULT 1,
+
+    PRIMARY KEY (`kind`)
+);
+
+INSERT INTO invoice_sequences (`kind`, `next`) VALUES ('invoice', 1), ('credit_note', 1);
+
+-- invoices are issued when an order is paid and credit notes for each of
+-- its refunds; both copy what they need from the order so they never
+-- change afterwards, and must be kept, so they keep their order from being
+-- deleted. Amounts are in currency.
+CREATE TABLE IF NOT EXISTS invoices (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `number` VARCHAR(32) NOT NULL,
+    `kind` ENUM('invoice', 'credit_note') NOT NULL,
+    `orderId` INT UNSIGNED NOT NULL,
+    `refundId` INT UNSIGNED NULL,
+    `creditedInvoiceId` INT UNSIGNED NULL,
+    `customerName` VARCHAR(511) NOT NULL,
+    `customerEmail` VARCHAR(255) NOT NULL,
+    `address` TEXT NOT NULL,
+    `country` CHAR(2) NULL,
+    `region` VARCHAR(64) NULL,
+    `subtotal` DECIMAL(10, 2) NOT NULL,
+    `discount` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `shipping` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `tax` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `total` DECIMAL(10, 2) NOT NULL,
+    `currency` CHAR(3) NOT NULL,
+    `issuedAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `invoices_number` (`number`),
+    UNIQUE KEY `invoices_refund` (`refundId`),
+    KEY `invoices_order` (`orderId`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`),
+    FOREIGN KEY (`refundId`) REFERENCES refunds(`id`),
+    FOREIGN KEY (`creditedInvoiceId`) REFERENCES invoices(`id`)
+);
+
+-- quantity is 0 on credit note lines that refund an amount rather than
+-- returned units
+CREATE TABLE IF NOT EXISTS invoice_lines (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `invoiceId` INT UNSIGNED NOT NULL,
+    `orderItemId` INT UNSIGNED NULL,
+    `description` VARCHAR(330) NOT NULL,
+    `quantity` INT UNSIGNED NOT NULL,
+    `unitPrice` DECIMAL(10, 2) NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+
+    PRIMARY KEY (`id`),
+    KEY `invoice_lines_invoice` (`invoiceId`),
+    FOREIGN KEY (`invoiceId`) REFERENCES invoices(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`orderItemId`) REFERENCES order_items(`id`) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS invoice_taxes (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `invoiceId` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(64) NOT NULL,
+    `rate` DECIMAL(7, 4) NOT NULL,
+    `inclusive` BOOLEAN NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+
+    PRIMARY KEY (`id`),
+    KEY `invoice_taxes_invoice` (`invoiceId`),
+    FOREIGN KEY (`invoiceId`) REFERENCES invoices(`id`) ON DELETE CASCADE
+);
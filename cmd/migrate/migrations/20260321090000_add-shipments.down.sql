@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS shipment_items;
+
+DROP TABLE IF EXISTS shipments;
+
+UPDATE orders SET `status` = 'paid' WHERE `status` IN ('shipped', 'delivered');
+
+ALTER TABLE orders
+    MODIFY COLUMN `status` ENUM('pending', 'paid', 'completed', 'cancelled') NOT NULL DEFAULT 'pending';
@@ -0,0 +1,31 @@
+ALTER TABLE orders
+    MODIFY COLUMN `status` ENUM('pending', 'paid', 'shipped', 'delivered', 'completed', 'cancelled') NOT NULL DEFAULT 'pending';
+
+-- a package an order was sent in; deliveredAt is set once the carrier
+-- delivered it
+CREATE TABLE IF NOT EXISTS shipments (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `carrier` VARCHAR(100) NOT NULL,
+    `trackingNumber` VARCHAR(100) NOT NULL,
+    `trackingUrl` VARCHAR(2048) NULL,
+    `shippedAt` TIMESTAMP NOT NULL,
+    `deliveredAt` TIMESTAMP NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `shipments_order` (`orderId`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shipment_items (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `shipmentId` INT UNSIGNED NOT NULL,
+    `orderItemId` INT UNSIGNED NOT NULL,
+    `quantity` INT UNSIGNED NOT NULL,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `shipment_items_line` (`shipmentId`, `orderItemId`),
+    FOREIGN KEY (`shipmentId`) REFERENCES shipments(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`orderItemId`) REFERENCES order_items(`id`) ON DELETE CASCADE
+);
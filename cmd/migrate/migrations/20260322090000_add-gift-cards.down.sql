@@ -0,0 +1,13 @@
+-- paymentId stays nullable: refunds without a payment have credit notes
+-- pointing at them, so they cannot be dropped
+ALTER TABLE refunds
+    DROP COLUMN `giftCard`;
+
+ALTER TABLE orders
+    DROP FOREIGN KEY `fk_orders_gift_card`,
+    DROP COLUMN `giftCardId`,
+    DROP COLUMN `giftCard`;
+
+DROP TABLE IF EXISTS gift_card_transactions;
+
+DROP TABLE IF EXISTS gift_cards;
@@ -0,0 +1,49 @@
+-- prepaid cards looked up by the SHA-256 hash of their code, which is
+-- never stored; last4 identifies a card to support and customers
+CREATE TABLE IF NOT EXISTS gift_cards (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `codeHash` CHAR(64) NOT NULL,
+    `last4` CHAR(4) NOT NULL,
+    `initialBalance` DECIMAL(10, 2) NOT NULL,
+    `balance` DECIMAL(10, 2) NOT NULL,
+    `currency` CHAR(3) NOT NULL,
+    `expiresAt` TIMESTAMP NULL,
+    `active` BOOLEAN NOT NULL DEFAULT TRUE,
+    `note` VARCHAR(255) NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `gift_cards_code` (`codeHash`)
+);
+
+-- the ledger of every change to a gift card balance; amount is signed and
+-- balance is the balance after the change
+CREATE TABLE IF NOT EXISTS gift_card_transactions (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `giftCardId` INT UNSIGNED NOT NULL,
+    `kind` ENUM('issue', 'redeem', 'release', 'refund') NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+    `balance` DECIMAL(10, 2) NOT NULL,
+    `orderId` INT UNSIGNED NULL,
+    `refundId` INT UNSIGNED NULL,
+    `createdAt` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `gift_card_transactions_card` (`giftCardId`),
+    KEY `gift_card_transactions_order` (`orderId`),
+    FOREIGN KEY (`giftCardId`) REFERENCES gift_cards(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`) ON DELETE SET NULL,
+    FOREIGN KEY (`refundId`) REFERENCES refunds(`id`) ON DELETE SET NULL
+);
+
+-- giftCard is the part of the total paid with the gift card
+ALTER TABLE orders
+    ADD COLUMN `giftCard` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `total`,
+    ADD COLUMN `giftCardId` INT UNSIGNED NULL AFTER `giftCard`,
+    ADD CONSTRAINT `fk_orders_gift_card` FOREIGN KEY (`giftCardId`) REFERENCES gift_cards(`id`) ON DELETE SET NULL;
+
+-- refunds of orders paid in full with a gift card have no payment; giftCard
+-- is the part credited back onto the card
+ALTER TABLE refunds
+    MODIFY COLUMN `paymentId` INT UNSIGNED NULL,
+    ADD COLUMN `giftCard` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `amount`;
@@ -0,0 +1,3 @@
+ALTER TABLE inventory_reservations
+    DROP FOREIGN KEY `fk_inventory_reservations_variant`,
+    ADD CONSTRAINT `inventory_reservations_ibfk_3` FOREIGN KEY (`variantId`) REFERENCES product_variants(`id`) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+-- a variant must not be deleted under the holds of pending orders, which
+-- would lose the variant their order lines were reserved for
+ALTER TABLE inventory_reservations
+    DROP FOREIGN KEY `inventory_reservations_ibfk_3`,
+    ADD CONSTRAINT `fk_inventory_reservations_variant` FOREIGN KEY (`variantId`) REFERENCES product_variants(`id`) ON DELETE RESTRICT;
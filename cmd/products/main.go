@@ -0,0 +1,161 @@
+// The products command runs catalog maintenance tasks against the database.
+//
+// Usage:
+//
+//	go run cmd/products/main.go import [-dry-run] [-format csv|ndjson] <file>
+//	go run cmd/products/main.go reconcile
+//	go run cmd/products/main.go rates <file>
+//
+// "import" upserts products by SKU from a CSV or NDJSON file, exactly like
+// the admin POST /products/import endpoint, and prints the per-row report
+// as JSON. The format defaults to the file extension. With -dry-run every
+// row is validated and tried without writing anything. The command exits
+// with status 1 when any row failed.
+//
+// "reconcile" compares the stock of every product, variant and location
+// with the sum of its inventory movements and prints the ones that differ
+// as JSON. The command exits with status 1 when there are any.
+//
+// "rates" replaces the exchange rates table with a CSV file of currency,rate
+// rows, exactly like the admin PUT /exchange-rates endpoint.
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/nandaiqbalh/go-backend-ecom/config"
+	"github.com/nandaiqbalh/go-backend-ecom/db"
+	"github.com/nandaiqbalh/go-backend-ecom/service/currency"
+	"github.com/nandaiqbalh/go-backend-ecom/service/inventory"
+	"github.com/nandaiqbalh/go-backend-ecom/service/product"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "import":
+		runImport(os.Args[2:])
+	case "reconcile":
+		runReconcile()
+	case "rates":
+		runRates(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	log.Fatalf("Usage: %s import [-dry-run] [-format csv|ndjson] <file> | reconcile | rates <file>", os.Args[0])
+}
+
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "validate and report without writing")
+	format := flags.String("format", "", "input format, csv or ndjson (default from the file extension)")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatalf("Usage: %s import [-dry-run] [-format csv|ndjson] <file>", os.Args[0])
+	}
+	path := flags.Arg(0)
+
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			*format = product.ImportFormatCSV
+		case ".ndjson", ".jsonl":
+			*format = product.ImportFormatNDJSON
+		default:
+			log.Fatalf("Cannot tell the format of %s; pass -format", path)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Failed to open import file: %v", err)
+	}
+	defer f.Close()
+
+	report, err := product.Import(product.NewStore(openDB()), f, *format, *dryRun, 0)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	printJSON(report)
+
+	log.Printf("Created %d, updated %d, failed %d (dry run: %t)", report.Created, report.Updated, report.Failed, report.DryRun)
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func runReconcile() {
+	discrepancies, err := inventory.NewStore(openDB()).Reconcile()
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	printJSON(discrepancies)
+
+	log.Printf("%d stock discrepancies", len(discrepancies))
+	if len(discrepancies) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runRates(args []string) {
+	if len(args) != 1 {
+		log.Fatalf("Usage: %s rates <file>", os.Args[0])
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		log.Fatalf("Failed to open rates file: %v", err)
+	}
+	defer f.Close()
+
+	rates, err := currency.ParseRates(f)
+	if err != nil {
+		log.Fatalf("Invalid rates file: %v", err)
+	}
+	if err := currency.NewStore(openDB()).ReplaceExchangeRates(rates); err != nil {
+		log.Fatalf("Failed to save exchange rates: %v", err)
+	}
+
+	printJSON(rates)
+
+	log.Printf("Loaded %d exchange rates", len(rates))
+}
+
+func openDB() *sql.DB {
+	db, err := db.NewMySQLStorage(mysql.Config{
+		User:                 config.Envs.DBUser,
+		Passwd:               config.Envs.DBPassword,
+		Net:                  "tcp",
+		Addr:                 config.Envs.DBAddress,
+		DBName:               config.Envs.DBName,
+		AllowNativePasswords: true,
+		ParseTime:            true,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	return db
+}
+
+func printJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
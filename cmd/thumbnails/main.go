@@ -0,0 +1,67 @@
+// The thumbnails command maintains the resized variants of product images.
+//
+// Usage:
+//
+//	go run cmd/thumbnails/main.go regenerate
+//
+// "regenerate" rebuilds every size of every stored product image, which is
+// needed after the configured sizes change or when uploads were made while
+// the background worker was unavailable.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/nandaiqbalh/go-backend-ecom/config"
+	"github.com/nandaiqbalh/go-backend-ecom/db"
+	"github.com/nandaiqbalh/go-backend-ecom/service/product"
+	"github.com/nandaiqbalh/go-backend-ecom/service/thumbnail"
+	"github.com/nandaiqbalh/go-backend-ecom/storage"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[len(os.Args)-1] != "regenerate" {
+		log.Fatalf("Usage: %s regenerate", os.Args[0])
+	}
+
+	db, err := db.NewMySQLStorage(mysql.Config{
+		User:                 config.Envs.DBUser,
+		Passwd:               config.Envs.DBPassword,
+		Net:                  "tcp",
+		Addr:                 config.Envs.DBAddress,
+		DBName:               config.Envs.DBName,
+		AllowNativePasswords: true,
+		ParseTime:            true,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	blobStore, err := storage.NewLocalStore(config.Envs.UploadDir, config.Envs.UploadURLPrefix)
+	if err != nil {
+		log.Fatalf("Failed to open upload storage: %v", err)
+	}
+
+	productStore := product.NewStore(db)
+	worker := thumbnail.NewWorker(productStore, blobStore, config.Envs.MaxImagePixels, 0)
+
+	images, err := productStore.ListAllProductImages()
+	if err != nil {
+		log.Fatalf("Failed to list product images: %v", err)
+	}
+
+	failed := 0
+	for _, img := range images {
+		if err := worker.Process(img); err != nil {
+			log.Printf("Image %d (%s): %v", img.ID, img.Key, err)
+			failed++
+		}
+	}
+
+	log.Printf("Regenerated sizes for %d images, %d failed", len(images)-failed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -35,6 +35,26 @@ type Config struct {
 
 	JWTExpirationSeconds int64
 	JWTSecret            string
+
+	UploadDir           string
+	UploadURLPrefix     string
+	MaxImageUploadBytes int64
+	MaxImagesPerProduct int64
+	MaxImagePixels      int64
+
+	ReservationTTLSeconds   int64
+	ReservationSweepSeconds int64
+
+	NotifyWebhookURL     string
+	NotifyWebhookSecret  string
+	AlertDispatchSeconds int64
+
+	PaymentProvider      string
+	PaymentWebhookSecret string
+
+	InvoiceSellerName    string
+	InvoiceSellerAddress string
+	InvoiceSellerTaxID   string
 }
 
 // Envs is the globally accessible configuration populated during init.
@@ -57,6 +77,21 @@ func initConfig() Config {
         DBName:     getEnv("DB_NAME", "go-backend-ecom"),
 		JWTExpirationSeconds: getEnvAsInt("JWT_EXPIRATION_SECONDS", 3600*24), // default to 24 hours
 		JWTSecret:            getEnv("JWT_SECRET", "asdfasdfasdf"), // default to a placeholder secret; should be overridden in production
+		UploadDir:            getEnv("UPLOAD_DIR", "uploads"),
+		UploadURLPrefix:      getEnv("UPLOAD_URL_PREFIX", "/uploads"),
+		MaxImageUploadBytes:  getEnvAsInt("MAX_IMAGE_UPLOAD_BYTES", 5<<20), // 5 MiB per image
+		MaxImagesPerProduct:  getEnvAsInt("MAX_IMAGES_PER_PRODUCT", 10),
+		MaxImagePixels:       getEnvAsInt("MAX_IMAGE_PIXELS", 25_000_000), // 25 megapixels, decoded in memory for resizing
+		ReservationTTLSeconds:   getEnvAsInt("RESERVATION_TTL_SECONDS", 15*60), // unpaid orders hold stock for 15 minutes
+		ReservationSweepSeconds: getEnvAsInt("RESERVATION_SWEEP_SECONDS", 30),
+		NotifyWebhookURL:        getEnv("NOTIFY_WEBHOOK_URL", ""), // stock notifications are only logged when empty
+		NotifyWebhookSecret:     getEnv("NOTIFY_WEBHOOK_SECRET", ""),
+		AlertDispatchSeconds:    getEnvAsInt("ALERT_DISPATCH_SECONDS", 30),
+		PaymentProvider:         getEnv("PAYMENT_PROVIDER", "fake"),           // the local fake gateway; no money moves
+		PaymentWebhookSecret:    getEnv("PAYMENT_WEBHOOK_SECRET", "whsecret"), // should be overridden in production
+		InvoiceSellerName:       getEnv("INVOICE_SELLER_NAME", "go-backend-ecom"),
+		InvoiceSellerAddress:    getEnv("INVOICE_SELLER_ADDRESS", ""), // lines separated by "\n"
+		InvoiceSellerTaxID:      getEnv("INVOICE_SELLER_TAX_ID", ""),
     }
 }
 
@@ -0,0 +1,38 @@
+// Package sqlutil holds the small database/sql helpers shared by the
+// stores of the service packages.
+package sqlutil
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Scanner is satisfied by both *sql.Row and *sql.Rows.
+type Scanner interface {
+	Scan(dest ...any) error
+}
+
+// Placeholders returns n comma-separated query placeholders, such as the
+// list of an IN clause.
+func Placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// NullString stores an empty string as NULL.
+func NullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// Querier is satisfied by both *sql.DB and *sql.Tx.
+type Querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// NullableInt returns nil for a NULL column.
+func NullableInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
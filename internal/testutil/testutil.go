@@ -0,0 +1,10 @@
+// Package testutil holds the small helpers shared by the tests of the
+// service packages.
+package testutil
+
+import "github.com/nandaiqbalh/go-backend-ecom/types"
+
+// USD returns cents in the default currency.
+func USD(cents int64) types.Money {
+	return types.NewMoney(cents, types.DefaultCurrency)
+}
@@ -10,9 +10,16 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/nandaiqbalh/go-backend-ecom/config"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
 	"github.com/nandaiqbalh/go-backend-ecom/utils"
 )
 
+// contextKey is an unexported type for values stored in the request
+// context so they cannot collide with keys set by other packages.
+type contextKey string
+
+const userIDKey contextKey = "userId"
+
 func CreateJWT(secret []byte, userId int) (string, error) {
 	// Implement JWT creation logic here
 
@@ -76,7 +83,7 @@ func RequireToken(next http.HandlerFunc) http.HandlerFunc {
         // attach userId to context if present
         if uid, ok := claims["userId"].(string); ok {
             if id, err := strconv.Atoi(uid); err == nil {
-                ctx := context.WithValue(r.Context(), "userId", id)
+                ctx := context.WithValue(r.Context(), userIDKey, id)
                 r = r.WithContext(ctx)
             }
         }
@@ -84,3 +91,39 @@ func RequireToken(next http.HandlerFunc) http.HandlerFunc {
         next(w, r)
     }
 }
+
+// UserIDFromContext returns the authenticated user ID stored by
+// RequireToken. The boolean is false when the request was not authenticated.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(userIDKey).(int)
+	return id, ok
+}
+
+// IsAdmin reports whether the authenticated user of ctx holds the admin
+// role. It returns false for unauthenticated requests.
+func IsAdmin(ctx context.Context, store types.UserStore) bool {
+	id, ok := UserIDFromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	u, err := store.GetUserByID(id)
+	if err != nil {
+		return false
+	}
+
+	return u.Role == types.RoleAdmin
+}
+
+// RequireAdmin behaves like RequireToken but additionally loads the user
+// from the store and rejects the request unless they hold the admin role.
+func RequireAdmin(next http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	return RequireToken(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r.Context(), store) {
+			utils.WriteError(w, http.StatusForbidden, fmt.Errorf("admin access required"))
+			return
+		}
+
+		next(w, r)
+	})
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// OwnOrder returns the order named by the {id} path variable when it
+// belongs to the authenticated user and writes the error response
+// otherwise. Orders of other users are reported as not found so their IDs
+// cannot be probed.
+func OwnOrder(w http.ResponseWriter, r *http.Request, orders types.OrderStore) (*types.Order, bool) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return nil, false
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	order, err := orders.GetOrderByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if order == nil || order.UserID != userID {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order not found"))
+		return nil, false
+	}
+	return order, true
+}
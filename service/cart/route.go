@@ -0,0 +1,633 @@
+// Package cart implements checkout: it turns the items a customer wants to
+// buy into an order, pricing every line from the catalog rather than
+// trusting prices sent by the client, in the currency the customer asked
+// for. It also quotes shipping for a cart and serves the server-side cart
+// that wishlist items are moved to.
+package cart
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/config"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/service/coupon"
+	"github.com/nandaiqbalh/go-backend-ecom/service/currency"
+	"github.com/nandaiqbalh/go-backend-ecom/service/shipping"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves the cart, shipping quotes and the checkout endpoint.
+type Handler struct {
+	store        types.CartStore
+	orderStore   types.OrderStore
+	productStore types.ProductStore
+	couponStore  types.CouponStore
+	giftCards    types.GiftCardStore
+	rates        types.ExchangeRateStore
+	taxes        types.TaxCalculator
+	shipping     types.ShippingStore
+	userStore    types.UserStore
+	clock        types.Clock
+}
+
+// NewHandler creates a Handler that keeps carts in store, reads the
+// catalog from productStore, coupons from couponStore and gift cards from
+// giftCards, and writes orders to orderStore. Prices are converted with
+// the exchange rates from rates into the requested currency or the one
+// preferred in userStore, orders are taxed by taxes and shipped with the
+// methods of shipping. clock dates the stock reservations made at
+// checkout and checks the coupon date windows and gift card expiries.
+func NewHandler(store types.CartStore, orderStore types.OrderStore, productStore types.ProductStore, couponStore types.CouponStore, giftCards types.GiftCardStore, rates types.ExchangeRateStore, taxes types.TaxCalculator, shipping types.ShippingStore, userStore types.UserStore, clock types.Clock) *Handler {
+	return &Handler{
+		store:        store,
+		orderStore:   orderStore,
+		productStore: productStore,
+		couponStore:  couponStore,
+		giftCards:    giftCards,
+		rates:        rates,
+		taxes:        taxes,
+		shipping:     shipping,
+		userStore:    userStore,
+		clock:        clock,
+	}
+}
+
+// RegisterRoutes attaches cart routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/cart", auth.RequireToken(h.handleGetCart)).Methods("GET")
+	router.HandleFunc("/cart/items/{id:[0-9]+}", auth.RequireToken(h.handleRemoveCartItem)).Methods("DELETE")
+	router.HandleFunc("/cart/checkout", auth.RequireToken(h.handleCheckout)).Methods("POST")
+	router.HandleFunc("/shipping/quote", auth.RequireToken(h.handleQuoteShipping)).Methods("POST")
+}
+
+func (h *Handler) handleGetCart(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	target, rates, ok := currency.FromRequest(w, r, h.rates, h.userStore)
+	if !ok {
+		return
+	}
+
+	items, err := h.store.ListCartItems(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to load cart: %v", err))
+		return
+	}
+	if err := h.setPrices(items, rates, target); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.CartResponse{
+		Message: "success",
+		Data:    items,
+	})
+}
+
+func (h *Handler) handleRemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	item, err := h.store.GetCartItemByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if item == nil || item.UserID != userID {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("cart item not found"))
+		return
+	}
+
+	if err := h.store.RemoveCartItem(id); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.CartItemResponse{
+		Message: "item removed",
+		Data:    item,
+	})
+}
+
+// handleCheckout creates a pending order for the authenticated user. The
+// flow is:
+//  1. Decode and validate the CartCheckoutPayload. Without items the
+//     lines of the user's cart are ordered.
+//  2. Price every line from the catalog in the order currency and check
+//     the available stock. The order currency is the one requested with
+//     ?currency=, else the user's preferred one, else DefaultCurrency.
+//  3. Apply the coupon, if any, to the priced lines.
+//  4. Price the shipping method, if any, for the destination.
+//  5. Work out the taxes of the destination on what is left to pay for
+//     the items, then add the shipping cost to the total.
+//  6. Pay as much of the total as the gift card, if any, holds.
+//  7. Persist the order; the store allocates every line to stock
+//     locations, reserves the stock, redeems the coupon and the gift card
+//     and takes the ordered lines out of the cart in the same
+//     transaction. The reservation lasts ReservationTTLSeconds, after
+//     which an unpaid order is cancelled. An order the gift card pays in
+//     full is marked paid in that transaction too; the rest of any other
+//     order is paid through the payment provider.
+func (h *Handler) handleCheckout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	var payload types.CartCheckoutPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	lines, cartItemIDs, status, err := h.checkoutLines(userID, payload.Items)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	target, rates, ok := currency.FromRequest(w, r, h.rates, h.userStore)
+	if !ok {
+		return
+	}
+	if target == "" {
+		target = types.DefaultCurrency
+	}
+
+	items, products, subtotal, err := h.priceItems(lines, rates, target)
+	if err != nil {
+		utils.WriteError(w, pricingStatus(err), err)
+		return
+	}
+
+	// the rates of every currency involved, so the order can be priced
+	// again from the catalog prices
+	currencies := []string{target}
+	for _, p := range products {
+		currencies = append(currencies, p.Currency)
+	}
+
+	order := &types.Order{
+		UserID:        userID,
+		Subtotal:      subtotal,
+		Total:         subtotal,
+		Shipping:      types.NewMoney(0, target),
+		GiftCard:      types.NewMoney(0, target),
+		Currency:      target,
+		ExchangeRates: rates.Snapshot(currencies...),
+		Status:        types.OrderStatusPending,
+		Address:       payload.Address,
+		Country:       payload.Country,
+		Region:        payload.Region,
+		Latitude:      payload.Latitude,
+		Longitude:     payload.Longitude,
+		Items:         items,
+		CartItemIDs:   cartItemIDs,
+	}
+
+	if payload.CouponCode != "" {
+		if status, err := h.applyCoupon(order, payload.CouponCode, products, rates); err != nil {
+			utils.WriteError(w, status, err)
+			return
+		}
+	}
+
+	if payload.ShippingMethodID != nil {
+		if status, err := h.applyShipping(order, *payload.ShippingMethodID, products, rates); err != nil {
+			utils.WriteError(w, status, err)
+			return
+		}
+	}
+
+	if err := h.taxes.CalculateTax(order); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to calculate taxes: %v", err))
+		return
+	}
+	order.Total = order.Total.Add(order.Shipping)
+
+	if payload.GiftCardCode != "" {
+		if status, err := h.applyGiftCard(order, payload.GiftCardCode); err != nil {
+			utils.WriteError(w, status, err)
+			return
+		}
+	}
+
+	holdUntil := h.clock.Now().Add(time.Duration(config.Envs.ReservationTTLSeconds) * time.Second)
+	if err := h.orderStore.CreateOrder(order, holdUntil); err != nil {
+		if errors.Is(err, types.ErrInsufficientStock) || errors.Is(err, types.ErrCouponUnavailable) || errors.Is(err, types.ErrGiftCardUnavailable) {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.OrderResponse{
+		Message: "order created",
+		Data:    order,
+	})
+}
+
+// handleQuoteShipping returns what every shipping method to the payload's
+// country costs for the cart, priced in the currency checkout would use
+// and after the coupon, if any. A country no zone covers gets no quotes.
+func (h *Handler) handleQuoteShipping(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	var payload types.ShippingQuotePayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	lines, _, status, err := h.checkoutLines(userID, payload.Items)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	target, rates, ok := currency.FromRequest(w, r, h.rates, h.userStore)
+	if !ok {
+		return
+	}
+	if target == "" {
+		target = types.DefaultCurrency
+	}
+
+	items, products, subtotal, err := h.priceItems(lines, rates, target)
+	if err != nil {
+		utils.WriteError(w, pricingStatus(err), err)
+		return
+	}
+
+	order := &types.Order{
+		Subtotal: subtotal,
+		Total:    subtotal,
+		Currency: target,
+		Country:  payload.Country,
+		Region:   payload.Region,
+		Items:    items,
+	}
+	if payload.CouponCode != "" {
+		if status, err := h.applyCoupon(order, payload.CouponCode, products, rates); err != nil {
+			utils.WriteError(w, status, err)
+			return
+		}
+	}
+
+	zone, err := h.shipping.FindShippingZone(payload.Country)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	quotes := []*types.ShippingQuote{}
+	if zone != nil {
+		for _, m := range zone.Methods {
+			cost, err := quoteMethod(m, order, products, rates)
+			if err != nil {
+				utils.WriteError(w, http.StatusUnprocessableEntity, err)
+				return
+			}
+			quotes = append(quotes, &types.ShippingQuote{MethodID: m.ID, Name: m.Name, Kind: m.Kind, Cost: cost})
+		}
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ShippingQuotesResponse{
+		Message: "success",
+		Data:    quotes,
+	})
+}
+
+// applyShipping prices the shipping method with the given ID for the
+// order and records it. The method must belong to the zone of the order's
+// country. It returns the HTTP status to report alongside the error.
+func (h *Handler) applyShipping(order *types.Order, methodID int, products map[int]*types.Product, rates currency.Rates) (int, error) {
+	zone, err := h.shipping.FindShippingZone(order.Country)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	var method *types.ShippingMethod
+	if zone != nil {
+		for _, m := range zone.Methods {
+			if m.ID == methodID {
+				method = m
+			}
+		}
+	}
+	if method == nil {
+		return http.StatusUnprocessableEntity, fmt.Errorf("shipping method %d does not ship to %s", methodID, order.Country)
+	}
+
+	cost, err := quoteMethod(method, order, products, rates)
+	if err != nil {
+		return http.StatusUnprocessableEntity, err
+	}
+	order.ShippingMethodID = &method.ID
+	order.ShippingMethod = method.Name
+	order.Shipping = cost
+	return 0, nil
+}
+
+// quoteMethod returns what shipping method m costs for the order's items
+// in the order currency. The amounts of m, in DefaultCurrency, are
+// converted with rates. Orders with a free-shipping coupon ship for free.
+func quoteMethod(m *types.ShippingMethod, order *types.Order, products map[int]*types.Product, rates currency.Rates) (types.Money, error) {
+	if order.FreeShipping {
+		return types.NewMoney(0, order.Currency), nil
+	}
+
+	converted := *m
+	var err error
+	if converted.Rate, err = rates.Convert(m.Rate, order.Currency); err != nil {
+		return types.Money{}, err
+	}
+	if m.PerKg != nil {
+		perKg, err := rates.Convert(*m.PerKg, order.Currency)
+		if err != nil {
+			return types.Money{}, err
+		}
+		converted.PerKg = &perKg
+	}
+	if m.FreeOver != nil {
+		freeOver, err := rates.Convert(*m.FreeOver, order.Currency)
+		if err != nil {
+			return types.Money{}, err
+		}
+		converted.FreeOver = &freeOver
+	}
+
+	return shipping.Quote(&converted, shipping.Parcel{
+		Value: order.Subtotal.Sub(order.Discount),
+		Grams: shipping.Weigh(order.Items, products),
+	}), nil
+}
+
+// pricingStatus returns the HTTP status to report alongside an error of
+// priceItems.
+func pricingStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrInsufficientStock):
+		return http.StatusConflict
+	case errors.Is(err, currency.ErrNoRate):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// applyCoupon prices the coupon with the given code on the order's lines
+// and takes the discount off its total. products holds the products of
+// the lines by ID. The amounts of the coupon, in DefaultCurrency, are
+// converted into the order currency with rates. It returns the HTTP
+// status to report alongside the error.
+func (h *Handler) applyCoupon(order *types.Order, code string, products map[int]*types.Product, rates currency.Rates) (int, error) {
+	c, err := h.couponStore.GetCouponByCode(code)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if c == nil {
+		return http.StatusUnprocessableEntity, fmt.Errorf("coupon %s not found", code)
+	}
+	if c.MinSubtotal, err = rates.Convert(c.MinSubtotal, order.Currency); err != nil {
+		return http.StatusUnprocessableEntity, err
+	}
+	if c.AmountOff != nil {
+		amountOff, err := rates.Convert(*c.AmountOff, order.Currency)
+		if err != nil {
+			return http.StatusUnprocessableEntity, err
+		}
+		c.AmountOff = &amountOff
+	}
+
+	lines := make([]coupon.Line, 0, len(order.Items))
+	for _, item := range order.Items {
+		line := coupon.Line{
+			ProductID:   item.ProductID,
+			CategoryIDs: products[item.ProductID].CategoryIDs,
+			UnitPrice:   item.Price,
+			Quantity:    item.Quantity,
+		}
+		if item.VariantID != nil {
+			line.VariantID = *item.VariantID
+		}
+		lines = append(lines, line)
+	}
+
+	result, err := coupon.Apply(c, lines, h.clock.Now())
+	if err != nil {
+		if errors.Is(err, coupon.ErrNotApplicable) {
+			return http.StatusUnprocessableEntity, err
+		}
+		return http.StatusInternalServerError, err
+	}
+
+	order.CouponCode = &c.Code
+	order.Discount = result.Discount
+	order.Total = order.Subtotal.Sub(result.Discount)
+	order.FreeShipping = result.FreeShipping
+	return 0, nil
+}
+
+// checkoutLines returns the lines to price: items when the client sent
+// them, else the lines of the user's server-side cart together with their
+// IDs, so the order can take them out of the cart. It returns the HTTP
+// status to report alongside the error.
+func (h *Handler) checkoutLines(userID int, items []types.CartItemPayload) ([]types.CartItemPayload, []int, int, error) {
+	if len(items) > 0 {
+		return items, nil, 0, nil
+	}
+
+	cartItems, err := h.store.ListCartItems(userID)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, fmt.Errorf("failed to load cart: %v", err)
+	}
+	if len(cartItems) == 0 {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("the cart is empty")
+	}
+	lines := make([]types.CartItemPayload, 0, len(cartItems))
+	ids := make([]int, 0, len(cartItems))
+	for _, item := range cartItems {
+		line := types.CartItemPayload{ProductID: item.ProductID, Quantity: item.Quantity}
+		if item.VariantID != nil {
+			line.VariantID = *item.VariantID
+		}
+		lines = append(lines, line)
+		ids = append(ids, item.ID)
+	}
+	return lines, ids, 0, nil
+}
+
+// applyGiftCard pays as much of the order total as the gift card with
+// code holds. The card must be active, unexpired and in the order
+// currency; the balance is checked again when the order is persisted.
+func (h *Handler) applyGiftCard(order *types.Order, code string) (int, error) {
+	card, err := h.giftCards.GetGiftCardByCode(code)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if card == nil {
+		return http.StatusUnprocessableEntity, fmt.Errorf("gift card not found")
+	}
+	if !card.Active || (card.ExpiresAt != nil && !card.ExpiresAt.After(h.clock.Now())) {
+		return http.StatusUnprocessableEntity, fmt.Errorf("gift card ending in %s is not active", card.Last4)
+	}
+	if card.Currency != order.Currency {
+		return http.StatusUnprocessableEntity, fmt.Errorf("gift card ending in %s is in %s, not %s", card.Last4, card.Currency, order.Currency)
+	}
+	if card.Balance.IsZero() {
+		return http.StatusUnprocessableEntity, fmt.Errorf("gift card ending in %s has no balance left", card.Last4)
+	}
+
+	order.GiftCard = card.Balance
+	if order.GiftCard.Cmp(order.Total) > 0 {
+		order.GiftCard = order.Total
+	}
+	order.GiftCardID = &card.ID
+	return 0, nil
+}
+
+// priceItems resolves each cart line to a product (and variant), checks
+// the stock currently available and returns the order lines priced in
+// currency target with the products they refer to, by ID, and the order
+// subtotal.
+func (h *Handler) priceItems(cartItems []types.CartItemPayload, rates currency.Rates, target string) ([]*types.OrderItem, map[int]*types.Product, types.Money, error) {
+	products := make(map[int]*types.Product)
+	requested := make(map[string]int)
+
+	var items []*types.OrderItem
+	total := types.NewMoney(0, target)
+	for _, ci := range cartItems {
+		product, ok := products[ci.ProductID]
+		if !ok {
+			var err error
+			product, err = h.productStore.GetProductByID(ci.ProductID)
+			if err != nil {
+				return nil, nil, types.Money{}, err
+			}
+			if product == nil {
+				return nil, nil, types.Money{}, fmt.Errorf("product %d not found", ci.ProductID)
+			}
+			products[ci.ProductID] = product
+		}
+
+		price, stock, variantID, err := resolveLine(product, ci.VariantID)
+		if err != nil {
+			return nil, nil, types.Money{}, err
+		}
+		if price, err = rates.Convert(price, target); err != nil {
+			return nil, nil, types.Money{}, fmt.Errorf("product %d: %w", ci.ProductID, err)
+		}
+
+		// the same product or variant may appear on several lines
+		key := fmt.Sprintf("%d/%d", ci.ProductID, ci.VariantID)
+		requested[key] += ci.Quantity
+		if requested[key] > stock {
+			return nil, nil, types.Money{}, fmt.Errorf("%w for product %d", types.ErrInsufficientStock, ci.ProductID)
+		}
+
+		items = append(items, &types.OrderItem{
+			ProductID: ci.ProductID,
+			VariantID: variantID,
+			Quantity:  ci.Quantity,
+			Price:     price,
+			TaxClass:  product.TaxClass,
+		})
+		total = total.Add(price.Mul(ci.Quantity))
+	}
+
+	return items, products, total, nil
+}
+
+// resolveLine returns the unit price, available stock (on hand minus
+// reservations) and variant ID for a cart line. Products with variants
+// must be bought by variant.
+func resolveLine(product *types.Product, variantID int) (types.Money, int, *int, error) {
+	if len(product.Variants) == 0 {
+		if variantID != 0 {
+			return types.Money{}, 0, nil, fmt.Errorf("product %d has no variants", product.ID)
+		}
+		return product.Price, product.Available, nil, nil
+	}
+
+	if variantID == 0 {
+		return types.Money{}, 0, nil, fmt.Errorf("product %d requires a variantId", product.ID)
+	}
+	for _, v := range product.Variants {
+		if v.ID == variantID {
+			id := v.ID
+			return v.PriceOr(product.Price), v.Available, &id, nil
+		}
+	}
+	return types.Money{}, 0, nil, fmt.Errorf("variant %d not found for product %d", variantID, product.ID)
+}
+
+// setPrices sets the current unit price of each cart item, in currency
+// target or, when it is empty, in the currency of the product. Items whose
+// product or variant is gone are left without a price.
+func (h *Handler) setPrices(items []*types.CartItem, rates currency.Rates, target string) error {
+	products := make(map[int]*types.Product)
+	for _, item := range items {
+		product, ok := products[item.ProductID]
+		if !ok {
+			var err error
+			product, err = h.productStore.GetProductByID(item.ProductID)
+			if err != nil {
+				return err
+			}
+			products[item.ProductID] = product
+		}
+		if product == nil {
+			continue
+		}
+
+		variantID := 0
+		if item.VariantID != nil {
+			variantID = *item.VariantID
+		}
+		price, _, _, err := resolveLine(product, variantID)
+		if err != nil {
+			continue
+		}
+		if target != "" {
+			if price, err = rates.Convert(price, target); err != nil {
+				return err
+			}
+		}
+		item.Price, item.Currency = &price, price.Currency
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package cart
+
+import (
+	"database/sql"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const cartItemColumns = "id, userId, productId, variantId, quantity, createdAt"
+
+func scanCartItem(row sqlutil.Scanner) (*types.CartItem, error) {
+	item := new(types.CartItem)
+	var variantID sql.NullInt64
+	if err := row.Scan(&item.ID, &item.UserID, &item.ProductID, &variantID, &item.Quantity, &item.CreatedAt); err != nil {
+		return nil, err
+	}
+	item.VariantID = sqlutil.NullableInt(variantID)
+	return item, nil
+}
+
+// ListCartItems returns the lines of the user's cart in the order they
+// were added.
+func (s *Store) ListCartItems(userID int) ([]*types.CartItem, error) {
+	rows, err := s.db.Query("SELECT "+cartItemColumns+" FROM cart_items WHERE userId = ? ORDER BY createdAt, id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*types.CartItem{}
+	for rows.Next() {
+		item, err := scanCartItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetCartItemByID returns the cart line with the given ID, or nil if there
+// is none.
+func (s *Store) GetCartItemByID(id int) (*types.CartItem, error) {
+	item, err := scanCartItem(s.db.QueryRow("SELECT "+cartItemColumns+" FROM cart_items WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Clear removes the lines with the given IDs from the user's cart inside
+// tx, the transaction creating the order they were checked out into.
+// Lines added since checkout read the cart are kept.
+func Clear(tx *sql.Tx, userID int, itemIDs []int) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+	args := []any{userID}
+	for _, id := range itemIDs {
+		args = append(args, id)
+	}
+	_, err := tx.Exec("DELETE FROM cart_items WHERE userId = ? AND id IN ("+sqlutil.Placeholders(len(itemIDs))+")", args...)
+	return err
+}
+
+// RemoveCartItem deletes a cart line.
+func (s *Store) RemoveCartItem(id int) error {
+	_, err := s.db.Exec("DELETE FROM cart_items WHERE id = ?", id)
+	return err
+}
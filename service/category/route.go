@@ -0,0 +1,258 @@
+package category
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves the category tree and the admin category endpoints.
+type Handler struct {
+	store        types.CategoryStore
+	productStore types.ProductStore
+	userStore    types.UserStore
+}
+
+// NewHandler creates a Handler. The product store is used to list products
+// in a category and the user store to authorize admin requests.
+func NewHandler(store types.CategoryStore, productStore types.ProductStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:        store,
+		productStore: productStore,
+		userStore:    userStore,
+	}
+}
+
+// RegisterRoutes attaches category routes to the provided router. Reading
+// the tree requires a token like the product endpoints; changing it
+// requires the admin role.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/categories", auth.RequireToken(h.handleGetTree)).Methods("GET")
+	router.HandleFunc("/categories/{slug}/products", auth.RequireToken(h.handleListCategoryProducts)).Methods("GET")
+
+	router.HandleFunc("/categories", auth.RequireAdmin(h.handleCreateCategory, h.userStore)).Methods("POST")
+	router.HandleFunc("/categories/{id:[0-9]+}", auth.RequireAdmin(h.handleUpdateCategory, h.userStore)).Methods("PUT")
+	router.HandleFunc("/categories/{id:[0-9]+}", auth.RequireAdmin(h.handleDeleteCategory, h.userStore)).Methods("DELETE")
+}
+
+func (h *Handler) handleGetTree(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.store.ListCategories()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list categories: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.CategoryTreeResponse{
+		Message: "success",
+		Data:    BuildTree(categories),
+	})
+}
+
+// handleListCategoryProducts lists products in the category identified by
+// slug and in all of its subcategories, using the same pagination as the
+// product listing.
+func (h *Handler) handleListCategoryProducts(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+
+	category, err := h.store.GetCategoryBySlug(slug)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if category == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("category not found"))
+		return
+	}
+
+	categories, err := h.store.ListCategories()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	params := types.ProductListParams{
+		Pagination:  utils.ParsePagination(r),
+		CategoryIDs: DescendantIDs(categories, category.ID),
+	}
+
+	products, total, err := h.productStore.ListProducts(params)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list products: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListProductsResponse{
+		Message: "success",
+		Data:    products,
+		Meta:    utils.NewPageMeta(params.Pagination, total),
+	})
+}
+
+func (h *Handler) handleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateCategoryPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	category := &types.Category{
+		ParentID:    payload.ParentID,
+		Name:        payload.Name,
+		Slug:        payload.Slug,
+		Description: payload.Description,
+	}
+	if category.Slug == "" {
+		category.Slug = Slugify(category.Name)
+	}
+
+	if status, err := h.checkCategory(category); err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	if err := h.store.CreateCategory(category); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.CategoryResponse{
+		Message: "category created",
+		Data:    category,
+	})
+}
+
+func (h *Handler) handleUpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	existing, err := h.store.GetCategoryByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if existing == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("category not found"))
+		return
+	}
+
+	var payload types.UpdateCategoryPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	category := &types.Category{
+		ID:          id,
+		ParentID:    payload.ParentID,
+		Name:        payload.Name,
+		Slug:        payload.Slug,
+		Description: payload.Description,
+		CreatedAt:   existing.CreatedAt,
+	}
+
+	if status, err := h.checkCategory(category); err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	if err := h.store.UpdateCategory(category); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.CategoryResponse{
+		Message: "category updated",
+		Data:    category,
+	})
+}
+
+func (h *Handler) handleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	categories, err := h.store.ListCategories()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// subcategories must be moved or deleted first
+	if len(DescendantIDs(categories, id)) > 1 {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("category has subcategories"))
+		return
+	}
+
+	if err := h.store.DeleteCategory(id); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.CategoryResponse{
+		Message: "category deleted",
+		Data:    &types.Category{ID: id},
+	})
+}
+
+// checkCategory enforces the rules that struct tags cannot express: the
+// slug must be unique and the parent must exist without creating a cycle.
+// It returns the HTTP status to report alongside the error.
+func (h *Handler) checkCategory(c *types.Category) (int, error) {
+	if c.Slug == "" {
+		return http.StatusBadRequest, fmt.Errorf("slug cannot be empty")
+	}
+
+	other, err := h.store.GetCategoryBySlug(c.Slug)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if other != nil && other.ID != c.ID {
+		return http.StatusConflict, fmt.Errorf("category with slug %s already exists", c.Slug)
+	}
+
+	if c.ParentID == nil {
+		return 0, nil
+	}
+
+	parent, err := h.store.GetCategoryByID(*c.ParentID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if parent == nil {
+		return http.StatusBadRequest, fmt.Errorf("parent category not found")
+	}
+
+	if c.ID != 0 {
+		categories, err := h.store.ListCategories()
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		for _, id := range DescendantIDs(categories, c.ID) {
+			if id == *c.ParentID {
+				return http.StatusBadRequest, fmt.Errorf("category cannot be nested under itself or its subcategories")
+			}
+		}
+	}
+
+	return 0, nil
+}
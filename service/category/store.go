@@ -0,0 +1,108 @@
+// Package category manages the product category hierarchy. Categories are
+// stored as a flat adjacency list (each row points at its parent) and the
+// tree is assembled in memory when it is served.
+package category
+
+import (
+	"database/sql"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+const categoryColumns = "id, parentId, name, slug, description, createdAt"
+
+// Store implements types.CategoryStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanCategory(row sqlutil.Scanner) (*types.Category, error) {
+	c := new(types.Category)
+	var parentID sql.NullInt64
+	var description sql.NullString
+	err := row.Scan(&c.ID, &parentID, &c.Name, &c.Slug, &description, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.ParentID = sqlutil.NullableInt(parentID)
+	c.Description = description.String
+	return c, nil
+}
+
+// ListCategories returns every category ordered by name.
+func (s *Store) ListCategories() ([]*types.Category, error) {
+	rows, err := s.db.Query("SELECT " + categoryColumns + " FROM categories ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*types.Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// GetCategoryByID returns nil without an error when no category exists.
+func (s *Store) GetCategoryByID(id int) (*types.Category, error) {
+	c, err := scanCategory(s.db.QueryRow("SELECT "+categoryColumns+" FROM categories WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// GetCategoryBySlug returns nil without an error when no category exists.
+func (s *Store) GetCategoryBySlug(slug string) (*types.Category, error) {
+	c, err := scanCategory(s.db.QueryRow("SELECT "+categoryColumns+" FROM categories WHERE slug = ?", slug))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// CreateCategory inserts the category and sets its generated ID.
+func (s *Store) CreateCategory(c *types.Category) error {
+	result, err := s.db.Exec(
+		"INSERT INTO categories (parentId, name, slug, description) VALUES (?, ?, ?, ?)",
+		c.ParentID, c.Name, c.Slug, c.Description,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	c.ID = int(id)
+
+	return nil
+}
+
+// UpdateCategory overwrites the editable fields of an existing category.
+func (s *Store) UpdateCategory(c *types.Category) error {
+	_, err := s.db.Exec(
+		"UPDATE categories SET parentId = ?, name = ?, slug = ?, description = ? WHERE id = ?",
+		c.ParentID, c.Name, c.Slug, c.Description, c.ID,
+	)
+	return err
+}
+
+// DeleteCategory removes a category. The foreign key on parentId prevents
+// deleting a category that still has children.
+func (s *Store) DeleteCategory(id int) error {
+	_, err := s.db.Exec("DELETE FROM categories WHERE id = ?", id)
+	return err
+}
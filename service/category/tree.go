@@ -0,0 +1,77 @@
+package category
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// BuildTree links the flat category list into a forest and returns the
+// root categories. Categories whose parent is missing from the list are
+// treated as roots so that no category is silently dropped.
+func BuildTree(categories []*types.Category) []*types.Category {
+	byID := make(map[int]*types.Category, len(categories))
+	for _, c := range categories {
+		c.Children = nil
+		byID[c.ID] = c
+	}
+
+	roots := []*types.Category{}
+	for _, c := range categories {
+		if c.ParentID != nil {
+			if parent, ok := byID[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, c)
+				continue
+			}
+		}
+		roots = append(roots, c)
+	}
+
+	return roots
+}
+
+// DescendantIDs returns id followed by the IDs of every category below it.
+func DescendantIDs(categories []*types.Category, id int) []int {
+	children := make(map[int][]int)
+	for _, c := range categories {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+		}
+	}
+
+	ids := []int{id}
+	seen := map[int]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+
+	return ids
+}
+
+// Slugify converts a category name into a lowercase, hyphen separated slug
+// suitable for URLs, e.g. "Men's T-Shirts" becomes "mens-t-shirts".
+func Slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			hyphen = false
+		case r == '\'':
+			// drop apostrophes so "men's" becomes "mens"
+		default:
+			if !hyphen && b.Len() > 0 {
+				b.WriteRune('-')
+				hyphen = true
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
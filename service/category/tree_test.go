@@ -0,0 +1,66 @@
+package category
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func intPtr(i int) *int { return &i }
+
+// sampleCategories returns Clothing > (Shirts > T-Shirts, Pants) and Shoes.
+func sampleCategories() []*types.Category {
+	return []*types.Category{
+		{ID: 1, Name: "Clothing"},
+		{ID: 2, ParentID: intPtr(1), Name: "Shirts"},
+		{ID: 3, ParentID: intPtr(2), Name: "T-Shirts"},
+		{ID: 4, ParentID: intPtr(1), Name: "Pants"},
+		{ID: 5, Name: "Shoes"},
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	roots := BuildTree(sampleCategories())
+
+	if len(roots) != 2 {
+		t.Fatalf("expected 2 roots, got %d", len(roots))
+	}
+	if got := len(roots[0].Children); got != 2 {
+		t.Errorf("expected Clothing to have 2 children, got %d", got)
+	}
+	if got := roots[0].Children[0].Children[0].Name; got != "T-Shirts" {
+		t.Errorf("expected grandchild T-Shirts, got %s", got)
+	}
+}
+
+func TestDescendantIDs(t *testing.T) {
+	categories := sampleCategories()
+
+	t.Run("includes nested subcategories", func(t *testing.T) {
+		got := DescendantIDs(categories, 1)
+		if want := []int{1, 2, 4, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("leaf returns only itself", func(t *testing.T) {
+		got := DescendantIDs(categories, 5)
+		if want := []int{5}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+}
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Men's T-Shirts":   "mens-t-shirts",
+		"  Shoes & Boots ": "shoes-boots",
+		"Kids":             "kids",
+	}
+	for in, want := range cases {
+		if got := Slugify(in); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package coupon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// ErrNotApplicable is wrapped by the errors Apply returns for a coupon
+// that cannot be used on the cart.
+var ErrNotApplicable = errors.New("coupon does not apply")
+
+// Line is a cart line as seen by Apply. CategoryIDs are the categories of
+// the line's product.
+type Line struct {
+	ProductID   int
+	VariantID   int
+	CategoryIDs []int
+	UnitPrice   types.Money
+	Quantity    int
+}
+
+// Result is what a coupon takes off a cart.
+type Result struct {
+	Discount     types.Money
+	FreeShipping bool
+}
+
+// Apply prices coupon c on the cart at time now. It checks the coupon is
+// active, within its date window and that the cart meets the minimum
+// subtotal, then computes the discount on the eligible lines, never more
+// than their price. Percentages are rounded half to even to the cent. The
+// result does not depend on the order of the lines. Usage limits are
+// checked when the order is placed, by Redeem.
+func Apply(c *types.Coupon, lines []Line, now time.Time) (Result, error) {
+	if !c.Active {
+		return Result{}, fmt.Errorf("%w: coupon %s is not active", ErrNotApplicable, c.Code)
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return Result{}, fmt.Errorf("%w: coupon %s is not valid yet", ErrNotApplicable, c.Code)
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return Result{}, fmt.Errorf("%w: coupon %s has expired", ErrNotApplicable, c.Code)
+	}
+
+	var subtotal types.Money
+	for _, l := range lines {
+		subtotal = subtotal.Add(l.UnitPrice.Mul(l.Quantity))
+	}
+	if subtotal.Cmp(c.MinSubtotal) < 0 {
+		return Result{}, fmt.Errorf("%w: coupon %s requires a subtotal of at least %s", ErrNotApplicable, c.Code, c.MinSubtotal)
+	}
+
+	var eligible []Line
+	for _, l := range lines {
+		if eligibleLine(c, l) {
+			eligible = append(eligible, l)
+		}
+	}
+	if len(eligible) == 0 {
+		return Result{}, fmt.Errorf("%w: coupon %s does not cover any item in the cart", ErrNotApplicable, c.Code)
+	}
+
+	var eligibleTotal types.Money
+	for _, l := range eligible {
+		eligibleTotal = eligibleTotal.Add(l.UnitPrice.Mul(l.Quantity))
+	}
+
+	result := Result{Discount: types.NewMoney(0, subtotal.Currency)}
+	switch c.Kind {
+	case types.CouponPercentage:
+		// percentages have two decimals, so basis points are exact
+		result.Discount = eligibleTotal.MulRatio(int64(math.Round(c.PercentOff*100)), 10000)
+	case types.CouponFixedAmount:
+		if c.AmountOff == nil {
+			return Result{}, fmt.Errorf("coupon %s has no amount", c.Code)
+		}
+		result.Discount = *c.AmountOff
+		if result.Discount.Cmp(eligibleTotal) > 0 {
+			result.Discount = eligibleTotal
+		}
+	case types.CouponFreeShipping:
+		result.FreeShipping = true
+	case types.CouponBuyXGetY:
+		discount, err := freeUnits(c, eligible)
+		if err != nil {
+			return Result{}, err
+		}
+		result.Discount = discount
+	default:
+		return Result{}, fmt.Errorf("unknown coupon kind %q", c.Kind)
+	}
+	return result, nil
+}
+
+// eligibleLine reports whether the coupon discounts the line.
+func eligibleLine(c *types.Coupon, l Line) bool {
+	if len(c.ProductIDs) == 0 && len(c.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range c.ProductIDs {
+		if id == l.ProductID {
+			return true
+		}
+	}
+	for _, id := range c.CategoryIDs {
+		for _, cid := range l.CategoryIDs {
+			if id == cid {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// freeUnits returns the price of the units a buy-X-get-Y coupon makes
+// free. The eligible units are ranked from the most expensive down and
+// split into groups of BuyQuantity+GetQuantity; the last GetQuantity
+// units of each full group, the cheapest ones, are free.
+func freeUnits(c *types.Coupon, lines []Line) (types.Money, error) {
+	group := c.BuyQuantity + c.GetQuantity
+	if c.BuyQuantity <= 0 || c.GetQuantity <= 0 {
+		return types.Money{}, fmt.Errorf("coupon %s has no buy and get quantities", c.Code)
+	}
+
+	var units []Line
+	for _, l := range lines {
+		for range l.Quantity {
+			units = append(units, l)
+		}
+	}
+	if len(units) < group {
+		return types.Money{}, fmt.Errorf("%w: coupon %s requires %d eligible items", ErrNotApplicable, c.Code, group)
+	}
+
+	sort.SliceStable(units, func(i, j int) bool {
+		a, b := units[i], units[j]
+		if cmp := a.UnitPrice.Cmp(b.UnitPrice); cmp != 0 {
+			return cmp > 0
+		}
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		return a.VariantID < b.VariantID
+	})
+
+	var discount types.Money
+	for start := 0; start+group <= len(units); start += group {
+		for _, u := range units[start+c.BuyQuantity : start+group] {
+			discount = discount.Add(u.UnitPrice)
+		}
+	}
+	return discount, nil
+}
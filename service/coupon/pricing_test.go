@@ -0,0 +1,87 @@
+package coupon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/testutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestApply(t *testing.T) {
+	off := func(cents int64) *types.Money { m := testutil.USD(cents); return &m }
+
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	yesterday, tomorrow := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+
+	cart := []Line{
+		{ProductID: 1, CategoryIDs: []int{10}, UnitPrice: testutil.USD(1999), Quantity: 2},
+		{ProductID: 2, VariantID: 5, CategoryIDs: []int{20}, UnitPrice: testutil.USD(500), Quantity: 3},
+		{ProductID: 3, UnitPrice: testutil.USD(1250), Quantity: 1},
+	}
+
+	tests := []struct {
+		name         string
+		coupon       types.Coupon
+		lines        []Line
+		discount     types.Money
+		freeShipping bool
+		notApplies   bool
+	}{
+		{"percentage of cart", types.Coupon{Kind: types.CouponPercentage, PercentOff: 10}, cart, testutil.USD(675), false, false},
+		{"percentage of product", types.Coupon{Kind: types.CouponPercentage, PercentOff: 15, ProductIDs: []int{1}}, cart, testutil.USD(600), false, false},
+		{"percentage of category", types.Coupon{Kind: types.CouponPercentage, PercentOff: 50, CategoryIDs: []int{20}}, cart, testutil.USD(750), false, false},
+		{"fixed amount", types.Coupon{Kind: types.CouponFixedAmount, AmountOff: off(2000)}, cart, testutil.USD(2000), false, false},
+		{"fixed amount capped by eligible items", types.Coupon{Kind: types.CouponFixedAmount, AmountOff: off(2000), ProductIDs: []int{3}}, cart, testutil.USD(1250), false, false},
+		{"free shipping", types.Coupon{Kind: types.CouponFreeShipping}, cart, testutil.USD(0), true, false},
+		{"buy two get one", types.Coupon{Kind: types.CouponBuyXGetY, BuyQuantity: 2, GetQuantity: 1}, cart, testutil.USD(1750), false, false},
+		{"buy two get one in category", types.Coupon{Kind: types.CouponBuyXGetY, BuyQuantity: 2, GetQuantity: 1, CategoryIDs: []int{20}}, cart, testutil.USD(500), false, false},
+		{"buy two get one short of items", types.Coupon{Kind: types.CouponBuyXGetY, BuyQuantity: 2, GetQuantity: 1, ProductIDs: []int{1}}, cart, testutil.USD(0), false, true},
+		{"minimum subtotal met", types.Coupon{Kind: types.CouponFixedAmount, AmountOff: off(500), MinSubtotal: testutil.USD(6748)}, cart, testutil.USD(500), false, false},
+		{"minimum subtotal missed", types.Coupon{Kind: types.CouponFixedAmount, AmountOff: off(500), MinSubtotal: testutil.USD(6749)}, cart, testutil.USD(0), false, true},
+		{"no eligible item", types.Coupon{Kind: types.CouponPercentage, PercentOff: 10, ProductIDs: []int{4}}, cart, testutil.USD(0), false, true},
+		{"within window", types.Coupon{Kind: types.CouponFixedAmount, AmountOff: off(100), StartsAt: &yesterday, EndsAt: &tomorrow}, cart, testutil.USD(100), false, false},
+		{"not started", types.Coupon{Kind: types.CouponFixedAmount, AmountOff: off(100), StartsAt: &tomorrow}, cart, testutil.USD(0), false, true},
+		{"ended", types.Coupon{Kind: types.CouponFixedAmount, AmountOff: off(100), EndsAt: &now}, cart, testutil.USD(0), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.coupon.Code = "TEST"
+			tt.coupon.Active = true
+
+			got, err := Apply(&tt.coupon, tt.lines, now)
+			if tt.notApplies {
+				if !errors.Is(err, ErrNotApplicable) {
+					t.Fatalf("expected ErrNotApplicable, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Discount != tt.discount || got.FreeShipping != tt.freeShipping {
+				t.Errorf("expected discount %s, free shipping %v; got %s, %v", tt.discount, tt.freeShipping, got.Discount, got.FreeShipping)
+			}
+
+			// the line order must not change the result
+			reversed := make([]Line, len(tt.lines))
+			for i, l := range tt.lines {
+				reversed[len(tt.lines)-1-i] = l
+			}
+			again, err := Apply(&tt.coupon, reversed, now)
+			if err != nil || again != got {
+				t.Errorf("expected the same result for reversed lines, got %+v, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestApplyInactive(t *testing.T) {
+	c := &types.Coupon{Code: "OFF", Kind: types.CouponPercentage, PercentOff: 10}
+	_, err := Apply(c, []Line{{ProductID: 1, UnitPrice: types.NewMoney(1000, types.DefaultCurrency), Quantity: 1}}, time.Now())
+	if !errors.Is(err, ErrNotApplicable) {
+		t.Fatalf("expected ErrNotApplicable, got %v", err)
+	}
+}
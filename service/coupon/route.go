@@ -0,0 +1,242 @@
+// Package coupon models promotions: it lets admins manage coupons, prices
+// a coupon on a cart and records redemptions against orders.
+package coupon
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves coupon management to admins.
+type Handler struct {
+	store         types.CouponStore
+	productStore  types.ProductStore
+	categoryStore types.CategoryStore
+	userStore     types.UserStore
+}
+
+// NewHandler creates a new Handler. The product and category stores check
+// the products and categories a coupon is restricted to; the user store
+// authorizes admin-only operations.
+func NewHandler(store types.CouponStore, productStore types.ProductStore, categoryStore types.CategoryStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:         store,
+		productStore:  productStore,
+		categoryStore: categoryStore,
+		userStore:     userStore,
+	}
+}
+
+// RegisterRoutes attaches coupon routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/coupons", auth.RequireAdmin(h.handleListCoupons, h.userStore)).Methods("GET")
+	router.HandleFunc("/coupons", auth.RequireAdmin(h.handleCreateCoupon, h.userStore)).Methods("POST")
+	router.HandleFunc("/coupons/{id}", auth.RequireAdmin(h.handleGetCoupon, h.userStore)).Methods("GET")
+	router.HandleFunc("/coupons/{id}/activate", auth.RequireAdmin(h.handleSetActive(true), h.userStore)).Methods("POST")
+	router.HandleFunc("/coupons/{id}/deactivate", auth.RequireAdmin(h.handleSetActive(false), h.userStore)).Methods("POST")
+}
+
+func (h *Handler) handleListCoupons(w http.ResponseWriter, r *http.Request) {
+	page := utils.ParsePagination(r)
+	coupons, total, err := h.store.ListCoupons(page)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list coupons: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListCouponsResponse{
+		Message: "success",
+		Data:    coupons,
+		Meta:    utils.NewPageMeta(page, total),
+	})
+}
+
+func (h *Handler) handleGetCoupon(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.couponFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.CouponResponse{
+		Message: "success",
+		Data:    c,
+	})
+}
+
+func (h *Handler) handleCreateCoupon(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateCouponPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := checkKind(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	existing, err := h.store.GetCouponByCode(payload.Code)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if existing != nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("coupon with code %s already exists", existing.Code))
+		return
+	}
+
+	if status, err := h.checkRules(payload); err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	c := &types.Coupon{
+		Code:         payload.Code,
+		Kind:         payload.Kind,
+		PercentOff:   payload.PercentOff,
+		AmountOff:    payload.AmountOff,
+		BuyQuantity:  payload.BuyQuantity,
+		GetQuantity:  payload.GetQuantity,
+		MinSubtotal:  payload.MinSubtotal,
+		ProductIDs:   payload.ProductIDs,
+		CategoryIDs:  payload.CategoryIDs,
+		StartsAt:     payload.StartsAt,
+		EndsAt:       payload.EndsAt,
+		UsageLimit:   payload.UsageLimit,
+		PerUserLimit: payload.PerUserLimit,
+		Active:       true,
+	}
+	if err := h.store.CreateCoupon(c); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to create coupon: %v", err))
+		return
+	}
+
+	created, err := h.store.GetCouponByID(c.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.CouponResponse{
+		Message: "coupon created",
+		Data:    created,
+	})
+}
+
+// handleSetActive returns a handler that enables or disables a coupon.
+func (h *Handler) handleSetActive(active bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := h.couponFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		if c.Active != active {
+			if err := h.store.SetCouponActive(c.ID, active); err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, err)
+				return
+			}
+			c.Active = active
+		}
+
+		message := "coupon activated"
+		if !active {
+			message = "coupon deactivated"
+		}
+		utils.WriteJson(w, http.StatusOK, types.CouponResponse{
+			Message: message,
+			Data:    c,
+		})
+	}
+}
+
+// couponFromPath loads the coupon named by the {id} path variable,
+// writing the error response when there is none.
+func (h *Handler) couponFromPath(w http.ResponseWriter, r *http.Request) (*types.Coupon, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	c, err := h.store.GetCouponByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if c == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("coupon not found"))
+		return nil, false
+	}
+	return c, true
+}
+
+// checkKind enforces the rules that depend on the coupon kind, which
+// struct tags cannot express.
+func checkKind(p types.CreateCouponPayload) error {
+	switch p.Kind {
+	case types.CouponPercentage:
+		if p.PercentOff <= 0 {
+			return fmt.Errorf("a percentage coupon needs percentOff")
+		}
+		// stored with two decimals
+		if basis := p.PercentOff * 100; math.Abs(basis-math.Round(basis)) > 1e-9 {
+			return fmt.Errorf("percentOff takes at most two decimals")
+		}
+	case types.CouponFixedAmount:
+		if p.AmountOff == nil {
+			return fmt.Errorf("a fixed-amount coupon needs amountOff")
+		}
+	case types.CouponBuyXGetY:
+		if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+			return fmt.Errorf("a buy-x-get-y coupon needs buyQuantity and getQuantity")
+		}
+	}
+	if p.Kind != types.CouponPercentage && p.PercentOff != 0 {
+		return fmt.Errorf("percentOff only applies to percentage coupons")
+	}
+	if p.Kind != types.CouponFixedAmount && p.AmountOff != nil {
+		return fmt.Errorf("amountOff only applies to fixed-amount coupons")
+	}
+	if p.Kind != types.CouponBuyXGetY && (p.BuyQuantity != 0 || p.GetQuantity != 0) {
+		return fmt.Errorf("buyQuantity and getQuantity only apply to buy-x-get-y coupons")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("endsAt must be after startsAt")
+	}
+	return nil
+}
+
+// checkRules checks the products and categories the coupon is restricted
+// to exist. It returns the HTTP status to report alongside the error.
+func (h *Handler) checkRules(p types.CreateCouponPayload) (int, error) {
+	for _, id := range p.ProductIDs {
+		product, err := h.productStore.GetProductByID(id)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if product == nil {
+			return http.StatusBadRequest, fmt.Errorf("product %d not found", id)
+		}
+	}
+	for _, id := range p.CategoryIDs {
+		category, err := h.categoryStore.GetCategoryByID(id)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if category == nil {
+			return http.StatusBadRequest, fmt.Errorf("category %d not found", id)
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,254 @@
+package coupon
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// couponColumns lists the columns read by scanCoupon, in scan order. The
+// last one counts the redemptions of orders that were not cancelled.
+const couponColumns = "id, code, kind, value, buyQuantity, getQuantity, minSubtotal, startsAt, endsAt, usageLimit, perUserLimit, active, createdAt, " +
+	"(SELECT COUNT(*) FROM coupon_redemptions r WHERE r.couponId = coupons.id AND r.releasedAt IS NULL)"
+
+// Store implements types.CouponStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanCoupon(row sqlutil.Scanner) (*types.Coupon, error) {
+	c := new(types.Coupon)
+	var value types.Money
+	var startsAt, endsAt sql.NullTime
+	var usageLimit, perUserLimit sql.NullInt64
+	err := row.Scan(
+		&c.ID,
+		&c.Code,
+		&c.Kind,
+		&value,
+		&c.BuyQuantity,
+		&c.GetQuantity,
+		&c.MinSubtotal,
+		&startsAt,
+		&endsAt,
+		&usageLimit,
+		&perUserLimit,
+		&c.Active,
+		&c.CreatedAt,
+		&c.Redemptions,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// the value column holds the percentage or the amount off
+	switch c.Kind {
+	case types.CouponPercentage:
+		c.PercentOff = float64(value.Amount) / 100
+	case types.CouponFixedAmount:
+		c.AmountOff = &value
+	}
+	if startsAt.Valid {
+		c.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		c.EndsAt = &endsAt.Time
+	}
+	c.UsageLimit = sqlutil.NullableInt(usageLimit)
+	c.PerUserLimit = sqlutil.NullableInt(perUserLimit)
+	return c, nil
+}
+
+// ListCoupons returns one page of coupons, newest first, together with
+// the total number of coupons. The product and category rules are not
+// loaded.
+func (s *Store) ListCoupons(page types.Pagination) ([]*types.Coupon, int, error) {
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM coupons").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query("SELECT "+couponColumns+" FROM coupons ORDER BY id DESC LIMIT ? OFFSET ?", page.PageSize, page.Offset())
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	coupons := []*types.Coupon{}
+	for rows.Next() {
+		c, err := scanCoupon(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		coupons = append(coupons, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return coupons, total, nil
+}
+
+// GetCouponByID returns the coupon with its rules, or nil if none exists.
+func (s *Store) GetCouponByID(id int) (*types.Coupon, error) {
+	return s.getCoupon("id = ?", id)
+}
+
+// GetCouponByCode returns the coupon with its rules, or nil if none
+// exists. Codes are matched case-insensitively.
+func (s *Store) GetCouponByCode(code string) (*types.Coupon, error) {
+	return s.getCoupon("code = ?", strings.ToUpper(code))
+}
+
+func (s *Store) getCoupon(where string, args ...any) (*types.Coupon, error) {
+	c, err := scanCoupon(s.db.QueryRow("SELECT "+couponColumns+" FROM coupons WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ProductIDs, err = s.queryIDs("SELECT productId FROM coupon_products WHERE couponId = ? ORDER BY productId", c.ID); err != nil {
+		return nil, err
+	}
+	if c.CategoryIDs, err = s.queryIDs("SELECT categoryId FROM coupon_categories WHERE couponId = ? ORDER BY categoryId", c.ID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Store) queryIDs(query string, args ...any) ([]int, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CreateCoupon inserts the coupon with its product and category rules in
+// one transaction. The code is stored upper-cased.
+func (s *Store) CreateCoupon(c *types.Coupon) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c.Code = strings.ToUpper(c.Code)
+	value := types.NewMoney(int64(math.Round(c.PercentOff*100)), types.DefaultCurrency)
+	if c.AmountOff != nil {
+		value = *c.AmountOff
+	}
+	result, err := tx.Exec(
+		`INSERT INTO coupons (code, kind, value, buyQuantity, getQuantity, minSubtotal, startsAt, endsAt, usageLimit, perUserLimit, active)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.Code, c.Kind, value, c.BuyQuantity, c.GetQuantity, c.MinSubtotal, c.StartsAt, c.EndsAt, c.UsageLimit, c.PerUserLimit, c.Active,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	c.ID = int(id)
+
+	for _, productID := range c.ProductIDs {
+		if _, err := tx.Exec("INSERT IGNORE INTO coupon_products (couponId, productId) VALUES (?, ?)", c.ID, productID); err != nil {
+			return err
+		}
+	}
+	for _, categoryID := range c.CategoryIDs {
+		if _, err := tx.Exec("INSERT IGNORE INTO coupon_categories (couponId, categoryId) VALUES (?, ?)", c.ID, categoryID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetCouponActive enables or disables a coupon. Orders already placed
+// with it keep their discount.
+func (s *Store) SetCouponActive(id int, active bool) error {
+	_, err := s.db.Exec("UPDATE coupons SET active = ? WHERE id = ?", active, id)
+	return err
+}
+
+// Redeem records the redemption of the order's coupon inside tx, the
+// transaction creating the order. The coupon row is locked so concurrent
+// checkouts see each other's redemptions: an error wrapping
+// types.ErrCouponUnavailable is returned when the coupon was deactivated,
+// is outside its date window or reached a usage limit. Orders without a
+// coupon are left alone.
+func Redeem(tx *sql.Tx, order *types.Order) error {
+	if order.CouponCode == nil {
+		return nil
+	}
+
+	var id int
+	var usable bool
+	var usageLimit, perUserLimit sql.NullInt64
+	err := tx.QueryRow(
+		`SELECT id, usageLimit, perUserLimit,
+		        active AND (startsAt IS NULL OR startsAt <= CURRENT_TIMESTAMP) AND (endsAt IS NULL OR endsAt > CURRENT_TIMESTAMP)
+		   FROM coupons WHERE code = ?
+		    FOR UPDATE`,
+		strings.ToUpper(*order.CouponCode),
+	).Scan(&id, &usageLimit, &perUserLimit, &usable)
+	if err == sql.ErrNoRows || (err == nil && !usable) {
+		return fmt.Errorf("%w: %s", types.ErrCouponUnavailable, *order.CouponCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	var redeemed, redeemedByUser int
+	err = tx.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(userId = ?), 0)
+		   FROM coupon_redemptions WHERE couponId = ? AND releasedAt IS NULL`,
+		order.UserID, id,
+	).Scan(&redeemed, &redeemedByUser)
+	if err != nil {
+		return err
+	}
+	if usageLimit.Valid && redeemed >= int(usageLimit.Int64) {
+		return fmt.Errorf("%w: %s reached its usage limit", types.ErrCouponUnavailable, *order.CouponCode)
+	}
+	if perUserLimit.Valid && redeemedByUser >= int(perUserLimit.Int64) {
+		return fmt.Errorf("%w: you already used %s %d times", types.ErrCouponUnavailable, *order.CouponCode, perUserLimit.Int64)
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO coupon_redemptions (couponId, orderId, userId, discount) VALUES (?, ?, ?, ?)",
+		id, order.ID, order.UserID, order.Discount,
+	)
+	return err
+}
+
+// Release gives back the coupon redemption of a cancelled order inside
+// tx, so it no longer counts towards the coupon's limits.
+func Release(tx *sql.Tx, orderID int) error {
+	_, err := tx.Exec(
+		"UPDATE coupon_redemptions SET releasedAt = CURRENT_TIMESTAMP WHERE orderId = ? AND releasedAt IS NULL",
+		orderID,
+	)
+	return err
+}
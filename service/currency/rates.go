@@ -0,0 +1,190 @@
+// Package currency converts prices between currencies. It keeps the
+// exchange rates table, loaded from a CSV file by admins or the products
+// command, and works out the currency a request wants prices shown in.
+package currency
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// ErrNoRate is wrapped by the errors returned for a currency missing from
+// the exchange rates table.
+var ErrNoRate = errors.New("no exchange rate")
+
+// rateDecimals and rateDigits bound a rate to the DECIMAL(18, 8) column
+// that stores it.
+const (
+	rateDecimals = 8
+	rateDigits   = 18
+)
+
+// Rates holds the exchange rates table by currency: the number of units
+// of the currency one unit of types.DefaultCurrency buys. The default
+// currency is always present with a rate of 1.
+type Rates map[string]*big.Rat
+
+// NewRates builds Rates from the rows of the exchange rates table.
+func NewRates(list []*types.ExchangeRate) (Rates, error) {
+	rates := Rates{types.DefaultCurrency: big.NewRat(1, 1)}
+	for _, r := range list {
+		rate, err := parseRate(r.Rate)
+		if err != nil {
+			return nil, fmt.Errorf("exchange rate of %s: %v", r.Currency, err)
+		}
+		rates[r.Currency] = rate
+	}
+	return rates, nil
+}
+
+// Load reads the exchange rates table from store.
+func Load(store types.ExchangeRateStore) (Rates, error) {
+	list, err := store.ListExchangeRates()
+	if err != nil {
+		return nil, err
+	}
+	return NewRates(list)
+}
+
+// Has reports whether currency has a rate.
+func (r Rates) Has(currency string) bool {
+	_, ok := r[currency]
+	return ok
+}
+
+// Convert returns m in currency, going through the default currency and
+// rounding half to even to the minor unit once. An error wrapping
+// ErrNoRate is returned when either currency has no rate.
+func (r Rates) Convert(m types.Money, currency string) (types.Money, error) {
+	if m.Currency == currency {
+		return m, nil
+	}
+	from, ok := r[m.Currency]
+	if !ok {
+		return types.Money{}, fmt.Errorf("%w for %s", ErrNoRate, m.Currency)
+	}
+	to, ok := r[currency]
+	if !ok {
+		return types.Money{}, fmt.Errorf("%w for %s", ErrNoRate, currency)
+	}
+	return m.Convert(currency, new(big.Rat).Quo(to, from)), nil
+}
+
+// Snapshot returns the rates of the given currencies, sorted by currency
+// and without duplicates, as recorded with an order. Currencies without a
+// rate are left out.
+func (r Rates) Snapshot(currencies ...string) []*types.ExchangeRate {
+	seen := make(map[string]bool)
+	var snapshot []*types.ExchangeRate
+	for _, c := range currencies {
+		rate, ok := r[c]
+		if !ok || seen[c] {
+			continue
+		}
+		seen[c] = true
+		snapshot = append(snapshot, &types.ExchangeRate{Currency: c, Rate: formatRate(rate)})
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].Currency < snapshot[j].Currency })
+	return snapshot
+}
+
+// parseRate parses a positive decimal rate that fits the rate column.
+func parseRate(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if !isDigits(whole) || (frac != "" && !isDigits(frac)) {
+		return nil, fmt.Errorf("invalid rate %q", s)
+	}
+	if len(frac) > rateDecimals || len(strings.TrimLeft(whole, "0")) > rateDigits-rateDecimals {
+		return nil, fmt.Errorf("rate %q does not fit %d digits with %d decimals", s, rateDigits, rateDecimals)
+	}
+	rate, _ := new(big.Rat).SetString(whole + "." + frac + "0")
+	if rate.Sign() <= 0 {
+		return nil, fmt.Errorf("rate %q must be positive", s)
+	}
+	return rate, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// formatRate formats a rate read by parseRate as a decimal without
+// trailing zeros.
+func formatRate(rate *big.Rat) string {
+	s := rate.FloatString(rateDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// ParseRates reads an exchange rates file: CSV with a currency,rate header
+// and one row per currency, such as "EUR,0.92". The rates are relative to
+// types.DefaultCurrency; a row for the default currency itself must have a
+// rate of 1 and is dropped. The whole file is rejected on the first bad
+// row so the table is never left half updated.
+func ParseRates(r io.Reader) ([]*types.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("the rates file is empty")
+		}
+		return nil, err
+	}
+	if strings.ToLower(strings.TrimPrefix(header[0], "\ufeff")) != "currency" || strings.ToLower(header[1]) != "rate" {
+		return nil, fmt.Errorf("the rates file must start with a currency,rate header")
+	}
+
+	seen := make(map[string]bool)
+	var rates []*types.ExchangeRate
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		code := strings.ToUpper(strings.TrimSpace(record[0]))
+		if !types.ValidCurrency(code) {
+			return nil, fmt.Errorf("line %d: unsupported currency %q", line, record[0])
+		}
+		if seen[code] {
+			return nil, fmt.Errorf("line %d: duplicate currency %s", line, code)
+		}
+		seen[code] = true
+
+		rate, err := parseRate(record[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if code == types.DefaultCurrency {
+			if rate.Cmp(big.NewRat(1, 1)) != 0 {
+				return nil, fmt.Errorf("line %d: the rate of %s must be 1", line, code)
+			}
+			continue
+		}
+		rates = append(rates, &types.ExchangeRate{Currency: code, Rate: formatRate(rate)})
+	}
+	return rates, nil
+}
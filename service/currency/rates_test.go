@@ -0,0 +1,93 @@
+package currency
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestParseRates(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string // currency=rate pairs, or "error"
+	}{
+		{"rates", "currency,rate\nEUR,0.92\njpy, 151.20\n", "EUR=0.92 JPY=151.2"},
+		{"default currency dropped", "\ufeffCurrency,Rate\nUSD,1.000\nGBP,0.79\n", "GBP=0.79"},
+		{"no rows", "currency,rate\n", ""},
+		{"empty file", "", "error"},
+		{"missing header", "EUR,0.92\n", "error"},
+		{"default currency not 1", "currency,rate\nUSD,1.1\n", "error"},
+		{"duplicate", "currency,rate\nEUR,0.92\nEUR,0.93\n", "error"},
+		{"three decimal currency", "currency,rate\nKWD,0.31\n", "error"},
+		{"bad code", "currency,rate\nEURO,0.92\n", "error"},
+		{"zero rate", "currency,rate\nEUR,0\n", "error"},
+		{"negative rate", "currency,rate\nEUR,-0.92\n", "error"},
+		{"too many decimals", "currency,rate\nEUR,0.123456789\n", "error"},
+		{"extra column", "currency,rate\nEUR,0.92,x\n", "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := ParseRates(strings.NewReader(tt.input))
+			if tt.want == "error" {
+				if err == nil {
+					t.Errorf("expected an error, got %v", rates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range rates {
+				got = append(got, r.Currency+"="+r.Rate)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, strings.Join(got, " "))
+			}
+		})
+	}
+}
+
+func TestRatesConvert(t *testing.T) {
+	rates, err := NewRates([]*types.ExchangeRate{
+		{Currency: "EUR", Rate: "0.92"},
+		{Currency: "JPY", Rate: "151.2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		amount   int64
+		from, to string
+		want     int64
+	}{
+		{1999, "USD", "EUR", 1839},
+		{1999, "USD", "JPY", 3022},
+		{1500, "JPY", "USD", 992},
+		{1000, "EUR", "JPY", 1643},
+		{1000, "EUR", "EUR", 1000},
+		// 0.50 * 0.92 = 0.46, 0.25 * 0.92 = 0.23
+		{50, "USD", "EUR", 46},
+		{25, "USD", "EUR", 23},
+	}
+
+	for _, tt := range tests {
+		got, err := rates.Convert(types.NewMoney(tt.amount, tt.from), tt.to)
+		if err != nil || got.Amount != tt.want || got.Currency != tt.to {
+			t.Errorf("%d %s in %s: expected %d, got %+v, %v", tt.amount, tt.from, tt.to, tt.want, got, err)
+		}
+	}
+
+	if _, err := rates.Convert(types.NewMoney(100, "USD"), "GBP"); err == nil {
+		t.Error("expected an error for a currency without a rate")
+	}
+
+	snapshot := rates.Snapshot("JPY", "USD", "JPY", "GBP")
+	if len(snapshot) != 2 || snapshot[0].Currency != "JPY" || snapshot[0].Rate != "151.2" || snapshot[1].Rate != "1" {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+}
@@ -0,0 +1,106 @@
+package currency
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// maxRatesBytes bounds the size of an uploaded rates file.
+const maxRatesBytes = 1 << 20
+
+// Handler serves the exchange rates table.
+type Handler struct {
+	store     types.ExchangeRateStore
+	userStore types.UserStore
+}
+
+// NewHandler creates a new Handler. The user store authorizes admin-only
+// operations.
+func NewHandler(store types.ExchangeRateStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+// RegisterRoutes attaches exchange rate routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/exchange-rates", auth.RequireToken(h.handleListRates)).Methods("GET")
+	router.HandleFunc("/exchange-rates", auth.RequireAdmin(h.handleReplaceRates, h.userStore)).Methods("PUT")
+}
+
+func (h *Handler) handleListRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.store.ListExchangeRates()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list exchange rates: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ExchangeRatesResponse{
+		Message: "success",
+		Data:    rates,
+	})
+}
+
+// handleReplaceRates replaces the exchange rates table with the rates file
+// sent as the request body; see ParseRates for its format.
+func (h *Handler) handleReplaceRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := ParseRates(http.MaxBytesReader(w, r.Body, maxRatesBytes))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.ReplaceExchangeRates(rates); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to save exchange rates: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ExchangeRatesResponse{
+		Message: "exchange rates replaced",
+		Data:    rates,
+	})
+}
+
+// FromRequest loads the exchange rates and works out the currency the
+// caller wants prices shown in: the ?currency= query parameter, else the
+// preferred currency of the authenticated user when it has a rate, else
+// none, returned as "". It writes the error response and returns false
+// when the requested currency is invalid or has no rate.
+func FromRequest(w http.ResponseWriter, r *http.Request, store types.ExchangeRateStore, users types.UserStore) (string, Rates, bool) {
+	rates, err := Load(store)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return "", nil, false
+	}
+
+	if v := r.URL.Query().Get("currency"); v != "" {
+		code := strings.ToUpper(v)
+		if !types.ValidCurrency(code) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid currency %q", v))
+			return "", nil, false
+		}
+		if !rates.Has(code) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("%w for %s", ErrNoRate, code))
+			return "", nil, false
+		}
+		return code, rates, true
+	}
+
+	// a preference left without a rate by a new rates file is ignored
+	// rather than failing every request of the user
+	if userID, ok := auth.UserIDFromContext(r.Context()); ok && users != nil {
+		u, err := users.GetUserByID(userID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return "", nil, false
+		}
+		if u != nil && u.Currency != nil && rates.Has(*u.Currency) {
+			return *u.Currency, rates, true
+		}
+	}
+	return "", rates, true
+}
@@ -0,0 +1,77 @@
+package currency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/config"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// rateStore serves a fixed rates table and users with a preference.
+type rateStore struct {
+	types.ExchangeRateStore
+	types.UserStore
+	preferences map[int]string
+}
+
+func (s *rateStore) ListExchangeRates() ([]*types.ExchangeRate, error) {
+	return []*types.ExchangeRate{{Currency: "EUR", Rate: "0.92"}, {Currency: "JPY", Rate: "151.2"}}, nil
+}
+
+func (s *rateStore) GetUserByID(id int) (*types.User, error) {
+	u := &types.User{ID: id}
+	if c, ok := s.preferences[id]; ok {
+		u.Currency = &c
+	}
+	return u, nil
+}
+
+func TestFromRequest(t *testing.T) {
+	store := &rateStore{preferences: map[int]string{2: "JPY", 3: "GBP"}}
+
+	tests := []struct {
+		name   string
+		userID int
+		query  string
+		want   string
+		status int
+	}{
+		{"nothing asked", 1, "", "", http.StatusOK},
+		{"query", 1, "?currency=eur", "EUR", http.StatusOK},
+		{"query over preference", 2, "?currency=EUR", "EUR", http.StatusOK},
+		{"preference", 2, "", "JPY", http.StatusOK},
+		{"preference without rate", 3, "", "", http.StatusOK},
+		{"query without rate", 1, "?currency=GBP", "", http.StatusBadRequest},
+		{"invalid query", 1, "?currency=euro", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := auth.CreateJWT([]byte(config.Envs.JWTSecret), tt.userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got string
+			handler := auth.RequireToken(func(w http.ResponseWriter, r *http.Request) {
+				code, _, ok := FromRequest(w, r, store, store)
+				if ok {
+					got = code
+					w.WriteHeader(http.StatusOK)
+				}
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/products"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			if rr.Code != tt.status || got != tt.want {
+				t.Errorf("expected %d %q, got %d %q: %s", tt.status, tt.want, rr.Code, got, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package currency
+
+import (
+	"database/sql"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// RateColumn selects a rate column as a decimal without trailing zeros,
+// the way rates are written.
+const RateColumn = "TRIM(TRAILING '.' FROM TRIM(TRAILING '0' FROM rate))"
+
+// Store implements types.ExchangeRateStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// ListExchangeRates returns the exchange rates table ordered by currency.
+// The default currency is implicit and not listed.
+func (s *Store) ListExchangeRates() ([]*types.ExchangeRate, error) {
+	rows, err := s.db.Query("SELECT currency, " + RateColumn + ", updatedAt FROM exchange_rates ORDER BY currency")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*types.ExchangeRate{}
+	for rows.Next() {
+		r := new(types.ExchangeRate)
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&r.Currency, &r.Rate, &updatedAt); err != nil {
+			return nil, err
+		}
+		if updatedAt.Valid {
+			r.UpdatedAt = &updatedAt.Time
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
+
+// ReplaceExchangeRates replaces the whole table with rates in one
+// transaction, so checkouts never see a mix of old and new rates.
+func (s *Store) ReplaceExchangeRates(rates []*types.ExchangeRate) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM exchange_rates"); err != nil {
+		return err
+	}
+	for _, r := range rates {
+		if _, err := tx.Exec("INSERT INTO exchange_rates (currency, rate) VALUES (?, ?)", r.Currency, r.Rate); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RecordOrderRates stores the rates snapshot of an order inside tx, the
+// transaction creating the order.
+func RecordOrderRates(tx *sql.Tx, order *types.Order) error {
+	for _, r := range order.ExchangeRates {
+		if _, err := tx.Exec(
+			"INSERT INTO order_exchange_rates (orderId, currency, rate) VALUES (?, ?, ?)",
+			order.ID, r.Currency, r.Rate,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Package giftcard issues prepaid gift cards and moves their balance:
+// orders redeem them at checkout, cancelled orders release what they
+// redeemed and refunds credit it back. Every change to a balance is
+// recorded in the card's ledger in the same transaction.
+package giftcard
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// codeAlphabet has the 32 characters of gift card codes, leaving out the
+// ones easily mistaken for another, such as O and 0.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// codeLength is the number of characters of a code, 80 random bits.
+const codeLength = 16
+
+// newCode returns a random code, in groups of four characters such as
+// "K7QD-2MXA-H9TC-WP4E".
+func newCode() (string, error) {
+	b := make([]byte, codeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	var code strings.Builder
+	for i, c := range b {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(codeAlphabet[c%byte(len(codeAlphabet))])
+	}
+	return code.String(), nil
+}
+
+// normalize returns code as stored: upper-cased, without the dashes and
+// spaces customers may type.
+func normalize(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
+
+// hashCode returns the hash a card is looked up by.
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(normalize(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// last4 returns the last four characters of code.
+func last4(code string) string {
+	code = normalize(code)
+	return code[max(len(code)-4, 0):]
+}
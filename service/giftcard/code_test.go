@@ -0,0 +1,39 @@
+package giftcard
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewCode(t *testing.T) {
+	format := regexp.MustCompile(`^[` + codeAlphabet + `]{4}(-[` + codeAlphabet + `]{4}){3}$`)
+	seen := make(map[string]bool)
+	for range 100 {
+		code, err := newCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !format.MatchString(code) {
+			t.Fatalf("unexpected code %q", code)
+		}
+		if seen[code] {
+			t.Fatalf("code %q issued twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashCode(t *testing.T) {
+	want := hashCode("K7QD-2MXA-H9TC-WP4E")
+	for _, code := range []string{"K7QD2MXAH9TCWP4E", "k7qd-2mxa-h9tc-wp4e", " K7QD 2MXA H9TC WP4E "} {
+		if got := hashCode(code); got != want {
+			t.Errorf("expected %q to hash like the issued code", code)
+		}
+	}
+	if hashCode("K7QD-2MXA-H9TC-WP4F") == want {
+		t.Error("expected another code to hash differently")
+	}
+	if got := last4("k7qd-2mxa-h9tc-wp4e"); got != "WP4E" {
+		t.Errorf("expected last4 WP4E, got %s", got)
+	}
+}
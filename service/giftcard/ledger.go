@@ -0,0 +1,60 @@
+package giftcard
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// card is a gift card locked for a change of its balance.
+type card struct {
+	Balance   types.Money
+	Active    bool
+	ExpiresAt *time.Time
+}
+
+// entry is a change to the balance of a gift card, as added to its ledger.
+type entry struct {
+	Kind    string
+	Amount  types.Money // negative when taken off the card
+	Balance types.Money // the balance after the change
+}
+
+// usable tells whether c can be redeemed at now.
+func (c card) usable(now time.Time) bool {
+	return c.Active && (c.ExpiresAt == nil || c.ExpiresAt.After(now))
+}
+
+// redeemEntry returns the entry taking amount off c at now. An error
+// wrapping types.ErrGiftCardUnavailable is returned when c is deactivated,
+// expired, in another currency or holds less than amount.
+func redeemEntry(c card, amount types.Money, now time.Time) (entry, error) {
+	if !c.usable(now) {
+		return entry{}, types.ErrGiftCardUnavailable
+	}
+	if c.Balance.Currency != amount.Currency {
+		return entry{}, fmt.Errorf("%w: the gift card is in %s, not %s", types.ErrGiftCardUnavailable, c.Balance.Currency, amount.Currency)
+	}
+	if c.Balance.Cmp(amount) < 0 {
+		return entry{}, fmt.Errorf("%w: the gift card holds %s %s", types.ErrGiftCardUnavailable, c.Balance, c.Balance.Currency)
+	}
+	taken := types.NewMoney(-amount.Amount, amount.Currency)
+	return entry{Kind: types.GiftCardRedeem, Amount: taken, Balance: c.Balance.Add(taken)}, nil
+}
+
+// releaseEntry returns the entry giving back to c what a cancelled order
+// redeemed, whether c is still usable or not.
+func releaseEntry(c card, redeemed types.Money) entry {
+	return entry{Kind: types.GiftCardRelease, Amount: redeemed, Balance: c.Balance.Add(redeemed)}
+}
+
+// refundEntry returns the entry crediting amount back onto c for an order
+// that redeemed redeemed and already got refunded back. An error wrapping
+// types.ErrRefundTooLarge is returned when amount is more than is left.
+func refundEntry(c card, redeemed, refunded, amount types.Money) (entry, error) {
+	if left := redeemed.Sub(refunded); amount.Cmp(left) > 0 {
+		return entry{}, fmt.Errorf("%w: %s left to credit to the gift card", types.ErrRefundTooLarge, left)
+	}
+	return entry{Kind: types.GiftCardRefund, Amount: amount, Balance: c.Balance.Add(amount)}, nil
+}
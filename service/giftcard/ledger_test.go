@@ -0,0 +1,85 @@
+package giftcard
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/testutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestRedeemEntry(t *testing.T) {
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	yesterday, tomorrow := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+
+	tests := []struct {
+		name        string
+		card        card
+		amount      types.Money
+		balance     types.Money
+		unavailable bool
+	}{
+		{"partial redemption", card{Balance: testutil.USD(5000), Active: true}, testutil.USD(1999), testutil.USD(3001), false},
+		{"whole balance", card{Balance: testutil.USD(5000), Active: true, ExpiresAt: &tomorrow}, testutil.USD(5000), testutil.USD(0), false},
+		{"more than the balance", card{Balance: testutil.USD(5000), Active: true}, testutil.USD(5001), types.Money{}, true},
+		{"expired", card{Balance: testutil.USD(5000), Active: true, ExpiresAt: &yesterday}, testutil.USD(100), types.Money{}, true},
+		{"expiring now", card{Balance: testutil.USD(5000), Active: true, ExpiresAt: &now}, testutil.USD(100), types.Money{}, true},
+		{"inactive", card{Balance: testutil.USD(5000)}, testutil.USD(100), types.Money{}, true},
+		{"another currency", card{Balance: types.NewMoney(5000, "EUR"), Active: true}, testutil.USD(100), types.Money{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := redeemEntry(tt.card, tt.amount, now)
+			if tt.unavailable {
+				if !errors.Is(err, types.ErrGiftCardUnavailable) {
+					t.Fatalf("expected ErrGiftCardUnavailable, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Kind != types.GiftCardRedeem || e.Amount != types.NewMoney(-tt.amount.Amount, types.DefaultCurrency) {
+				t.Errorf("expected a redeem entry of -%s, got %s of %s", tt.amount, e.Kind, e.Amount)
+			}
+			if e.Balance != tt.balance {
+				t.Errorf("expected balance %s, got %s", tt.balance, e.Balance)
+			}
+		})
+	}
+}
+
+func TestReleaseEntry(t *testing.T) {
+	yesterday := time.Date(2026, 3, 13, 12, 0, 0, 0, time.UTC)
+
+	// A cancelled order gets its redemption back even when the card was
+	// deactivated or expired in the meantime.
+	e := releaseEntry(card{Balance: testutil.USD(3001), ExpiresAt: &yesterday}, testutil.USD(1999))
+	if e.Kind != types.GiftCardRelease || e.Amount != testutil.USD(1999) || e.Balance != testutil.USD(5000) {
+		t.Errorf("expected a release of 19.99 back to 50.00, got %s of %s to %s", e.Kind, e.Amount, e.Balance)
+	}
+}
+
+func TestRefundEntry(t *testing.T) {
+	c := card{Balance: testutil.USD(3001)}
+
+	e, err := refundEntry(c, testutil.USD(1999), testutil.USD(0), testutil.USD(999))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Kind != types.GiftCardRefund || e.Amount != testutil.USD(999) || e.Balance != testutil.USD(4000) {
+		t.Errorf("expected a refund of 9.99 to 40.00, got %s of %s to %s", e.Kind, e.Amount, e.Balance)
+	}
+
+	if _, err := refundEntry(c, testutil.USD(1999), testutil.USD(999), testutil.USD(1000)); err != nil {
+		t.Errorf("expected the rest of the redemption to be refunded, got %v", err)
+	}
+	if _, err := refundEntry(c, testutil.USD(1999), testutil.USD(999), testutil.USD(1001)); !errors.Is(err, types.ErrRefundTooLarge) {
+		t.Errorf("expected ErrRefundTooLarge past what was redeemed, got %v", err)
+	}
+	if _, err := refundEntry(c, testutil.USD(1999), testutil.USD(0), testutil.USD(2000)); !errors.Is(err, types.ErrRefundTooLarge) {
+		t.Errorf("expected ErrRefundTooLarge for more than was redeemed, got %v", err)
+	}
+}
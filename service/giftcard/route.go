@@ -0,0 +1,216 @@
+package giftcard
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves gift card issuing to admins and balance checks to
+// customers.
+type Handler struct {
+	store     types.GiftCardStore
+	userStore types.UserStore
+	clock     types.Clock
+}
+
+// NewHandler creates a new Handler. The user store authorizes admin-only
+// operations and clock tells whether an expiry is in the future.
+func NewHandler(store types.GiftCardStore, userStore types.UserStore, clock types.Clock) *Handler {
+	return &Handler{store: store, userStore: userStore, clock: clock}
+}
+
+// RegisterRoutes attaches gift card routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/gift-cards/balance", auth.RequireToken(h.handleBalance)).Methods("POST")
+
+	router.HandleFunc("/gift-cards", auth.RequireAdmin(h.handleListGiftCards, h.userStore)).Methods("GET")
+	router.HandleFunc("/gift-cards", auth.RequireAdmin(h.handleIssueGiftCard, h.userStore)).Methods("POST")
+	router.HandleFunc("/gift-cards/{id:[0-9]+}", auth.RequireAdmin(h.handleGetGiftCard, h.userStore)).Methods("GET")
+	router.HandleFunc("/gift-cards/{id:[0-9]+}/transactions", auth.RequireAdmin(h.handleListTransactions, h.userStore)).Methods("GET")
+	router.HandleFunc("/gift-cards/{id:[0-9]+}/activate", auth.RequireAdmin(h.handleSetActive(true), h.userStore)).Methods("POST")
+	router.HandleFunc("/gift-cards/{id:[0-9]+}/deactivate", auth.RequireAdmin(h.handleSetActive(false), h.userStore)).Methods("POST")
+}
+
+// handleBalance tells a customer holding a gift card code what is left on
+// it. The admin note is not shown.
+func (h *Handler) handleBalance(w http.ResponseWriter, r *http.Request) {
+	var payload types.GiftCardBalancePayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	card, err := h.store.GetGiftCardByCode(payload.Code)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if card == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("gift card not found"))
+		return
+	}
+	card.Note = ""
+
+	utils.WriteJson(w, http.StatusOK, types.GiftCardResponse{
+		Message: "success",
+		Data:    card,
+	})
+}
+
+func (h *Handler) handleListGiftCards(w http.ResponseWriter, r *http.Request) {
+	page := utils.ParsePagination(r)
+	cards, total, err := h.store.ListGiftCards(page)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list gift cards: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListGiftCardsResponse{
+		Message: "success",
+		Data:    cards,
+		Meta:    utils.NewPageMeta(page, total),
+	})
+}
+
+// handleIssueGiftCard issues a gift card with a new random code. The code
+// is only ever returned here: the store keeps a hash of it.
+func (h *Handler) handleIssueGiftCard(w http.ResponseWriter, r *http.Request) {
+	var payload types.IssueGiftCardPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	currency := types.DefaultCurrency
+	if payload.Currency != "" {
+		currency = strings.ToUpper(payload.Currency)
+	}
+	if !types.ValidCurrency(currency) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unsupported currency %s", payload.Currency))
+		return
+	}
+	amount, err := payload.Amount.In(currency)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(h.clock.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("expiresAt must be in the future"))
+		return
+	}
+
+	code, err := newCode()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	card := &types.GiftCard{
+		Code:           code,
+		InitialBalance: amount,
+		Currency:       currency,
+		ExpiresAt:      payload.ExpiresAt,
+		Note:           payload.Note,
+	}
+	if err := h.store.CreateGiftCard(card); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to issue gift card: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.GiftCardResponse{
+		Message: "gift card issued",
+		Data:    card,
+	})
+}
+
+func (h *Handler) handleGetGiftCard(w http.ResponseWriter, r *http.Request) {
+	card, ok := h.giftCardFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.GiftCardResponse{
+		Message: "success",
+		Data:    card,
+	})
+}
+
+func (h *Handler) handleListTransactions(w http.ResponseWriter, r *http.Request) {
+	card, ok := h.giftCardFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	transactions, err := h.store.ListGiftCardTransactions(card.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list gift card transactions: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListGiftCardTransactionsResponse{
+		Message: "success",
+		Data:    transactions,
+	})
+}
+
+// handleSetActive returns a handler that enables or disables a gift card.
+func (h *Handler) handleSetActive(active bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		card, ok := h.giftCardFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		if card.Active != active {
+			if err := h.store.SetGiftCardActive(card.ID, active); err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, err)
+				return
+			}
+			card.Active = active
+		}
+
+		message := "gift card activated"
+		if !active {
+			message = "gift card deactivated"
+		}
+		utils.WriteJson(w, http.StatusOK, types.GiftCardResponse{
+			Message: message,
+			Data:    card,
+		})
+	}
+}
+
+// giftCardFromPath loads the gift card named by the {id} path variable,
+// writing the error response when there is none.
+func (h *Handler) giftCardFromPath(w http.ResponseWriter, r *http.Request) (*types.GiftCard, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	card, err := h.store.GetGiftCardByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if card == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("gift card not found"))
+		return nil, false
+	}
+	return card, true
+}
@@ -0,0 +1,302 @@
+package giftcard
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// giftCardColumns lists the columns read by scanGiftCard, in scan order.
+const giftCardColumns = "id, last4, initialBalance, balance, currency, expiresAt, active, note, createdAt"
+
+// Store implements types.GiftCardStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanGiftCard(row sqlutil.Scanner) (*types.GiftCard, error) {
+	c := new(types.GiftCard)
+	var initial, balance string
+	var expiresAt sql.NullTime
+	var note sql.NullString
+	if err := row.Scan(&c.ID, &c.Last4, &initial, &balance, &c.Currency, &expiresAt, &c.Active, &note, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if c.InitialBalance, err = types.ParseMoney(initial, c.Currency); err != nil {
+		return nil, err
+	}
+	if c.Balance, err = types.ParseMoney(balance, c.Currency); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		c.ExpiresAt = &expiresAt.Time
+	}
+	c.Note = note.String
+	return c, nil
+}
+
+// CreateGiftCard inserts the card with its whole initial balance and the
+// ledger entry of its issue, then reloads it. Code is kept on card, since
+// it cannot be read back.
+func (s *Store) CreateGiftCard(card *types.GiftCard) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO gift_cards (codeHash, last4, initialBalance, balance, currency, expiresAt, active, note) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		hashCode(card.Code), last4(card.Code), card.InitialBalance, card.InitialBalance, card.Currency, card.ExpiresAt, true, sqlutil.NullString(card.Note),
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO gift_card_transactions (giftCardId, kind, amount, balance) VALUES (?, ?, ?, ?)",
+		id, types.GiftCardIssue, card.InitialBalance, card.InitialBalance,
+	); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	created, err := s.GetGiftCardByID(int(id))
+	if err != nil {
+		return err
+	}
+	created.Code = card.Code
+	*card = *created
+	return nil
+}
+
+// GetGiftCardByID returns nil when there is no gift card with that ID.
+func (s *Store) GetGiftCardByID(id int) (*types.GiftCard, error) {
+	return s.getGiftCard("id = ?", id)
+}
+
+// GetGiftCardByCode returns the gift card with that code, however it is
+// cased or grouped, or nil when there is none.
+func (s *Store) GetGiftCardByCode(code string) (*types.GiftCard, error) {
+	return s.getGiftCard("codeHash = ?", hashCode(code))
+}
+
+func (s *Store) getGiftCard(where string, args ...any) (*types.GiftCard, error) {
+	c, err := scanGiftCard(s.db.QueryRow("SELECT "+giftCardColumns+" FROM gift_cards WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// ListGiftCards returns one page of gift cards, newest first, together
+// with the total number of gift cards.
+func (s *Store) ListGiftCards(page types.Pagination) ([]*types.GiftCard, int, error) {
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM gift_cards").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query("SELECT "+giftCardColumns+" FROM gift_cards ORDER BY id DESC LIMIT ? OFFSET ?", page.PageSize, page.Offset())
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	cards := []*types.GiftCard{}
+	for rows.Next() {
+		c, err := scanGiftCard(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		cards = append(cards, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return cards, total, nil
+}
+
+// SetGiftCardActive enables or disables a gift card. Orders already paid
+// with it keep what they redeemed.
+func (s *Store) SetGiftCardActive(id int, active bool) error {
+	_, err := s.db.Exec("UPDATE gift_cards SET active = ? WHERE id = ?", active, id)
+	return err
+}
+
+// ListGiftCardTransactions returns the ledger of a gift card, oldest
+// first.
+func (s *Store) ListGiftCardTransactions(cardID int) ([]*types.GiftCardTransaction, error) {
+	rows, err := s.db.Query(
+		`SELECT t.id, t.giftCardId, t.kind, t.amount, t.balance, t.orderId, t.refundId, t.createdAt, c.currency
+		   FROM gift_card_transactions t
+		   JOIN gift_cards c ON c.id = t.giftCardId
+		  WHERE t.giftCardId = ?
+		  ORDER BY t.id`,
+		cardID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []*types.GiftCardTransaction{}
+	for rows.Next() {
+		t := new(types.GiftCardTransaction)
+		var amount, balance, currency string
+		var orderID, refundID sql.NullInt64
+		if err := rows.Scan(&t.ID, &t.GiftCardID, &t.Kind, &amount, &balance, &orderID, &refundID, &t.CreatedAt, &currency); err != nil {
+			return nil, err
+		}
+		if t.Amount, err = types.ParseMoney(amount, currency); err != nil {
+			return nil, err
+		}
+		if t.Balance, err = types.ParseMoney(balance, currency); err != nil {
+			return nil, err
+		}
+		t.OrderID = sqlutil.NullableInt(orderID)
+		t.RefundID = sqlutil.NullableInt(refundID)
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+// Redeem takes the order's GiftCard amount off the balance of its gift
+// card inside tx, the transaction creating the order. The card row is
+// locked so concurrent checkouts see each other's redemptions: an error
+// wrapping types.ErrGiftCardUnavailable is returned when the card was
+// deactivated, expired or no longer holds the amount. Orders without a
+// gift card are left alone.
+func Redeem(tx *sql.Tx, order *types.Order) error {
+	if order.GiftCardID == nil || order.GiftCard.IsZero() {
+		return nil
+	}
+
+	c, now, err := lock(tx, *order.GiftCardID)
+	if err == sql.ErrNoRows {
+		return types.ErrGiftCardUnavailable
+	}
+	if err != nil {
+		return err
+	}
+	e, err := redeemEntry(c, types.NewMoney(order.GiftCard.Amount, order.Currency), now)
+	if err != nil {
+		return err
+	}
+	return record(tx, *order.GiftCardID, e, &order.ID, nil)
+}
+
+// Release gives back to its gift card what a cancelled order redeemed,
+// inside the transaction cancelling it.
+func Release(tx *sql.Tx, orderID int) error {
+	cardID, redeemed, err := orderGiftCard(tx, orderID)
+	if err != nil || cardID == 0 {
+		return err
+	}
+	c, _, err := lock(tx, cardID)
+	if err != nil {
+		return err
+	}
+	return record(tx, cardID, releaseEntry(c, redeemed), &orderID, nil)
+}
+
+// Refund credits the GiftCard part of refund back onto the gift card its
+// order was paid with, inside tx, the transaction recording the refund,
+// so refund.ID must be set. The card is credited whether it is active or
+// expired. An error wrapping types.ErrRefundTooLarge is returned when
+// the cards would get back more than the order redeemed.
+func Refund(tx *sql.Tx, refund *types.Refund) error {
+	if refund.GiftCard.IsZero() {
+		return nil
+	}
+	cardID, redeemed, err := orderGiftCard(tx, refund.OrderID)
+	if err != nil {
+		return err
+	}
+	if cardID == 0 {
+		return fmt.Errorf("%w: order %d was not paid with a gift card", types.ErrRefundTooLarge, refund.OrderID)
+	}
+	c, _, err := lock(tx, cardID)
+	if err != nil {
+		return err
+	}
+
+	var refunded string
+	if err := tx.QueryRow(
+		"SELECT COALESCE(SUM(amount), 0) FROM gift_card_transactions WHERE orderId = ? AND kind = ?",
+		refund.OrderID, types.GiftCardRefund,
+	).Scan(&refunded); err != nil {
+		return err
+	}
+	given, err := types.ParseMoney(refunded, redeemed.Currency)
+	if err != nil {
+		return err
+	}
+	e, err := refundEntry(c, redeemed, given, refund.GiftCard)
+	if err != nil {
+		return err
+	}
+	return record(tx, cardID, e, &refund.OrderID, &refund.ID)
+}
+
+// orderGiftCard returns the gift card an order was paid with and the
+// amount it redeemed, or a zero ID when there is none.
+func orderGiftCard(tx *sql.Tx, orderID int) (int, types.Money, error) {
+	var cardID sql.NullInt64
+	var amount, currency string
+	if err := tx.QueryRow("SELECT giftCardId, giftCard, currency FROM orders WHERE id = ?", orderID).Scan(&cardID, &amount, &currency); err != nil {
+		return 0, types.Money{}, err
+	}
+	redeemed, err := types.ParseMoney(amount, currency)
+	if err != nil || !cardID.Valid || redeemed.IsZero() {
+		return 0, redeemed, err
+	}
+	return int(cardID.Int64), redeemed, nil
+}
+
+// lock locks a gift card and returns it together with the time of the
+// database, which its expiry is checked against.
+func lock(tx *sql.Tx, cardID int) (card, time.Time, error) {
+	var c card
+	var balance, currency string
+	var expiresAt sql.NullTime
+	var now time.Time
+	if err := tx.QueryRow(
+		"SELECT balance, currency, active, expiresAt, CURRENT_TIMESTAMP FROM gift_cards WHERE id = ? FOR UPDATE",
+		cardID,
+	).Scan(&balance, &currency, &c.Active, &expiresAt, &now); err != nil {
+		return card{}, now, err
+	}
+	if expiresAt.Valid {
+		c.ExpiresAt = &expiresAt.Time
+	}
+	var err error
+	c.Balance, err = types.ParseMoney(balance, currency)
+	return c, now, err
+}
+
+// record writes e to the balance and the ledger of a locked gift card.
+func record(tx *sql.Tx, cardID int, e entry, orderID, refundID *int) error {
+	if _, err := tx.Exec("UPDATE gift_cards SET balance = ? WHERE id = ?", e.Balance, cardID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(
+		"INSERT INTO gift_card_transactions (giftCardId, kind, amount, balance, orderId, refundId) VALUES (?, ?, ?, ?, ?, ?)",
+		cardID, e.Kind, e.Amount, e.Balance, orderID, refundID,
+	)
+	return err
+}
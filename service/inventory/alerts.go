@@ -0,0 +1,182 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// dispatchBatchSize caps the number of alerts one dispatch delivers.
+const dispatchBatchSize = 100
+
+// raiseAlerts writes the stock alerts caused by a change of a product's
+// stock to the stock_alerts outbox, after the change was applied in tx.
+// quantityDelta is the change of the on-hand quantity and availableDelta
+// the change of the stock available to shoppers; they differ when
+// reservations are released.
+//
+// A low-stock alert is raised when the quantity drops below the product's
+// threshold, and a back-in-stock alert when the available stock goes from
+// none to some.
+func raiseAlerts(tx *sql.Tx, productID, quantityDelta, availableDelta int) error {
+	var quantity, available int
+	var threshold sql.NullInt64
+	err := tx.QueryRow(
+		`SELECT quantity, lowStockThreshold,
+		        quantity - (SELECT COALESCE(SUM(r.quantity), 0) FROM inventory_reservations r WHERE r.productId = products.id AND r.status = 'active')
+		   FROM products WHERE id = ?`,
+		productID,
+	).Scan(&quantity, &threshold, &available)
+	if err != nil {
+		return err
+	}
+
+	var kinds []string
+	if threshold.Valid {
+		t := int(threshold.Int64)
+		if quantity < t && quantity-quantityDelta >= t {
+			kinds = append(kinds, types.AlertLowStock)
+		}
+	}
+	if available > 0 && available-availableDelta <= 0 {
+		kinds = append(kinds, types.AlertBackInStock)
+	}
+
+	for _, kind := range kinds {
+		if _, err := tx.Exec(
+			"INSERT INTO stock_alerts (productId, kind, quantity) VALUES (?, ?, ?)",
+			productID, kind, quantity,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dispatcher delivers the alerts in the stock_alerts outbox through a
+// Notifier: low-stock alerts once to the merchandisers, back-in-stock
+// alerts to every waiting subscriber of the product.
+type Dispatcher struct {
+	alerts   types.AlertStore
+	products types.ProductStore
+	notifier types.Notifier
+	interval time.Duration
+}
+
+// NewDispatcher creates a Dispatcher that looks for pending alerts every
+// interval.
+func NewDispatcher(alerts types.AlertStore, products types.ProductStore, notifier types.Notifier, interval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		alerts:   alerts,
+		products: products,
+		notifier: notifier,
+		interval: interval,
+	}
+}
+
+// Run dispatches every interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := d.Dispatch(ctx)
+			if err != nil {
+				log.Printf("stock alert dispatch failed: %v", err)
+			}
+			if n > 0 {
+				log.Printf("sent %d stock notifications", n)
+			}
+		}
+	}
+}
+
+// Dispatch delivers pending alerts in the order they were raised and
+// returns how many notifications were sent. An alert whose delivery fails
+// stays pending, and so do the ones after it, until the next dispatch;
+// subscribers already notified are not notified again.
+//
+// Alerts are checked against the product as it is now: a low-stock alert
+// for a product that was restocked in the meantime, or a back-in-stock
+// alert for one that sold out again, is dropped.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	alerts, err := d.alerts.PendingAlerts(dispatchBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, a := range alerts {
+		n, err := d.deliver(ctx, a)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+		if err := d.alerts.MarkAlertSent(a.ID); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, a *types.StockAlert) (int, error) {
+	p, err := d.products.GetProductByID(a.ProductID)
+	if err != nil || p == nil {
+		return 0, err
+	}
+
+	switch a.Kind {
+	case types.AlertLowStock:
+		if p.LowStockThreshold == nil || p.Quantity >= *p.LowStockThreshold {
+			return 0, nil
+		}
+		err := d.notifier.Notify(ctx, &types.Notification{
+			Kind:        types.AlertLowStock,
+			ProductID:   p.ID,
+			ProductName: p.Name,
+			Quantity:    p.Quantity,
+			Available:   p.Available,
+			Threshold:   p.LowStockThreshold,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return 1, nil
+
+	case types.AlertBackInStock:
+		if p.Available <= 0 {
+			return 0, nil
+		}
+		subs, err := d.alerts.WaitingSubscriptions(p.ID)
+		if err != nil {
+			return 0, err
+		}
+		sent := 0
+		for _, sub := range subs {
+			if err := d.notifier.Notify(ctx, &types.Notification{
+				Kind:        types.AlertBackInStock,
+				ProductID:   p.ID,
+				ProductName: p.Name,
+				Quantity:    p.Quantity,
+				Available:   p.Available,
+				UserID:      sub.UserID,
+				Email:       sub.Email,
+			}); err != nil {
+				return sent, err
+			}
+			if err := d.alerts.MarkSubscriptionNotified(sub.ID); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+		return sent, nil
+	}
+	return 0, nil
+}
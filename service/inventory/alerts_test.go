@@ -0,0 +1,143 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// outbox keeps alerts, subscriptions and products in memory.
+type outbox struct {
+	types.AlertStore
+	types.ProductStore
+	alerts   []*types.StockAlert
+	sent     map[int]bool
+	subs     []*types.StockSubscription
+	notified map[int]bool
+	products map[int]*types.Product
+}
+
+func (o *outbox) PendingAlerts(limit int) ([]*types.StockAlert, error) {
+	var pending []*types.StockAlert
+	for _, a := range o.alerts {
+		if !o.sent[a.ID] && len(pending) < limit {
+			pending = append(pending, a)
+		}
+	}
+	return pending, nil
+}
+
+func (o *outbox) MarkAlertSent(id int) error {
+	o.sent[id] = true
+	return nil
+}
+
+func (o *outbox) WaitingSubscriptions(productID int) ([]*types.StockSubscription, error) {
+	var waiting []*types.StockSubscription
+	for _, s := range o.subs {
+		if s.ProductID == productID && !o.notified[s.ID] {
+			waiting = append(waiting, s)
+		}
+	}
+	return waiting, nil
+}
+
+func (o *outbox) MarkSubscriptionNotified(id int) error {
+	o.notified[id] = true
+	return nil
+}
+
+func (o *outbox) GetProductByID(id int) (*types.Product, error) {
+	return o.products[id], nil
+}
+
+// inbox records notifications and fails once failAt of them were sent.
+type inbox struct {
+	got    []*types.Notification
+	failAt int
+}
+
+func (n *inbox) Notify(ctx context.Context, msg *types.Notification) error {
+	if n.failAt > 0 && len(n.got) == n.failAt {
+		return errors.New("webhook down")
+	}
+	n.got = append(n.got, msg)
+	return nil
+}
+
+func TestDispatch(t *testing.T) {
+	threshold := 5
+	newOutbox := func() *outbox {
+		return &outbox{
+			alerts: []*types.StockAlert{
+				{ID: 1, ProductID: 1, Kind: types.AlertLowStock},
+				{ID: 2, ProductID: 2, Kind: types.AlertBackInStock},
+				{ID: 3, ProductID: 3, Kind: types.AlertLowStock},
+				{ID: 4, ProductID: 4, Kind: types.AlertBackInStock},
+			},
+			subs: []*types.StockSubscription{
+				{ID: 1, ProductID: 2, UserID: 7, Email: "a@example.com"},
+				{ID: 2, ProductID: 2, UserID: 8, Email: "b@example.com"},
+				{ID: 3, ProductID: 4, UserID: 7, Email: "a@example.com"},
+			},
+			products: map[int]*types.Product{
+				1: {ID: 1, Name: "Shirt", Quantity: 2, Available: 2, LowStockThreshold: &threshold},
+				2: {ID: 2, Name: "Hat", Quantity: 3, Available: 3},
+				// restocked before the alert went out
+				3: {ID: 3, Name: "Scarf", Quantity: 9, Available: 9, LowStockThreshold: &threshold},
+				// sold out again before the alert went out
+				4: {ID: 4, Name: "Belt", Quantity: 1, Available: 0},
+			},
+			sent:     map[int]bool{},
+			notified: map[int]bool{},
+		}
+	}
+
+	t.Run("delivers current alerts", func(t *testing.T) {
+		store := newOutbox()
+		notifier := &inbox{}
+		n, err := NewDispatcher(store, store, notifier, 0).Dispatch(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != 3 || len(notifier.got) != 3 {
+			t.Fatalf("expected 3 notifications, sent %d: %+v", n, notifier.got)
+		}
+		if got := notifier.got[0]; got.Kind != types.AlertLowStock || got.ProductID != 1 || *got.Threshold != 5 {
+			t.Errorf("unexpected low-stock notification %+v", got)
+		}
+		if notifier.got[1].Email != "a@example.com" || notifier.got[2].Email != "b@example.com" {
+			t.Errorf("expected both hat subscribers, got %+v", notifier.got[1:])
+		}
+		if len(store.sent) != 4 {
+			t.Errorf("expected every alert to leave the outbox, sent %v", store.sent)
+		}
+		if store.notified[3] {
+			t.Error("the belt subscriber must keep waiting")
+		}
+	})
+
+	t.Run("retries after a failure", func(t *testing.T) {
+		store := newOutbox()
+		notifier := &inbox{failAt: 2}
+		d := NewDispatcher(store, store, notifier, 0)
+
+		if _, err := d.Dispatch(context.Background()); err == nil {
+			t.Fatal("expected the failed delivery to be reported")
+		}
+		if store.sent[2] || !store.notified[1] || store.notified[2] {
+			t.Fatalf("expected the hat alert pending with one subscriber notified, sent %v notified %v", store.sent, store.notified)
+		}
+
+		notifier.failAt = 0
+		if _, err := d.Dispatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(notifier.got) != 3 {
+			t.Errorf("expected no duplicate notifications, got %d", len(notifier.got))
+		}
+	})
+}
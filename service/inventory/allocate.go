@@ -0,0 +1,168 @@
+package inventory
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// Unit identifies what stock is counted for: a product without variants
+// (VariantID 0) or one variant of a product.
+type Unit struct {
+	ProductID int
+	VariantID int
+}
+
+// unitOf returns the Unit an order line takes stock from.
+func unitOf(productID int, variantID *int) Unit {
+	u := Unit{ProductID: productID}
+	if variantID != nil {
+		u.VariantID = *variantID
+	}
+	return u
+}
+
+// Source is a location that may ship an order. Distance is how far it is
+// from the delivery point, +Inf when unknown; Stock is what it has
+// available of each unit.
+type Source struct {
+	LocationID int
+	Distance   float64
+	Stock      map[Unit]int
+}
+
+// AllocationLine is one order line to allocate.
+type AllocationLine struct {
+	Unit     Unit
+	Quantity int
+}
+
+// Allocate picks the locations that ship each line and returns the
+// allocations in line order. The strategy is:
+//
+//  1. Ship the whole order from a single location when one has the stock
+//     for every line.
+//  2. Otherwise ship each line from a single location when one has the
+//     stock for it.
+//  3. Otherwise split the line across locations.
+//
+// Whenever several locations qualify the closest wins, then the one with
+// the most stock of the units concerned, then the lowest ID. Lines of the
+// same unit share its stock. An error wrapping types.ErrInsufficientStock
+// is returned when the locations together cannot ship a line.
+func Allocate(lines []AllocationLine, sources []*Source) ([][]*types.Allocation, error) {
+	remaining := make(map[int]map[Unit]int, len(sources))
+	for _, s := range sources {
+		stock := make(map[Unit]int, len(s.Stock))
+		for u, q := range s.Stock {
+			stock[u] = q
+		}
+		remaining[s.LocationID] = stock
+	}
+
+	wanted := make(map[Unit]int)
+	for _, l := range lines {
+		wanted[l.Unit] += l.Quantity
+	}
+
+	allocations := make([][]*types.Allocation, len(lines))
+
+	if best := pickSource(sources, remaining, wanted); best != nil {
+		for i, l := range lines {
+			allocations[i] = []*types.Allocation{{LocationID: best.LocationID, Quantity: l.Quantity}}
+		}
+		return allocations, nil
+	}
+
+	for i, l := range lines {
+		want := map[Unit]int{l.Unit: l.Quantity}
+		if best := pickSource(sources, remaining, want); best != nil {
+			remaining[best.LocationID][l.Unit] -= l.Quantity
+			allocations[i] = []*types.Allocation{{LocationID: best.LocationID, Quantity: l.Quantity}}
+			continue
+		}
+
+		ranked := rankSources(sources, remaining, want)
+		left := l.Quantity
+		for _, s := range ranked {
+			take := min(left, remaining[s.LocationID][l.Unit])
+			if take <= 0 {
+				continue
+			}
+			remaining[s.LocationID][l.Unit] -= take
+			allocations[i] = append(allocations[i], &types.Allocation{LocationID: s.LocationID, Quantity: take})
+			left -= take
+			if left == 0 {
+				break
+			}
+		}
+		if left > 0 {
+			return nil, fmt.Errorf("%w for product %d", types.ErrInsufficientStock, l.Unit.ProductID)
+		}
+	}
+	return allocations, nil
+}
+
+// pickSource returns the best source that has all of want, or nil.
+func pickSource(sources []*Source, remaining map[int]map[Unit]int, want map[Unit]int) *Source {
+	for _, s := range rankSources(sources, remaining, want) {
+		if covers(remaining[s.LocationID], want) {
+			return s
+		}
+	}
+	return nil
+}
+
+func covers(stock map[Unit]int, want map[Unit]int) bool {
+	for u, q := range want {
+		if stock[u] < q {
+			return false
+		}
+	}
+	return true
+}
+
+// rankSources orders sources by distance, then by their remaining stock
+// of the wanted units (most first), then by location ID.
+func rankSources(sources []*Source, remaining map[int]map[Unit]int, want map[Unit]int) []*Source {
+	held := func(s *Source) int {
+		n := 0
+		for u := range want {
+			n += remaining[s.LocationID][u]
+		}
+		return n
+	}
+
+	ranked := append([]*Source(nil), sources...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if ha, hb := held(a), held(b); ha != hb {
+			return ha > hb
+		}
+		return a.LocationID < b.LocationID
+	})
+	return ranked
+}
+
+// earthRadiusKm is the mean radius of the Earth.
+const earthRadiusKm = 6371.0
+
+// Distance returns the great-circle distance in kilometres between the
+// location and the point, or +Inf when either has no coordinates.
+func Distance(l *types.StockLocation, lat, lon *float64) float64 {
+	if l.Latitude == nil || l.Longitude == nil || lat == nil || lon == nil {
+		return math.Inf(1)
+	}
+
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := rad(*lat - *l.Latitude)
+	dLon := rad(*lon - *l.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(*l.Latitude))*math.Cos(rad(*lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,125 @@
+package inventory
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestAllocate(t *testing.T) {
+	shirt := Unit{ProductID: 1}
+	hat := Unit{ProductID: 2}
+	far := math.Inf(1)
+
+	tests := []struct {
+		name    string
+		lines   []AllocationLine
+		sources []*Source
+		want    [][][2]int // location, quantity per line
+	}{
+		{
+			name:  "single location beats a closer partial one",
+			lines: []AllocationLine{{shirt, 1}, {hat, 1}},
+			sources: []*Source{
+				{LocationID: 1, Distance: 10, Stock: map[Unit]int{shirt: 5}},
+				{LocationID: 2, Distance: 500, Stock: map[Unit]int{shirt: 1, hat: 1}},
+			},
+			want: [][][2]int{{{2, 1}}, {{2, 1}}},
+		},
+		{
+			name:  "closest of the complete locations",
+			lines: []AllocationLine{{shirt, 2}},
+			sources: []*Source{
+				{LocationID: 1, Distance: 300, Stock: map[Unit]int{shirt: 9}},
+				{LocationID: 2, Distance: 40, Stock: map[Unit]int{shirt: 2}},
+				{LocationID: 3, Distance: 80, Stock: map[Unit]int{shirt: 9}},
+			},
+			want: [][][2]int{{{2, 2}}},
+		},
+		{
+			name:  "most stock without coordinates",
+			lines: []AllocationLine{{shirt, 2}},
+			sources: []*Source{
+				{LocationID: 1, Distance: far, Stock: map[Unit]int{shirt: 3}},
+				{LocationID: 2, Distance: far, Stock: map[Unit]int{shirt: 8}},
+			},
+			want: [][][2]int{{{2, 2}}},
+		},
+		{
+			name:  "lines from different locations",
+			lines: []AllocationLine{{shirt, 2}, {hat, 1}},
+			sources: []*Source{
+				{LocationID: 1, Distance: 10, Stock: map[Unit]int{shirt: 2}},
+				{LocationID: 2, Distance: 20, Stock: map[Unit]int{hat: 4}},
+			},
+			want: [][][2]int{{{1, 2}}, {{2, 1}}},
+		},
+		{
+			name:  "split line, closest first",
+			lines: []AllocationLine{{shirt, 5}},
+			sources: []*Source{
+				{LocationID: 1, Distance: 90, Stock: map[Unit]int{shirt: 4}},
+				{LocationID: 2, Distance: 10, Stock: map[Unit]int{shirt: 3}},
+			},
+			want: [][][2]int{{{2, 3}, {1, 2}}},
+		},
+		{
+			name:  "repeated unit shares stock",
+			lines: []AllocationLine{{shirt, 2}, {shirt, 2}},
+			sources: []*Source{
+				{LocationID: 1, Distance: 10, Stock: map[Unit]int{shirt: 3}},
+				{LocationID: 2, Distance: 20, Stock: map[Unit]int{shirt: 2}},
+			},
+			want: [][][2]int{{{1, 2}}, {{2, 2}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Allocate(tt.lines, tt.sources)
+			if err != nil {
+				t.Fatal(err)
+			}
+			flat := make([][][2]int, len(got))
+			for i, line := range got {
+				for _, a := range line {
+					flat[i] = append(flat[i], [2]int{a.LocationID, a.Quantity})
+				}
+			}
+			if !reflect.DeepEqual(flat, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, flat)
+			}
+		})
+	}
+
+	t.Run("insufficient stock", func(t *testing.T) {
+		sources := []*Source{
+			{LocationID: 1, Distance: 10, Stock: map[Unit]int{shirt: 2}},
+			{LocationID: 2, Distance: 20, Stock: map[Unit]int{shirt: 2}},
+		}
+		_, err := Allocate([]AllocationLine{{shirt, 5}}, sources)
+		if !errors.Is(err, types.ErrInsufficientStock) {
+			t.Errorf("expected insufficient stock, got %v", err)
+		}
+	})
+}
+
+func TestDistance(t *testing.T) {
+	lat, lon := 52.52, 13.405 // Berlin
+	paris := &types.StockLocation{Latitude: ptr(48.8566), Longitude: ptr(2.3522)}
+
+	if d := Distance(paris, &lat, &lon); d < 870 || d > 890 {
+		t.Errorf("expected about 878 km, got %.1f", d)
+	}
+	if d := Distance(&types.StockLocation{}, &lat, &lon); !math.IsInf(d, 1) {
+		t.Errorf("expected +Inf without coordinates, got %v", d)
+	}
+	if d := Distance(paris, nil, nil); !math.IsInf(d, 1) {
+		t.Errorf("expected +Inf without a destination, got %v", d)
+	}
+}
+
+func ptr(f float64) *float64 { return &f }
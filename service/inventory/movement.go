@@ -0,0 +1,144 @@
+package inventory
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// Move applies a stock movement inside tx and appends it to the ledger.
+// The delta applies to the stock at m.LocationID and to the totals of the
+// variant, if any, and the product. Stock alerts the change causes are
+// raised in the same transaction. A movement that would take stock below
+// zero is refused with an error wrapping types.ErrInsufficientStock. Move
+// does not touch the product version; callers bump it once per write.
+//
+// Every change of products.quantity, product_variants.quantity or
+// stock_levels.quantity must go through Move (or SetQuantity or Clear) so
+// the ledger stays complete.
+func Move(tx *sql.Tx, m *types.StockMovement) error {
+	if m.LocationID == 0 {
+		return fmt.Errorf("movement of product %d has no location", m.ProductID)
+	}
+	unit := unitOf(m.ProductID, m.VariantID)
+
+	// the quantity columns are unsigned, so the guards compare instead of
+	// adding the delta first
+	if m.VariantID != nil {
+		if err := updateStock(tx,
+			"UPDATE product_variants SET quantity = quantity + ? WHERE id = ? AND productId = ? AND quantity >= ?",
+			m.Delta, *m.VariantID, m.ProductID, -m.Delta,
+		); err != nil {
+			return fmt.Errorf("%w for variant %d", err, *m.VariantID)
+		}
+	}
+	if err := updateStock(tx,
+		"UPDATE products SET quantity = quantity + ? WHERE id = ? AND quantity >= ?",
+		m.Delta, m.ProductID, -m.Delta,
+	); err != nil {
+		return fmt.Errorf("%w for product %d", err, m.ProductID)
+	}
+	if err := raiseAlerts(tx, m.ProductID, m.Delta, m.Delta); err != nil {
+		return err
+	}
+
+	if m.Delta > 0 {
+		if _, err := tx.Exec(
+			"INSERT INTO stock_levels (locationId, productId, variantId, quantity) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE quantity = quantity + VALUES(quantity)",
+			m.LocationID, m.ProductID, m.VariantID, m.Delta,
+		); err != nil {
+			return err
+		}
+	} else if err := updateStock(tx,
+		"UPDATE stock_levels SET quantity = quantity + ? WHERE locationId = ? AND productId = ? AND variantKey = ? AND quantity >= ?",
+		m.Delta, m.LocationID, unit.ProductID, unit.VariantID, -m.Delta,
+	); err != nil {
+		return fmt.Errorf("%w for product %d at location %d", err, m.ProductID, m.LocationID)
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO inventory_movements (productId, variantId, locationId, delta, reason, referenceId, actorId) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		m.ProductID, m.VariantID, m.LocationID, m.Delta, m.Reason, m.ReferenceID, m.ActorID,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	m.ID = int(id)
+	return nil
+}
+
+// SetQuantity brings the total stock of a product, or of one of its
+// variants, to quantity and records the difference with reason. The
+// difference is taken from or added to the default location; lowering the
+// stock by more than the default location holds fails with
+// types.ErrInsufficientStock, and the stock must then be moved per
+// location. Nothing is recorded when the stock already matches.
+func SetQuantity(tx *sql.Tx, productID int, variantID *int, quantity int, reason string, actorID int) error {
+	var current int
+	var err error
+	if variantID != nil {
+		err = tx.QueryRow("SELECT quantity FROM product_variants WHERE id = ? AND productId = ? FOR UPDATE", *variantID, productID).Scan(&current)
+	} else {
+		err = tx.QueryRow("SELECT quantity FROM products WHERE id = ? FOR UPDATE", productID).Scan(&current)
+	}
+	if err != nil {
+		return err
+	}
+	if current == quantity {
+		return nil
+	}
+
+	return Move(tx, &types.StockMovement{
+		ProductID:  productID,
+		VariantID:  variantID,
+		LocationID: types.DefaultLocationID,
+		Delta:      quantity - current,
+		Reason:     reason,
+		ActorID:    Actor(actorID),
+	})
+}
+
+// Clear moves the stock of a product, or of one of its variants, to zero
+// at every location and drops its stock levels. It is used before a
+// variant is deleted and when a product's own stock is replaced by
+// variants.
+func Clear(tx *sql.Tx, productID int, variantID *int, reason string, actorID int) error {
+	unit := unitOf(productID, variantID)
+	levels, err := queryStockLevels(tx, "WHERE s.productId = ? AND s.variantKey = ? FOR UPDATE", unit.ProductID, unit.VariantID)
+	if err != nil {
+		return err
+	}
+
+	for _, level := range levels {
+		if level.Quantity == 0 {
+			continue
+		}
+		if err := Move(tx, &types.StockMovement{
+			ProductID:  productID,
+			VariantID:  variantID,
+			LocationID: level.LocationID,
+			Delta:      -level.Quantity,
+			Reason:     reason,
+			ActorID:    Actor(actorID),
+		}); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("DELETE FROM stock_levels WHERE productId = ? AND variantKey = ?", unit.ProductID, unit.VariantID)
+	return err
+}
+
+// Actor converts a user ID into a movement's ActorID; zero means the
+// system.
+func Actor(userID int) *int {
+	if userID == 0 {
+		return nil
+	}
+	return &userID
+}
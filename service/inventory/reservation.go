@@ -0,0 +1,256 @@
+// Package inventory keeps stock honest between checkout and payment.
+// Stock is kept per location; products.quantity and
+// product_variants.quantity hold the total across locations. Checkout
+// allocates each order line to locations and reserves the stock there with
+// an expiring hold instead of decrementing it; the stock available to
+// other shoppers is the on-hand quantity minus all active holds. Paying
+// the order commits its holds into real decrements, and a Sweeper
+// releases holds that expire first.
+//
+// Every change of the stock on hand goes through Move, which records it in
+// the append-only inventory_movements ledger in the same transaction.
+// Stock changes also raise low-stock and back-in-stock alerts into the
+// stock_alerts outbox, which a Dispatcher delivers afterwards.
+//
+// Hold, Commit, Release, Move and SetQuantity run inside a transaction
+// owned by the caller, so other stores can change their own rows and the
+// stock atomically.
+package inventory
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// Hold allocates every line of an order to stock locations with Allocate
+// and reserves the allocated stock at each location until expiresAt. The
+// allocations are set on the order items. An error wrapping
+// types.ErrInsufficientStock is returned when the order cannot be shipped
+// from the stock available. Products are locked in ID order so that
+// concurrent checkouts cannot deadlock.
+func Hold(tx *sql.Tx, order *types.Order, expiresAt time.Time) error {
+	var productIDs []int
+	seen := make(map[int]bool)
+	for _, item := range order.Items {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+	sort.Ints(productIDs)
+
+	// the product row is locked even for variant lines, which serializes
+	// every reservation of the product
+	for _, id := range productIDs {
+		var locked int
+		err := tx.QueryRow("SELECT id FROM products WHERE id = ? AND deletedAt IS NULL FOR UPDATE", id).Scan(&locked)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("product %d not found", id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	locations, err := queryLocations(tx, "ORDER BY id")
+	if err != nil {
+		return err
+	}
+	sources := make([]*Source, len(locations))
+	byID := make(map[int]*Source, len(locations))
+	for i, l := range locations {
+		sources[i] = &Source{
+			LocationID: l.ID,
+			Distance:   Distance(l, order.Latitude, order.Longitude),
+			Stock:      make(map[Unit]int),
+		}
+		byID[l.ID] = sources[i]
+	}
+
+	lines := make([]AllocationLine, len(order.Items))
+	loaded := make(map[Unit]bool)
+	for i, item := range order.Items {
+		unit := unitOf(item.ProductID, item.VariantID)
+		lines[i] = AllocationLine{Unit: unit, Quantity: item.Quantity}
+		if loaded[unit] {
+			continue
+		}
+		loaded[unit] = true
+
+		if item.VariantID != nil {
+			var exists bool
+			if err := tx.QueryRow(
+				"SELECT EXISTS (SELECT 1 FROM product_variants WHERE id = ? AND productId = ?)",
+				*item.VariantID, item.ProductID,
+			).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("variant %d not found for product %d", *item.VariantID, item.ProductID)
+			}
+		}
+
+		levels, err := queryStockLevels(tx, "WHERE s.productId = ? AND s.variantKey = ?", unit.ProductID, unit.VariantID)
+		if err != nil {
+			return err
+		}
+		for _, level := range levels {
+			if s, ok := byID[level.LocationID]; ok {
+				s.Stock[unit] = level.Available
+			}
+		}
+	}
+
+	allocations, err := Allocate(lines, sources)
+	if err != nil {
+		return err
+	}
+
+	for i, item := range order.Items {
+		item.Allocations = allocations[i]
+		for _, a := range allocations[i] {
+			if _, err := tx.Exec(
+				"INSERT INTO inventory_reservations (orderId, productId, variantId, locationId, quantity, status, expiresAt) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				order.ID, item.ProductID, item.VariantID, a.LocationID, a.Quantity, types.ReservationActive, expiresAt.UTC(),
+			); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CheckUnreserved returns an error wrapping types.ErrVariantReserved when
+// active holds reserve stock of the variant, which must then be kept until
+// their orders are paid or cancelled.
+func CheckUnreserved(tx *sql.Tx, variantID int) error {
+	var held int
+	if err := tx.QueryRow(
+		"SELECT COUNT(*) FROM inventory_reservations WHERE variantId = ? AND status = ?",
+		variantID, types.ReservationActive,
+	).Scan(&held); err != nil {
+		return err
+	}
+	if held > 0 {
+		return fmt.Errorf("%w: variant %d has %d active holds", types.ErrVariantReserved, variantID, held)
+	}
+	return nil
+}
+
+// Commit turns the active holds of an order into sale movements and marks
+// them committed. The holds guarantee the stock, so a failing movement
+// means the on-hand quantity was lowered by hand in the meantime; an error
+// wrapping types.ErrInsufficientStock is returned in that case.
+func Commit(tx *sql.Tx, orderID int) error {
+	rows, err := tx.Query(
+		"SELECT productId, variantId, locationId, quantity FROM inventory_reservations WHERE orderId = ? AND status = ? ORDER BY productId FOR UPDATE",
+		orderID, types.ReservationActive,
+	)
+	if err != nil {
+		return err
+	}
+	var holds []*types.Reservation
+	for rows.Next() {
+		r := new(types.Reservation)
+		var variantID sql.NullInt64
+		if err := rows.Scan(&r.ProductID, &variantID, &r.LocationID, &r.Quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		r.VariantID = sqlutil.NullableInt(variantID)
+		holds = append(holds, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	bumped := make(map[int]bool)
+	for _, r := range holds {
+		if err := Move(tx, &types.StockMovement{
+			ProductID:   r.ProductID,
+			VariantID:   r.VariantID,
+			LocationID:  r.LocationID,
+			Delta:       -r.Quantity,
+			Reason:      types.MovementSale,
+			ReferenceID: &orderID,
+		}); err != nil {
+			return err
+		}
+		if !bumped[r.ProductID] {
+			bumped[r.ProductID] = true
+			if _, err := tx.Exec("UPDATE products SET version = version + 1 WHERE id = ?", r.ProductID); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE inventory_reservations SET status = ? WHERE orderId = ? AND status = ?",
+		types.ReservationCommitted, orderID, types.ReservationActive,
+	)
+	return err
+}
+
+// Release gives the stock held for an order back to other shoppers, which
+// raises a back-in-stock alert for products that were sold out.
+func Release(tx *sql.Tx, orderID int) error {
+	rows, err := tx.Query(
+		"SELECT productId, SUM(quantity) FROM inventory_reservations WHERE orderId = ? AND status = ? GROUP BY productId ORDER BY productId",
+		orderID, types.ReservationActive,
+	)
+	if err != nil {
+		return err
+	}
+	released := make(map[int]int)
+	var productIDs []int
+	for rows.Next() {
+		var productID, quantity int
+		if err := rows.Scan(&productID, &quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		released[productID] = quantity
+		productIDs = append(productIDs, productID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE inventory_reservations SET status = ? WHERE orderId = ? AND status = ?",
+		types.ReservationReleased, orderID, types.ReservationActive,
+	); err != nil {
+		return err
+	}
+
+	for _, id := range productIDs {
+		if err := raiseAlerts(tx, id, 0, released[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateStock runs a conditional stock update and reports
+// types.ErrInsufficientStock when no row matched.
+func updateStock(tx *sql.Tx, query string, args ...any) error {
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return types.ErrInsufficientStock
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves the inventory ledger and stock locations to admins, and
+// back-in-stock subscriptions to customers.
+type Handler struct {
+	store         types.MovementStore
+	locationStore types.LocationStore
+	alertStore    types.AlertStore
+	productStore  types.ProductStore
+	userStore     types.UserStore
+}
+
+// NewHandler creates a new Handler. The product store resolves the
+// products and variants named by a movement; the user store authorizes
+// admin-only operations.
+func NewHandler(store types.MovementStore, locationStore types.LocationStore, alertStore types.AlertStore, productStore types.ProductStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:         store,
+		locationStore: locationStore,
+		alertStore:    alertStore,
+		productStore:  productStore,
+		userStore:     userStore,
+	}
+}
+
+// RegisterRoutes attaches inventory routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/products/{id}/movements", auth.RequireAdmin(h.handleListMovements, h.userStore)).Methods("GET")
+	router.HandleFunc("/products/{id}/movements", auth.RequireAdmin(h.handleRecordMovement, h.userStore)).Methods("POST")
+	router.HandleFunc("/products/{id}/stock", auth.RequireAdmin(h.handleListStockLevels, h.userStore)).Methods("GET")
+	router.HandleFunc("/products/{id}/notify-me", auth.RequireToken(h.handleNotifyMe)).Methods("POST")
+	router.HandleFunc("/inventory/reconciliation", auth.RequireAdmin(h.handleReconcile, h.userStore)).Methods("GET")
+	router.HandleFunc("/inventory/locations", auth.RequireAdmin(h.handleListLocations, h.userStore)).Methods("GET")
+	router.HandleFunc("/inventory/locations", auth.RequireAdmin(h.handleCreateLocation, h.userStore)).Methods("POST")
+}
+
+func (h *Handler) handleListMovements(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	page := utils.ParsePagination(r)
+	movements, total, err := h.store.ListMovements(product.ID, page)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list movements: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListMovementsResponse{
+		Message: "success",
+		Data:    movements,
+		Meta:    utils.NewPageMeta(page, total),
+	})
+}
+
+// handleRecordMovement records a manual stock change such as a supplier
+// delivery or a stock count correction. Sales and cancellations are
+// recorded by checkout and cannot be entered by hand.
+func (h *Handler) handleRecordMovement(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.RecordMovementPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := checkMovementVariant(payload.VariantID, product.Variants); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if payload.LocationID == 0 {
+		payload.LocationID = types.DefaultLocationID
+	}
+	location, err := h.locationStore.GetLocationByID(payload.LocationID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if location == nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("location %d not found", payload.LocationID))
+		return
+	}
+
+	actorID, _ := auth.UserIDFromContext(r.Context())
+	movement := &types.StockMovement{
+		ProductID:   product.ID,
+		VariantID:   payload.VariantID,
+		LocationID:  location.ID,
+		Delta:       payload.Delta,
+		Reason:      payload.Reason,
+		ReferenceID: payload.ReferenceID,
+		ActorID:     Actor(actorID),
+	}
+	if err := h.store.RecordMovement(movement); err != nil {
+		if errors.Is(err, types.ErrInsufficientStock) {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.MovementResponse{
+		Message: "movement recorded",
+		Data:    movement,
+	})
+}
+
+// handleReconcile lists every product and variant whose stock does not
+// match the sum of its movements. An empty list means the ledger is
+// consistent.
+func (h *Handler) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	discrepancies, err := h.store.Reconcile()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ReconciliationResponse{
+		Message: "success",
+		Data:    discrepancies,
+	})
+}
+
+// handleListStockLevels shows how a product's stock is spread over the
+// locations. The product's own quantity is the sum of the levels.
+func (h *Handler) handleListStockLevels(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	levels, err := h.locationStore.ListStockLevels(product.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListStockLevelsResponse{
+		Message: "success",
+		Data:    levels,
+	})
+}
+
+func (h *Handler) handleListLocations(w http.ResponseWriter, r *http.Request) {
+	locations, err := h.locationStore.ListLocations()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list locations: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListLocationsResponse{
+		Message: "success",
+		Data:    locations,
+	})
+}
+
+// handleCreateLocation adds a stock location. Stock is brought into it
+// with restock movements naming the location.
+func (h *Handler) handleCreateLocation(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateLocationPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	existing, err := h.locationStore.GetLocationByCode(payload.Code)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if existing != nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("location with code %s already exists", payload.Code))
+		return
+	}
+
+	location := &types.StockLocation{
+		Code:      payload.Code,
+		Name:      payload.Name,
+		Latitude:  payload.Latitude,
+		Longitude: payload.Longitude,
+	}
+	if err := h.locationStore.CreateLocation(location); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.LocationResponse{
+		Message: "location created",
+		Data:    location,
+	})
+}
+
+// handleNotifyMe subscribes the caller to a back-in-stock notification for
+// a sold-out product. Subscribing again while waiting returns the existing
+// subscription.
+func (h *Handler) handleNotifyMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	product, err := h.productStore.GetProductByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if product == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+	if product.Available > 0 {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("product %d is in stock", id))
+		return
+	}
+
+	sub, created, err := h.alertStore.Subscribe(product.ID, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	status, message := http.StatusOK, "already subscribed"
+	if created {
+		status, message = http.StatusCreated, "subscribed"
+	}
+	utils.WriteJson(w, status, types.SubscriptionResponse{
+		Message: message,
+		Data:    sub,
+	})
+}
+
+// productFromPath loads the product named by the {id} path variable,
+// writing the error response and returning false when it cannot.
+func (h *Handler) productFromPath(w http.ResponseWriter, r *http.Request) (*types.Product, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	product, err := h.productStore.GetProductByIDIncludingDeleted(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if product == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+		return nil, false
+	}
+	return product, true
+}
+
+// checkMovementVariant requires a variant of the product for products with
+// variants and none otherwise.
+func checkMovementVariant(variantID *int, variants []*types.ProductVariant) error {
+	if variantID == nil {
+		if len(variants) > 0 {
+			return fmt.Errorf("the product has variants; set variantId")
+		}
+		return nil
+	}
+	for _, v := range variants {
+		if v.ID == *variantID {
+			return nil
+		}
+	}
+	return fmt.Errorf("variant %d does not belong to the product", *variantID)
+}
@@ -0,0 +1,89 @@
+package inventory
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// stockStore keeps one product's stock in memory and records movements
+// the way the MySQL store does.
+type stockStore struct {
+	types.MovementStore
+	types.LocationStore
+	types.ProductStore
+	product   *types.Product
+	movements []*types.StockMovement
+}
+
+func (s *stockStore) GetLocationByID(id int) (*types.StockLocation, error) {
+	if id != types.DefaultLocationID {
+		return nil, nil
+	}
+	return &types.StockLocation{ID: id, Code: "MAIN"}, nil
+}
+
+func (s *stockStore) GetProductByIDIncludingDeleted(id int) (*types.Product, error) {
+	if s.product.ID != id {
+		return nil, nil
+	}
+	return s.product, nil
+}
+
+func (s *stockStore) RecordMovement(m *types.StockMovement) error {
+	if s.product.Quantity+m.Delta < 0 {
+		return fmt.Errorf("%w for product %d", types.ErrInsufficientStock, m.ProductID)
+	}
+	s.product.Quantity += m.Delta
+	m.ID = len(s.movements) + 1
+	s.movements = append(s.movements, m)
+	return nil
+}
+
+func TestHandleRecordMovement(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		variants []*types.ProductVariant
+		body     string
+		status   int
+		quantity int
+	}{
+		{"restock", "/products/1/movements", nil, `{"delta":5,"reason":"restock"}`, http.StatusCreated, 8},
+		{"correction down", "/products/1/movements", nil, `{"delta":-3,"reason":"adjustment"}`, http.StatusCreated, 0},
+		{"below zero", "/products/1/movements", nil, `{"delta":-4,"reason":"adjustment"}`, http.StatusConflict, 3},
+		{"zero delta", "/products/1/movements", nil, `{"delta":0,"reason":"restock"}`, http.StatusBadRequest, 3},
+		{"system reason", "/products/1/movements", nil, `{"delta":-1,"reason":"sale"}`, http.StatusBadRequest, 3},
+		{"missing variant", "/products/1/movements", []*types.ProductVariant{{ID: 7}}, `{"delta":1,"reason":"restock"}`, http.StatusBadRequest, 3},
+		{"foreign variant", "/products/1/movements", []*types.ProductVariant{{ID: 7}}, `{"variantId":8,"delta":1,"reason":"restock"}`, http.StatusBadRequest, 3},
+		{"variant", "/products/1/movements", []*types.ProductVariant{{ID: 7}}, `{"variantId":7,"delta":2,"reason":"return"}`, http.StatusCreated, 5},
+		{"unknown location", "/products/1/movements", nil, `{"locationId":9,"delta":1,"reason":"restock"}`, http.StatusBadRequest, 3},
+		{"unknown product", "/products/2/movements", nil, `{"delta":1,"reason":"restock"}`, http.StatusNotFound, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &stockStore{product: &types.Product{ID: 1, Quantity: 3, Variants: tt.variants}}
+			h := NewHandler(store, store, nil, store, nil)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/products/{id}/movements", h.handleRecordMovement)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+			if store.product.Quantity != tt.quantity {
+				t.Errorf("expected quantity %d, got %d", tt.quantity, store.product.Quantity)
+			}
+		})
+	}
+}
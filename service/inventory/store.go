@@ -0,0 +1,369 @@
+package inventory
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// sweepBatchSize caps the number of orders one sweep looks at.
+const sweepBatchSize = 500
+
+// Store implements types.ReservationStore, types.MovementStore,
+// types.LocationStore and types.AlertStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// ExpiredOrderIDs returns up to sweepBatchSize pending orders with an
+// active reservation that expired at or before now.
+func (s *Store) ExpiredOrderIDs(now time.Time) ([]int, error) {
+	rows, err := s.db.Query(
+		`SELECT DISTINCT r.orderId
+		   FROM inventory_reservations r
+		   JOIN orders o ON o.id = r.orderId
+		  WHERE r.status = ? AND r.expiresAt <= ? AND o.status = ?
+		  ORDER BY r.orderId
+		  LIMIT ?`,
+		types.ReservationActive, now.UTC(), types.OrderStatusPending, sweepBatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RecordMovement applies a manual movement and bumps the product version
+// in one transaction. Movements of products with variants must name a
+// variant, otherwise the product would drift from the sum of its variants.
+func (s *Store) RecordMovement(m *types.StockMovement) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var hasVariants bool
+	err = tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM product_variants v WHERE v.productId = products.id) FROM products WHERE id = ? FOR UPDATE",
+		m.ProductID,
+	).Scan(&hasVariants)
+	if err != nil {
+		return err
+	}
+	if hasVariants && m.VariantID == nil {
+		return fmt.Errorf("product %d has variants; name the variant to move", m.ProductID)
+	}
+	if !hasVariants && m.VariantID != nil {
+		return fmt.Errorf("product %d has no variants", m.ProductID)
+	}
+
+	if err := Move(tx, m); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE products SET version = version + 1 WHERE id = ?", m.ProductID); err != nil {
+		return err
+	}
+	if err := tx.QueryRow("SELECT createdAt FROM inventory_movements WHERE id = ?", m.ID).Scan(&m.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListMovements returns one page of a product's movements, newest first,
+// with the total number of movements.
+func (s *Store) ListMovements(productID int, page types.Pagination) ([]*types.StockMovement, int, error) {
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM inventory_movements WHERE productId = ?", productID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(
+		`SELECT id, productId, variantId, locationId, delta, reason, referenceId, actorId, createdAt
+		   FROM inventory_movements
+		  WHERE productId = ?
+		  ORDER BY id DESC
+		  LIMIT ? OFFSET ?`,
+		productID, page.PageSize, page.Offset(),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	movements := []*types.StockMovement{}
+	for rows.Next() {
+		m := new(types.StockMovement)
+		var variantID, referenceID, actorID sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.ProductID, &variantID, &m.LocationID, &m.Delta, &m.Reason, &referenceID, &actorID, &m.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		m.VariantID = sqlutil.NullableInt(variantID)
+		m.ReferenceID = sqlutil.NullableInt(referenceID)
+		m.ActorID = sqlutil.NullableInt(actorID)
+		movements = append(movements, m)
+	}
+	return movements, total, rows.Err()
+}
+
+// Reconcile compares the stock on hand of every product, variant and
+// stock level with the sum of its ledger movements and returns the ones
+// that differ.
+func (s *Store) Reconcile() ([]*types.StockDiscrepancy, error) {
+	rows, err := s.db.Query(
+		`SELECT p.id, NULL, NULL, p.quantity, COALESCE(SUM(m.delta), 0)
+		   FROM products p
+		   LEFT JOIN inventory_movements m ON m.productId = p.id
+		  GROUP BY p.id, p.quantity
+		 HAVING p.quantity <> COALESCE(SUM(m.delta), 0)
+		 UNION ALL
+		 SELECT v.productId, v.id, NULL, v.quantity, COALESCE(SUM(m.delta), 0)
+		   FROM product_variants v
+		   LEFT JOIN inventory_movements m ON m.variantId = v.id
+		  GROUP BY v.id, v.productId, v.quantity
+		 HAVING v.quantity <> COALESCE(SUM(m.delta), 0)
+		 UNION ALL
+		 SELECT l.productId, l.variantId, l.locationId, l.quantity, COALESCE(SUM(m.delta), 0)
+		   FROM stock_levels l
+		   LEFT JOIN inventory_movements m
+		     ON m.productId = l.productId AND COALESCE(m.variantId, 0) = l.variantKey AND m.locationId = l.locationId
+		  GROUP BY l.id, l.productId, l.variantId, l.locationId, l.quantity
+		 HAVING l.quantity <> COALESCE(SUM(m.delta), 0)
+		  ORDER BY 1, 2, 3`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	discrepancies := []*types.StockDiscrepancy{}
+	for rows.Next() {
+		d := new(types.StockDiscrepancy)
+		var variantID, locationID sql.NullInt64
+		if err := rows.Scan(&d.ProductID, &variantID, &locationID, &d.OnHand, &d.Ledger); err != nil {
+			return nil, err
+		}
+		d.VariantID = sqlutil.NullableInt(variantID)
+		d.LocationID = sqlutil.NullableInt(locationID)
+		discrepancies = append(discrepancies, d)
+	}
+	return discrepancies, rows.Err()
+}
+
+// ListLocations returns every stock location by ID.
+func (s *Store) ListLocations() ([]*types.StockLocation, error) {
+	return queryLocations(s.db, "ORDER BY id")
+}
+
+// GetLocationByID returns nil without an error when no location exists.
+func (s *Store) GetLocationByID(id int) (*types.StockLocation, error) {
+	return getLocation(s.db, "WHERE id = ?", id)
+}
+
+// GetLocationByCode returns nil without an error when no location exists.
+func (s *Store) GetLocationByCode(code string) (*types.StockLocation, error) {
+	return getLocation(s.db, "WHERE code = ?", code)
+}
+
+// CreateLocation inserts the location and fills its ID and createdAt.
+func (s *Store) CreateLocation(l *types.StockLocation) error {
+	result, err := s.db.Exec(
+		"INSERT INTO stock_locations (code, name, latitude, longitude) VALUES (?, ?, ?, ?)",
+		l.Code, l.Name, l.Latitude, l.Longitude,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	l.ID = int(id)
+	return s.db.QueryRow("SELECT createdAt FROM stock_locations WHERE id = ?", l.ID).Scan(&l.CreatedAt)
+}
+
+// ListStockLevels returns the stock of a product and its variants at every
+// location that keeps some, by variant then location.
+func (s *Store) ListStockLevels(productID int) ([]*types.StockLevel, error) {
+	return queryStockLevels(s.db, "WHERE s.productId = ? ORDER BY s.variantKey, s.locationId", productID)
+}
+
+// PendingAlerts returns up to limit undelivered alerts, oldest first.
+func (s *Store) PendingAlerts(limit int) ([]*types.StockAlert, error) {
+	rows, err := s.db.Query(
+		"SELECT id, productId, kind, quantity, createdAt FROM stock_alerts WHERE sentAt IS NULL ORDER BY id LIMIT ?",
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []*types.StockAlert
+	for rows.Next() {
+		a := new(types.StockAlert)
+		if err := rows.Scan(&a.ID, &a.ProductID, &a.Kind, &a.Quantity, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// MarkAlertSent takes the alert out of the outbox.
+func (s *Store) MarkAlertSent(id int) error {
+	_, err := s.db.Exec("UPDATE stock_alerts SET sentAt = CURRENT_TIMESTAMP WHERE id = ?", id)
+	return err
+}
+
+// WaitingSubscriptions returns the subscriptions to a product that have
+// not been notified yet, with the subscribers' email addresses.
+func (s *Store) WaitingSubscriptions(productID int) ([]*types.StockSubscription, error) {
+	rows, err := s.db.Query(
+		`SELECT s.id, s.productId, s.userId, u.email, s.createdAt, s.notifiedAt
+		   FROM stock_subscriptions s
+		   JOIN users u ON u.id = s.userId
+		  WHERE s.productId = ? AND s.notifiedAt IS NULL
+		  ORDER BY s.id`,
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []*types.StockSubscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// MarkSubscriptionNotified records that the subscriber was notified. The
+// user may subscribe to the product again afterwards.
+func (s *Store) MarkSubscriptionNotified(id int) error {
+	_, err := s.db.Exec("UPDATE stock_subscriptions SET notifiedAt = CURRENT_TIMESTAMP WHERE id = ?", id)
+	return err
+}
+
+// Subscribe returns the user's waiting subscription to the product,
+// creating it unless one exists.
+func (s *Store) Subscribe(productID, userID int) (*types.StockSubscription, bool, error) {
+	// the unique key on waiting subscriptions turns a repeated request
+	// into a no-op
+	result, err := s.db.Exec("INSERT IGNORE INTO stock_subscriptions (productId, userId) VALUES (?, ?)", productID, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	sub, err := scanSubscription(s.db.QueryRow(
+		`SELECT s.id, s.productId, s.userId, u.email, s.createdAt, s.notifiedAt
+		   FROM stock_subscriptions s
+		   JOIN users u ON u.id = s.userId
+		  WHERE s.productId = ? AND s.userId = ? AND s.notifiedAt IS NULL`,
+		productID, userID,
+	))
+	if err != nil {
+		return nil, false, err
+	}
+	return sub, n == 1, nil
+}
+
+func scanSubscription(row sqlutil.Scanner) (*types.StockSubscription, error) {
+	sub := new(types.StockSubscription)
+	var notifiedAt sql.NullTime
+	if err := row.Scan(&sub.ID, &sub.ProductID, &sub.UserID, &sub.Email, &sub.CreatedAt, &notifiedAt); err != nil {
+		return nil, err
+	}
+	if notifiedAt.Valid {
+		sub.NotifiedAt = &notifiedAt.Time
+	}
+	return sub, nil
+}
+
+func getLocation(q sqlutil.Querier, where string, args ...any) (*types.StockLocation, error) {
+	locations, err := queryLocations(q, where, args...)
+	if err != nil || len(locations) == 0 {
+		return nil, err
+	}
+	return locations[0], nil
+}
+
+func queryLocations(q sqlutil.Querier, where string, args ...any) ([]*types.StockLocation, error) {
+	rows, err := q.Query("SELECT id, code, name, latitude, longitude, createdAt FROM stock_locations "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []*types.StockLocation{}
+	for rows.Next() {
+		l := new(types.StockLocation)
+		var lat, lon sql.NullFloat64
+		if err := rows.Scan(&l.ID, &l.Code, &l.Name, &lat, &lon, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		if lat.Valid && lon.Valid {
+			l.Latitude, l.Longitude = &lat.Float64, &lon.Float64
+		}
+		locations = append(locations, l)
+	}
+	return locations, rows.Err()
+}
+
+// queryStockLevels reads stock levels aliased as s. Available subtracts the
+// active reservations at the same location.
+func queryStockLevels(q sqlutil.Querier, where string, args ...any) ([]*types.StockLevel, error) {
+	rows, err := q.Query(
+		`SELECT s.locationId, s.productId, s.variantId, s.quantity,
+		        CAST(s.quantity AS SIGNED) - (
+		            SELECT COALESCE(SUM(r.quantity), 0) FROM inventory_reservations r
+		             WHERE r.productId = s.productId AND COALESCE(r.variantId, 0) = s.variantKey
+		               AND r.locationId = s.locationId AND r.status = 'active')
+		   FROM stock_levels s `+where,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := []*types.StockLevel{}
+	for rows.Next() {
+		l := new(types.StockLevel)
+		var variantID sql.NullInt64
+		if err := rows.Scan(&l.LocationID, &l.ProductID, &variantID, &l.Quantity, &l.Available); err != nil {
+			return nil, err
+		}
+		l.VariantID = sqlutil.NullableInt(variantID)
+		levels = append(levels, l)
+	}
+	return levels, rows.Err()
+}
@@ -0,0 +1,79 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// SystemClock is the types.Clock used outside tests.
+type SystemClock struct{}
+
+// Now returns the current time.
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// Sweeper cancels pending orders whose reservations expired, which
+// releases the stock they held.
+type Sweeper struct {
+	reservations types.ReservationStore
+	orders       types.OrderStore
+	clock        types.Clock
+	interval     time.Duration
+}
+
+// NewSweeper creates a Sweeper that looks for expired reservations every
+// interval, judging expiry by clock.
+func NewSweeper(reservations types.ReservationStore, orders types.OrderStore, clock types.Clock, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		reservations: reservations,
+		orders:       orders,
+		clock:        clock,
+		interval:     interval,
+	}
+}
+
+// Run sweeps every interval until ctx is cancelled.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Sweep()
+			if err != nil {
+				log.Printf("reservation sweep failed: %v", err)
+			}
+			if n > 0 {
+				log.Printf("released the reservations of %d expired orders", n)
+			}
+		}
+	}
+}
+
+// Sweep cancels every pending order with an expired reservation and
+// returns how many were cancelled. Orders paid while the sweep runs are
+// skipped.
+func (s *Sweeper) Sweep() (int, error) {
+	ids, err := s.reservations.ExpiredOrderIDs(s.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	for _, id := range ids {
+		if err := s.orders.CancelOrder(id); err != nil {
+			if errors.Is(err, types.ErrOrderNotPending) {
+				continue
+			}
+			return cancelled, err
+		}
+		cancelled++
+	}
+	return cancelled, nil
+}
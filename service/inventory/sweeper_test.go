@@ -0,0 +1,107 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// fakeClock is a types.Clock that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// memoryStore keeps reservations and order statuses in memory.
+type memoryStore struct {
+	types.OrderStore
+	holds  []*types.Reservation
+	orders map[int]string
+}
+
+func (s *memoryStore) ExpiredOrderIDs(now time.Time) ([]int, error) {
+	var ids []int
+	seen := make(map[int]bool)
+	for _, r := range s.holds {
+		if r.Status == types.ReservationActive && !r.ExpiresAt.After(now) &&
+			s.orders[r.OrderID] == types.OrderStatusPending && !seen[r.OrderID] {
+			seen[r.OrderID] = true
+			ids = append(ids, r.OrderID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *memoryStore) CancelOrder(orderID int) error {
+	if s.orders[orderID] != types.OrderStatusPending {
+		return types.ErrOrderNotPending
+	}
+	for _, r := range s.holds {
+		if r.OrderID == orderID && r.Status == types.ReservationActive {
+			r.Status = types.ReservationReleased
+		}
+	}
+	s.orders[orderID] = types.OrderStatusCancelled
+	return nil
+}
+
+func TestSweeper(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC)}
+	ttl := 15 * time.Minute
+
+	store := &memoryStore{orders: map[int]string{}}
+	hold := func(orderID int, at time.Time) {
+		store.orders[orderID] = types.OrderStatusPending
+		store.holds = append(store.holds,
+			&types.Reservation{OrderID: orderID, ProductID: 1, Quantity: 1, Status: types.ReservationActive, ExpiresAt: at.Add(ttl)},
+			&types.Reservation{OrderID: orderID, ProductID: 2, Quantity: 2, Status: types.ReservationActive, ExpiresAt: at.Add(ttl)},
+		)
+	}
+
+	hold(1, clock.Now())
+	clock.Advance(5 * time.Minute)
+	hold(2, clock.Now())
+
+	sweeper := NewSweeper(store, store, clock, time.Minute)
+	sweep := func() int {
+		t.Helper()
+		n, err := sweeper.Sweep()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if n := sweep(); n != 0 {
+		t.Fatalf("expected nothing to expire yet, cancelled %d", n)
+	}
+
+	// order 1 expires exactly at its deadline; order 2 is paid in time
+	clock.Advance(10 * time.Minute)
+	store.orders[2] = types.OrderStatusPaid
+	if n := sweep(); n != 1 {
+		t.Fatalf("expected 1 cancelled order, got %d", n)
+	}
+	if store.orders[1] != types.OrderStatusCancelled || store.orders[2] != types.OrderStatusPaid {
+		t.Errorf("unexpected order statuses %v", store.orders)
+	}
+
+	var statuses []string
+	for _, r := range store.holds {
+		statuses = append(statuses, r.Status)
+	}
+	want := []string{types.ReservationReleased, types.ReservationReleased, types.ReservationActive, types.ReservationActive}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("expected reservations %v, got %v", want, statuses)
+	}
+
+	clock.Advance(time.Hour)
+	if n := sweep(); n != 0 {
+		t.Errorf("paid orders must not be swept, cancelled %d", n)
+	}
+}
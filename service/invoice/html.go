@@ -0,0 +1,19 @@
+package invoice
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+//go:embed invoice.html
+var htmlSource string
+
+var htmlTemplate = template.Must(template.New("invoice").Parse(htmlSource))
+
+// RenderHTML writes inv, issued by seller, as an HTML page.
+func RenderHTML(w io.Writer, inv *types.Invoice, seller types.InvoiceParty) error {
+	return htmlTemplate.Execute(w, newView(inv, seller))
+}
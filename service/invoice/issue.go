@@ -0,0 +1,312 @@
+// Package invoice issues invoices for paid orders and credit notes for
+// refunds, and renders them as HTML or PDF. Documents are issued inside
+// the transaction that pays the order or records the refund, which also
+// takes their number, so numbers have no gaps.
+package invoice
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// prefixes are the prefixes of the numbers of each kind of document.
+var prefixes = map[string]string{
+	types.InvoiceKindInvoice:    "INV-",
+	types.InvoiceKindCreditNote: "CN-",
+}
+
+// nextNumber takes the next number of a kind of document. The sequence row
+// stays locked until tx ends, so documents are numbered in the order they
+// commit and a rolled back document gives its number back.
+func nextNumber(tx *sql.Tx, kind string) (string, error) {
+	var next int
+	if err := tx.QueryRow("SELECT next FROM invoice_sequences WHERE kind = ? FOR UPDATE", kind).Scan(&next); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec("UPDATE invoice_sequences SET next = next + 1 WHERE kind = ?", kind); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%06d", prefixes[kind], next), nil
+}
+
+// Issue issues the invoice of an order inside tx, the transaction that
+// marks it paid. Orders are invoiced once; Issue does nothing for an order
+// that already has an invoice.
+func Issue(tx *sql.Tx, orderID int) error {
+	var existing int
+	err := tx.QueryRow("SELECT id FROM invoices WHERE orderId = ? AND kind = ?", orderID, types.InvoiceKindInvoice).Scan(&existing)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	inv, err := loadOrder(tx, orderID)
+	if err != nil {
+		return err
+	}
+	inv.Kind = types.InvoiceKindInvoice
+
+	lines, err := loadLines(tx, orderID, inv.Currency)
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		inv.Lines = append(inv.Lines, &types.InvoiceLine{
+			OrderItemID: &l.id,
+			Description: l.description,
+			Quantity:    l.quantity,
+			UnitPrice:   l.price,
+			Amount:      l.price.Mul(l.quantity),
+		})
+	}
+	if inv.TaxLines, err = queryTaxLines(tx, "SELECT name, rate, inclusive, amount FROM order_taxes WHERE orderId = ? ORDER BY id", orderID, inv.Currency); err != nil {
+		return err
+	}
+
+	return insert(tx, inv, nil)
+}
+
+// IssueCreditNote issues the credit note of refund inside tx, the
+// transaction that records it, so refund.ID must be set. The taxes of
+// every line are credited in proportion to the part of the line total
+// given back.
+func IssueCreditNote(tx *sql.Tx, refund *types.Refund) error {
+	inv, err := loadOrder(tx, refund.OrderID)
+	if err != nil {
+		return err
+	}
+	inv.Kind = types.InvoiceKindCreditNote
+	inv.RefundID = &refund.ID
+
+	var credited sql.NullInt64
+	if err := tx.QueryRow("SELECT id FROM invoices WHERE orderId = ? AND kind = ?", refund.OrderID, types.InvoiceKindInvoice).Scan(&credited); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	lines, err := loadLines(tx, refund.OrderID, inv.Currency)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]*line, len(lines))
+	for _, l := range lines {
+		byID[l.id] = l
+	}
+	inv.Subtotal = types.NewMoney(0, inv.Currency)
+	inv.Discount = types.NewMoney(0, inv.Currency)
+	inv.Shipping = types.NewMoney(0, inv.Currency)
+	inv.Tax = types.NewMoney(0, inv.Currency)
+	inv.TaxLines = nil
+	for _, item := range refund.Items {
+		l, ok := byID[item.OrderItemID]
+		if !ok {
+			return fmt.Errorf("order item %d is not part of order %d", item.OrderItemID, refund.OrderID)
+		}
+
+		quantity := 0
+		if refund.ReturnID != nil {
+			err := tx.QueryRow("SELECT quantity FROM return_items WHERE returnId = ? AND orderItemId = ?", *refund.ReturnID, l.id).Scan(&quantity)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+		}
+		inv.Lines = append(inv.Lines, &types.InvoiceLine{
+			OrderItemID: &l.id,
+			Description: l.description,
+			Quantity:    quantity,
+			UnitPrice:   l.price,
+			Amount:      item.Amount,
+		})
+		inv.Subtotal = inv.Subtotal.Add(item.Amount)
+
+		taxes, err := queryTaxLines(tx, "SELECT name, rate, inclusive, amount FROM order_item_taxes WHERE orderItemId = ? ORDER BY id", l.id, inv.Currency)
+		if err != nil {
+			return err
+		}
+		for _, t := range creditTaxes(taxes, item.Amount, l.total) {
+			inv.Tax = inv.Tax.Add(t.Amount)
+			inv.TaxLines = addTaxLine(inv.TaxLines, t)
+		}
+	}
+	inv.Total = refund.Amount
+
+	return insert(tx, inv, sqlutil.NullableInt(credited))
+}
+
+// creditTaxes returns the part of the tax lines of an order line that a
+// refund of amount out of the line total gives back, rounded half to even.
+func creditTaxes(lines []*types.TaxLine, amount, total types.Money) []*types.TaxLine {
+	if total.IsZero() {
+		return nil
+	}
+	credited := make([]*types.TaxLine, 0, len(lines))
+	for _, l := range lines {
+		c := *l
+		c.Amount = l.Amount.MulRatio(amount.Amount, total.Amount)
+		credited = append(credited, &c)
+	}
+	return credited
+}
+
+// addTaxLine adds line to lines, summing it into the one with the same
+// name, rate and inclusiveness if there is one.
+func addTaxLine(lines []*types.TaxLine, line *types.TaxLine) []*types.TaxLine {
+	for _, l := range lines {
+		if l.Name == line.Name && l.Rate == line.Rate && l.Inclusive == line.Inclusive {
+			l.Amount = l.Amount.Add(line.Amount)
+			return lines
+		}
+	}
+	return append(lines, line)
+}
+
+// loadOrder returns a document with the customer and amounts of an order.
+func loadOrder(tx *sql.Tx, orderID int) (*types.Invoice, error) {
+	inv := &types.Invoice{OrderID: orderID}
+	var subtotal, discount, shipping, tax, total string
+	var firstName, lastName string
+	var country, region sql.NullString
+	err := tx.QueryRow(
+		`SELECT o.subtotal, o.discount, o.shipping, o.tax, o.total, o.currency, o.address, o.country, o.region, u.firstName, u.lastName, u.email
+		 FROM orders o JOIN users u ON u.id = o.userId
+		 WHERE o.id = ?`,
+		orderID,
+	).Scan(&subtotal, &discount, &shipping, &tax, &total, &inv.Currency, &inv.Customer.Address, &country, &region, &firstName, &lastName, &inv.Customer.Email)
+	if err != nil {
+		return nil, err
+	}
+	inv.Customer.Name = firstName + " " + lastName
+	inv.Customer.Country, inv.Customer.Region = country.String, region.String
+
+	// the amounts are in the order currency
+	if inv.Subtotal, err = types.ParseMoney(subtotal, inv.Currency); err != nil {
+		return nil, err
+	}
+	if inv.Discount, err = types.ParseMoney(discount, inv.Currency); err != nil {
+		return nil, err
+	}
+	if inv.Shipping, err = types.ParseMoney(shipping, inv.Currency); err != nil {
+		return nil, err
+	}
+	if inv.Tax, err = types.ParseMoney(tax, inv.Currency); err != nil {
+		return nil, err
+	}
+	if inv.Total, err = types.ParseMoney(total, inv.Currency); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// line is an order item as invoiced.
+type line struct {
+	id          int
+	description string
+	quantity    int
+	price       types.Money
+	total       types.Money
+}
+
+// loadLines returns the items of an order, described by the name of their
+// product and the SKU of their variant.
+func loadLines(tx *sql.Tx, orderID int, currency string) ([]*line, error) {
+	rows, err := tx.Query(
+		`SELECT i.id, p.name, v.sku, i.quantity, i.price, i.total
+		 FROM order_items i
+		 JOIN products p ON p.id = i.productId
+		 LEFT JOIN product_variants v ON v.id = i.variantId
+		 WHERE i.orderId = ? ORDER BY i.id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []*line
+	for rows.Next() {
+		l := new(line)
+		var sku sql.NullString
+		var price, total string
+		if err := rows.Scan(&l.id, &l.description, &sku, &l.quantity, &price, &total); err != nil {
+			return nil, err
+		}
+		if sku.Valid {
+			l.description += " (" + sku.String + ")"
+		}
+		if l.price, err = types.ParseMoney(price, currency); err != nil {
+			return nil, err
+		}
+		if l.total, err = types.ParseMoney(total, currency); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// queryTaxLines reads tax lines in currency with query.
+func queryTaxLines(tx *sql.Tx, query string, id int, currency string) ([]*types.TaxLine, error) {
+	rows, err := tx.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []*types.TaxLine
+	for rows.Next() {
+		l := new(types.TaxLine)
+		var amount string
+		if err := rows.Scan(&l.Name, &l.Rate, &l.Inclusive, &amount); err != nil {
+			return nil, err
+		}
+		if l.Amount, err = types.ParseMoney(amount, currency); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// insert numbers the document and inserts it with its lines and taxes.
+func insert(tx *sql.Tx, inv *types.Invoice, creditedID *int) error {
+	number, err := nextNumber(tx, inv.Kind)
+	if err != nil {
+		return err
+	}
+	inv.Number = number
+
+	result, err := tx.Exec(
+		"INSERT INTO invoices (number, kind, orderId, refundId, creditedInvoiceId, customerName, customerEmail, address, country, region, subtotal, discount, shipping, tax, total, currency) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		inv.Number, inv.Kind, inv.OrderID, inv.RefundID, creditedID, inv.Customer.Name, inv.Customer.Email, inv.Customer.Address, sqlutil.NullString(inv.Customer.Country), sqlutil.NullString(inv.Customer.Region), inv.Subtotal, inv.Discount, inv.Shipping, inv.Tax, inv.Total, inv.Currency,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	inv.ID = int(id)
+
+	for _, l := range inv.Lines {
+		if _, err := tx.Exec(
+			"INSERT INTO invoice_lines (invoiceId, orderItemId, description, quantity, unitPrice, amount) VALUES (?, ?, ?, ?, ?, ?)",
+			inv.ID, l.OrderItemID, l.Description, l.Quantity, l.UnitPrice, l.Amount,
+		); err != nil {
+			return err
+		}
+	}
+	for _, t := range inv.TaxLines {
+		if _, err := tx.Exec(
+			"INSERT INTO invoice_taxes (invoiceId, name, rate, inclusive, amount) VALUES (?, ?, ?, ?, ?)",
+			inv.ID, t.Name, t.Rate, t.Inclusive, t.Amount,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
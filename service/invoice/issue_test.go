@@ -0,0 +1,42 @@
+package invoice
+
+import (
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/testutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestCreditTaxes(t *testing.T) {
+	// a line of 30.00 with 2.50 of included VAT and 0.75 of added tax
+	lines := []*types.TaxLine{
+		{Name: "VAT", Rate: 20, Inclusive: true, Amount: testutil.USD(250)},
+		{Name: "Levy", Rate: 2.5, Amount: testutil.USD(75)},
+	}
+
+	credited := creditTaxes(lines, testutil.USD(1000), testutil.USD(3000))
+	if len(credited) != 2 || credited[0].Amount != testutil.USD(83) || credited[1].Amount != testutil.USD(25) {
+		t.Fatalf("expected 0.83 and 0.25 credited, got %v and %v", credited[0].Amount, credited[1].Amount)
+	}
+	if lines[0].Amount != testutil.USD(250) {
+		t.Errorf("expected the order tax lines untouched, got %s", lines[0].Amount)
+	}
+
+	if credited := creditTaxes(lines, testutil.USD(3000), testutil.USD(3000)); credited[0].Amount != testutil.USD(250) || credited[1].Amount != testutil.USD(75) {
+		t.Errorf("expected a full refund to credit all taxes, got %v", credited)
+	}
+	if credited := creditTaxes(lines, testutil.USD(0), testutil.USD(0)); len(credited) != 0 {
+		t.Errorf("expected nothing credited on a free line, got %v", credited)
+	}
+}
+
+func TestAddTaxLine(t *testing.T) {
+	var lines []*types.TaxLine
+	lines = addTaxLine(lines, &types.TaxLine{Name: "VAT", Rate: 20, Inclusive: true, Amount: testutil.USD(100)})
+	lines = addTaxLine(lines, &types.TaxLine{Name: "VAT", Rate: 10, Inclusive: true, Amount: testutil.USD(50)})
+	lines = addTaxLine(lines, &types.TaxLine{Name: "VAT", Rate: 20, Inclusive: true, Amount: testutil.USD(30)})
+
+	if len(lines) != 2 || lines[0].Amount != testutil.USD(130) || lines[1].Amount != testutil.USD(50) {
+		t.Errorf("expected 1.30 at 20%% and 0.50 at 10%%, got %v", lines)
+	}
+}
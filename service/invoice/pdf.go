@@ -0,0 +1,281 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// The page is A4 in points, the unit of PDF coordinates, which start at the
+// bottom left corner.
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+	margin     = 50.0
+)
+
+// The columns of the lines table: the description starts at colDescription
+// and wraps before colQuantity, the other columns are aligned right on
+// their x.
+const (
+	colDescription = margin
+	descWidth      = 290.0
+	colQuantity    = 385.0
+	colUnitPrice   = 465.0
+	colAmount      = pageWidth - margin
+)
+
+// font is one of the two standard fonts the documents use, which every PDF
+// reader has, so nothing needs embedding.
+type font int
+
+const (
+	regular font = iota
+	bold
+)
+
+var fontNames = [...]string{regular: "Helvetica", bold: "Helvetica-Bold"}
+
+// fontWidths are the widths of the printable ASCII characters, from the
+// space to the tilde, in thousandths of the font size. Other characters
+// are taken as wide as a digit.
+var fontWidths = [...][95]int{
+	regular: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	bold: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// winAnsi maps the characters of WinAnsiEncoding outside Latin-1 to their
+// byte. Latin-1 characters are their own byte.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// encode converts s to WinAnsiEncoding, replacing what it cannot encode
+// with a question mark.
+func encode(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch c, ok := winAnsi[r]; {
+		case ok:
+			b = append(b, c)
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b = append(b, byte(r))
+		default:
+			b = append(b, '?')
+		}
+	}
+	return b
+}
+
+// textWidth returns the width of s in points when set in f at size.
+func textWidth(f font, size float64, s string) float64 {
+	w := 0
+	for _, c := range encode(s) {
+		if c >= 0x20 && c < 0x7f {
+			w += fontWidths[f][c-0x20]
+		} else {
+			w += 556
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// wrap breaks s into lines no wider than width, between words. A word
+// wider than width gets a line of its own.
+func wrap(f font, size float64, s string, width float64) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(s) {
+		next := word
+		if current != "" {
+			next = current + " " + word
+		}
+		if current != "" && textWidth(f, size, next) > width {
+			lines = append(lines, current)
+			next = word
+		}
+		current = next
+	}
+	return append(lines, current)
+}
+
+// pdf lays text out on pages, starting a new page when the current one is
+// full.
+type pdf struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func newPDF() *pdf {
+	d := new(pdf)
+	d.newPage()
+	return d
+}
+
+func (d *pdf) newPage() {
+	d.pages = append(d.pages, new(bytes.Buffer))
+	d.y = pageHeight - margin
+}
+
+// room starts a new page unless height points are left on this one.
+func (d *pdf) room(height float64) {
+	if d.y-height < margin {
+		d.newPage()
+	}
+}
+
+// text sets s at x on the baseline y of the current page.
+func (d *pdf) text(f font, size, x, y float64, s string) {
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "BT /F%d %.2f Tf %.2f %.2f Td ", f+1, size, x, y)
+	writeString(page, s)
+	page.WriteString(" Tj ET\n")
+}
+
+// textRight sets s so that it ends at x.
+func (d *pdf) textRight(f font, size, x, y float64, s string) {
+	d.text(f, size, x-textWidth(f, size, s), y, s)
+}
+
+// rule draws a horizontal line from x1 to x2 at y.
+func (d *pdf) rule(x1, x2, y, width float64) {
+	fmt.Fprintf(d.pages[len(d.pages)-1], "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y, x2, y)
+}
+
+// writeString writes s as a PDF string literal.
+func writeString(w *bytes.Buffer, s string) {
+	w.WriteByte('(')
+	for _, c := range encode(s) {
+		if c == '(' || c == ')' || c == '\\' {
+			w.WriteByte('\\')
+		}
+		w.WriteByte(c)
+	}
+	w.WriteByte(')')
+}
+
+// RenderPDF writes inv, issued by seller, as a PDF document.
+func RenderPDF(w io.Writer, inv *types.Invoice, seller types.InvoiceParty) error {
+	v := newView(inv, seller)
+	d := newPDF()
+
+	d.text(bold, 22, margin, d.y-22, v.Title)
+	d.y -= 40
+	meta := fmt.Sprintf("%s  ·  issued %s  ·  order #%d", v.Number, v.IssuedOn, v.OrderID)
+	if v.Credits != "" {
+		meta += "  ·  credits invoice " + v.Credits
+	}
+	d.text(regular, 10, margin, d.y, meta)
+	d.y -= 36
+
+	// the seller on the left, the customer on the right
+	top := d.y
+	d.text(bold, 8, margin, top, "FROM")
+	d.text(bold, 8, pageWidth/2+20, top, "BILL TO")
+	for i, l := range v.Seller {
+		d.text(regular, 10, margin, top-14*float64(i+1), l)
+	}
+	for i, l := range v.Customer {
+		d.text(regular, 10, pageWidth/2+20, top-14*float64(i+1), l)
+	}
+	d.y = top - 14*float64(max(len(v.Seller), len(v.Customer))+1) - 24
+
+	header := func() {
+		d.text(bold, 10, colDescription, d.y, "Description")
+		d.textRight(bold, 10, colQuantity, d.y, "Qty")
+		d.textRight(bold, 10, colUnitPrice, d.y, "Unit price")
+		d.textRight(bold, 10, colAmount, d.y, "Amount ("+v.Currency+")")
+		d.rule(margin, colAmount, d.y-6, 1)
+		d.y -= 22
+	}
+	header()
+	for _, l := range v.Lines {
+		desc := wrap(regular, 10, l.Description, descWidth)
+		height := 14*float64(len(desc)) + 8
+		if d.y-height < margin {
+			d.newPage()
+			header()
+		}
+		d.textRight(regular, 10, colQuantity, d.y, l.Quantity)
+		d.textRight(regular, 10, colUnitPrice, d.y, l.UnitPrice)
+		d.textRight(regular, 10, colAmount, d.y, l.Amount)
+		for i, s := range desc {
+			d.text(regular, 10, colDescription, d.y-14*float64(i), s)
+		}
+		d.y -= height
+		d.rule(margin, colAmount, d.y+10, 0.25)
+	}
+
+	d.y -= 10
+	for _, t := range v.Totals {
+		d.room(18)
+		f := regular
+		if t.Strong {
+			f = bold
+			d.rule(colUnitPrice-120, colAmount, d.y+12, 1)
+			d.y -= 4
+		}
+		d.textRight(f, 10, colUnitPrice, d.y, t.Label)
+		d.textRight(f, 10, colAmount, d.y, t.Amount)
+		d.y -= 16
+	}
+
+	return d.write(w)
+}
+
+// write writes the document: the catalog, the page tree and the two fonts,
+// then a page and its content stream for each page, and the cross
+// reference table with the offset of every object.
+func (d *pdf) write(w io.Writer) error {
+	var b bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	for _, name := range fontNames {
+		object("<< /Type /Font /Subtype /Type1 /BaseFont /" + name + " /Encoding /WinAnsiEncoding >>")
+	}
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", page.Len(), page.Bytes()))
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
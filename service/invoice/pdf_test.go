@@ -0,0 +1,90 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/testutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func testInvoice(lines int) *types.Invoice {
+	inv := &types.Invoice{
+		ID:       1,
+		Number:   "INV-000042",
+		Kind:     types.InvoiceKindInvoice,
+		OrderID:  7,
+		Customer: types.InvoiceParty{Name: "Ada <Lovelace>", Email: "ada@example.com", Address: "1 Main St (rear)\nLondon", Country: "GB"},
+		Subtotal: testutil.USD(1000 * int64(lines)),
+		Discount: testutil.USD(0),
+		Shipping: testutil.USD(499),
+		TaxLines: []*types.TaxLine{{Name: "VAT", Rate: 20, Inclusive: true, Amount: testutil.USD(167)}},
+		Total:    testutil.USD(1000*int64(lines) + 499),
+		Currency: types.DefaultCurrency,
+		IssuedAt: "2026-03-20T09:00:00Z",
+	}
+	for i := range lines {
+		inv.Lines = append(inv.Lines, &types.InvoiceLine{Description: fmt.Sprintf("Café mug %d – blue", i), Quantity: 2, UnitPrice: testutil.USD(500), Amount: testutil.USD(1000)})
+	}
+	return inv
+}
+
+func TestRenderHTML(t *testing.T) {
+	var b bytes.Buffer
+	if err := RenderHTML(&b, testInvoice(1), types.InvoiceParty{Name: "Shop"}); err != nil {
+		t.Fatal(err)
+	}
+	page := b.String()
+	for _, want := range []string{"INV-000042", "20 March 2026", "Ada &lt;Lovelace&gt;", "VAT 20% (included)", "14.99 USD"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected the page to contain %q", want)
+		}
+	}
+	if strings.Contains(page, "<Lovelace>") {
+		t.Error("expected the customer name to be escaped")
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	var b bytes.Buffer
+	if err := RenderPDF(&b, testInvoice(80), types.InvoiceParty{Name: "Shop"}); err != nil {
+		t.Fatal(err)
+	}
+	doc := b.Bytes()
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(doc, []byte("%%EOF\n")) {
+		t.Fatal("expected a PDF header and trailer")
+	}
+
+	// every entry of the cross reference table points at its object
+	start := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(doc)
+	if start == nil {
+		t.Fatal("expected startxref")
+	}
+	xref, _ := strconv.Atoi(string(start[1]))
+	if !bytes.HasPrefix(doc[xref:], []byte("xref\n")) {
+		t.Fatalf("expected the cross reference table at %d", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(doc[xref:], -1)
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(doc[offset:], []byte(want)) {
+			t.Errorf("expected object %d at offset %d", i+1, offset)
+		}
+	}
+
+	// 80 lines do not fit one page
+	count := regexp.MustCompile(`/Count (\d+) `).FindSubmatch(doc)
+	if pages, _ := strconv.Atoi(string(count[1])); pages < 2 {
+		t.Errorf("expected the lines to spread over several pages, got %d", pages)
+	}
+	if !bytes.Contains(doc, []byte("(1 Main St \\(rear\\))")) {
+		t.Error("expected parentheses to be escaped")
+	}
+	if !bytes.Contains(doc, []byte("Caf\xe9 mug 0 \x96 blue")) {
+		t.Error("expected text in WinAnsiEncoding")
+	}
+}
@@ -0,0 +1,114 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves the invoices and credit notes of orders to their owners.
+type Handler struct {
+	store      types.InvoiceStore
+	orderStore types.OrderStore
+	seller     types.InvoiceParty
+}
+
+// NewHandler creates a new Handler rendering documents as issued by
+// seller.
+func NewHandler(store types.InvoiceStore, orderStore types.OrderStore, seller types.InvoiceParty) *Handler {
+	return &Handler{store: store, orderStore: orderStore, seller: seller}
+}
+
+// RegisterRoutes attaches invoice routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/orders/{id:[0-9]+}/invoice", auth.RequireToken(h.handleGetOrderInvoice)).Methods("GET")
+	router.HandleFunc("/orders/{id:[0-9]+}/invoices", auth.RequireToken(h.handleListInvoices)).Methods("GET")
+	router.HandleFunc("/orders/{id:[0-9]+}/invoices/{invoiceId:[0-9]+}", auth.RequireToken(h.handleGetInvoice)).Methods("GET")
+}
+
+func (h *Handler) handleGetOrderInvoice(w http.ResponseWriter, r *http.Request) {
+	order, ok := auth.OwnOrder(w, r, h.orderStore)
+	if !ok {
+		return
+	}
+
+	inv, err := h.store.GetOrderInvoice(order.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if inv == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order %d has no invoice until it is paid", order.ID))
+		return
+	}
+	h.render(w, r, inv)
+}
+
+func (h *Handler) handleListInvoices(w http.ResponseWriter, r *http.Request) {
+	order, ok := auth.OwnOrder(w, r, h.orderStore)
+	if !ok {
+		return
+	}
+
+	invoices, err := h.store.ListInvoicesByOrder(order.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJson(w, http.StatusOK, types.ListInvoicesResponse{Message: "invoices fetched", Data: invoices})
+}
+
+func (h *Handler) handleGetInvoice(w http.ResponseWriter, r *http.Request) {
+	order, ok := auth.OwnOrder(w, r, h.orderStore)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["invoiceId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid invoice id"))
+		return
+	}
+
+	inv, err := h.store.GetInvoiceByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if inv == nil || inv.OrderID != order.ID {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("invoice not found"))
+		return
+	}
+	h.render(w, r, inv)
+}
+
+// render writes the document as a PDF when the request asks for one with
+// ?format=pdf or its Accept header, and as HTML otherwise. The document is
+// rendered in memory first so a failure can still be reported.
+func (h *Handler) render(w http.ResponseWriter, r *http.Request, inv *types.Invoice) {
+	var body bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	var err error
+	if r.URL.Query().Get("format") == "pdf" || strings.Contains(r.Header.Get("Accept"), "application/pdf") {
+		contentType = "application/pdf"
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", inv.Number+".pdf"))
+		err = RenderPDF(&body, inv, h.seller)
+	} else {
+		err = RenderHTML(&body, inv, h.seller)
+	}
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	body.WriteTo(w)
+}
@@ -0,0 +1,178 @@
+package invoice
+
+import (
+	"database/sql"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// invoiceColumns lists the columns read by scanInvoice, in scan order. The
+// last one is the number of the invoice a credit note corrects.
+const invoiceColumns = "i.id, i.number, i.kind, i.orderId, i.refundId, i.customerName, i.customerEmail, i.address, i.country, i.region, i.subtotal, i.discount, i.shipping, i.tax, i.total, i.currency, i.issuedAt, c.number"
+
+// Store implements types.InvoiceStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanInvoice(row sqlutil.Scanner) (*types.Invoice, error) {
+	inv := new(types.Invoice)
+	var subtotal, discount, shipping, tax, total string
+	var refundID sql.NullInt64
+	var country, region, credited sql.NullString
+	err := row.Scan(&inv.ID, &inv.Number, &inv.Kind, &inv.OrderID, &refundID, &inv.Customer.Name, &inv.Customer.Email, &inv.Customer.Address, &country, &region, &subtotal, &discount, &shipping, &tax, &total, &inv.Currency, &inv.IssuedAt, &credited)
+	if err != nil {
+		return nil, err
+	}
+	// the amounts are in the invoice currency
+	if inv.Subtotal, err = types.ParseMoney(subtotal, inv.Currency); err != nil {
+		return nil, err
+	}
+	if inv.Discount, err = types.ParseMoney(discount, inv.Currency); err != nil {
+		return nil, err
+	}
+	if inv.Shipping, err = types.ParseMoney(shipping, inv.Currency); err != nil {
+		return nil, err
+	}
+	if inv.Tax, err = types.ParseMoney(tax, inv.Currency); err != nil {
+		return nil, err
+	}
+	if inv.Total, err = types.ParseMoney(total, inv.Currency); err != nil {
+		return nil, err
+	}
+	inv.RefundID = sqlutil.NullableInt(refundID)
+	inv.Customer.Country, inv.Customer.Region = country.String, region.String
+	inv.CreditedNumber = credited.String
+	return inv, nil
+}
+
+// GetInvoiceByID returns the invoice or credit note with that ID, or nil.
+func (s *Store) GetInvoiceByID(id int) (*types.Invoice, error) {
+	invoices, err := s.queryInvoices("WHERE i.id = ?", id)
+	if err != nil || len(invoices) == 0 {
+		return nil, err
+	}
+	return invoices[0], nil
+}
+
+// GetOrderInvoice returns the invoice of an order, or nil when the order
+// was not paid yet.
+func (s *Store) GetOrderInvoice(orderID int) (*types.Invoice, error) {
+	invoices, err := s.queryInvoices("WHERE i.orderId = ? AND i.kind = ?", orderID, types.InvoiceKindInvoice)
+	if err != nil || len(invoices) == 0 {
+		return nil, err
+	}
+	return invoices[0], nil
+}
+
+// ListInvoicesByOrder returns the invoice and credit notes of an order in
+// the order they were issued.
+func (s *Store) ListInvoicesByOrder(orderID int) ([]*types.Invoice, error) {
+	return s.queryInvoices("WHERE i.orderId = ? ORDER BY i.id", orderID)
+}
+
+// queryInvoices loads the documents matching the clause with their lines
+// and tax lines.
+func (s *Store) queryInvoices(clause string, args ...any) ([]*types.Invoice, error) {
+	rows, err := s.db.Query("SELECT "+invoiceColumns+" FROM invoices i LEFT JOIN invoices c ON c.id = i.creditedInvoiceId "+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []*types.Invoice{}
+	byID := make(map[int]*types.Invoice)
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		inv.Lines, inv.TaxLines = []*types.InvoiceLine{}, []*types.TaxLine{}
+		invoices = append(invoices, inv)
+		byID[inv.ID] = inv
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return invoices, nil
+	}
+
+	ids := make([]any, 0, len(invoices))
+	for _, inv := range invoices {
+		ids = append(ids, inv.ID)
+	}
+	if err := s.loadLines(byID, ids); err != nil {
+		return nil, err
+	}
+	if err := s.loadTaxLines(byID, ids); err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// loadLines attaches their lines to the documents, which are keyed by ID.
+func (s *Store) loadLines(byID map[int]*types.Invoice, ids []any) error {
+	rows, err := s.db.Query(
+		"SELECT invoiceId, orderItemId, description, quantity, unitPrice, amount FROM invoice_lines WHERE invoiceId IN ("+sqlutil.Placeholders(len(ids))+") ORDER BY id",
+		ids...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		l := new(types.InvoiceLine)
+		var invoiceID int
+		var orderItemID sql.NullInt64
+		var unitPrice, amount string
+		if err := rows.Scan(&invoiceID, &orderItemID, &l.Description, &l.Quantity, &unitPrice, &amount); err != nil {
+			return err
+		}
+		inv := byID[invoiceID]
+		if l.UnitPrice, err = types.ParseMoney(unitPrice, inv.Currency); err != nil {
+			return err
+		}
+		if l.Amount, err = types.ParseMoney(amount, inv.Currency); err != nil {
+			return err
+		}
+		l.OrderItemID = sqlutil.NullableInt(orderItemID)
+		inv.Lines = append(inv.Lines, l)
+	}
+	return rows.Err()
+}
+
+// loadTaxLines attaches their tax lines to the documents, which are keyed
+// by ID.
+func (s *Store) loadTaxLines(byID map[int]*types.Invoice, ids []any) error {
+	rows, err := s.db.Query(
+		"SELECT invoiceId, name, rate, inclusive, amount FROM invoice_taxes WHERE invoiceId IN ("+sqlutil.Placeholders(len(ids))+") ORDER BY id",
+		ids...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		l := new(types.TaxLine)
+		var invoiceID int
+		var amount string
+		if err := rows.Scan(&invoiceID, &l.Name, &l.Rate, &l.Inclusive, &amount); err != nil {
+			return err
+		}
+		inv := byID[invoiceID]
+		if l.Amount, err = types.ParseMoney(amount, inv.Currency); err != nil {
+			return err
+		}
+		inv.TaxLines = append(inv.TaxLines, l)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,119 @@
+package invoice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// view is a document as both renderers lay it out, with every amount
+// formatted.
+type view struct {
+	Title    string
+	Number   string
+	IssuedOn string
+	OrderID  int
+	Credits  string
+	Seller   []string
+	Customer []string
+	Currency string
+	Lines    []viewLine
+	Totals   []viewTotal
+}
+
+type viewLine struct {
+	Description string
+	Quantity    string
+	UnitPrice   string
+	Amount      string
+}
+
+type viewTotal struct {
+	Label  string
+	Amount string
+	Strong bool
+}
+
+// newView lays out inv as issued by seller. The amounts of credit notes
+// include their taxes, so their tax lines are shown as included.
+func newView(inv *types.Invoice, seller types.InvoiceParty) *view {
+	v := &view{
+		Title:    "Invoice",
+		Number:   inv.Number,
+		IssuedOn: issuedOn(inv.IssuedAt),
+		OrderID:  inv.OrderID,
+		Credits:  inv.CreditedNumber,
+		Seller:   partyLines(seller),
+		Customer: partyLines(inv.Customer),
+		Currency: inv.Currency,
+	}
+	credit := inv.Kind == types.InvoiceKindCreditNote
+	if credit {
+		v.Title = "Credit note"
+	}
+
+	for _, l := range inv.Lines {
+		line := viewLine{Description: l.Description, UnitPrice: l.UnitPrice.String(), Amount: l.Amount.String()}
+		if l.Quantity > 0 {
+			line.Quantity = strconv.Itoa(l.Quantity)
+		}
+		v.Lines = append(v.Lines, line)
+	}
+
+	if !credit {
+		v.Totals = append(v.Totals, viewTotal{Label: "Subtotal", Amount: inv.Subtotal.String()})
+		if !inv.Discount.IsZero() {
+			v.Totals = append(v.Totals, viewTotal{Label: "Discount", Amount: "-" + inv.Discount.String()})
+		}
+		if !inv.Shipping.IsZero() {
+			v.Totals = append(v.Totals, viewTotal{Label: "Shipping", Amount: inv.Shipping.String()})
+		}
+	}
+	for _, t := range inv.TaxLines {
+		label := fmt.Sprintf("%s %s%%", t.Name, strconv.FormatFloat(t.Rate, 'f', -1, 64))
+		if credit || t.Inclusive {
+			label += " (included)"
+		}
+		v.Totals = append(v.Totals, viewTotal{Label: label, Amount: t.Amount.String()})
+	}
+	total := viewTotal{Label: "Total", Amount: inv.Total.String() + " " + inv.Currency, Strong: true}
+	if credit {
+		total.Label = "Total credited"
+	}
+	v.Totals = append(v.Totals, total)
+	return v
+}
+
+// partyLines returns the name, address lines, region and country, email
+// and tax ID of a party, skipping what is empty.
+func partyLines(p types.InvoiceParty) []string {
+	var lines []string
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			lines = append(lines, s)
+		}
+	}
+	add(p.Name)
+	for _, l := range strings.Split(p.Address, "\n") {
+		add(l)
+	}
+	add(strings.TrimSpace(p.Region + " " + p.Country))
+	add(p.Email)
+	if p.TaxID != "" {
+		add("Tax ID " + p.TaxID)
+	}
+	return lines
+}
+
+// issuedOn formats the issue timestamp of a document as a date, or returns
+// it as it is when it cannot be parsed.
+func issuedOn(issuedAt string) string {
+	t, err := time.Parse(time.RFC3339, issuedAt)
+	if err != nil {
+		return issuedAt
+	}
+	return t.Format("2 January 2006")
+}
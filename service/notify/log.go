@@ -0,0 +1,32 @@
+// Package notify delivers stock notifications. Log writes them to the
+// application log; Webhook posts them as JSON to an HTTP endpoint, which
+// can forward them by email, chat or any other channel.
+package notify
+
+import (
+	"context"
+	"log"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// Log is a types.Notifier that writes notifications to the standard
+// logger. It is used when no webhook is configured.
+type Log struct{}
+
+// Notify logs n and never fails.
+func (Log) Notify(ctx context.Context, n *types.Notification) error {
+	switch n.Kind {
+	case types.AlertLowStock:
+		threshold := 0
+		if n.Threshold != nil {
+			threshold = *n.Threshold
+		}
+		log.Printf("low stock: product %d (%s) has %d left, threshold %d", n.ProductID, n.ProductName, n.Quantity, threshold)
+	case types.AlertBackInStock:
+		log.Printf("back in stock: product %d (%s) has %d available, notify user %d <%s>", n.ProductID, n.ProductName, n.Available, n.UserID, n.Email)
+	default:
+		log.Printf("%s: product %d (%s)", n.Kind, n.ProductID, n.ProductName)
+	}
+	return nil
+}
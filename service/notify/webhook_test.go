@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestWebhook(t *testing.T) {
+	var got types.Notification
+	var body []byte
+	var signature string
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		json.Unmarshal(body, &got)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	hook := NewWebhook(server.URL, "s3cret", server.Client())
+	n := &types.Notification{Kind: types.AlertBackInStock, ProductID: 4, ProductName: "Hat", Available: 2, UserID: 9, Email: "a@example.com"}
+
+	if err := hook.Notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if got != *n {
+		t.Errorf("expected %+v, got %+v", *n, got)
+	}
+	if signature != Sign("s3cret", body) {
+		t.Errorf("bad signature %q", signature)
+	}
+
+	status = http.StatusBadGateway
+	if err := hook.Notify(context.Background(), n); err == nil {
+		t.Error("expected an error for a 502 response")
+	}
+
+	unsigned := NewWebhook(server.URL, "", server.Client())
+	status = http.StatusOK
+	unsigned.Notify(context.Background(), n)
+	if signature != "" {
+		t.Errorf("expected no signature without a secret, got %q", signature)
+	}
+}
@@ -0,0 +1,136 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves the authenticated user's orders.
+type Handler struct {
+	store     types.OrderStore
+	userStore types.UserStore
+}
+
+// NewHandler creates a new Handler with the given OrderStore. The user
+// store authorizes admin-only operations.
+func NewHandler(store types.OrderStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+// RegisterRoutes attaches order routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/orders", auth.RequireToken(h.handleListOrders)).Methods("GET")
+	router.HandleFunc("/orders/{id}", auth.RequireToken(h.handleGetOrder)).Methods("GET")
+	router.HandleFunc("/orders/{id}/cancel", auth.RequireToken(h.handleCancelOrder)).Methods("POST")
+	router.HandleFunc("/orders/{id}/mark-paid", auth.RequireAdmin(h.handleMarkPaid, h.userStore)).Methods("POST")
+}
+
+func (h *Handler) handleListOrders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	orders, err := h.store.ListOrdersByUser(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list orders: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListOrdersResponse{
+		Message: "success",
+		Data:    orders,
+	})
+}
+
+// handleGetOrder returns one order with its items. Orders belonging to other
+// users are reported as not found so their IDs cannot be probed.
+func (h *Handler) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := auth.OwnOrder(w, r, h.store)
+	if !ok {
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.OrderResponse{
+		Message: "success",
+		Data:    order,
+	})
+}
+
+// handleCancelOrder lets a customer cancel their own pending order, which
+// releases the stock reserved for it.
+func (h *Handler) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := auth.OwnOrder(w, r, h.store)
+	if !ok {
+		return
+	}
+
+	h.leavePending(w, order.ID, h.store.CancelOrder, "order cancelled")
+}
+
+// handleMarkPaid records that an order was paid outside the API, such as
+// by bank transfer, and commits its reserved stock. Admins only.
+func (h *Handler) handleMarkPaid(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.orderFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	h.leavePending(w, order.ID, h.store.MarkOrderPaid, "order paid")
+}
+
+// leavePending applies a status change to a pending order and responds
+// with the updated order.
+func (h *Handler) leavePending(w http.ResponseWriter, id int, change func(int) error, message string) {
+	if err := change(id); err != nil {
+		switch {
+		case errors.Is(err, types.ErrOrderNotPending):
+			utils.WriteError(w, http.StatusConflict, err)
+		case errors.Is(err, types.ErrInsufficientStock):
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("reserved stock is no longer on hand: %w", err))
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	order, err := h.store.GetOrderByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.OrderResponse{
+		Message: message,
+		Data:    order,
+	})
+}
+
+// orderFromPath loads the order named by {id}, writing the error response
+// itself when it cannot.
+func (h *Handler) orderFromPath(w http.ResponseWriter, r *http.Request) (*types.Order, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	order, err := h.store.GetOrderByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if order == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order not found"))
+		return nil, false
+	}
+	return order, true
+}
@@ -0,0 +1,463 @@
+// Package order provides data access for orders and their line items, and
+// the endpoints customers use to look up their own orders.
+package order
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/service/cart"
+	"github.com/nandaiqbalh/go-backend-ecom/service/coupon"
+	"github.com/nandaiqbalh/go-backend-ecom/service/currency"
+	"github.com/nandaiqbalh/go-backend-ecom/service/giftcard"
+	"github.com/nandaiqbalh/go-backend-ecom/service/inventory"
+	"github.com/nandaiqbalh/go-backend-ecom/service/invoice"
+	"github.com/nandaiqbalh/go-backend-ecom/service/tax"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// orderColumns lists the columns read by scanOrder, in scan order. The
+// last one is the code of the coupon the order was placed with.
+const orderColumns = "id, userId, subtotal, discount, tax, shipping, shippingMethodId, shippingMethod, total, giftCard, giftCardId, currency, freeShipping, status, address, country, region, latitude, longitude, createdAt, " +
+	"(SELECT c.code FROM coupon_redemptions r JOIN coupons c ON c.id = r.couponId WHERE r.orderId = orders.id)"
+
+// Store implements types.OrderStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanOrder(row sqlutil.Scanner) (*types.Order, error) {
+	o := new(types.Order)
+	var subtotal, discount, tax, shipping, total, giftCard string
+	var lat, lon sql.NullFloat64
+	var methodID, giftCardID sql.NullInt64
+	var method, country, region, couponCode sql.NullString
+	err := row.Scan(&o.ID, &o.UserID, &subtotal, &discount, &tax, &shipping, &methodID, &method, &total, &giftCard, &giftCardID, &o.Currency, &o.FreeShipping, &o.Status, &o.Address, &country, &region, &lat, &lon, &o.CreatedAt, &couponCode)
+	if err != nil {
+		return nil, err
+	}
+	// the amounts are in the order currency
+	if o.Subtotal, err = types.ParseMoney(subtotal, o.Currency); err != nil {
+		return nil, err
+	}
+	if o.Discount, err = types.ParseMoney(discount, o.Currency); err != nil {
+		return nil, err
+	}
+	if o.Tax, err = types.ParseMoney(tax, o.Currency); err != nil {
+		return nil, err
+	}
+	if o.Shipping, err = types.ParseMoney(shipping, o.Currency); err != nil {
+		return nil, err
+	}
+	if o.Total, err = types.ParseMoney(total, o.Currency); err != nil {
+		return nil, err
+	}
+	if o.GiftCard, err = types.ParseMoney(giftCard, o.Currency); err != nil {
+		return nil, err
+	}
+	o.GiftCardID = sqlutil.NullableInt(giftCardID)
+	o.ShippingMethodID = sqlutil.NullableInt(methodID)
+	o.ShippingMethod = method.String
+	o.Country, o.Region = country.String, region.String
+	if couponCode.Valid {
+		o.CouponCode = &couponCode.String
+	}
+	if lat.Valid && lon.Valid {
+		o.Latitude, o.Longitude = &lat.Float64, &lon.Float64
+	}
+	return o, nil
+}
+
+// CreateOrder inserts the order and its items and reserves stock for every
+// line in a single transaction. Each line is allocated to the locations it
+// ships from and the allocations are stored with it. Stock is not
+// decremented until the order is paid; until then the reservations keep
+// other checkouts from taking it. If the stock is no longer available the
+// whole order is rolled back and an error wrapping
+// types.ErrInsufficientStock is returned. The coupon of the order, if any,
+// is redeemed in the same transaction; see coupon.Redeem. So are its gift
+// card amount, see giftcard.Redeem, the snapshot of the exchange rates
+// used to price the order and its tax lines, and the removal of the cart
+// lines it was checked out from. An order its gift card pays in full is
+// marked paid before the transaction commits, as MarkOrderPaid would.
+func (s *Store) CreateOrder(order *types.Order, holdUntil time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO orders (userId, subtotal, discount, tax, shipping, shippingMethodId, shippingMethod, total, giftCard, giftCardId, currency, freeShipping, status, address, country, region, latitude, longitude) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		order.UserID, order.Subtotal, order.Discount, order.Tax, order.Shipping, order.ShippingMethodID, sqlutil.NullString(order.ShippingMethod), order.Total, order.GiftCard, order.GiftCardID, order.Currency, order.FreeShipping, order.Status, order.Address, sqlutil.NullString(order.Country), sqlutil.NullString(order.Region), order.Latitude, order.Longitude,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	order.ID = int(id)
+
+	if err := coupon.Redeem(tx, order); err != nil {
+		return err
+	}
+	if err := giftcard.Redeem(tx, order); err != nil {
+		return err
+	}
+	if err := currency.RecordOrderRates(tx, order); err != nil {
+		return err
+	}
+	if err := cart.Clear(tx, order.UserID, order.CartItemIDs); err != nil {
+		return err
+	}
+
+	for _, item := range order.Items {
+		item.OrderID = order.ID
+		result, err := tx.Exec(
+			"INSERT INTO order_items (orderId, productId, variantId, quantity, price, taxClass, total) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			item.OrderID, item.ProductID, item.VariantID, item.Quantity, item.Price, taxClass(item), item.Total,
+		)
+		if err != nil {
+			return err
+		}
+		itemID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		item.ID = int(itemID)
+	}
+	if err := tax.RecordOrderTaxes(tx, order); err != nil {
+		return err
+	}
+
+	if err := inventory.Hold(tx, order, holdUntil); err != nil {
+		return err
+	}
+	for _, item := range order.Items {
+		for _, a := range item.Allocations {
+			if _, err := tx.Exec(
+				"INSERT INTO order_item_allocations (orderItemId, locationId, quantity) VALUES (?, ?, ?)",
+				item.ID, a.LocationID, a.Quantity,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	paid := order.GiftCardID != nil && order.Due().IsZero()
+	if paid {
+		if err := settlePaid(tx, order.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE orders SET status = ? WHERE id = ?", types.OrderStatusPaid, order.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if paid {
+		order.Status = types.OrderStatusPaid
+		return nil
+	}
+	order.HoldExpiresAt = &holdUntil
+	return nil
+}
+
+// MarkOrderPaid moves a pending order to paid, commits its reservations
+// into stock decrements and issues its invoice. types.ErrOrderNotPending
+// is returned for orders in any other state.
+func (s *Store) MarkOrderPaid(orderID int) error {
+	return s.leavePending(orderID, types.OrderStatusPaid, settlePaid)
+}
+
+// settlePaid commits the reservations of an order into stock decrements
+// and issues its invoice, inside the transaction marking it paid.
+func settlePaid(tx *sql.Tx, orderID int) error {
+	if err := inventory.Commit(tx, orderID); err != nil {
+		return err
+	}
+	return invoice.Issue(tx, orderID)
+}
+
+// CancelOrder cancels a pending order, releases its reservations and
+// gives back its coupon redemption and what it took off a gift card.
+// types.ErrOrderNotPending is returned for orders in any other state.
+func (s *Store) CancelOrder(orderID int) error {
+	return s.leavePending(orderID, types.OrderStatusCancelled, func(tx *sql.Tx, orderID int) error {
+		if err := inventory.Release(tx, orderID); err != nil {
+			return err
+		}
+		if err := coupon.Release(tx, orderID); err != nil {
+			return err
+		}
+		return giftcard.Release(tx, orderID)
+	})
+}
+
+// leavePending locks a pending order, settles its reservations with
+// settle and sets its new status, all in one transaction.
+func (s *Store) leavePending(orderID int, status string, settle func(tx *sql.Tx, orderID int) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	if err := tx.QueryRow("SELECT status FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&current); err != nil {
+		return err
+	}
+	if current != types.OrderStatusPending {
+		return types.ErrOrderNotPending
+	}
+
+	if err := settle(tx, orderID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE orders SET status = ? WHERE id = ?", status, orderID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetOrderByID returns the order with its items and shipments, or nil if
+// none exists.
+func (s *Store) GetOrderByID(id int) (*types.Order, error) {
+	o, err := scanOrder(s.db.QueryRow("SELECT "+orderColumns+" FROM orders WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		"SELECT id, orderId, productId, variantId, quantity, price, taxClass, total, refunded FROM order_items WHERE orderId = ? ORDER BY id",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[int]*types.OrderItem)
+	for rows.Next() {
+		item := new(types.OrderItem)
+		var price, total, refunded string
+		var variantID sql.NullInt64
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &variantID, &item.Quantity, &price, &item.TaxClass, &total, &refunded); err != nil {
+			return nil, err
+		}
+		if item.Price, err = types.ParseMoney(price, o.Currency); err != nil {
+			return nil, err
+		}
+		if item.Total, err = types.ParseMoney(total, o.Currency); err != nil {
+			return nil, err
+		}
+		if item.Refunded, err = types.ParseMoney(refunded, o.Currency); err != nil {
+			return nil, err
+		}
+		item.VariantID = sqlutil.NullableInt(variantID)
+		o.Items = append(o.Items, item)
+		items[item.ID] = item
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.loadAllocations(id, items); err != nil {
+		return nil, err
+	}
+	if o.ExchangeRates, err = s.loadExchangeRates(id); err != nil {
+		return nil, err
+	}
+	if err := s.loadTaxLines(o, items); err != nil {
+		return nil, err
+	}
+	if o.Shipments, err = s.loadShipments(id); err != nil {
+		return nil, err
+	}
+
+	if o.Status == types.OrderStatusPending {
+		var expiresAt sql.NullTime
+		if err := s.db.QueryRow(
+			"SELECT MIN(expiresAt) FROM inventory_reservations WHERE orderId = ? AND status = ?",
+			id, types.ReservationActive,
+		).Scan(&expiresAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			o.HoldExpiresAt = &expiresAt.Time
+		}
+	}
+
+	return o, nil
+}
+
+// loadAllocations attaches the stored allocations of an order to its items,
+// which are keyed by ID.
+func (s *Store) loadAllocations(orderID int, items map[int]*types.OrderItem) error {
+	rows, err := s.db.Query(
+		`SELECT a.orderItemId, a.locationId, a.quantity
+		   FROM order_item_allocations a
+		   JOIN order_items i ON i.id = a.orderItemId
+		  WHERE i.orderId = ?
+		  ORDER BY a.orderItemId, a.locationId`,
+		orderID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var itemID int
+		a := new(types.Allocation)
+		if err := rows.Scan(&itemID, &a.LocationID, &a.Quantity); err != nil {
+			return err
+		}
+		if item, ok := items[itemID]; ok {
+			item.Allocations = append(item.Allocations, a)
+		}
+	}
+	return rows.Err()
+}
+
+// loadExchangeRates returns the exchange rates snapshot of an order.
+func (s *Store) loadExchangeRates(orderID int) ([]*types.ExchangeRate, error) {
+	rows, err := s.db.Query(
+		"SELECT currency, "+currency.RateColumn+" FROM order_exchange_rates WHERE orderId = ? ORDER BY currency",
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []*types.ExchangeRate
+	for rows.Next() {
+		r := new(types.ExchangeRate)
+		if err := rows.Scan(&r.Currency, &r.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
+
+// loadShipments returns the shipments of an order with their items,
+// oldest first.
+func (s *Store) loadShipments(orderID int) ([]*types.Shipment, error) {
+	rows, err := s.db.Query(
+		`SELECT s.id, s.carrier, s.trackingNumber, s.trackingUrl, s.shippedAt, s.deliveredAt, s.createdAt, si.id, si.orderItemId, si.quantity
+		   FROM shipments s
+		   JOIN shipment_items si ON si.shipmentId = s.id
+		  WHERE s.orderId = ?
+		  ORDER BY s.id, si.id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shipments []*types.Shipment
+	for rows.Next() {
+		sh := &types.Shipment{OrderID: orderID}
+		item := new(types.ShipmentItem)
+		var trackingURL sql.NullString
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&sh.ID, &sh.Carrier, &sh.TrackingNumber, &trackingURL, &sh.ShippedAt, &deliveredAt, &sh.CreatedAt, &item.ID, &item.OrderItemID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		if n := len(shipments); n > 0 && shipments[n-1].ID == sh.ID {
+			sh = shipments[n-1]
+		} else {
+			sh.TrackingURL = trackingURL.String
+			if deliveredAt.Valid {
+				sh.DeliveredAt = &deliveredAt.Time
+			}
+			shipments = append(shipments, sh)
+		}
+		item.ShipmentID = sh.ID
+		sh.Items = append(sh.Items, item)
+	}
+	return shipments, rows.Err()
+}
+
+// loadTaxLines attaches the stored tax lines of order o and of its items,
+// which are keyed by ID.
+func (s *Store) loadTaxLines(o *types.Order, items map[int]*types.OrderItem) error {
+	rows, err := s.db.Query(
+		`SELECT 0, id, name, rate, inclusive, amount FROM order_taxes WHERE orderId = ?
+		 UNION ALL
+		 SELECT t.orderItemId, t.id, t.name, t.rate, t.inclusive, t.amount
+		   FROM order_item_taxes t
+		   JOIN order_items i ON i.id = t.orderItemId
+		  WHERE i.orderId = ?
+		  ORDER BY 1, 2`,
+		o.ID, o.ID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var itemID, id int
+		var amount string
+		line := new(types.TaxLine)
+		if err := rows.Scan(&itemID, &id, &line.Name, &line.Rate, &line.Inclusive, &amount); err != nil {
+			return err
+		}
+		if line.Amount, err = types.ParseMoney(amount, o.Currency); err != nil {
+			return err
+		}
+		// item 0 stands for the order lines
+		if itemID == 0 {
+			o.TaxLines = append(o.TaxLines, line)
+		} else if item, ok := items[itemID]; ok {
+			item.TaxLines = append(item.TaxLines, line)
+		}
+	}
+	return rows.Err()
+}
+
+// taxClass returns the tax class of an order item, DefaultTaxClass when
+// it has none.
+func taxClass(item *types.OrderItem) string {
+	if item.TaxClass == "" {
+		return types.DefaultTaxClass
+	}
+	return item.TaxClass
+}
+
+// ListOrdersByUser returns the user's orders, newest first, without items.
+func (s *Store) ListOrdersByUser(userID int) ([]*types.Order, error) {
+	rows, err := s.db.Query("SELECT "+orderColumns+" FROM orders WHERE userId = ? ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []*types.Order{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
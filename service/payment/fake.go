@@ -0,0 +1,265 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/service/notify"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// Payment methods the Fake provider understands; each picks the outcome
+// of confirming an intent.
+const (
+	FakeCardSuccess = "fake_card_success" // authorized at once
+	FakeCardDecline = "fake_card_decline" // declined
+	FakeCard3DS     = "fake_card_3ds"     // authorized once the customer authenticates
+)
+
+// FakeSignatureHeader carries the HMAC-SHA256 of the body of the webhooks
+// of the Fake provider, as "sha256=<hex>".
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// Fake is a types.PaymentProvider that keeps intents in memory, so the
+// whole checkout runs offline. Intents are lost on restart.
+type Fake struct {
+	secret    string
+	actionURL string
+
+	// run sets the identifiers of this process apart from those stored
+	// before a restart
+	run     string
+	mu      sync.Mutex
+	seq     int
+	intents map[string]*fakeIntent
+}
+
+type fakeIntent struct {
+	amount   types.Money
+	status   string
+	refunded types.Money
+	// authenticated is nil until the customer passes or fails the 3-D
+	// Secure challenge
+	authenticated *bool
+}
+
+// NewFake creates a Fake signing its webhooks with secret. actionURL is
+// the base of the URLs where customers authenticate 3-D Secure payments;
+// the Handler serves them under /payments/fake/intents.
+func NewFake(secret, actionURL string) *Fake {
+	return &Fake{
+		secret:    secret,
+		actionURL: actionURL,
+		run:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		intents:   make(map[string]*fakeIntent),
+	}
+}
+
+// Name implements types.PaymentProvider.
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+// nextID returns a new identifier with prefix. f.mu must be held.
+func (f *Fake) nextID(prefix string) string {
+	f.seq++
+	return prefix + f.run + "_" + strconv.Itoa(f.seq)
+}
+
+// event returns a new event of intent id. f.mu must be held.
+func (f *Fake) event(id, eventType string) *types.PaymentEvent {
+	return &types.PaymentEvent{ID: f.nextID("fake_evt_"), Type: eventType, IntentID: id}
+}
+
+// intent returns the intent with the given ID. f.mu must be held.
+func (f *Fake) intent(id string) (*fakeIntent, error) {
+	in, ok := f.intents[id]
+	if !ok {
+		return nil, fmt.Errorf("no such intent %s", id)
+	}
+	return in, nil
+}
+
+// CreateIntent implements types.PaymentProvider.
+func (f *Fake) CreateIntent(ctx context.Context, orderID int, amount types.Money) (*types.PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := f.nextID("fake_pi_")
+	f.intents[id] = &fakeIntent{amount: amount, status: types.PaymentPending, refunded: types.NewMoney(0, amount.Currency)}
+	return &types.PaymentIntent{ID: id, Amount: amount}, nil
+}
+
+// ConfirmIntent implements types.PaymentProvider. The outcome depends on
+// paymentMethod; see FakeCardSuccess, FakeCardDecline and FakeCard3DS.
+func (f *Fake) ConfirmIntent(ctx context.Context, id, paymentMethod string) (*types.PaymentEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	in, err := f.intent(id)
+	if err != nil {
+		return nil, err
+	}
+	if in.status != types.PaymentPending && in.status != types.PaymentRequiresAction && in.status != types.PaymentFailed {
+		return nil, fmt.Errorf("intent %s is %s", id, in.status)
+	}
+
+	var e *types.PaymentEvent
+	switch paymentMethod {
+	case FakeCardSuccess:
+		e = f.event(id, types.PaymentEventAuthorized)
+	case FakeCardDecline:
+		e = f.event(id, types.PaymentEventFailed)
+		e.FailureReason = "card_declined"
+	case FakeCard3DS:
+		switch {
+		case in.authenticated == nil:
+			e = f.event(id, types.PaymentEventRequiresAction)
+			e.NextActionURL = f.actionURL + "/" + id + "/authenticate"
+		case *in.authenticated:
+			e = f.event(id, types.PaymentEventAuthorized)
+		default:
+			e = f.event(id, types.PaymentEventFailed)
+			e.FailureReason = "authentication_failed"
+		}
+	default:
+		e = f.event(id, types.PaymentEventFailed)
+		e.FailureReason = "invalid_payment_method"
+	}
+	in.status = statusAfter(e.Type)
+	return e, nil
+}
+
+// Authenticate completes the 3-D Secure challenge of an intent and
+// returns the event the gateway reports to the webhook: the payment is
+// authorized when the customer passed and fails otherwise.
+func (f *Fake) Authenticate(id string, passed bool) (*types.PaymentEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	in, err := f.intent(id)
+	if err != nil {
+		return nil, err
+	}
+	if in.status != types.PaymentRequiresAction {
+		return nil, fmt.Errorf("intent %s does not require authentication", id)
+	}
+
+	in.authenticated = &passed
+	e := f.event(id, types.PaymentEventAuthorized)
+	if !passed {
+		e = f.event(id, types.PaymentEventFailed)
+		e.FailureReason = "authentication_failed"
+	}
+	in.status = statusAfter(e.Type)
+	return e, nil
+}
+
+// CaptureIntent implements types.PaymentProvider.
+func (f *Fake) CaptureIntent(ctx context.Context, id string, amount types.Money) (*types.PaymentEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	in, err := f.intent(id)
+	if err != nil {
+		return nil, err
+	}
+	if in.status != types.PaymentAuthorized {
+		return nil, fmt.Errorf("intent %s is %s, not authorized", id, in.status)
+	}
+	if amount != in.amount {
+		return nil, fmt.Errorf("intent %s can only be captured in full", id)
+	}
+
+	in.status = types.PaymentCaptured
+	e := f.event(id, types.PaymentEventCaptured)
+	e.Amount = &amount
+	return e, nil
+}
+
+// Refund implements types.PaymentProvider.
+func (f *Fake) Refund(ctx context.Context, id string, amount types.Money) (*types.PaymentEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	in, err := f.intent(id)
+	if err != nil {
+		return nil, err
+	}
+	if in.status != types.PaymentCaptured {
+		return nil, fmt.Errorf("intent %s is %s, not captured", id, in.status)
+	}
+	if in.refunded.Add(amount).Cmp(in.amount) > 0 {
+		return nil, fmt.Errorf("intent %s: %w", id, types.ErrRefundTooLarge)
+	}
+
+	in.refunded = in.refunded.Add(amount)
+	if in.refunded == in.amount {
+		in.status = types.PaymentRefunded
+	}
+	e := f.event(id, types.PaymentEventRefunded)
+	e.Amount = &amount
+	return e, nil
+}
+
+// Webhook returns the body and headers of the webhook delivering e, as
+// the gateway would post it.
+func (f *Fake) Webhook(e *types.PaymentEvent) ([]byte, http.Header, error) {
+	if e.Amount != nil {
+		e.Currency = e.Amount.Currency
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set(FakeSignatureHeader, notify.Sign(f.secret, body))
+	return body, header, nil
+}
+
+// VerifyWebhook implements types.PaymentProvider.
+func (f *Fake) VerifyWebhook(body []byte, header http.Header) (*types.PaymentEvent, error) {
+	want := notify.Sign(f.secret, body)
+	if !hmac.Equal([]byte(header.Get(FakeSignatureHeader)), []byte(want)) {
+		return nil, fmt.Errorf("invalid webhook signature")
+	}
+
+	var e types.PaymentEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %v", err)
+	}
+	if e.ID == "" || e.IntentID == "" || e.Type == "" {
+		return nil, fmt.Errorf("webhook event is missing id, type or intentId")
+	}
+	// amounts are decoded in DefaultCurrency
+	if e.Amount != nil {
+		amount, err := e.Amount.In(e.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook amount: %v", err)
+		}
+		e.Amount = &amount
+	}
+	return &e, nil
+}
+
+// statusAfter returns the status an event type moves an intent to.
+func statusAfter(eventType string) string {
+	switch eventType {
+	case types.PaymentEventRequiresAction:
+		return types.PaymentRequiresAction
+	case types.PaymentEventAuthorized:
+		return types.PaymentAuthorized
+	case types.PaymentEventCaptured:
+		return types.PaymentCaptured
+	case types.PaymentEventRefunded:
+		return types.PaymentRefunded
+	}
+	return types.PaymentFailed
+}
@@ -0,0 +1,215 @@
+// Package payment takes the money for orders through a payment provider.
+// Payments only change status on events from the provider, whether they
+// come back from a call to it or are delivered to the webhook, so both
+// paths go through the same transitions.
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+var (
+	// ErrUnknownIntent is returned for events about intents no payment
+	// was opened for.
+	ErrUnknownIntent = errors.New("unknown payment intent")
+	// ErrNotCaptured is returned when refunding a payment that has no
+	// captured funds left.
+	ErrNotCaptured = errors.New("payment is not captured")
+	// ErrProvider wraps the errors of calls to the payment provider, so
+	// callers can tell an outage upstream from a failure of their own.
+	ErrProvider = errors.New("payment provider failed")
+	// ErrAmountMismatch is returned for capture events of another amount
+	// or currency than the payment, which are not applied.
+	ErrAmountMismatch = errors.New("captured amount does not match the payment")
+)
+
+// appliesTo lists, by event type, the statuses an event moves a payment
+// from. Events that do not fit the status of the payment, such as an
+// authorization reported after the capture, are recorded but leave the
+// payment as it is.
+var appliesTo = map[string][]string{
+	types.PaymentEventRequiresAction: {types.PaymentPending, types.PaymentFailed},
+	types.PaymentEventAuthorized:     {types.PaymentPending, types.PaymentRequiresAction, types.PaymentFailed},
+	types.PaymentEventCaptured:       {types.PaymentPending, types.PaymentRequiresAction, types.PaymentAuthorized, types.PaymentFailed},
+	types.PaymentEventFailed:         {types.PaymentPending, types.PaymentRequiresAction, types.PaymentAuthorized},
+	types.PaymentEventRefunded:       {types.PaymentCaptured},
+}
+
+// transition moves payment p as event e tells.
+func transition(p *types.Payment, e *types.PaymentEvent) error {
+	if !slices.Contains(appliesTo[e.Type], p.Status) {
+		return nil
+	}
+
+	p.NextActionURL, p.FailureReason = "", ""
+	switch e.Type {
+	case types.PaymentEventRequiresAction:
+		p.Status = types.PaymentRequiresAction
+		p.NextActionURL = e.NextActionURL
+	case types.PaymentEventAuthorized:
+		p.Status = types.PaymentAuthorized
+	case types.PaymentEventCaptured:
+		if e.Amount != nil && (e.Amount.Currency != p.Currency || e.Amount.Cmp(p.Amount) != 0) {
+			return fmt.Errorf("%w: event %s captured %s %s of %s %s", ErrAmountMismatch, e.ID, e.Amount, e.Amount.Currency, p.Amount, p.Currency)
+		}
+		p.Status = types.PaymentCaptured
+	case types.PaymentEventFailed:
+		p.Status = types.PaymentFailed
+		p.FailureReason = e.FailureReason
+	case types.PaymentEventRefunded:
+		if e.Amount == nil || e.Amount.Currency != p.Currency {
+			return fmt.Errorf("refund event %s has no amount in %s", e.ID, p.Currency)
+		}
+		refunded := p.Refunded.Add(*e.Amount)
+		if refunded.Cmp(p.Amount) > 0 {
+			return fmt.Errorf("refund event %s: %w", e.ID, types.ErrRefundTooLarge)
+		}
+		p.Refunded = refunded
+		if refunded.Cmp(p.Amount) == 0 {
+			p.Status = types.PaymentRefunded
+		}
+	}
+	return nil
+}
+
+// Processor drives payments: it opens them with the provider, applies
+// the events the provider reports and settles the orders they pay.
+type Processor struct {
+	store    types.PaymentStore
+	orders   types.OrderStore
+	provider types.PaymentProvider
+}
+
+// NewProcessor creates a Processor keeping payments in store and paying
+// the orders of orders through provider.
+func NewProcessor(store types.PaymentStore, orders types.OrderStore, provider types.PaymentProvider) *Processor {
+	return &Processor{store: store, orders: orders, provider: provider}
+}
+
+// Open opens a payment with the provider of what is due on the order once
+// its gift card, if any, is taken off the total.
+func (p *Processor) Open(ctx context.Context, order *types.Order) (*types.Payment, error) {
+	intent, err := p.provider.CreateIntent(ctx, order.ID, order.Due())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+
+	payment := &types.Payment{
+		OrderID:  order.ID,
+		Provider: p.provider.Name(),
+		IntentID: intent.ID,
+		Status:   types.PaymentPending,
+		Amount:   intent.Amount,
+		Currency: intent.Amount.Currency,
+	}
+	if err := p.store.CreatePayment(payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// Confirm confirms payment with a payment method from the client and
+// applies the outcome.
+func (p *Processor) Confirm(ctx context.Context, payment *types.Payment, paymentMethod string) (*types.Payment, error) {
+	e, err := p.provider.ConfirmIntent(ctx, payment.IntentID, paymentMethod)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+	return p.Apply(ctx, e)
+}
+
+// Refund gives amount of a captured payment back through the provider,
+// never more than was captured and not refunded yet.
+func (p *Processor) Refund(ctx context.Context, payment *types.Payment, amount types.Money) (*types.Payment, error) {
+	if payment.Status != types.PaymentCaptured {
+		return nil, ErrNotCaptured
+	}
+	left := payment.Amount.Sub(payment.Refunded)
+	if amount.Currency != payment.Currency || amount.Amount <= 0 || amount.Cmp(left) > 0 {
+		return nil, fmt.Errorf("%w: %s %s left", types.ErrRefundTooLarge, left, payment.Currency)
+	}
+
+	e, err := p.provider.Refund(ctx, payment.IntentID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+	return p.Apply(ctx, e)
+}
+
+// Apply records event e against its payment and moves the payment. An
+// authorized payment is captured straight away and a captured one pays
+// its order. It returns types.ErrPaymentEventReplayed for events applied
+// before, once it has run that step again for the payment as it stands:
+// the event is recorded before the capture and the order are, so the
+// provider redelivering an event is what retries them when they failed.
+func (p *Processor) Apply(ctx context.Context, e *types.PaymentEvent) (*types.Payment, error) {
+	var before string
+	payment, err := p.store.ApplyPaymentEvent(p.provider.Name(), e, func(payment *types.Payment) error {
+		before = payment.Status
+		return transition(payment, e)
+	})
+	if errors.Is(err, types.ErrPaymentEventReplayed) && payment != nil {
+		if _, err := p.followUp(ctx, payment); err != nil {
+			return nil, err
+		}
+		return nil, types.ErrPaymentEventReplayed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, fmt.Errorf("%w %s", ErrUnknownIntent, e.IntentID)
+	}
+	if payment.Status == before {
+		return payment, nil
+	}
+	return p.followUp(ctx, payment)
+}
+
+// followUp captures an authorized payment and settles the order of a
+// captured one.
+func (p *Processor) followUp(ctx context.Context, payment *types.Payment) (*types.Payment, error) {
+	switch payment.Status {
+	case types.PaymentAuthorized:
+		captured, err := p.provider.CaptureIntent(ctx, payment.IntentID, payment.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrProvider, err)
+		}
+		return p.Apply(ctx, captured)
+	case types.PaymentCaptured:
+		return p.settleOrder(ctx, payment)
+	}
+	return payment, nil
+}
+
+// settleOrder marks the order of a captured payment paid. An order that
+// cannot be paid any more, because it was cancelled when its reservation
+// expired or its stock is gone, gets the payment refunded in full.
+func (p *Processor) settleOrder(ctx context.Context, payment *types.Payment) (*types.Payment, error) {
+	err := p.orders.MarkOrderPaid(payment.OrderID)
+	if err == nil {
+		return payment, nil
+	}
+	if !errors.Is(err, types.ErrOrderNotPending) && !errors.Is(err, types.ErrInsufficientStock) {
+		return nil, err
+	}
+
+	order, err := p.orders.GetOrderByID(payment.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if order != nil && (order.Status == types.OrderStatusPaid || order.Status == types.OrderStatusShipped || order.Status == types.OrderStatusDelivered || order.Status == types.OrderStatusCompleted) {
+		return payment, nil
+	}
+	if order != nil && order.Status == types.OrderStatusPending {
+		if err := p.orders.CancelOrder(order.ID); err != nil && !errors.Is(err, types.ErrOrderNotPending) {
+			return nil, err
+		}
+	}
+	return p.Refund(ctx, payment, payment.Amount.Sub(payment.Refunded))
+}
@@ -0,0 +1,372 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/testutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// memoryStore keeps payments, the events applied to them and order
+// statuses in memory.
+type memoryStore struct {
+	types.PaymentStore
+	types.OrderStore
+	payments []*types.Payment
+	events   map[string]bool
+	orders   map[int]string
+	failPaid bool // fail the next MarkOrderPaid
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{events: map[string]bool{}, orders: map[int]string{}}
+}
+
+func (s *memoryStore) CreatePayment(p *types.Payment) error {
+	p.ID = len(s.payments) + 1
+	p.Refunded = types.NewMoney(0, p.Currency)
+	stored := *p
+	s.payments = append(s.payments, &stored)
+	return nil
+}
+
+func (s *memoryStore) ApplyPaymentEvent(provider string, e *types.PaymentEvent, apply func(*types.Payment) error) (*types.Payment, error) {
+	for _, stored := range s.payments {
+		if stored.Provider != provider || stored.IntentID != e.IntentID {
+			continue
+		}
+		if s.events[e.ID] {
+			p := *stored
+			return &p, types.ErrPaymentEventReplayed
+		}
+		p := *stored
+		if err := apply(&p); err != nil {
+			return nil, err
+		}
+		s.events[e.ID] = true
+		*stored = p
+		return &p, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) GetOrderByID(id int) (*types.Order, error) {
+	status, ok := s.orders[id]
+	if !ok {
+		return nil, nil
+	}
+	return &types.Order{ID: id, Status: status}, nil
+}
+
+func (s *memoryStore) MarkOrderPaid(orderID int) error {
+	if s.failPaid {
+		s.failPaid = false
+		return errors.New("deadlock found when trying to get lock")
+	}
+	if s.orders[orderID] != types.OrderStatusPending {
+		return types.ErrOrderNotPending
+	}
+	s.orders[orderID] = types.OrderStatusPaid
+	return nil
+}
+
+func (s *memoryStore) CancelOrder(orderID int) error {
+	if s.orders[orderID] != types.OrderStatusPending {
+		return types.ErrOrderNotPending
+	}
+	s.orders[orderID] = types.OrderStatusCancelled
+	return nil
+}
+
+// open opens a payment of 25.00 for a new pending order.
+func open(t *testing.T, store *memoryStore, processor *Processor) *types.Payment {
+	t.Helper()
+	id := len(store.orders) + 1
+	store.orders[id] = types.OrderStatusPending
+	p, err := processor.Open(context.Background(), &types.Order{ID: id, Total: testutil.USD(2500)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProcessorConfirm(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		wantStatus string
+		wantOrder  string
+		wantReason string
+	}{
+		{"success", FakeCardSuccess, types.PaymentCaptured, types.OrderStatusPaid, ""},
+		{"decline", FakeCardDecline, types.PaymentFailed, types.OrderStatusPending, "card_declined"},
+		{"3-D Secure", FakeCard3DS, types.PaymentRequiresAction, types.OrderStatusPending, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			processor := NewProcessor(store, store, NewFake("secret", "http://localhost/fake"))
+
+			p, err := processor.Confirm(context.Background(), open(t, store, processor), tt.method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Status != tt.wantStatus {
+				t.Errorf("expected payment %s, got %s", tt.wantStatus, p.Status)
+			}
+			if p.FailureReason != tt.wantReason {
+				t.Errorf("expected failure reason %q, got %q", tt.wantReason, p.FailureReason)
+			}
+			if got := store.orders[p.OrderID]; got != tt.wantOrder {
+				t.Errorf("expected order %s, got %s", tt.wantOrder, got)
+			}
+		})
+	}
+}
+
+func TestProcessorAuthenticate(t *testing.T) {
+	tests := []struct {
+		name       string
+		passed     bool
+		wantStatus string
+		wantOrder  string
+	}{
+		{"passed", true, types.PaymentCaptured, types.OrderStatusPaid},
+		{"failed", false, types.PaymentFailed, types.OrderStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			fake := NewFake("secret", "http://localhost/fake")
+			processor := NewProcessor(store, store, fake)
+
+			p, err := processor.Confirm(context.Background(), open(t, store, processor), FakeCard3DS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "http://localhost/fake/" + p.IntentID + "/authenticate"; p.NextActionURL != want {
+				t.Errorf("expected next action %s, got %s", want, p.NextActionURL)
+			}
+
+			e, err := fake.Authenticate(p.IntentID, tt.passed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the outcome reaches the store through the webhook
+			body, header, err := fake.Webhook(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			verified, err := fake.VerifyWebhook(body, header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p, err = processor.Apply(context.Background(), verified); err != nil {
+				t.Fatal(err)
+			}
+
+			if p.Status != tt.wantStatus {
+				t.Errorf("expected payment %s, got %s", tt.wantStatus, p.Status)
+			}
+			if p.NextActionURL != "" {
+				t.Errorf("expected no next action, got %s", p.NextActionURL)
+			}
+			if got := store.orders[p.OrderID]; got != tt.wantOrder {
+				t.Errorf("expected order %s, got %s", tt.wantOrder, got)
+			}
+		})
+	}
+}
+
+func TestProcessorRefund(t *testing.T) {
+	store := newMemoryStore()
+	processor := NewProcessor(store, store, NewFake("secret", ""))
+	ctx := context.Background()
+
+	p, err := processor.Confirm(ctx, open(t, store, processor), FakeCardSuccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p, err = processor.Refund(ctx, p, testutil.USD(1000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != types.PaymentCaptured || p.Refunded != testutil.USD(1000) {
+		t.Errorf("expected captured with 10.00 refunded, got %s with %s", p.Status, p.Refunded)
+	}
+
+	if _, err := processor.Refund(ctx, p, testutil.USD(1501)); !errors.Is(err, types.ErrRefundTooLarge) {
+		t.Errorf("expected ErrRefundTooLarge, got %v", err)
+	}
+
+	if p, err = processor.Refund(ctx, p, testutil.USD(1500)); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != types.PaymentRefunded || p.Refunded != testutil.USD(2500) {
+		t.Errorf("expected refunded in full, got %s with %s", p.Status, p.Refunded)
+	}
+
+	if _, err := processor.Refund(ctx, p, testutil.USD(1)); !errors.Is(err, ErrNotCaptured) {
+		t.Errorf("expected ErrNotCaptured, got %v", err)
+	}
+}
+
+// unavailableProvider fails every refund, like a gateway that is down.
+type unavailableProvider struct {
+	types.PaymentProvider
+}
+
+func (unavailableProvider) Refund(ctx context.Context, id string, amount types.Money) (*types.PaymentEvent, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestProcessorRefundWrapsProviderErrors(t *testing.T) {
+	store := newMemoryStore()
+	processor := NewProcessor(store, store, unavailableProvider{})
+	p := &types.Payment{IntentID: "pi_1", Status: types.PaymentCaptured, Amount: testutil.USD(2500), Currency: types.DefaultCurrency, Refunded: testutil.USD(0)}
+
+	if _, err := processor.Refund(context.Background(), p, testutil.USD(500)); !errors.Is(err, ErrProvider) {
+		t.Errorf("expected ErrProvider, got %v", err)
+	}
+}
+
+func TestProcessorRefundsCancelledOrders(t *testing.T) {
+	store := newMemoryStore()
+	processor := NewProcessor(store, store, NewFake("secret", ""))
+
+	p := open(t, store, processor)
+	// the reservation expired while the customer was paying
+	store.orders[p.OrderID] = types.OrderStatusCancelled
+
+	p, err := processor.Confirm(context.Background(), p, FakeCardSuccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != types.PaymentRefunded || p.Refunded != testutil.USD(2500) {
+		t.Errorf("expected refunded in full, got %s with %s", p.Status, p.Refunded)
+	}
+}
+
+func TestProcessorRejectsCaptureOfAnotherAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount types.Money
+	}{
+		{"less", testutil.USD(2400)},
+		{"more", testutil.USD(2600)},
+		{"another currency", types.NewMoney(2500, "EUR")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			processor := NewProcessor(store, store, NewFake("secret", ""))
+			p := open(t, store, processor)
+
+			e := &types.PaymentEvent{ID: "evt_" + tt.name, Type: types.PaymentEventCaptured, IntentID: p.IntentID, Amount: &tt.amount}
+			if _, err := processor.Apply(context.Background(), e); !errors.Is(err, ErrAmountMismatch) {
+				t.Fatalf("expected ErrAmountMismatch, got %v", err)
+			}
+			if got := store.payments[0].Status; got != types.PaymentPending {
+				t.Errorf("expected the payment to stay pending, got %s", got)
+			}
+			if got := store.orders[p.OrderID]; got != types.OrderStatusPending {
+				t.Errorf("expected the order to stay pending, got %s", got)
+			}
+		})
+	}
+}
+
+func TestProcessorReplay(t *testing.T) {
+	store := newMemoryStore()
+	fake := NewFake("secret", "")
+	processor := NewProcessor(store, store, fake)
+	ctx := context.Background()
+
+	p, err := processor.Confirm(ctx, open(t, store, processor), FakeCardSuccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := fake.Refund(ctx, p.IntentID, testutil.USD(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := processor.Apply(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := processor.Apply(ctx, e); !errors.Is(err, types.ErrPaymentEventReplayed) {
+		t.Errorf("expected ErrPaymentEventReplayed, got %v", err)
+	}
+	if got := store.payments[0].Refunded; got != testutil.USD(500) {
+		t.Errorf("expected 5.00 refunded once, got %s", got)
+	}
+
+	if _, err := processor.Apply(ctx, &types.PaymentEvent{ID: "evt", Type: types.PaymentEventCaptured, IntentID: "unknown"}); !errors.Is(err, ErrUnknownIntent) {
+		t.Errorf("expected ErrUnknownIntent, got %v", err)
+	}
+}
+
+func TestProcessorReplayRetriesTheOrder(t *testing.T) {
+	store := newMemoryStore()
+	fake := NewFake("secret", "")
+	processor := NewProcessor(store, store, fake)
+	ctx := context.Background()
+
+	p := open(t, store, processor)
+	e, err := fake.ConfirmIntent(ctx, p.IntentID, FakeCardSuccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.failPaid = true
+	if _, err := processor.Apply(ctx, e); err == nil || errors.Is(err, types.ErrPaymentEventReplayed) {
+		t.Fatalf("expected the order to fail to be paid, got %v", err)
+	}
+	if got := store.payments[0].Status; got != types.PaymentCaptured {
+		t.Fatalf("expected the payment captured, got %s", got)
+	}
+	if got := store.orders[p.OrderID]; got != types.OrderStatusPending {
+		t.Fatalf("expected the order to stay pending, got %s", got)
+	}
+
+	// the webhook answered 500, so the provider delivers the event again
+	if _, err := processor.Apply(ctx, e); !errors.Is(err, types.ErrPaymentEventReplayed) {
+		t.Fatalf("expected ErrPaymentEventReplayed, got %v", err)
+	}
+	if got := store.orders[p.OrderID]; got != types.OrderStatusPaid {
+		t.Errorf("expected the redelivery to pay the order, got %s", got)
+	}
+	if got := store.payments[0]; got.Status != types.PaymentCaptured || !got.Refunded.IsZero() {
+		t.Errorf("expected the payment captured and not refunded, got %s with %s refunded", got.Status, got.Refunded)
+	}
+}
+
+func TestFakeVerifyWebhook(t *testing.T) {
+	fake := NewFake("secret", "")
+	amount := testutil.USD(1234)
+	body, header, err := fake.Webhook(&types.PaymentEvent{ID: "evt", Type: types.PaymentEventRefunded, IntentID: "pi", Amount: &amount})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := fake.VerifyWebhook(body, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Amount == nil || *e.Amount != amount {
+		t.Errorf("expected amount %s, got %v", amount, e.Amount)
+	}
+
+	if _, err := NewFake("other", "").VerifyWebhook(body, header); err == nil {
+		t.Error("expected a signature with another secret to be rejected")
+	}
+	header.Set(FakeSignatureHeader, "sha256=00")
+	if _, err := fake.VerifyWebhook(body, header); err == nil {
+		t.Error("expected a forged signature to be rejected")
+	}
+}
@@ -0,0 +1,225 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// maxWebhookBytes bounds the size of a webhook delivered by the provider.
+const maxWebhookBytes = 64 << 10
+
+// Handler serves the payments of the authenticated user's orders and the
+// webhook of the payment provider.
+type Handler struct {
+	store      types.PaymentStore
+	orderStore types.OrderStore
+	provider   types.PaymentProvider
+	processor  *Processor
+}
+
+// NewHandler creates a new Handler taking payments through provider.
+func NewHandler(store types.PaymentStore, orderStore types.OrderStore, provider types.PaymentProvider) *Handler {
+	return &Handler{
+		store:      store,
+		orderStore: orderStore,
+		provider:   provider,
+		processor:  NewProcessor(store, orderStore, provider),
+	}
+}
+
+// RegisterRoutes attaches payment routes to the provided router. With the
+// Fake provider it also serves the page 3-D Secure payments send the
+// customer to.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/orders/{id:[0-9]+}/payments", auth.RequireToken(h.handleListPayments)).Methods("GET")
+	router.HandleFunc("/orders/{id:[0-9]+}/payments", auth.RequireToken(h.handleCreatePayment)).Methods("POST")
+	router.HandleFunc("/payments/{id:[0-9]+}/confirm", auth.RequireToken(h.handleConfirmPayment)).Methods("POST")
+	router.HandleFunc("/payments/webhook", h.handleWebhook).Methods("POST")
+	if _, ok := h.provider.(*Fake); ok {
+		router.HandleFunc("/payments/fake/intents/{intent}/authenticate", h.handleFakeAuthenticate).Methods("POST")
+	}
+}
+
+func (h *Handler) handleListPayments(w http.ResponseWriter, r *http.Request) {
+	order, ok := auth.OwnOrder(w, r, h.orderStore)
+	if !ok {
+		return
+	}
+
+	payments, err := h.store.ListPaymentsByOrder(order.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list payments: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListPaymentsResponse{
+		Message: "success",
+		Data:    payments,
+	})
+}
+
+// handleCreatePayment opens a payment of the total of a pending order. An
+// order has at most one payment in progress; a failed one can be retried
+// with a new payment.
+func (h *Handler) handleCreatePayment(w http.ResponseWriter, r *http.Request) {
+	order, ok := auth.OwnOrder(w, r, h.orderStore)
+	if !ok {
+		return
+	}
+	if order.Status != types.OrderStatusPending {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("order is %s, not pending", order.Status))
+		return
+	}
+	if order.Due().IsZero() {
+		utils.WriteError(w, http.StatusUnprocessableEntity, fmt.Errorf("order has nothing to pay"))
+		return
+	}
+
+	payments, err := h.store.ListPaymentsByOrder(order.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, p := range payments {
+		if p.Status != types.PaymentFailed {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("order already has payment %d, which is %s", p.ID, p.Status))
+			return
+		}
+	}
+
+	payment, err := h.processor.Open(r.Context(), order)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, fmt.Errorf("failed to open payment: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.PaymentResponse{
+		Message: "payment created",
+		Data:    payment,
+	})
+}
+
+// handleConfirmPayment confirms a payment with the payment method the
+// client got from the provider. The payment comes back captured, failed,
+// or requiring action at its nextActionUrl.
+func (h *Handler) handleConfirmPayment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	var payload types.ConfirmPaymentPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	payment, err := h.store.GetPaymentByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if payment == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("payment not found"))
+		return
+	}
+	order, err := h.orderStore.GetOrderByID(payment.OrderID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if order == nil || order.UserID != userID {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("payment not found"))
+		return
+	}
+	if payment.Status != types.PaymentPending && payment.Status != types.PaymentRequiresAction {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("payment is %s", payment.Status))
+		return
+	}
+
+	payment, err = h.processor.Confirm(r.Context(), payment, payload.PaymentMethod)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, fmt.Errorf("failed to confirm payment: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.PaymentResponse{
+		Message: "payment " + payment.Status,
+		Data:    payment,
+	})
+}
+
+// handleWebhook applies an event the provider delivers. Providers retry
+// deliveries that are not acknowledged, so events applied before are
+// acknowledged as well.
+func (h *Handler) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	e, err := h.provider.VerifyWebhook(body, r.Header)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	h.apply(w, r, e)
+}
+
+// handleFakeAuthenticate stands in for the 3-D Secure page of a bank:
+// ?result=pass authenticates the customer and anything else fails them.
+// The outcome is applied as the provider would deliver it.
+func (h *Handler) handleFakeAuthenticate(w http.ResponseWriter, r *http.Request) {
+	fake := h.provider.(*Fake)
+	e, err := fake.Authenticate(mux.Vars(r)["intent"], r.URL.Query().Get("result") == "pass")
+	if err != nil {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+
+	h.apply(w, r, e)
+}
+
+func (h *Handler) apply(w http.ResponseWriter, r *http.Request, e *types.PaymentEvent) {
+	payment, err := h.processor.Apply(r.Context(), e)
+	switch {
+	case errors.Is(err, types.ErrPaymentEventReplayed):
+		utils.WriteJson(w, http.StatusOK, types.PaymentResponse{Message: "event already processed"})
+		return
+	case errors.Is(err, ErrUnknownIntent):
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, ErrAmountMismatch):
+		utils.WriteError(w, http.StatusUnprocessableEntity, err)
+		return
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to apply payment event: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.PaymentResponse{
+		Message: "event processed",
+		Data:    payment,
+	})
+}
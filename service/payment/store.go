@@ -0,0 +1,159 @@
+package payment
+
+import (
+	"database/sql"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// paymentColumns lists the columns read by scanPayment, in scan order.
+const paymentColumns = "id, orderId, provider, intentId, status, amount, refunded, currency, nextActionUrl, failureReason, createdAt, updatedAt"
+
+// Store implements types.PaymentStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanPayment(row sqlutil.Scanner) (*types.Payment, error) {
+	p := new(types.Payment)
+	var amount, refunded string
+	var nextActionURL, failureReason sql.NullString
+	err := row.Scan(
+		&p.ID,
+		&p.OrderID,
+		&p.Provider,
+		&p.IntentID,
+		&p.Status,
+		&amount,
+		&refunded,
+		&p.Currency,
+		&nextActionURL,
+		&failureReason,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// the amounts are in the payment currency
+	if p.Amount, err = types.ParseMoney(amount, p.Currency); err != nil {
+		return nil, err
+	}
+	if p.Refunded, err = types.ParseMoney(refunded, p.Currency); err != nil {
+		return nil, err
+	}
+	p.NextActionURL, p.FailureReason = nextActionURL.String, failureReason.String
+	return p, nil
+}
+
+func (s *Store) getPayment(where string, args ...any) (*types.Payment, error) {
+	p, err := scanPayment(s.db.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// GetPaymentByID returns nil when there is no payment with that ID.
+func (s *Store) GetPaymentByID(id int) (*types.Payment, error) {
+	return s.getPayment("id = ?", id)
+}
+
+// ListPaymentsByOrder returns the payments of an order, oldest first.
+func (s *Store) ListPaymentsByOrder(orderID int) ([]*types.Payment, error) {
+	rows, err := s.db.Query("SELECT "+paymentColumns+" FROM payments WHERE orderId = ? ORDER BY id", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []*types.Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// CreatePayment inserts the payment and reloads it, so it comes back with
+// its ID and timestamps.
+func (s *Store) CreatePayment(p *types.Payment) error {
+	result, err := s.db.Exec(
+		"INSERT INTO payments (orderId, provider, intentId, status, amount, currency) VALUES (?, ?, ?, ?, ?, ?)",
+		p.OrderID, p.Provider, p.IntentID, p.Status, p.Amount, p.Currency,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	created, err := s.GetPaymentByID(int(id))
+	if err != nil {
+		return err
+	}
+	*p = *created
+	return nil
+}
+
+// ApplyPaymentEvent locks the payment of event, lets apply move it and
+// writes its status, refunded amount, next action and failure reason
+// together with the event, in one transaction. Concurrent events of a
+// payment, such as a webhook racing the response of the call that caused
+// it, are applied one after the other.
+func (s *Store) ApplyPaymentEvent(provider string, event *types.PaymentEvent, apply func(*types.Payment) error) (*types.Payment, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	p, err := scanPayment(tx.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE provider = ? AND intentId = ? FOR UPDATE", provider, event.IntentID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	result, err := tx.Exec(
+		"INSERT IGNORE INTO payment_events (paymentId, provider, eventId, type, amount) VALUES (?, ?, ?, ?, ?)",
+		p.ID, provider, event.ID, event.Type, event.Amount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return p, types.ErrPaymentEventReplayed
+	}
+
+	if err := apply(p); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(
+		"UPDATE payments SET status = ?, refunded = ?, nextActionUrl = ?, failureReason = ? WHERE id = ?",
+		p.Status, p.Refunded, sqlutil.NullString(p.NextActionURL), sqlutil.NullString(p.FailureReason), p.ID,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
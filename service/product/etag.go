@@ -0,0 +1,33 @@
+package product
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// formatETag renders a product version as a strong entity tag.
+func formatETag(version int) string {
+	return fmt.Sprintf("%q", strconv.Itoa(version))
+}
+
+// parseIfMatch extracts the product version from an If-Match header. Only
+// a single entity tag produced by formatETag is accepted; "*" and lists of
+// tags are rejected because they cannot guard against lost updates.
+func parseIfMatch(header string) (int, error) {
+	tag := strings.TrimSpace(header)
+	if tag == "" {
+		return 0, fmt.Errorf("missing If-Match header")
+	}
+
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, fmt.Errorf("invalid If-Match header %q", header)
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid If-Match header %q", header)
+	}
+
+	return version, nil
+}
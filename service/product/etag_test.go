@@ -0,0 +1,15 @@
+package product
+
+import "testing"
+
+func TestParseIfMatch(t *testing.T) {
+	if got, err := parseIfMatch(formatETag(7)); err != nil || got != 7 {
+		t.Errorf("expected version 7, got %d (%v)", got, err)
+	}
+
+	for _, header := range []string{"", "*", "7", `"abc"`, `"1", "2"`, `W/"3"`, `"0"`} {
+		if _, err := parseIfMatch(header); err == nil {
+			t.Errorf("expected an error for %q", header)
+		}
+	}
+}
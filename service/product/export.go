@@ -0,0 +1,306 @@
+package product
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Formats accepted by GET /products/export.
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatJSON   = "json"
+)
+
+// exportFields are the product fields an export can contain, in the order
+// they are written when ?fields= is not given.
+var exportFields = []string{
+	"id", "sku", "name", "description", "image", "price", "currency", "taxClass", "quantity", "available",
+	"lowStockThreshold", "createdAt", "version", "deletedAt",
+}
+
+// exportFlushEvery is how many products are written between flushes to
+// the client.
+const exportFlushEvery = 100
+
+// handleExportProducts streams every product matching the listing filters
+// as CSV, NDJSON or a JSON array. Products are written as they are read
+// from the database cursor, so memory use does not grow with the catalog.
+// ?fields=id,name,price limits and orders the fields of each product.
+//
+// Errors after the first byte has been sent cannot change the status code,
+// so the connection is aborted instead; clients see a truncated response
+// rather than a complete-looking partial export.
+func (h *Handler) handleExportProducts(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.listFilters(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = ExportFormatCSV
+	}
+
+	fields, err := parseExportFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	exporter, contentType, err := newExporter(w, format, fields)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+	w.Header().Set("Cache-Control", "no-store")
+
+	rc := http.NewResponseController(w)
+	count := 0
+	err = h.store.EachProduct(params, func(p *types.Product) error {
+		if err := exporter.Write(p); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := exporter.Flush(); err != nil {
+				return err
+			}
+			// not every ResponseWriter supports flushing; the data still
+			// arrives, only later
+			rc.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = exporter.Close()
+	}
+	if err != nil {
+		log.Printf("product export failed after %d rows: %v", count, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// parseExportFields validates a comma separated ?fields= value. Repeated
+// fields are written once. An empty value selects every field.
+func parseExportFields(v string) ([]string, error) {
+	if v == "" {
+		return exportFields, nil
+	}
+
+	known := make(map[string]bool, len(exportFields))
+	for _, f := range exportFields {
+		known[f] = true
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	for _, f := range strings.Split(v, ",") {
+		f = strings.TrimSpace(f)
+		if !known[f] {
+			return nil, fmt.Errorf("unknown field %q; available fields are %s", f, strings.Join(exportFields, ", "))
+		}
+		if !seen[f] {
+			seen[f] = true
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+// exportValue returns a product field as it is written to JSON. Unset
+// optional fields are nil.
+func exportValue(p *types.Product, field string) any {
+	switch field {
+	case "id":
+		return p.ID
+	case "sku":
+		if p.SKU == nil {
+			return nil
+		}
+		return *p.SKU
+	case "name":
+		return p.Name
+	case "description":
+		return p.Description
+	case "image":
+		return p.Image
+	case "price":
+		return p.Price
+	case "currency":
+		return p.Currency
+	case "taxClass":
+		return p.TaxClass
+	case "quantity":
+		return p.Quantity
+	case "available":
+		return p.Available
+	case "lowStockThreshold":
+		if p.LowStockThreshold == nil {
+			return nil
+		}
+		return *p.LowStockThreshold
+	case "createdAt":
+		return p.CreatedAt
+	case "version":
+		return p.Version
+	case "deletedAt":
+		if p.DeletedAt == nil {
+			return nil
+		}
+		return *p.DeletedAt
+	}
+	return nil
+}
+
+// exporter writes products in one export format. Flush pushes buffered
+// output to the underlying writer; Close finishes the document.
+type exporter interface {
+	Write(p *types.Product) error
+	Flush() error
+	Close() error
+}
+
+// newExporter returns the exporter for format writing to w, and the
+// Content-Type of its output. Nothing is written until the first product
+// or Close, so an error here can still be reported normally.
+func newExporter(w io.Writer, format string, fields []string) (exporter, string, error) {
+	switch format {
+	case ExportFormatCSV:
+		return &csvExporter{w: csv.NewWriter(w), fields: fields}, "text/csv; charset=utf-8", nil
+	case ExportFormatNDJSON:
+		return &jsonExporter{w: bufio.NewWriter(w), fields: fields}, "application/x-ndjson", nil
+	case ExportFormatJSON:
+		return &jsonExporter{w: bufio.NewWriter(w), fields: fields, array: true}, "application/json", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported format %q; use %s, %s or %s",
+			format, ExportFormatCSV, ExportFormatNDJSON, ExportFormatJSON)
+	}
+}
+
+// csvExporter writes a header row followed by one row per product. Unset
+// optional fields are empty cells.
+type csvExporter struct {
+	w       *csv.Writer
+	fields  []string
+	started bool
+	record  []string
+}
+
+func (e *csvExporter) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	e.record = make([]string, len(e.fields))
+	return e.w.Write(e.fields)
+}
+
+func (e *csvExporter) Write(p *types.Product) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	for i, field := range e.fields {
+		e.record[i] = csvCell(exportValue(p, field))
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvExporter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExporter) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+func csvCell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// jsonExporter writes one JSON object per product, either one per line
+// (NDJSON) or as the elements of a single array. Object keys follow the
+// order of fields.
+type jsonExporter struct {
+	w       *bufio.Writer
+	fields  []string
+	array   bool
+	written int
+	buf     bytes.Buffer
+}
+
+func (e *jsonExporter) Write(p *types.Product) error {
+	e.buf.Reset()
+	switch {
+	case e.array && e.written == 0:
+		e.buf.WriteString("[\n")
+	case e.array:
+		e.buf.WriteString(",\n")
+	}
+
+	e.buf.WriteByte('{')
+	for i, field := range e.fields {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field)
+		value, err := json.Marshal(exportValue(p, field))
+		if err != nil {
+			return err
+		}
+		e.buf.Write(key)
+		e.buf.WriteByte(':')
+		e.buf.Write(value)
+	}
+	e.buf.WriteByte('}')
+	if !e.array {
+		e.buf.WriteByte('\n')
+	}
+
+	e.written++
+	_, err := e.w.Write(e.buf.Bytes())
+	return err
+}
+
+func (e *jsonExporter) Flush() error {
+	return e.w.Flush()
+}
+
+func (e *jsonExporter) Close() error {
+	if e.array {
+		if e.written == 0 {
+			e.w.WriteString("[")
+		}
+		e.w.WriteString("\n]\n")
+	}
+	return e.Flush()
+}
@@ -0,0 +1,92 @@
+package product
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// catalogStore serves a fixed list of products to EachProduct.
+type catalogStore struct {
+	types.ProductStore
+	products []*types.Product
+}
+
+func (s *catalogStore) EachProduct(params types.ProductListParams, fn func(*types.Product) error) error {
+	for _, p := range s.products {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestHandleExportProducts(t *testing.T) {
+	sku := "SHIRT-1"
+	store := &catalogStore{products: []*types.Product{
+		{ID: 1, SKU: &sku, Name: "Shirt, blue", Price: types.NewMoney(950, types.DefaultCurrency), Quantity: 3},
+		{ID: 2, Name: `Hat "classic"`, Price: types.NewMoney(1200, types.DefaultCurrency), Quantity: 0},
+	}}
+	h := &Handler{store: store}
+
+	export := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/products/export?"+query, nil)
+		rr := httptest.NewRecorder()
+		h.handleExportProducts(rr, req)
+		return rr
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		rr := export("format=csv&fields=id,sku,name,price")
+		want := "id,sku,name,price\n1,SHIRT-1,\"Shirt, blue\",9.50\n2,,\"Hat \"\"classic\"\"\",12.00\n"
+		if rr.Code != http.StatusOK || rr.Body.String() != want {
+			t.Errorf("got %d %q", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		rr := export("format=ndjson&fields=name,sku")
+		want := "{\"name\":\"Shirt, blue\",\"sku\":\"SHIRT-1\"}\n{\"name\":\"Hat \\\"classic\\\"\",\"sku\":null}\n"
+		if rr.Body.String() != want {
+			t.Errorf("got %q", rr.Body.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		rr := export("format=json")
+		var got []map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatalf("invalid JSON %q: %v", rr.Body.String(), err)
+		}
+		if len(got) != 2 || len(got[0]) != len(exportFields) || got[1]["quantity"] != float64(0) {
+			t.Errorf("unexpected export %v", got)
+		}
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		empty := &Handler{store: &catalogStore{}}
+		req := httptest.NewRequest(http.MethodGet, "/products/export?format=json", nil)
+		rr := httptest.NewRecorder()
+		empty.handleExportProducts(rr, req)
+
+		var got []any
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil || len(got) != 0 {
+			t.Errorf("expected an empty array, got %q", rr.Body.String())
+		}
+	})
+
+	for name, query := range map[string]string{
+		"unknown field":  "fields=id,cost",
+		"unknown format": "format=xlsx",
+		"bad category":   "categoryIds=1,x",
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if rr := export(query); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,391 @@
+package product
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/config"
+	"github.com/nandaiqbalh/go-backend-ecom/service/thumbnail"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// allowedImageTypes are the MIME types accepted for product images. The
+// type is sniffed from the file contents; the client supplied
+// Content-Type and file name are ignored.
+var allowedImageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// errImageTooLarge is reported with 413 Request Entity Too Large.
+var errImageTooLarge = errors.New("image exceeds the maximum upload size")
+
+// handleUploadImages accepts one or more files in the multipart field
+// "images" and appends them to the product's images. The flow is:
+//  1. Limit the request body and parse the multipart form.
+//  2. For each file, check its size, sniff its MIME type and check the
+//     dimensions its header declares. One bad file rejects the upload
+//     before any file is stored.
+//  3. Store the bytes through the BlobStore and record the image rows,
+//     removing the ones stored already when one fails.
+//  4. Queue the images for resizing and mirror the first image into
+//     products.image.
+func (h *Handler) handleUploadImages(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.productIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	maxImage := config.Envs.MaxImageUploadBytes
+	maxImages := config.Envs.MaxImagesPerProduct
+
+	// allow some room for the multipart framing around the files
+	r.Body = http.MaxBytesReader(w, r.Body, maxImage*maxImages+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body too large"))
+			return
+		}
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid multipart form: %v", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["images"]
+	if len(files) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("no files in the images field"))
+		return
+	}
+
+	existing, err := h.store.ListProductImages(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if int64(len(existing)+len(files)) > maxImages {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("a product can have at most %d images", maxImages))
+		return
+	}
+
+	mtypes := make([]*mimetype.MIME, len(files))
+	for i, fh := range files {
+		mtype, err := checkImage(fh, maxImage)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, errImageTooLarge) || errors.Is(err, thumbnail.ErrTooManyPixels) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			utils.WriteError(w, status, fmt.Errorf("%s: %w", fh.Filename, err))
+			return
+		}
+		mtypes[i] = mtype
+	}
+
+	var uploaded []*types.ProductImage
+	for i, fh := range files {
+		img, err := h.storeImage(id, fh, mtypes[i])
+		if err != nil {
+			h.removeImages(id, uploaded)
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("%s: %w", fh.Filename, err))
+			return
+		}
+		uploaded = append(uploaded, img)
+	}
+	for _, img := range uploaded {
+		h.thumbnails.Enqueue(img)
+	}
+
+	if err := h.syncCoverImage(id); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.ProductImagesResponse{
+		Message: "images uploaded",
+		Data:    uploaded,
+	})
+}
+
+// checkImage validates a single uploaded file and returns its sniffed
+// MIME type.
+func checkImage(fh *multipart.FileHeader, maxSize int64) (*mimetype.MIME, error) {
+	if fh.Size > maxSize {
+		return nil, errImageTooLarge
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mtype, err := mimetype.DetectReader(f)
+	if err != nil {
+		return nil, err
+	}
+	if !mimetype.EqualsAny(mtype.String(), allowedImageTypes...) {
+		return nil, fmt.Errorf("unsupported image type %s", mtype.String())
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := thumbnail.CheckPixels(f, mtype.String(), config.Envs.MaxImagePixels); err != nil {
+		return nil, err
+	}
+	return mtype, nil
+}
+
+// storeImage writes a file checked by checkImage to the BlobStore and
+// records it against the product.
+func (h *Handler) storeImage(productID int, fh *multipart.FileHeader, mtype *mimetype.MIME) (*types.ProductImage, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	img := &types.ProductImage{
+		ProductID:   productID,
+		Key:         fmt.Sprintf("products/%d/%s%s", productID, name, mtype.Extension()),
+		ContentType: mtype.String(),
+		Size:        fh.Size,
+	}
+
+	if err := h.blobs.Put(img.Key, f, img.ContentType); err != nil {
+		return nil, err
+	}
+	if err := h.store.AddProductImage(img); err != nil {
+		// do not leave an orphaned file behind
+		h.blobs.Delete(img.Key)
+		return nil, err
+	}
+	img.URL = h.blobs.URL(img.Key)
+
+	return img, nil
+}
+
+// removeImages deletes images stored by an upload that failed part way,
+// with their files.
+func (h *Handler) removeImages(productID int, images []*types.ProductImage) {
+	for _, img := range images {
+		if _, err := h.store.DeleteProductImage(productID, img.ID); err != nil {
+			log.Printf("failed to delete image %d: %v", img.ID, err)
+			continue
+		}
+		if err := h.blobs.Delete(img.Key); err != nil {
+			log.Printf("failed to delete blob %s: %v", img.Key, err)
+		}
+	}
+}
+
+func (h *Handler) handleListImages(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.productIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	images, err := h.store.ListProductImages(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.setImageURLs(images)
+
+	utils.WriteJson(w, http.StatusOK, types.ProductImagesResponse{
+		Message: "success",
+		Data:    images,
+	})
+}
+
+// handleReorderImages sets the display order of a product's images. The
+// payload must list every image exactly once.
+func (h *Handler) handleReorderImages(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.productIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.ReorderProductImagesPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	images, err := h.store.ListProductImages(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	known := make(map[int]bool, len(images))
+	for _, img := range images {
+		known[img.ID] = true
+	}
+	if len(payload.ImageIDs) != len(images) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("imageIds must list all %d images of the product", len(images)))
+		return
+	}
+	for _, imageID := range payload.ImageIDs {
+		if !known[imageID] {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("image %d does not belong to the product or is listed twice", imageID))
+			return
+		}
+		delete(known, imageID)
+	}
+
+	if err := h.store.ReorderProductImages(id, payload.ImageIDs); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.syncCoverImage(id); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.handleListImages(w, r)
+}
+
+func (h *Handler) handleDeleteImage(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.productIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	imageID, err := strconv.Atoi(mux.Vars(r)["imageId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid image id"))
+		return
+	}
+
+	img, err := h.store.DeleteProductImage(id, imageID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if img == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("image not found"))
+		return
+	}
+
+	// the row is gone, so a failure here only leaves unreferenced files
+	keys := []string{img.Key}
+	for _, key := range img.SizeKeys {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		if err := h.blobs.Delete(key); err != nil {
+			log.Printf("failed to delete blob %s: %v", key, err)
+		}
+	}
+
+	if err := h.syncCoverImage(id); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	img.SizeKeys = nil
+	h.setImageURLs([]*types.ProductImage{img})
+	utils.WriteJson(w, http.StatusOK, types.ProductImagesResponse{
+		Message: "image deleted",
+		Data:    []*types.ProductImage{img},
+	})
+}
+
+// productIDFromPath parses {id} and checks that the product exists,
+// writing the error response itself when it does not.
+func (h *Handler) productIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return 0, false
+	}
+
+	prod, err := h.store.GetProductByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return 0, false
+	}
+	if prod == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+		return 0, false
+	}
+
+	return id, true
+}
+
+// syncCoverImage mirrors the URL of the product's first image into
+// products.image so listings can show it without loading every image.
+func (h *Handler) syncCoverImage(productID int) error {
+	images, err := h.store.ListProductImages(productID)
+	if err != nil {
+		return err
+	}
+
+	url := ""
+	if len(images) > 0 {
+		url = h.blobs.URL(images[0].Key)
+	}
+	return h.store.SetProductCoverImage(productID, url)
+}
+
+// setImageURLs fills the URLs of images and of their resized variants.
+func (h *Handler) setImageURLs(images []*types.ProductImage) {
+	for _, img := range images {
+		img.URL = h.blobs.URL(img.Key)
+		if len(img.SizeKeys) == 0 {
+			continue
+		}
+		img.Sizes = make(map[string]string, len(img.SizeKeys))
+		for size, key := range img.SizeKeys {
+			img.Sizes[size] = h.blobs.URL(key)
+		}
+	}
+}
+
+// setCoverImageSizes sets ImageSizes on each product from its first image.
+func (h *Handler) setCoverImageSizes(products []*types.Product) error {
+	ids := make([]int, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	covers, err := h.store.ListCoverImages(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		if cover, ok := covers[p.ID]; ok {
+			h.setImageURLs([]*types.ProductImage{cover})
+			p.ImageSizes = cover.Sizes
+		}
+	}
+	return nil
+}
+
+// randomName returns a random hex string used as a blob file name, so that
+// stored files can be cached forever and never collide.
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
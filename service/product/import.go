@@ -0,0 +1,401 @@
+package product
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Formats accepted by Import.
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// ImportBatchSize is the number of rows written per transaction.
+const ImportBatchSize = 100
+
+// maxImportBytes bounds the size of an uploaded import file.
+const maxImportBytes = 10 << 20
+
+// csvColumns maps the lower-cased CSV header names Import understands to
+// their canonical spelling. Only sku, name, description and price are
+// required; quantity defaults to zero, categoryIds is a semicolon
+// separated list of category IDs and currency, the currency of the price,
+// defaults to DefaultCurrency for new products and to the stored currency
+// for existing ones.
+var csvColumns = map[string]string{
+	"sku":         "sku",
+	"name":        "name",
+	"description": "description",
+	"price":       "price",
+	"quantity":    "quantity",
+	"currency":    "currency",
+	"categoryids": "categoryIds",
+}
+
+var requiredCSVColumns = []string{"sku", "name", "description", "price"}
+
+// importRow is one parsed input row. err is set when the row could not be
+// parsed, in which case payload is incomplete.
+type importRow struct {
+	line    int
+	payload types.CreateProductPayload
+	err     error
+}
+
+// handleImportProducts upserts products by SKU from a CSV (text/csv) or
+// NDJSON (application/x-ndjson) body. With ?dryRun=true every row is
+// validated and tried but nothing is written. The response reports the
+// outcome of every row; failing rows do not stop the others.
+func (h *Handler) handleImportProducts(w http.ResponseWriter, r *http.Request) {
+	format, err := importFormat(r.Header.Get("Content-Type"))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnsupportedMediaType, err)
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dryRun"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid dryRun value"))
+			return
+		}
+	}
+
+	actorID, _ := auth.UserIDFromContext(r.Context())
+	report, err := Import(h.store, http.MaxBytesReader(w, r.Body, maxImportBytes), format, dryRun, actorID)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("import file too large"))
+			return
+		}
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	message := "import completed"
+	if dryRun {
+		message = "dry run completed"
+	}
+	utils.WriteJson(w, http.StatusOK, types.ImportProductsResponse{
+		Message: message,
+		Data:    report,
+	})
+}
+
+// importFormat maps a request Content-Type to an import format.
+func importFormat(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return ImportFormatCSV, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return ImportFormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported Content-Type %q; use text/csv or application/x-ndjson", contentType)
+	}
+}
+
+// Import reads products in the given format from r, validates every row
+// with the CreateProductPayload rules and upserts the valid ones by SKU in
+// transactions of ImportBatchSize rows. Options and variants cannot be
+// imported. The error is only non-nil when the input as a whole could not
+// be read, such as a CSV file with an unknown column. Stock changes are
+// attributed to actorID in the inventory ledger; zero means the system.
+func Import(store types.ProductStore, r io.Reader, format string, dryRun bool, actorID int) (*types.ImportReport, error) {
+	var rows []importRow
+	var err error
+	switch format {
+	case ImportFormatCSV:
+		rows, err = parseCSV(r)
+	case ImportFormatNDJSON:
+		rows, err = parseNDJSON(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("import file has no rows")
+	}
+
+	report := &types.ImportReport{DryRun: dryRun, Rows: make([]*types.ImportRowResult, len(rows))}
+
+	var batch []*types.Product
+	var batchIdx []int
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		results, err := store.UpsertProductsBySKU(batch, dryRun, actorID)
+		for i, idx := range batchIdx {
+			result := &types.ImportRowResult{SKU: *batch[i].SKU, Status: types.ImportFailed}
+			if err != nil {
+				result.Errors = []string{fmt.Sprintf("batch failed: %v", err)}
+			} else {
+				result = results[i]
+			}
+			result.Row = rows[idx].line
+			report.Rows[idx] = result
+		}
+		batch, batchIdx = nil, nil
+	}
+
+	seen := make(map[string]int)
+	for i, row := range rows {
+		errs := validateImportRow(row)
+		if len(errs) == 0 && row.payload.Currency != "" {
+			var err error
+			if row.payload.Price, err = row.payload.Price.In(row.payload.Currency); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if len(errs) > 0 {
+			report.Rows[i] = &types.ImportRowResult{
+				Row:    row.line,
+				SKU:    row.payload.SKU,
+				Status: types.ImportFailed,
+				Errors: errs,
+			}
+			continue
+		}
+		if line, ok := seen[row.payload.SKU]; ok {
+			report.Rows[i] = &types.ImportRowResult{
+				Row:    row.line,
+				SKU:    row.payload.SKU,
+				Status: types.ImportFailed,
+				Errors: []string{fmt.Sprintf("sku already appears on row %d", line)},
+			}
+			continue
+		}
+		seen[row.payload.SKU] = row.line
+
+		sku := row.payload.SKU
+		batch = append(batch, &types.Product{
+			SKU:         &sku,
+			Name:        row.payload.Name,
+			Description: row.payload.Description,
+			Price:       row.payload.Price,
+			Currency:    row.payload.Currency,
+			Quantity:    row.payload.Quantity,
+			CategoryIDs: row.payload.CategoryIDs,
+		})
+		batchIdx = append(batchIdx, i)
+		if len(batch) == ImportBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	for _, result := range report.Rows {
+		switch result.Status {
+		case types.ImportCreated:
+			report.Created++
+		case types.ImportUpdated:
+			report.Updated++
+		default:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// validateImportRow returns the reasons a row cannot be imported.
+func validateImportRow(row importRow) []string {
+	if row.err != nil {
+		return []string{row.err.Error()}
+	}
+
+	var errs []string
+	if row.payload.SKU == "" {
+		errs = append(errs, "sku is required")
+	}
+	if len(row.payload.Options) > 0 || len(row.payload.Variants) > 0 {
+		errs = append(errs, "options and variants cannot be imported")
+	}
+	if err := utils.Validate.Struct(row.payload); err != nil {
+		errs = append(errs, fieldErrors(err)...)
+	}
+	return errs
+}
+
+// fieldErrors turns validator errors into messages that name the JSON
+// field, which is also the CSV column.
+func fieldErrors(err error) []string {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []string{err.Error()}
+	}
+
+	payloadType := reflect.TypeOf(types.CreateProductPayload{})
+	msgs := make([]string, 0, len(verrs))
+	for _, fe := range verrs {
+		name := fe.Field()
+		if f, ok := payloadType.FieldByName(fe.StructField()); ok {
+			name = strings.Split(f.Tag.Get("json"), ",")[0]
+		}
+		if fe.Param() != "" {
+			msgs = append(msgs, fmt.Sprintf("%s must satisfy %s=%s", name, fe.Tag(), fe.Param()))
+		} else {
+			msgs = append(msgs, fmt.Sprintf("%s must satisfy %s", name, fe.Tag()))
+		}
+	}
+	return msgs
+}
+
+// parseCSV reads a CSV file whose first record is a header naming the
+// columns in csvColumns, in any order.
+func parseCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // short rows are reported per row below
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("import file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // spreadsheet exports often start with a BOM
+		}
+		column, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		if _, dup := index[column]; dup {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		index[column] = i
+	}
+	for _, column := range requiredCSVColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("missing CSV column %q", column)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, importRow{line: parseErr.StartLine, err: fmt.Errorf("invalid CSV: %v", parseErr.Err)})
+			continue
+		}
+
+		row := importRow{line: line}
+		if len(record) != len(header) {
+			row.err = fmt.Errorf("expected %d columns, got %d", len(header), len(record))
+		} else {
+			row.payload, row.err = csvPayload(record, index)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// csvPayload converts one CSV record into a payload.
+func csvPayload(record []string, index map[string]int) (types.CreateProductPayload, error) {
+	cell := func(column string) string {
+		if i, ok := index[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	payload := types.CreateProductPayload{
+		SKU:         cell("sku"),
+		Name:        cell("name"),
+		Description: cell("description"),
+		Currency:    strings.ToUpper(cell("currency")),
+	}
+
+	var err error
+	if v := cell("price"); v != "" {
+		if payload.Price, err = types.ParseMoney(v, types.DefaultCurrency); err != nil {
+			return payload, fmt.Errorf("invalid price %q", v)
+		}
+	}
+	if v := cell("quantity"); v != "" {
+		if payload.Quantity, err = strconv.Atoi(v); err != nil {
+			return payload, fmt.Errorf("invalid quantity %q", v)
+		}
+	}
+	if v := cell("categoryIds"); v != "" {
+		for _, part := range strings.Split(v, ";") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				return payload, fmt.Errorf("invalid category id %q", part)
+			}
+			payload.CategoryIDs = append(payload.CategoryIDs, id)
+		}
+	}
+	return payload, nil
+}
+
+// parseNDJSON reads one JSON CreateProductPayload per line. Blank lines
+// are skipped.
+func parseNDJSON(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+
+	var rows []importRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		row := importRow{line: line}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.payload); err != nil {
+			row.err = fmt.Errorf("invalid JSON: %v", err)
+		} else if decoder.More() {
+			row.err = fmt.Errorf("invalid JSON: more than one value on the line")
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
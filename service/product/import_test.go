@@ -0,0 +1,130 @@
+package product
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// upsertRecorder records the batches Import writes. Every SKU starting
+// with "old" is reported as updated, all others as created.
+type upsertRecorder struct {
+	types.ProductStore
+	batches [][]string
+}
+
+func (s *upsertRecorder) UpsertProductsBySKU(products []*types.Product, dryRun bool, actorID int) ([]*types.ImportRowResult, error) {
+	var skus []string
+	var results []*types.ImportRowResult
+	for _, p := range products {
+		skus = append(skus, *p.SKU)
+		status := types.ImportCreated
+		if strings.HasPrefix(*p.SKU, "old") {
+			status = types.ImportUpdated
+		}
+		results = append(results, &types.ImportRowResult{SKU: *p.SKU, Status: status})
+	}
+	s.batches = append(s.batches, skus)
+	return results, nil
+}
+
+func TestParseCSV(t *testing.T) {
+	t.Run("reads columns in any order", func(t *testing.T) {
+		input := "\ufeffPrice,SKU,name,description,categoryIds\n" +
+			"9.5,A-1,Shirt,Cotton shirt,1; 2\n" +
+			"oops,A-2,Pants,Jeans,\n" +
+			"1,A-3,Hat\n"
+		rows, err := parseCSV(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 3 {
+			t.Fatalf("expected 3 rows, got %d", len(rows))
+		}
+
+		want := types.CreateProductPayload{SKU: "A-1", Name: "Shirt", Description: "Cotton shirt", Price: types.NewMoney(950, types.DefaultCurrency), CategoryIDs: []int{1, 2}}
+		if rows[0].err != nil || !reflect.DeepEqual(rows[0].payload, want) {
+			t.Errorf("row 1: got %+v, %v", rows[0].payload, rows[0].err)
+		}
+		if rows[0].line != 2 || rows[2].line != 4 {
+			t.Errorf("wrong line numbers %d, %d", rows[0].line, rows[2].line)
+		}
+		if rows[1].err == nil {
+			t.Error("expected an invalid price error")
+		}
+		if rows[2].err == nil {
+			t.Error("expected a column count error")
+		}
+	})
+
+	for name, header := range map[string]string{
+		"unknown column": "sku,name,description,price,color\n",
+		"missing column": "sku,name,price\n",
+		"empty file":     "",
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if _, err := parseCSV(strings.NewReader(header)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseNDJSON(t *testing.T) {
+	input := `{"sku":"A-1","name":"Shirt","description":"Cotton","price":9.5,"quantity":3}
+
+{"sku":"A-2","color":"red"}
+`
+	rows, err := parseNDJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if rows[0].err != nil || rows[0].payload.Quantity != 3 {
+		t.Errorf("row 1: got %+v, %v", rows[0].payload, rows[0].err)
+	}
+	if rows[1].line != 3 || rows[1].err == nil {
+		t.Errorf("expected an unknown field error on line 3, got line %d: %v", rows[1].line, rows[1].err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("sku,name,description,price,quantity\n")
+	for i := 0; i < ImportBatchSize; i++ {
+		fmt.Fprintf(&b, "new-%d,Shirt,Cotton,10,1\n", i)
+	}
+	b.WriteString("old-1,Shirt,Cotton,10,1\n")
+	b.WriteString("old-1,Duplicate,Cotton,10,1\n")
+	b.WriteString(",No sku,Cotton,10,1\n")
+	b.WriteString("bad-price,Shirt,Cotton,0,1\n")
+
+	store := &upsertRecorder{}
+	report, err := Import(store, strings.NewReader(b.String()), ImportFormatCSV, true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.batches) != 2 || len(store.batches[0]) != ImportBatchSize || len(store.batches[1]) != 1 {
+		t.Errorf("expected batches of %d and 1 rows, got %d batches", ImportBatchSize, len(store.batches))
+	}
+	if !report.DryRun || report.Created != ImportBatchSize || report.Updated != 1 || report.Failed != 3 {
+		t.Errorf("unexpected totals %+v", report)
+	}
+	if len(report.Rows) != ImportBatchSize+4 {
+		t.Fatalf("expected a result per row, got %d", len(report.Rows))
+	}
+
+	last := report.Rows[len(report.Rows)-1]
+	if last.Row != ImportBatchSize+5 || last.Status != types.ImportFailed || len(last.Errors) == 0 {
+		t.Errorf("unexpected result for the last row %+v", last)
+	}
+	if dup := report.Rows[ImportBatchSize+1]; !strings.Contains(strings.Join(dup.Errors, ""), "row") {
+		t.Errorf("expected a duplicate sku error, got %+v", dup)
+	}
+}
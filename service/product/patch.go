@@ -0,0 +1,285 @@
+package product
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// maxPatchBytes bounds the size of a PATCH body.
+const maxPatchBytes = 1 << 20
+
+// handlePatchProduct applies a partial update. The flow is:
+//  1. Check If-Match against the stored version, as for PUT.
+//  2. Render the current product as an UpdateProductPayload document.
+//  3. Apply the body as a JSON Merge Patch (RFC 7396) or JSON Patch
+//     (RFC 6902), chosen by Content-Type.
+//  4. Decode and validate the result with the UpdateProductPayload tags.
+//  5. Write only the columns and related records that actually changed.
+func (h *Handler) handlePatchProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	if r.Header.Get("If-Match") == "" {
+		utils.WriteError(w, http.StatusPreconditionRequired, fmt.Errorf("If-Match header is required"))
+		return
+	}
+	version, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	apply, err := patchFunc(r.Header.Get("Content-Type"))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnsupportedMediaType, err)
+		return
+	}
+
+	current, err := h.store.GetProductByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if current == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+	if current.Version != version {
+		h.writeVersionConflict(w, id)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("failed to read body: %v", err))
+		return
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("request body cannot be empty"))
+		return
+	}
+
+	before := toUpdatePayload(current)
+	doc, err := json.Marshal(before)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	patched, err := apply(doc, body)
+	if err != nil {
+		utils.WriteError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var after types.UpdateProductPayload
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&after); err != nil {
+		utils.WriteError(w, http.StatusUnprocessableEntity, fmt.Errorf("patched product is invalid: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(after); err != nil {
+		utils.WriteError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if after.ID != id {
+		utils.WriteError(w, http.StatusUnprocessableEntity, fmt.Errorf("id cannot be changed"))
+		return
+	}
+	if status, err := h.setCurrency(&after.Currency, &after.Price, after.Variants); err != nil {
+		if status == http.StatusBadRequest {
+			status = http.StatusUnprocessableEntity
+		}
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	actorID, _ := auth.UserIDFromContext(r.Context())
+	if status, err := h.applyProductPatch(id, version, before, after, actorID); err != nil {
+		if errors.Is(err, types.ErrVersionConflict) {
+			h.writeVersionConflict(w, id)
+			return
+		}
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	prod, err := h.store.GetProductByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.setImageURLs(prod.Images)
+
+	w.Header().Set("ETag", formatETag(prod.Version))
+	utils.WriteJson(w, http.StatusOK, types.UpdateProductResponse{
+		Message: "product updated",
+		Data:    prod,
+	})
+}
+
+// applyProductPatch persists the differences between before and after. It
+// returns the HTTP status to report with a non-nil error. Stock changes
+// are attributed to actorID in the inventory ledger.
+func (h *Handler) applyProductPatch(id, version int, before, after types.UpdateProductPayload, actorID int) (int, error) {
+	changes := make(map[string]any)
+	if after.Name != before.Name {
+		changes["name"] = after.Name
+	}
+	if after.Description != before.Description {
+		changes["description"] = after.Description
+	}
+	if after.Price != before.Price {
+		changes["price"] = after.Price
+	}
+	if after.Currency != before.Currency {
+		changes["currency"] = after.Currency
+	}
+	if after.TaxClass = taxClassOrDefault(after.TaxClass); after.TaxClass != before.TaxClass {
+		changes["taxClass"] = after.TaxClass
+	}
+	if !reflect.DeepEqual(after.Weight, before.Weight) {
+		changes["weight"] = after.Weight
+	}
+	if !reflect.DeepEqual(after.Dimensions, before.Dimensions) {
+		changes["length"], changes["width"], changes["height"] = dimensionColumns(after.Dimensions)
+	}
+	if !reflect.DeepEqual(after.LowStockThreshold, before.LowStockThreshold) {
+		changes["lowStockThreshold"] = after.LowStockThreshold
+	}
+	quantityChanged := after.Quantity != before.Quantity
+
+	variantsChanged := !reflect.DeepEqual(before.Options, after.Options) || !reflect.DeepEqual(before.Variants, after.Variants)
+	categoriesChanged := !sameIDs(before.CategoryIDs, after.CategoryIDs)
+
+	var options []*types.ProductOption
+	var variants []*types.ProductVariant
+	if variantsChanged {
+		var err error
+		options, variants, err = buildVariants(after.Options, after.Variants)
+		if err != nil {
+			return http.StatusUnprocessableEntity, err
+		}
+		if status, err := h.checkSKUs(id, variants); err != nil {
+			return status, err
+		}
+	}
+
+	// the quantity of a product with variants is the sum of their stock;
+	// removing all variants keeps the stock they held unless the patch
+	// also sets a new quantity
+	hasVariants := len(after.Variants) > 0
+	if quantityChanged && hasVariants {
+		return http.StatusUnprocessableEntity, fmt.Errorf("quantity is derived from the variants; patch their quantities instead")
+	}
+	setStock := !hasVariants && (quantityChanged || variantsChanged)
+
+	if len(changes) == 0 && !variantsChanged && !categoriesChanged && !setStock {
+		return 0, nil
+	}
+
+	var related types.ProductRelations
+	if variantsChanged {
+		related.Options, related.Variants = options, variants
+	}
+	if setStock {
+		related.Quantity = &after.Quantity
+	}
+	if categoriesChanged {
+		// an empty list unlinks every category
+		related.CategoryIDs = append([]int{}, after.CategoryIDs...)
+	}
+
+	// claims the version even when only related records change
+	if err := h.store.PatchProduct(id, version, changes, related, actorID); err != nil {
+		switch {
+		case errors.Is(err, types.ErrVersionConflict):
+			return 0, err
+		case errors.Is(err, types.ErrUnknownCategory):
+			return http.StatusUnprocessableEntity, err
+		}
+		return stockErrorStatus(err), err
+	}
+
+	return 0, nil
+}
+
+// patchFunc picks the patch algorithm from the request Content-Type.
+func patchFunc(contentType string) (func(doc, patch []byte) ([]byte, error), error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+
+	switch mediaType {
+	case utils.MergePatchMediaType:
+		return utils.MergePatch, nil
+	case utils.JSONPatchMediaType:
+		return utils.ApplyJSONPatch, nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Type %q; use %s or %s",
+			contentType, utils.MergePatchMediaType, utils.JSONPatchMediaType)
+	}
+}
+
+// toUpdatePayload renders a product in the shape accepted by PUT, which is
+// also the document PATCH operates on.
+func toUpdatePayload(p *types.Product) types.UpdateProductPayload {
+	payload := types.UpdateProductPayload{
+		ID:                p.ID,
+		Name:              p.Name,
+		Description:       p.Description,
+		Price:             p.Price,
+		Currency:          p.Currency,
+		TaxClass:          p.TaxClass,
+		Weight:            p.Weight,
+		Dimensions:        p.Dimensions,
+		Quantity:          p.Quantity,
+		LowStockThreshold: p.LowStockThreshold,
+		CategoryIDs:       p.CategoryIDs,
+	}
+	for _, o := range p.Options {
+		payload.Options = append(payload.Options, types.ProductOptionPayload{
+			Name:   o.Name,
+			Values: o.Values,
+		})
+	}
+	for _, v := range p.Variants {
+		payload.Variants = append(payload.Variants, types.ProductVariantPayload{
+			SKU:      v.SKU,
+			Price:    v.Price,
+			Quantity: v.Quantity,
+			Options:  v.Options,
+		})
+	}
+	return payload
+}
+
+// sameIDs reports whether a and b hold the same IDs in any order.
+func sameIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]int(nil), a...)
+	y := append([]int(nil), b...)
+	sort.Ints(x)
+	sort.Ints(y)
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,80 @@
+package product
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/nandaiqbalh/go-backend-ecom/service/currency"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// setCurrency settles the currency of a product payload, DefaultCurrency
+// when it is empty, and reinterprets its prices, which JSON decodes in
+// DefaultCurrency, in that currency. Other currencies must have an
+// exchange rate so the product can be shown in every currency. It returns
+// the HTTP status to report alongside the error.
+func (h *Handler) setCurrency(code *string, price *types.Money, variants []types.ProductVariantPayload) (int, error) {
+	if *code == "" {
+		*code = types.DefaultCurrency
+	}
+	if *code != types.DefaultCurrency {
+		rates, err := currency.Load(h.rates)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if !rates.Has(*code) {
+			return http.StatusBadRequest, fmt.Errorf("%w for %s", currency.ErrNoRate, *code)
+		}
+	}
+
+	p, err := price.In(*code)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid price: %v", err)
+	}
+	*price = p
+	for i, v := range variants {
+		if v.Price == nil {
+			continue
+		}
+		p, err := v.Price.In(*code)
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("invalid price of variant %s: %v", v.SKU, err)
+		}
+		variants[i].Price = &p
+	}
+	return 0, nil
+}
+
+// convertPrices converts the prices of p, and of its variants, into the
+// currency target. An empty target leaves them in the product currency.
+func convertPrices(p *types.Product, rates currency.Rates, target string) error {
+	if target == "" || target == p.Currency {
+		return nil
+	}
+
+	price, err := rates.Convert(p.Price, target)
+	if err != nil {
+		return err
+	}
+	for _, v := range p.Variants {
+		if v.Price == nil {
+			continue
+		}
+		converted, err := rates.Convert(*v.Price, target)
+		if err != nil {
+			return err
+		}
+		v.Price = &converted
+	}
+	p.Price, p.Currency = price, target
+	return nil
+}
+
+// taxClassOrDefault returns class, or DefaultTaxClass for products sent
+// without one.
+func taxClassOrDefault(class string) string {
+	if class == "" {
+		return types.DefaultTaxClass
+	}
+	return class
+}
@@ -1,44 +1,89 @@
 package product
 
 import (
+    "errors"
     "fmt"
     "net/http"
     "strconv"
+    "strings"
 
     "github.com/gorilla/mux"
     "github.com/nandaiqbalh/go-backend-ecom/service/auth"
+    "github.com/nandaiqbalh/go-backend-ecom/service/currency"
     "github.com/nandaiqbalh/go-backend-ecom/types"
     "github.com/nandaiqbalh/go-backend-ecom/utils"
 )
 
 type Handler struct {
-    store types.ProductStore
+    store      types.ProductStore
+    userStore  types.UserStore
+    rates      types.ExchangeRateStore
+    blobs      types.BlobStore
+    thumbnails types.ThumbnailQueue
 }
 
-// NewHandler creates a new Handler with the given ProductStore.
-func NewHandler(store types.ProductStore) *Handler {
-    return &Handler{store: store}
+// NewHandler creates a new Handler with the given ProductStore. The user
+// store authorizes admin-only operations and holds the preferred currency
+// prices are shown in, converted with the exchange rates from rates.
+// Uploaded product images are written to blobs and queued on thumbnails so
+// their resized variants are generated in the background.
+func NewHandler(store types.ProductStore, userStore types.UserStore, rates types.ExchangeRateStore, blobs types.BlobStore, thumbnails types.ThumbnailQueue) *Handler {
+    return &Handler{store: store, userStore: userStore, rates: rates, blobs: blobs, thumbnails: thumbnails}
 }
 
 // RegisterRoutes attaches product-related routes to the provided router.
 // All product endpoints require a valid JWT bearer token; we apply the
-// authentication middleware here.
+// authentication middleware here. Patches, deletes, image changes,
+// restores and imports are for admins only.
 func (h *Handler) RegisterRoutes(router *mux.Router) {
     router.HandleFunc("/products", auth.RequireToken(h.handleListProducts)).Methods("GET")
     router.HandleFunc("/products", auth.RequireToken(h.handleCreateProduct)).Methods("POST")
+    router.HandleFunc("/products/export", auth.RequireToken(h.handleExportProducts)).Methods("GET")
+    router.HandleFunc("/products/import", auth.RequireAdmin(h.handleImportProducts, h.userStore)).Methods("POST")
     router.HandleFunc("/products/{id}", auth.RequireToken(h.handleGetProduct)).Methods("GET")
     router.HandleFunc("/products/{id}", auth.RequireToken(h.handleUpdateProduct)).Methods("PUT")
-    router.HandleFunc("/products/{id}", auth.RequireToken(h.handleDeleteProduct)).Methods("DELETE")
+    router.HandleFunc("/products/{id}", auth.RequireAdmin(h.handlePatchProduct, h.userStore)).Methods("PATCH")
+    router.HandleFunc("/products/{id}", auth.RequireAdmin(h.handleDeleteProduct, h.userStore)).Methods("DELETE")
+    router.HandleFunc("/products/{id}/restore", auth.RequireAdmin(h.handleRestoreProduct, h.userStore)).Methods("POST")
+
+    router.HandleFunc("/products/{id}/images", auth.RequireToken(h.handleListImages)).Methods("GET")
+    router.HandleFunc("/products/{id}/images", auth.RequireAdmin(h.handleUploadImages, h.userStore)).Methods("POST")
+    router.HandleFunc("/products/{id}/images/order", auth.RequireAdmin(h.handleReorderImages, h.userStore)).Methods("PUT")
+    router.HandleFunc("/products/{id}/images/{imageId}", auth.RequireAdmin(h.handleDeleteImage, h.userStore)).Methods("DELETE")
 }
 
 func (h *Handler) handleListProducts(w http.ResponseWriter, r *http.Request) {
-    products, err := h.store.ListProducts()
+    params, ok := h.listFilters(w, r)
+    if !ok {
+        return
+    }
+    params.Pagination = utils.ParsePagination(r)
+    target, rates, ok := currency.FromRequest(w, r, h.rates, h.userStore)
+    if !ok {
+        return
+    }
+
+    products, total, err := h.store.ListProducts(params)
     if err != nil {
         utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list products: %v", err))
         return
     }
+    if err := h.setCoverImageSizes(products); err != nil {
+        utils.WriteError(w, http.StatusInternalServerError, err)
+        return
+    }
+    for _, p := range products {
+        if err := convertPrices(p, rates, target); err != nil {
+            utils.WriteError(w, http.StatusInternalServerError, err)
+            return
+        }
+    }
 
-    utils.WriteJson(w, http.StatusOK, products)
+    utils.WriteJson(w, http.StatusOK, types.ListProductsResponse{
+        Message: "success",
+        Data:    products,
+        Meta:    utils.NewPageMeta(params.Pagination, total),
+    })
 }
 
 func (h *Handler) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
@@ -57,37 +102,82 @@ func (h *Handler) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
         utils.WriteError(w, http.StatusBadRequest, err)
         return
     }
+    if status, err := h.setCurrency(&payload.Currency, &payload.Price, payload.Variants); err != nil {
+        utils.WriteError(w, status, err)
+        return
+    }
+
+    options, variants, err := buildVariants(payload.Options, payload.Variants)
+    if err != nil {
+        utils.WriteError(w, http.StatusBadRequest, err)
+        return
+    }
+    if status, err := h.checkSKUs(0, variants); err != nil {
+        utils.WriteError(w, status, err)
+        return
+    }
+
+    if payload.SKU != "" {
+        existing, err := h.store.GetProductBySKU(payload.SKU)
+        if err != nil {
+            utils.WriteError(w, http.StatusInternalServerError, err)
+            return
+        }
+        if existing != nil {
+            utils.WriteError(w, http.StatusConflict, fmt.Errorf("sku %s is already used by product %d", payload.SKU, existing.ID))
+            return
+        }
+    }
 
     prod := &types.Product{
-        Name:        payload.Name,
-        Description: payload.Description,
-        Price:       payload.Price,
-        Quantity:    payload.Quantity,
+        Name:              payload.Name,
+        Description:       payload.Description,
+        Price:             payload.Price,
+        Currency:          payload.Currency,
+        TaxClass:          taxClassOrDefault(payload.TaxClass),
+        Weight:            payload.Weight,
+        Dimensions:        payload.Dimensions,
+        Quantity:          payload.Quantity,
+        LowStockThreshold: payload.LowStockThreshold,
+    }
+    if payload.SKU != "" {
+        prod.SKU = &payload.SKU
+    }
+    // the stock of a product with variants is added by saving them
+    if len(variants) > 0 {
+        prod.Quantity = 0
     }
 
-    if err := h.store.CreateProduct(prod); err != nil {
-        utils.WriteError(w, http.StatusInternalServerError, err)
+    related := types.ProductRelations{CategoryIDs: payload.CategoryIDs}
+    if len(variants) > 0 {
+        related.Options, related.Variants = options, variants
+    }
+
+    actorID, _ := auth.UserIDFromContext(r.Context())
+    if err := h.store.CreateProduct(prod, related, actorID); err != nil {
+        utils.WriteError(w, saveErrorStatus(err), err)
         return
     }
 
+    if len(variants) > 0 {
+        prod.Options, prod.Variants = options, variants
+        prod.Quantity = totalStock(variants)
+    }
+    if len(payload.CategoryIDs) > 0 {
+        prod.CategoryIDs = uniqueInts(payload.CategoryIDs)
+    }
+
+    // nothing can be reserved yet
+    prod.Available = prod.Quantity
+    for _, v := range variants {
+        v.Available = v.Quantity
+    }
+
     resp := types.CreateProductResponse{
         Message: "product created",
-        Data: struct {
-            ID          int     `json:"id"`
-            Name        string  `json:"name"`
-            Description string  `json:"description"`
-            Price       float64 `json:"price"`
-            Quantity    int     `json:"quantity"`
-            CreatedAt   string  `json:"createdAt"`
-        }{
-            ID:          prod.ID,
-            Name:        prod.Name,
-            Description: prod.Description,
-            Price:       prod.Price,
-            Quantity:    prod.Quantity,
-            CreatedAt:   prod.CreatedAt,
-        },
+        Data:    prod,
     }
+    w.Header().Set("ETag", formatETag(prod.Version))
     utils.WriteJson(w, http.StatusCreated, resp)
 }
 
@@ -100,7 +190,21 @@ func (h *Handler) handleGetProduct(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    prod, err := h.store.GetProductByID(id)
+    includeDeleted, ok := h.includeDeleted(w, r)
+    if !ok {
+        return
+    }
+    target, rates, ok := currency.FromRequest(w, r, h.rates, h.userStore)
+    if !ok {
+        return
+    }
+
+    var prod *types.Product
+    if includeDeleted {
+        prod, err = h.store.GetProductByIDIncludingDeleted(id)
+    } else {
+        prod, err = h.store.GetProductByID(id)
+    }
     if err != nil {
         utils.WriteError(w, http.StatusInternalServerError, err)
         return
@@ -109,25 +213,20 @@ func (h *Handler) handleGetProduct(w http.ResponseWriter, r *http.Request) {
         utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
         return
     }
+    h.setImageURLs(prod.Images)
+    if len(prod.Images) > 0 {
+        prod.ImageSizes = prod.Images[0].Sizes
+    }
+    if err := convertPrices(prod, rates, target); err != nil {
+        utils.WriteError(w, http.StatusInternalServerError, err)
+        return
+    }
 
     resp := types.GetProductByIDResponse{
         Message: "success",
-        Data: struct {
-            ID          int     `json:"id"`
-            Name        string  `json:"name"`
-            Description string  `json:"description"`
-            Price       float64 `json:"price"`
-            Quantity    int     `json:"quantity"`
-            CreatedAt   string  `json:"createdAt"`
-        }{
-            ID:          prod.ID,
-            Name:        prod.Name,
-            Description: prod.Description,
-            Price:       prod.Price,
-            Quantity:    prod.Quantity,
-            CreatedAt:   prod.CreatedAt,
-        },
+        Data:    prod,
     }
+    w.Header().Set("ETag", formatETag(prod.Version))
     utils.WriteJson(w, http.StatusOK, resp)
 }
 
@@ -140,6 +239,17 @@ func (h *Handler) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    // clients must prove they saw the current version to avoid lost updates
+    if r.Header.Get("If-Match") == "" {
+        utils.WriteError(w, http.StatusPreconditionRequired, fmt.Errorf("If-Match header is required"))
+        return
+    }
+    version, err := parseIfMatch(r.Header.Get("If-Match"))
+    if err != nil {
+        utils.WriteError(w, http.StatusBadRequest, err)
+        return
+    }
+
     if r.Body == nil {
         utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("request body is empty"))
         return
@@ -160,41 +270,96 @@ func (h *Handler) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
         utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("id mismatch"))
         return
     }
+    if status, err := h.setCurrency(&payload.Currency, &payload.Price, payload.Variants); err != nil {
+        utils.WriteError(w, status, err)
+        return
+    }
 
     prod := &types.Product{
-        ID:          id,
-        Name:        payload.Name,
-        Description: payload.Description,
-        Price:       payload.Price,
-        Quantity:    payload.Quantity,
+        ID:                id,
+        Name:              payload.Name,
+        Description:       payload.Description,
+        Price:             payload.Price,
+        Currency:          payload.Currency,
+        TaxClass:          taxClassOrDefault(payload.TaxClass),
+        Weight:            payload.Weight,
+        Dimensions:        payload.Dimensions,
+        Quantity:          payload.Quantity,
+        LowStockThreshold: payload.LowStockThreshold,
+        Version:           version,
+    }
+
+    // variants are only replaced when the client sends them; otherwise the
+    // stored ones are kept and still determine the product quantity
+    options, variants, err := buildVariants(payload.Options, payload.Variants)
+    if err != nil {
+        utils.WriteError(w, http.StatusBadRequest, err)
+        return
+    }
+    // categories are only replaced when the client sends the field
+    related := types.ProductRelations{CategoryIDs: payload.CategoryIDs}
+    if payload.Variants == nil {
+        options, variants, err = h.store.GetProductVariants(id)
+        if err != nil {
+            utils.WriteError(w, http.StatusInternalServerError, err)
+            return
+        }
+    } else if status, err := h.checkSKUs(id, variants); err != nil {
+        utils.WriteError(w, status, err)
+        return
+    } else {
+        related.Options, related.Variants = options, variants
+    }
+    // the quantity is only used for products without variants
+    if len(variants) == 0 {
+        related.Quantity = &payload.Quantity
+    }
+
+    actorID, _ := auth.UserIDFromContext(r.Context())
+    if err := h.store.UpdateProduct(prod, related, actorID); err != nil {
+        if errors.Is(err, types.ErrVersionConflict) {
+            h.writeVersionConflict(w, id)
+            return
+        }
+        utils.WriteError(w, saveErrorStatus(err), err)
+        return
     }
 
-    if err := h.store.UpdateProduct(prod); err != nil {
+    // reload so the response and ETag reflect every write made above,
+    // including the version bump from replacing variants
+    prod, err = h.store.GetProductByID(id)
+    if err != nil {
         utils.WriteError(w, http.StatusInternalServerError, err)
         return
     }
+    h.setImageURLs(prod.Images)
 
     resp := types.UpdateProductResponse{
         Message: "product updated",
-        Data: struct {
-            ID          int     `json:"id"`
-            Name        string  `json:"name"`
-            Description string  `json:"description"`
-            Price       float64 `json:"price"`
-            Quantity    int     `json:"quantity"`
-            CreatedAt   string  `json:"createdAt"`
-        }{
-            ID:          prod.ID,
-            Name:        prod.Name,
-            Description: prod.Description,
-            Price:       prod.Price,
-            Quantity:    prod.Quantity,
-            CreatedAt:   prod.CreatedAt,
-        },
+        Data:    prod,
     }
+    w.Header().Set("ETag", formatETag(prod.Version))
     utils.WriteJson(w, http.StatusOK, resp)
 }
 
+// writeVersionConflict reports a failed conditional update: 404 when the
+// product no longer exists, otherwise 412 with the current ETag so the
+// client can re-read and retry.
+func (h *Handler) writeVersionConflict(w http.ResponseWriter, id int) {
+    current, err := h.store.GetProductByID(id)
+    if err != nil {
+        utils.WriteError(w, http.StatusInternalServerError, err)
+        return
+    }
+    if current == nil {
+        utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+        return
+    }
+
+    w.Header().Set("ETag", formatETag(current.Version))
+    utils.WriteError(w, http.StatusPreconditionFailed, fmt.Errorf("product was modified by someone else; fetch it again and retry"))
+}
+
 func (h *Handler) handleDeleteProduct(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     idStr := vars["id"]
@@ -204,6 +369,17 @@ func (h *Handler) handleDeleteProduct(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    prod, err := h.store.GetProductByID(id)
+    if err != nil {
+        utils.WriteError(w, http.StatusInternalServerError, err)
+        return
+    }
+    if prod == nil {
+        utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+        return
+    }
+
+    // products are soft deleted so existing orders keep their lines
     if err := h.store.DeleteProduct(id); err != nil {
         utils.WriteError(w, http.StatusInternalServerError, err)
         return
@@ -211,14 +387,122 @@ func (h *Handler) handleDeleteProduct(w http.ResponseWriter, r *http.Request) {
 
     resp := types.DeleteProductResponse{
         Message: "product deleted",
-        Data: struct {
-            ID          int     `json:"id"`
-            Name        string  `json:"name"`
-            Description string  `json:"description"`
-            Price       float64 `json:"price"`
-            Quantity    int     `json:"quantity"`
-            CreatedAt   string  `json:"createdAt"`
-        }{},
+        Data:    &types.Product{ID: id},
     }
     utils.WriteJson(w, http.StatusOK, resp)
 }
+
+// stockErrorStatus is the status reported for a failed stock change: 409
+// when it would take stock below zero, for instance at the default
+// location, or remove a variant pending orders hold, 500 otherwise.
+func stockErrorStatus(err error) int {
+    if errors.Is(err, types.ErrInsufficientStock) || errors.Is(err, types.ErrVariantReserved) {
+        return http.StatusConflict
+    }
+    return http.StatusInternalServerError
+}
+
+// saveErrorStatus is the status reported when writing a product with its
+// variants, stock and categories fails with err.
+func saveErrorStatus(err error) int {
+    if errors.Is(err, types.ErrUnknownCategory) {
+        return http.StatusBadRequest
+    }
+    return stockErrorStatus(err)
+}
+
+// checkSKUs rejects variants whose SKU already belongs to another product.
+// productID is zero for products that have not been created yet.
+func (h *Handler) checkSKUs(productID int, variants []*types.ProductVariant) (int, error) {
+    for _, v := range variants {
+        existing, err := h.store.GetVariantBySKU(v.SKU)
+        if err != nil {
+            return http.StatusInternalServerError, err
+        }
+        if existing != nil && existing.ProductID != productID {
+            return http.StatusConflict, fmt.Errorf("sku %s is already used by product %d", v.SKU, existing.ProductID)
+        }
+    }
+    return 0, nil
+}
+
+// handleRestoreProduct undoes a soft delete. Only admins may restore.
+func (h *Handler) handleRestoreProduct(w http.ResponseWriter, r *http.Request) {
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+        return
+    }
+
+    prod, err := h.store.GetProductByIDIncludingDeleted(id)
+    if err != nil {
+        utils.WriteError(w, http.StatusInternalServerError, err)
+        return
+    }
+    if prod == nil {
+        utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+        return
+    }
+    if prod.DeletedAt == nil {
+        utils.WriteError(w, http.StatusConflict, fmt.Errorf("product is not deleted"))
+        return
+    }
+
+    if err := h.store.RestoreProduct(id); err != nil {
+        utils.WriteError(w, http.StatusInternalServerError, err)
+        return
+    }
+    prod.DeletedAt = nil
+    h.setImageURLs(prod.Images)
+
+    utils.WriteJson(w, http.StatusOK, types.GetProductByIDResponse{
+        Message: "product restored",
+        Data:    prod,
+    })
+}
+
+// includeDeleted reports whether the request asked for soft deleted
+// products with ?includeDeleted=true. Only admins may ask; for anyone else
+// a 403 is written and ok is false.
+func (h *Handler) includeDeleted(w http.ResponseWriter, r *http.Request) (include bool, ok bool) {
+    v := r.URL.Query().Get("includeDeleted")
+    if v == "" {
+        return false, true
+    }
+
+    include, err := strconv.ParseBool(v)
+    if err != nil {
+        utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid includeDeleted value"))
+        return false, false
+    }
+    if include && !auth.IsAdmin(r.Context(), h.userStore) {
+        utils.WriteError(w, http.StatusForbidden, fmt.Errorf("admin access required to include deleted products"))
+        return false, false
+    }
+
+    return include, true
+}
+
+// listFilters reads the filters shared by the product listing and export:
+// ?categoryIds=1,2 keeps products in any of the categories and
+// ?includeDeleted=true (admins only) adds soft deleted products. On
+// invalid input the error response is written and ok is false.
+func (h *Handler) listFilters(w http.ResponseWriter, r *http.Request) (params types.ProductListParams, ok bool) {
+    params.IncludeDeleted, ok = h.includeDeleted(w, r)
+    if !ok {
+        return params, false
+    }
+
+    if v := r.URL.Query().Get("categoryIds"); v != "" {
+        for _, part := range strings.Split(v, ",") {
+            id, err := strconv.Atoi(strings.TrimSpace(part))
+            if err != nil || id <= 0 {
+                utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid category id %q", part))
+                return params, false
+            }
+            params.CategoryIDs = append(params.CategoryIDs, id)
+        }
+    }
+
+    return params, true
+}
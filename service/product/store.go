@@ -2,10 +2,22 @@ package product
 
 import (
 	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/service/inventory"
 	"github.com/nandaiqbalh/go-backend-ecom/types"
 )
 
+// productColumns lists the columns read by scanProduct, in scan order.
+// Selecting them explicitly keeps the scans stable as columns are added.
+// The last one is the stock left after active reservations.
+const productColumns = "id, sku, name, description, image, price, currency, taxClass, weight, length, width, height, quantity, lowStockThreshold, ratingAverage, ratingCount, createdAt, deletedAt, version, " +
+	"quantity - (SELECT COALESCE(SUM(r.quantity), 0) FROM inventory_reservations r WHERE r.productId = products.id AND r.status = 'active')"
+
 type Store struct {
 	db *sql.DB
 }
@@ -14,58 +26,159 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
-// function to query products in db (list, get by id, create, update, delete) would be defined here, following similar patterns to the user store: execute SQL queries and scan results into types.Product structs.
-func (s *Store) ListProducts() ([]*types.Product, error) {
-	// Implementation for listing products from the database
-	rows, err := s.db.Query("SELECT * FROM products")
+// scanProduct reads a row selected with productColumns into a Product.
+func scanProduct(row sqlutil.Scanner) (*types.Product, error) {
+	product := new(types.Product)
+	var price string
+	var sku, img, deletedAt sql.NullString
+	var threshold, weight, length, width, height sql.NullInt64
+	var rating sql.NullFloat64
+	err := row.Scan(
+		&product.ID,
+		&sku,
+		&product.Name,
+		&product.Description,
+		&img,
+		&price,
+		&product.Currency,
+		&product.TaxClass,
+		&weight,
+		&length,
+		&width,
+		&height,
+		&product.Quantity,
+		&threshold,
+		&rating,
+		&product.RatingCount,
+		&product.CreatedAt,
+		&deletedAt,
+		&product.Version,
+		&product.Available,
+	)
 	if err != nil {
 		return nil, err
 	}
+	if product.Price, err = types.ParseMoney(price, product.Currency); err != nil {
+		return nil, err
+	}
+	if sku.Valid {
+		product.SKU = &sku.String
+	}
+	product.LowStockThreshold = sqlutil.NullableInt(threshold)
+	if rating.Valid {
+		product.RatingAverage = &rating.Float64
+	}
+	product.Weight = sqlutil.NullableInt(weight)
+	if length.Valid && width.Valid && height.Valid {
+		product.Dimensions = &types.Dimensions{Length: int(length.Int64), Width: int(width.Int64), Height: int(height.Int64)}
+	}
+	if img.Valid {
+		product.Image = img.String
+	}
+	if deletedAt.Valid {
+		product.DeletedAt = &deletedAt.String
+	}
+	return product, nil
+}
+
+// ListProducts returns one page of products matching params together with
+// the total number of matching rows. When params.CategoryIDs is set only
+// products linked to at least one of those categories are returned.
+func (s *Store) ListProducts(params types.ProductListParams) ([]*types.Product, int, error) {
+	where, args := productFilter(params)
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM products"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(
+		"SELECT "+productColumns+" FROM products"+where+" ORDER BY id LIMIT ? OFFSET ?",
+		append(args, params.PageSize, params.Offset())...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer rows.Close()
 
-	var products []*types.Product
+	products := []*types.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// productFilter builds the WHERE clause, with a leading space, selecting
+// the products that match the filters in params. Pagination is ignored.
+func productFilter(params types.ProductListParams) (string, []any) {
+	var conds []string
+	var args []any
+	if !params.IncludeDeleted {
+		conds = append(conds, "deletedAt IS NULL")
+	}
+	if len(params.CategoryIDs) > 0 {
+		conds = append(conds, "id IN (SELECT productId FROM product_categories WHERE categoryId IN ("+
+			sqlutil.Placeholders(len(params.CategoryIDs))+"))")
+		for _, id := range params.CategoryIDs {
+			args = append(args, id)
+		}
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// EachProduct calls fn for every product matching the filters in params,
+// in ID order, reading them from a single cursor so that the whole catalog
+// is never held in memory. Only the products columns are loaded. Iteration
+// stops at the first error returned by fn.
+func (s *Store) EachProduct(params types.ProductListParams, fn func(*types.Product) error) error {
+	where, args := productFilter(params)
+
+	rows, err := s.db.Query("SELECT "+productColumns+" FROM products"+where+" ORDER BY id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
 	for rows.Next() {
-        product := new(types.Product)
-        var img sql.NullString
-        err := rows.Scan(
-            &product.ID,
-            &product.Name,
-            &product.Description,
-            &img,
-            &product.Price,
-            &product.Quantity,
-            &product.CreatedAt,
-        )
-        if err != nil {
-            return nil, err
-        }
-        if img.Valid {
-            product.Image = img.String
-        }
-        products = append(products, product)
-    }
-	return products, nil
+		product, err := scanProduct(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
+// GetProductByID returns the product with its categories, variants and
+// images, or nil if it does not exist or has been soft deleted.
 func (s *Store) GetProductByID(id int) (*types.Product, error) {
-	// Implementation for getting a single product by ID from the database
-	row := s.db.QueryRow("SELECT * FROM products WHERE id = ?", id)
+	return s.getProduct("WHERE id = ? AND deletedAt IS NULL", id)
+}
 
-	product := new(types.Product)
-    var img sql.NullString
-	err := row.Scan(
-        &product.ID,
-        &product.Name,
-        &product.Description,
-        &img,
-        &product.Price,
-        &product.Quantity,
-        &product.CreatedAt,
-    )
-    if img.Valid {
-        product.Image = img.String
-    }
+// GetProductByIDIncludingDeleted is like GetProductByID but also returns
+// soft deleted products.
+func (s *Store) GetProductByIDIncludingDeleted(id int) (*types.Product, error) {
+	return s.getProduct("WHERE id = ?", id)
+}
+
+func (s *Store) getProduct(where string, id int) (*types.Product, error) {
+	row := s.db.QueryRow("SELECT "+productColumns+" FROM products "+where, id)
+
+	product, err := scanProduct(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No product found with the given ID
@@ -73,17 +186,68 @@ func (s *Store) GetProductByID(id int) (*types.Product, error) {
 		return nil, err
 	}
 
+	product.CategoryIDs, err = s.getProductCategoryIDs(id)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Options, product.Variants, err = s.GetProductVariants(id)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Images, err = s.ListProductImages(id)
+	if err != nil {
+		return nil, err
+	}
+
 	return product, nil
 }
 
-func (s *Store) CreateProduct(product *types.Product) error {
-	// Implementation for creating a new product in the database
-	result, err := s.db.Exec(
-		"INSERT INTO products (name, description, price, quantity) VALUES (?, ?, ?, ?)",
+func (s *Store) getProductCategoryIDs(productID int) ([]int, error) {
+	rows, err := s.db.Query("SELECT categoryId FROM product_categories WHERE productId = ? ORDER BY categoryId", productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CreateProduct inserts the product with the variants and categories of
+// related in a single transaction, so a product is never left without
+// them. Its initial quantity is recorded as a restock in the inventory
+// ledger; products with variants are created empty and get their stock
+// from the variants.
+func (s *Store) CreateProduct(product *types.Product, related types.ProductRelations, actorID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	length, width, height := dimensionColumns(product.Dimensions)
+	result, err := tx.Exec(
+		"INSERT INTO products (sku, name, description, price, currency, taxClass, weight, length, width, height, lowStockThreshold, quantity) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0)",
+		product.SKU,
 		product.Name,
 		product.Description,
 		product.Price,
-		product.Quantity,
+		product.Currency,
+		product.TaxClass,
+		product.Weight,
+		length,
+		width,
+		height,
+		product.LowStockThreshold,
 	)
 	if err != nil {
 		return err
@@ -94,25 +258,881 @@ func (s *Store) CreateProduct(product *types.Product) error {
 		return err
 	}
 	product.ID = int(id)
+	product.Version = 1
+
+	if product.Quantity > 0 && len(related.Variants) == 0 {
+		if err := inventory.Move(tx, &types.StockMovement{
+			ProductID:  product.ID,
+			LocationID: types.DefaultLocationID,
+			Delta:      product.Quantity,
+			Reason:     types.MovementRestock,
+			ActorID:    inventory.Actor(actorID),
+		}); err != nil {
+			return err
+		}
+	}
+	if err := saveRelations(tx, product.ID, related, actorID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
 
+// saveRelations writes the variants, stock and categories of related for
+// a product, leaving the ones related does not set as stored.
+func saveRelations(tx *sql.Tx, productID int, related types.ProductRelations, actorID int) error {
+	if related.Variants != nil {
+		if err := saveVariants(tx, productID, related.Options, related.Variants, actorID); err != nil {
+			return err
+		}
+	}
+	if related.Quantity != nil {
+		if err := setStock(tx, productID, *related.Quantity, actorID); err != nil {
+			return err
+		}
+	}
+	if related.CategoryIDs != nil {
+		if err := checkCategories(tx, related.CategoryIDs); err != nil {
+			return err
+		}
+		if err := replaceCategories(tx, productID, related.CategoryIDs); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (s *Store) UpdateProduct(product *types.Product) error {
-	// Implementation for updating an existing product in the database
-	_, err := s.db.Exec(
-		"UPDATE products SET name = ?, description = ?, price = ?, quantity = ? WHERE id = ?",
+// UpdateProduct writes the product only if its stored version still equals
+// product.Version, then increments the version. The variants, stock and
+// categories of related are written in the same transaction. If the
+// product changed in the meantime (or was deleted)
+// types.ErrVersionConflict is returned and nothing is written. The
+// quantity of product is not written; stock changes come from related so
+// they are recorded in the inventory ledger.
+func (s *Store) UpdateProduct(product *types.Product, related types.ProductRelations, actorID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	length, width, height := dimensionColumns(product.Dimensions)
+	result, err := tx.Exec(
+		"UPDATE products SET name = ?, description = ?, price = ?, currency = ?, taxClass = ?, weight = ?, length = ?, width = ?, height = ?, lowStockThreshold = ?, version = version + 1 WHERE id = ? AND version = ? AND deletedAt IS NULL",
 		product.Name,
 		product.Description,
 		product.Price,
-		product.Quantity,
+		product.Currency,
+		product.TaxClass,
+		product.Weight,
+		length,
+		width,
+		height,
+		product.LowStockThreshold,
 		product.ID,
+		product.Version,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return types.ErrVersionConflict
+	}
+	if err := saveRelations(tx, product.ID, related, actorID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	product.Version++
+	return nil
+}
+
+// dimensionColumns returns the length, width and height columns of d,
+// NULL when the product has no dimensions.
+func dimensionColumns(d *types.Dimensions) (length, width, height any) {
+	if d == nil {
+		return nil, nil, nil
+	}
+	return d.Length, d.Width, d.Height
+}
+
+// setStock sets the quantity of a product without variants and records
+// the change as an adjustment in the inventory ledger.
+func setStock(tx *sql.Tx, productID, quantity, actorID int) error {
+	var hasVariants bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM product_variants WHERE productId = ?)", productID).Scan(&hasVariants); err != nil {
+		return err
+	}
+	if hasVariants {
+		return fmt.Errorf("the quantity of product %d is derived from its variants", productID)
+	}
+	return inventory.SetQuantity(tx, productID, nil, quantity, types.MovementAdjustment, actorID)
+}
+
+// patchableColumns are the products columns PatchProduct may write.
+var patchableColumns = map[string]bool{
+	"name":              true,
+	"description":       true,
+	"price":             true,
+	"currency":          true,
+	"taxClass":          true,
+	"weight":            true,
+	"length":            true,
+	"width":             true,
+	"height":            true,
+	"lowStockThreshold": true,
+}
+
+// PatchProduct writes only the given columns, under the same version check
+// as UpdateProduct, and the variants, stock and categories of related in
+// the same transaction. Like UpdateProduct it never writes the quantity
+// column. The version is incremented even when changes is empty, so a
+// patch of related records only still claims the version.
+func (s *Store) PatchProduct(id, version int, changes map[string]any, related types.ProductRelations, actorID int) error {
+	columns := make([]string, 0, len(changes))
+	for column := range changes {
+		if !patchableColumns[column] {
+			return fmt.Errorf("column %q cannot be patched", column)
+		}
+		columns = append(columns, column)
+	}
+	// deterministic statement text keeps the query plan cache useful
+	sort.Strings(columns)
+
+	set := ""
+	args := make([]any, 0, len(changes)+2)
+	for _, column := range columns {
+		set += column + " = ?, "
+		args = append(args, changes[column])
+	}
+	args = append(args, id, version)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE products SET "+set+"version = version + 1 WHERE id = ? AND version = ? AND deletedAt IS NULL",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return types.ErrVersionConflict
+	}
+	if err := saveRelations(tx, id, related, actorID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// DeleteProduct soft deletes a product by setting deletedAt. The row is
+// kept so that order history referencing it stays intact.
 func (s *Store) DeleteProduct(id int) error {
-	// Implementation for deleting a product from the database
-	_, err := s.db.Exec("DELETE FROM products WHERE id = ?", id)
+	_, err := s.db.Exec("UPDATE products SET deletedAt = CURRENT_TIMESTAMP WHERE id = ? AND deletedAt IS NULL", id)
+	return err
+}
+
+// RestoreProduct clears deletedAt on a soft deleted product.
+func (s *Store) RestoreProduct(id int) error {
+	_, err := s.db.Exec("UPDATE products SET deletedAt = NULL WHERE id = ?", id)
 	return err
-}
\ No newline at end of file
+}
+
+// checkCategories returns an error wrapping types.ErrUnknownCategory that
+// names the IDs of categoryIDs no category has.
+func checkCategories(tx *sql.Tx, categoryIDs []int) error {
+	ids := uniqueInts(categoryIDs)
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := tx.Query("SELECT id FROM categories WHERE id IN ("+sqlutil.Placeholders(len(args))+")", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	found := make(map[int]bool, len(ids))
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		found[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var unknown []string
+	for _, id := range ids {
+		if !found[id] {
+			unknown = append(unknown, strconv.Itoa(id))
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w in categoryIds: %s", types.ErrUnknownCategory, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// replaceCategories links a product to exactly categoryIDs, which must
+// exist; see checkCategories. IDs listed twice are linked once.
+func replaceCategories(tx *sql.Tx, productID int, categoryIDs []int) error {
+	if _, err := tx.Exec("DELETE FROM product_categories WHERE productId = ?", productID); err != nil {
+		return err
+	}
+	for _, categoryID := range uniqueInts(categoryIDs) {
+		if _, err := tx.Exec(
+			"INSERT INTO product_categories (productId, categoryId) VALUES (?, ?)",
+			productID, categoryID,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetProductVariants loads the options and variants of a product. Both
+// slices are empty for products sold without variants.
+func (s *Store) GetProductVariants(productID int) ([]*types.ProductOption, []*types.ProductVariant, error) {
+	rows, err := s.db.Query(
+		`SELECT o.id, o.name, v.value
+		   FROM product_options o
+		   JOIN product_option_values v ON v.optionId = o.id
+		  WHERE o.productId = ?
+		  ORDER BY o.position, v.position`,
+		productID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var options []*types.ProductOption
+	for rows.Next() {
+		var id int
+		var name, value string
+		if err := rows.Scan(&id, &name, &value); err != nil {
+			return nil, nil, err
+		}
+		if n := len(options); n == 0 || options[n-1].ID != id {
+			options = append(options, &types.ProductOption{ID: id, Name: name})
+		}
+		last := options[len(options)-1]
+		last.Values = append(last.Values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	variants, err := s.queryVariants("WHERE productId = ?", productID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return options, variants, nil
+}
+
+// GetVariantBySKU returns nil without an error when no variant has the SKU.
+func (s *Store) GetVariantBySKU(sku string) (*types.ProductVariant, error) {
+	variants, err := s.queryVariants("WHERE sku = ?", sku)
+	if err != nil || len(variants) == 0 {
+		return nil, err
+	}
+	return variants[0], nil
+}
+
+// queryVariants selects variants matching where and attaches their option
+// values.
+func (s *Store) queryVariants(where string, args ...any) ([]*types.ProductVariant, error) {
+	rows, err := s.db.Query(
+		`SELECT id, productId, sku, price,
+		        (SELECT p.currency FROM products p WHERE p.id = product_variants.productId),
+		        quantity, createdAt,
+		        quantity - (SELECT COALESCE(SUM(r.quantity), 0) FROM inventory_reservations r
+		                     WHERE r.variantId = product_variants.id AND r.status = 'active')
+		   FROM product_variants `+where+" ORDER BY id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []*types.ProductVariant
+	byID := make(map[int]*types.ProductVariant)
+	for rows.Next() {
+		v := &types.ProductVariant{Options: map[string]string{}}
+		var price sql.NullString
+		var currency string
+		if err := rows.Scan(&v.ID, &v.ProductID, &v.SKU, &price, &currency, &v.Quantity, &v.CreatedAt, &v.Available); err != nil {
+			return nil, err
+		}
+		if price.Valid {
+			p, err := types.ParseMoney(price.String, currency)
+			if err != nil {
+				return nil, err
+			}
+			v.Price = &p
+		}
+		variants = append(variants, v)
+		byID[v.ID] = v
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(variants) == 0 {
+		return variants, nil
+	}
+
+	ids := make([]any, 0, len(variants))
+	for _, v := range variants {
+		ids = append(ids, v.ID)
+	}
+	optRows, err := s.db.Query(
+		`SELECT vov.variantId, o.name, v.value
+		   FROM product_variant_option_values vov
+		   JOIN product_option_values v ON v.id = vov.optionValueId
+		   JOIN product_options o ON o.id = v.optionId
+		  WHERE vov.variantId IN (`+sqlutil.Placeholders(len(ids))+`)`,
+		ids...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer optRows.Close()
+
+	for optRows.Next() {
+		var variantID int
+		var name, value string
+		if err := optRows.Scan(&variantID, &name, &value); err != nil {
+			return nil, err
+		}
+		byID[variantID].Options[name] = value
+	}
+
+	return variants, optRows.Err()
+}
+
+// saveVariants replaces the option definitions of a product and
+// reconciles its variants by SKU: existing SKUs are updated in place so
+// that order lines keep pointing at them, new SKUs are inserted and SKUs
+// missing from variants are removed. Every stock change is recorded in the
+// inventory ledger: new SKUs as restocks at the default location, edits
+// and removals as adjustments. Removed variants lose their stock at every
+// location; an error wrapping types.ErrVariantReserved is returned for
+// variants that pending orders still hold stock of. A product that gains
+// its first variants gives up its own stock, so its quantity stays equal
+// to the sum of the variant stock. The version of the product is left to
+// the caller.
+func saveVariants(tx *sql.Tx, productID int, options []*types.ProductOption, variants []*types.ProductVariant, actorID int) error {
+	var locked int
+	if err := tx.QueryRow("SELECT id FROM products WHERE id = ? FOR UPDATE", productID).Scan(&locked); err != nil {
+		return err
+	}
+
+	// option values cascade to the variant links, which are rebuilt below
+	if _, err := tx.Exec("DELETE FROM product_options WHERE productId = ?", productID); err != nil {
+		return err
+	}
+
+	valueIDs := make(map[string]map[string]int64)
+	for i, option := range options {
+		result, err := tx.Exec("INSERT INTO product_options (productId, name, position) VALUES (?, ?, ?)", productID, option.Name, i)
+		if err != nil {
+			return err
+		}
+		optionID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		option.ID = int(optionID)
+
+		valueIDs[option.Name] = make(map[string]int64)
+		for j, value := range option.Values {
+			result, err := tx.Exec("INSERT INTO product_option_values (optionId, value, position) VALUES (?, ?, ?)", optionID, value, j)
+			if err != nil {
+				return err
+			}
+			if valueIDs[option.Name][value], err = result.LastInsertId(); err != nil {
+				return err
+			}
+		}
+	}
+
+	existing := make(map[string]*types.ProductVariant)
+	rows, err := tx.Query("SELECT id, sku, quantity FROM product_variants WHERE productId = ? FOR UPDATE", productID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		v := new(types.ProductVariant)
+		if err := rows.Scan(&v.ID, &v.SKU, &v.Quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[v.SKU] = v
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(existing) == 0 && len(variants) > 0 {
+		if err := inventory.Clear(tx, productID, nil, types.MovementAdjustment, actorID); err != nil {
+			return err
+		}
+	}
+
+	keep := make(map[int]bool)
+	for _, v := range variants {
+		v.ProductID = productID
+		if old, ok := existing[v.SKU]; ok {
+			v.ID = old.ID
+			if _, err := tx.Exec("UPDATE product_variants SET price = ? WHERE id = ?", v.Price, v.ID); err != nil {
+				return err
+			}
+			if err := inventory.SetQuantity(tx, productID, &v.ID, v.Quantity, types.MovementAdjustment, actorID); err != nil {
+				return err
+			}
+		} else {
+			result, err := tx.Exec(
+				"INSERT INTO product_variants (productId, sku, price, quantity) VALUES (?, ?, ?, 0)",
+				productID, v.SKU, v.Price,
+			)
+			if err != nil {
+				return err
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			v.ID = int(id)
+			if v.Quantity > 0 {
+				if err := inventory.Move(tx, &types.StockMovement{
+					ProductID:  productID,
+					VariantID:  &v.ID,
+					LocationID: types.DefaultLocationID,
+					Delta:      v.Quantity,
+					Reason:     types.MovementRestock,
+					ActorID:    inventory.Actor(actorID),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		keep[v.ID] = true
+
+		for name, value := range v.Options {
+			if _, err := tx.Exec(
+				"INSERT INTO product_variant_option_values (variantId, optionValueId) VALUES (?, ?)",
+				v.ID, valueIDs[name][value],
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, old := range existing {
+		if keep[old.ID] {
+			continue
+		}
+		if err := inventory.CheckUnreserved(tx, old.ID); err != nil {
+			return err
+		}
+		if err := inventory.Clear(tx, productID, &old.ID, types.MovementAdjustment, actorID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM product_variants WHERE id = ?", old.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const productImageColumns = "id, productId, storageKey, contentType, size, position, createdAt"
+
+// ListProductImages returns the images of a product in display order. The
+// URL fields are left empty; they depend on the BlobStore serving the files.
+func (s *Store) ListProductImages(productID int) ([]*types.ProductImage, error) {
+	return s.queryImages("WHERE productId = ? ORDER BY position, id", productID)
+}
+
+// ListAllProductImages returns every product image, for maintenance tasks
+// such as regenerating resized variants.
+func (s *Store) ListAllProductImages() ([]*types.ProductImage, error) {
+	return s.queryImages("ORDER BY id")
+}
+
+// ListCoverImages returns the first image of each given product, keyed by
+// product ID. Products without images are absent from the map.
+func (s *Store) ListCoverImages(productIDs []int) (map[int]*types.ProductImage, error) {
+	covers := make(map[int]*types.ProductImage)
+	if len(productIDs) == 0 {
+		return covers, nil
+	}
+
+	args := make([]any, 0, len(productIDs))
+	for _, id := range productIDs {
+		args = append(args, id)
+	}
+	images, err := s.queryImages(
+		`WHERE productId IN (`+sqlutil.Placeholders(len(args))+`)
+		 AND NOT EXISTS (
+			SELECT 1 FROM product_images p
+			 WHERE p.productId = product_images.productId
+			   AND (p.position < product_images.position
+			    OR (p.position = product_images.position AND p.id < product_images.id)))`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, img := range images {
+		covers[img.ProductID] = img
+	}
+	return covers, nil
+}
+
+// queryImages selects images with the given clause and attaches the blob
+// keys of their resized variants.
+func (s *Store) queryImages(clause string, args ...any) ([]*types.ProductImage, error) {
+	rows, err := s.db.Query("SELECT "+productImageColumns+" FROM product_images "+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []*types.ProductImage
+	byID := make(map[int]*types.ProductImage)
+	for rows.Next() {
+		img := new(types.ProductImage)
+		if err := rows.Scan(&img.ID, &img.ProductID, &img.Key, &img.ContentType, &img.Size, &img.Position, &img.CreatedAt); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+		byID[img.ID] = img
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return images, nil
+	}
+
+	ids := make([]any, 0, len(images))
+	for _, img := range images {
+		ids = append(ids, img.ID)
+	}
+	sizeRows, err := s.db.Query(
+		"SELECT imageId, size, storageKey FROM product_image_sizes WHERE imageId IN ("+sqlutil.Placeholders(len(ids))+")",
+		ids...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer sizeRows.Close()
+
+	for sizeRows.Next() {
+		var imageID int
+		var size, key string
+		if err := sizeRows.Scan(&imageID, &size, &key); err != nil {
+			return nil, err
+		}
+		img := byID[imageID]
+		if img.SizeKeys == nil {
+			img.SizeKeys = make(map[string]string)
+		}
+		img.SizeKeys[size] = key
+	}
+
+	return images, sizeRows.Err()
+}
+
+// AddProductImage appends an image after the product's existing images and
+// sets its ID and Position.
+func (s *Store) AddProductImage(img *types.ProductImage) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// lock the product row so concurrent uploads get distinct positions
+	var locked int
+	if err := tx.QueryRow("SELECT id FROM products WHERE id = ? FOR UPDATE", img.ProductID).Scan(&locked); err != nil {
+		return err
+	}
+	if err := tx.QueryRow(
+		"SELECT COALESCE(MAX(position) + 1, 0) FROM product_images WHERE productId = ?",
+		img.ProductID,
+	).Scan(&img.Position); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO product_images (productId, storageKey, contentType, size, position) VALUES (?, ?, ?, ?, ?)",
+		img.ProductID, img.Key, img.ContentType, img.Size, img.Position,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	img.ID = int(id)
+
+	return tx.Commit()
+}
+
+// DeleteProductImage removes an image row and returns it, including the
+// keys of its resized variants, so the caller can delete the stored files.
+// It returns nil when the image does not belong to the product.
+func (s *Store) DeleteProductImage(productID, imageID int) (*types.ProductImage, error) {
+	images, err := s.queryImages("WHERE id = ? AND productId = ?", imageID, productID)
+	if err != nil || len(images) == 0 {
+		return nil, err
+	}
+	img := images[0]
+
+	if _, err := s.db.Exec("DELETE FROM product_images WHERE id = ?", imageID); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// ReorderProductImages assigns positions following the order of imageIDs.
+// The caller is expected to pass every image of the product.
+func (s *Store) ReorderProductImages(productID int, imageIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for position, id := range imageIDs {
+		if _, err := tx.Exec(
+			"UPDATE product_images SET position = ? WHERE id = ? AND productId = ?",
+			position, id, productID,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetProductCoverImage stores the URL of the product's first image in the
+// products.image column; an empty url clears it.
+func (s *Store) SetProductCoverImage(productID int, url string) error {
+	var image sql.NullString
+	if url != "" {
+		image = sql.NullString{String: url, Valid: true}
+	}
+	_, err := s.db.Exec("UPDATE products SET image = ? WHERE id = ?", image, productID)
+	return err
+}
+
+// SetProductImageSizes replaces the recorded resized variants of an image.
+func (s *Store) SetProductImageSizes(imageID int, sizes []*types.ProductImageSize) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM product_image_sizes WHERE imageId = ?", imageID); err != nil {
+		return err
+	}
+	for _, size := range sizes {
+		if _, err := tx.Exec(
+			"INSERT INTO product_image_sizes (imageId, size, storageKey, width, height) VALUES (?, ?, ?, ?, ?)",
+			imageID, size.Size, size.Key, size.Width, size.Height,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetProductBySKU returns the product with the SKU, or nil if none has it.
+// Soft deleted products are included because they keep their SKU.
+func (s *Store) GetProductBySKU(sku string) (*types.Product, error) {
+	product, err := scanProduct(s.db.QueryRow("SELECT "+productColumns+" FROM products WHERE sku = ?", sku))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return product, nil
+}
+
+// UpsertProductsBySKU inserts every product whose SKU is new and updates
+// the one holding the SKU otherwise, all in a single transaction. Each
+// product is written under its own savepoint, so a failing product is
+// reported in its result and does not affect the others. When dryRun is
+// set the transaction is rolled back after every product has been tried,
+// so the results show what would happen without writing anything.
+//
+// The returned results are in the order of products. The error is only
+// non-nil when the transaction itself failed.
+func (s *Store) UpsertProductsBySKU(products []*types.Product, dryRun bool, actorID int) ([]*types.ImportRowResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]*types.ImportRowResult, 0, len(products))
+	for _, p := range products {
+		if _, err := tx.Exec("SAVEPOINT import_row"); err != nil {
+			return nil, err
+		}
+
+		result := &types.ImportRowResult{SKU: *p.SKU}
+		status, err := upsertProduct(tx, p, actorID)
+		if err != nil {
+			if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT import_row"); rbErr != nil {
+				return nil, rbErr
+			}
+			result.Status = types.ImportFailed
+			result.Errors = []string{err.Error()}
+		} else {
+			result.Status = status
+			// IDs of rows inserted in a dry run are rolled back with it
+			if !dryRun || status == types.ImportUpdated {
+				result.ProductID = p.ID
+			}
+		}
+		results = append(results, result)
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	return results, tx.Commit()
+}
+
+// upsertProduct writes one product for UpsertProductsBySKU and reports
+// whether it was created or updated. Stock of new products is recorded as
+// a restock and changes to existing ones as adjustments. The price of a
+// product without a Currency is taken in the stored currency, failing
+// when it has more decimals than that currency allows.
+func upsertProduct(tx *sql.Tx, p *types.Product, actorID int) (string, error) {
+	if err := checkCategories(tx, p.CategoryIDs); err != nil {
+		return "", err
+	}
+
+	var quantity int
+	var currency string
+	var deletedAt sql.NullString
+	var hasVariants bool
+	err := tx.QueryRow(
+		`SELECT id, quantity, currency, deletedAt,
+		        EXISTS (SELECT 1 FROM product_variants v WHERE v.productId = products.id)
+		   FROM products WHERE sku = ? FOR UPDATE`,
+		*p.SKU,
+	).Scan(&p.ID, &quantity, &currency, &deletedAt, &hasVariants)
+
+	status := types.ImportUpdated
+	switch {
+	case err == sql.ErrNoRows:
+		result, err := tx.Exec(
+			"INSERT INTO products (sku, name, description, price, currency, quantity) VALUES (?, ?, ?, ?, ?, 0)",
+			*p.SKU, p.Name, p.Description, p.Price, p.Price.Currency,
+		)
+		if err != nil {
+			return "", err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return "", err
+		}
+		p.ID = int(id)
+		status = types.ImportCreated
+		if p.Quantity > 0 {
+			if err := inventory.Move(tx, &types.StockMovement{
+				ProductID:  p.ID,
+				LocationID: types.DefaultLocationID,
+				Delta:      p.Quantity,
+				Reason:     types.MovementRestock,
+				ActorID:    inventory.Actor(actorID),
+			}); err != nil {
+				return "", err
+			}
+		}
+	case err != nil:
+		return "", err
+	case deletedAt.Valid:
+		return "", fmt.Errorf("product %d with this sku is deleted; restore it first", p.ID)
+	case hasVariants && p.Quantity != quantity:
+		return "", fmt.Errorf("quantity of product %d is derived from its variants and cannot be imported", p.ID)
+	default:
+		// rows without a currency keep the stored one, their price read
+		// in DefaultCurrency is taken as an amount of it
+		if p.Currency != "" {
+			currency = p.Currency
+		}
+		price, err := p.Price.In(currency)
+		if err != nil {
+			return "", err
+		}
+		if _, err := tx.Exec(
+			"UPDATE products SET name = ?, description = ?, price = ?, currency = ?, version = version + 1 WHERE id = ?",
+			p.Name, p.Description, price, currency, p.ID,
+		); err != nil {
+			return "", err
+		}
+		if !hasVariants {
+			if err := inventory.SetQuantity(tx, p.ID, nil, p.Quantity, types.MovementAdjustment, actorID); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// categories are only replaced when the row names some
+	if len(p.CategoryIDs) > 0 {
+		if err := replaceCategories(tx, p.ID, p.CategoryIDs); err != nil {
+			return "", err
+		}
+	}
+
+	return status, nil
+}
+
+// uniqueInts returns ids without duplicates, in first-seen order.
+func uniqueInts(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package product
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// buildVariants converts the option and variant payloads into store types
+// and checks the rules that struct tags cannot express: option names and
+// values are unique, every variant picks exactly one declared value per
+// option, no two variants share a combination and SKUs are unique.
+func buildVariants(optionPayloads []types.ProductOptionPayload, variantPayloads []types.ProductVariantPayload) ([]*types.ProductOption, []*types.ProductVariant, error) {
+	if len(variantPayloads) > 0 && len(optionPayloads) == 0 {
+		return nil, nil, fmt.Errorf("variants require at least one option")
+	}
+	if len(optionPayloads) > 0 && len(variantPayloads) == 0 {
+		return nil, nil, fmt.Errorf("options require at least one variant")
+	}
+
+	allowed := make(map[string]map[string]bool, len(optionPayloads))
+	options := make([]*types.ProductOption, 0, len(optionPayloads))
+	for _, op := range optionPayloads {
+		if allowed[op.Name] != nil {
+			return nil, nil, fmt.Errorf("duplicate option %q", op.Name)
+		}
+		allowed[op.Name] = make(map[string]bool, len(op.Values))
+		for _, value := range op.Values {
+			if allowed[op.Name][value] {
+				return nil, nil, fmt.Errorf("duplicate value %q for option %q", value, op.Name)
+			}
+			allowed[op.Name][value] = true
+		}
+		options = append(options, &types.ProductOption{Name: op.Name, Values: op.Values})
+	}
+
+	skus := make(map[string]bool, len(variantPayloads))
+	combinations := make(map[string]string, len(variantPayloads))
+	variants := make([]*types.ProductVariant, 0, len(variantPayloads))
+	for _, vp := range variantPayloads {
+		if skus[vp.SKU] {
+			return nil, nil, fmt.Errorf("duplicate sku %q", vp.SKU)
+		}
+		skus[vp.SKU] = true
+
+		if len(vp.Options) != len(allowed) {
+			return nil, nil, fmt.Errorf("variant %s must set a value for every option", vp.SKU)
+		}
+		for name, value := range vp.Options {
+			values, ok := allowed[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("variant %s uses unknown option %q", vp.SKU, name)
+			}
+			if !values[value] {
+				return nil, nil, fmt.Errorf("variant %s uses unknown value %q for option %q", vp.SKU, value, name)
+			}
+		}
+
+		key := combinationKey(vp.Options)
+		if other, ok := combinations[key]; ok {
+			return nil, nil, fmt.Errorf("variants %s and %s have the same options", other, vp.SKU)
+		}
+		combinations[key] = vp.SKU
+
+		variants = append(variants, &types.ProductVariant{
+			SKU:      vp.SKU,
+			Price:    vp.Price,
+			Quantity: vp.Quantity,
+			Options:  vp.Options,
+		})
+	}
+
+	return options, variants, nil
+}
+
+// combinationKey returns a canonical string for a set of option values.
+func combinationKey(options map[string]string) string {
+	pairs := make([]string, 0, len(options))
+	for name, value := range options {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+// totalStock sums the stock of all variants.
+func totalStock(variants []*types.ProductVariant) int {
+	total := 0
+	for _, v := range variants {
+		total += v.Quantity
+	}
+	return total
+}
@@ -0,0 +1,53 @@
+package product
+
+import (
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestBuildVariants(t *testing.T) {
+	options := []types.ProductOptionPayload{
+		{Name: "size", Values: []string{"S", "M"}},
+		{Name: "color", Values: []string{"red"}},
+	}
+
+	t.Run("accepts a valid set of variants", func(t *testing.T) {
+		variants := []types.ProductVariantPayload{
+			{SKU: "TS-S-RED", Quantity: 2, Options: map[string]string{"size": "S", "color": "red"}},
+			{SKU: "TS-M-RED", Quantity: 3, Options: map[string]string{"size": "M", "color": "red"}},
+		}
+
+		_, got, err := buildVariants(options, variants)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if totalStock(got) != 5 {
+			t.Errorf("expected total stock 5, got %d", totalStock(got))
+		}
+	})
+
+	cases := map[string][]types.ProductVariantPayload{
+		"duplicate sku": {
+			{SKU: "A", Options: map[string]string{"size": "S", "color": "red"}},
+			{SKU: "A", Options: map[string]string{"size": "M", "color": "red"}},
+		},
+		"missing option": {
+			{SKU: "A", Options: map[string]string{"size": "S"}},
+		},
+		"unknown value": {
+			{SKU: "A", Options: map[string]string{"size": "XL", "color": "red"}},
+		},
+		"duplicate combination": {
+			{SKU: "A", Options: map[string]string{"size": "S", "color": "red"}},
+			{SKU: "B", Options: map[string]string{"size": "S", "color": "red"}},
+		},
+	}
+	for name, variants := range cases {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if _, _, err := buildVariants(options, variants); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package returns handles return requests (RMAs) and refunds: customers
+// ask to send lines of their orders back, support approves them, restocks
+// what comes back and gives the money back onto gift cards and through
+// the payment layer. Refunds are tracked per order line and never exceed
+// what was paid for the line.
+package returns
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// returnable lists the statuses of the orders whose items can be returned.
+var returnable = []string{types.OrderStatusPaid, types.OrderStatusShipped, types.OrderStatusDelivered, types.OrderStatusCompleted}
+
+// checkReturnItems checks a return request against its order: the order
+// was paid, every line belongs to it, is listed once and is returned at
+// most in the quantity ordered. Quantities in earlier returns are checked
+// by the store. It returns the HTTP status to report alongside the error.
+func checkReturnItems(order *types.Order, items []types.ReturnItemPayload) (int, error) {
+	if !slices.Contains(returnable, order.Status) {
+		return http.StatusConflict, fmt.Errorf("items of %s orders cannot be returned", order.Status)
+	}
+
+	seen := make(map[int]bool)
+	for _, item := range items {
+		if seen[item.OrderItemID] {
+			return http.StatusBadRequest, fmt.Errorf("order item %d is listed twice", item.OrderItemID)
+		}
+		seen[item.OrderItemID] = true
+
+		line := orderItem(order, item.OrderItemID)
+		if line == nil {
+			return http.StatusBadRequest, fmt.Errorf("order item %d is not part of the order", item.OrderItemID)
+		}
+		if item.Quantity > line.Quantity {
+			return http.StatusUnprocessableEntity, fmt.Errorf("only %d of order item %d were ordered", line.Quantity, item.OrderItemID)
+		}
+	}
+	return 0, nil
+}
+
+// returnRefund returns what the lines of ret get back by default: the
+// part of the line total paid for the units returned, rounded half to
+// even, and never more than is left to refund on the line. Lines with
+// nothing left are skipped.
+func returnRefund(order *types.Order, ret *types.Return) []*types.RefundItem {
+	var items []*types.RefundItem
+	for _, item := range ret.Items {
+		line := orderItem(order, item.OrderItemID)
+		if line == nil || line.Quantity == 0 {
+			continue
+		}
+		amount := line.Total.MulRatio(int64(item.Quantity), int64(line.Quantity))
+		if left := line.Total.Sub(line.Refunded); amount.Cmp(left) > 0 {
+			amount = left
+		}
+		if amount.Amount > 0 {
+			items = append(items, &types.RefundItem{OrderItemID: line.ID, Amount: amount})
+		}
+	}
+	return items
+}
+
+// refundItems converts the lines of a refund request into the order
+// currency and checks each belongs to order, is listed once and gets back
+// no more than is left to refund on it. lines, when not nil, restricts the
+// order items that can be refunded, such as to the lines of a return. It
+// returns the HTTP status to report alongside the error.
+func refundItems(order *types.Order, payload []types.RefundItemPayload, lines []int) ([]*types.RefundItem, int, error) {
+	var items []*types.RefundItem
+	seen := make(map[int]bool)
+	for _, p := range payload {
+		if seen[p.OrderItemID] {
+			return nil, http.StatusBadRequest, fmt.Errorf("order item %d is listed twice", p.OrderItemID)
+		}
+		seen[p.OrderItemID] = true
+
+		line := orderItem(order, p.OrderItemID)
+		if line == nil || (lines != nil && !slices.Contains(lines, p.OrderItemID)) {
+			return nil, http.StatusBadRequest, fmt.Errorf("order item %d cannot be refunded here", p.OrderItemID)
+		}
+		// amounts are decoded in DefaultCurrency
+		amount, err := p.Amount.In(order.Currency)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		if left := line.Total.Sub(line.Refunded); amount.Cmp(left) > 0 {
+			return nil, http.StatusUnprocessableEntity, fmt.Errorf("only %s %s is left to refund on order item %d", left, order.Currency, line.ID)
+		}
+		items = append(items, &types.RefundItem{OrderItemID: line.ID, Amount: amount})
+	}
+	return items, 0, nil
+}
+
+// sumRefund returns the total of the refund items, in currency.
+func sumRefund(items []*types.RefundItem, currency string) types.Money {
+	total := types.NewMoney(0, currency)
+	for _, item := range items {
+		total = total.Add(item.Amount)
+	}
+	return total
+}
+
+// giftCardRefund returns the part of a refund of total that goes back
+// onto the gift card order was paid with: as much as the card paid and
+// earlier refunds did not credit back yet. The rest goes back on the
+// payment.
+func giftCardRefund(order *types.Order, refunds []*types.Refund, total types.Money) types.Money {
+	left := order.GiftCard
+	for _, r := range refunds {
+		left = left.Sub(r.GiftCard)
+	}
+	if order.GiftCardID == nil || left.Amount <= 0 {
+		return types.NewMoney(0, total.Currency)
+	}
+	if total.Cmp(left) < 0 {
+		return total
+	}
+	return left
+}
+
+// capturedPayment returns the payment that paid an order, or nil when the
+// order was not paid through the payment layer or was refunded in full.
+func capturedPayment(payments []*types.Payment) *types.Payment {
+	for _, p := range payments {
+		if p.Status == types.PaymentCaptured {
+			return p
+		}
+	}
+	return nil
+}
+
+// orderItem returns the item of order with the given ID, or nil.
+func orderItem(order *types.Order, id int) *types.OrderItem {
+	for _, item := range order.Items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package returns
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/testutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// paidOrder has two lines: 3 units paid 30.00 in all, of which 10.00 was
+// refunded already, and 1 unit paid 7.99.
+func paidOrder() *types.Order {
+	return &types.Order{
+		ID:       1,
+		Status:   types.OrderStatusPaid,
+		Currency: types.DefaultCurrency,
+		Items: []*types.OrderItem{
+			{ID: 10, Quantity: 3, Price: testutil.USD(1100), Total: testutil.USD(3000), Refunded: testutil.USD(1000)},
+			{ID: 11, Quantity: 1, Price: testutil.USD(799), Total: testutil.USD(799), Refunded: testutil.USD(0)},
+		},
+	}
+}
+
+func TestCheckReturnItems(t *testing.T) {
+	item := func(id, quantity int) types.ReturnItemPayload {
+		return types.ReturnItemPayload{OrderItemID: id, Quantity: quantity, Reason: types.ReturnReasonDamaged}
+	}
+
+	tests := []struct {
+		name   string
+		status string
+		items  []types.ReturnItemPayload
+		want   int
+	}{
+		{"valid", types.OrderStatusPaid, []types.ReturnItemPayload{item(10, 2), item(11, 1)}, 0},
+		{"completed order", types.OrderStatusCompleted, []types.ReturnItemPayload{item(10, 3)}, 0},
+		{"delivered order", types.OrderStatusDelivered, []types.ReturnItemPayload{item(10, 3)}, 0},
+		{"pending order", types.OrderStatusPending, []types.ReturnItemPayload{item(10, 1)}, http.StatusConflict},
+		{"line listed twice", types.OrderStatusPaid, []types.ReturnItemPayload{item(10, 1), item(10, 1)}, http.StatusBadRequest},
+		{"line of another order", types.OrderStatusPaid, []types.ReturnItemPayload{item(12, 1)}, http.StatusBadRequest},
+		{"more than ordered", types.OrderStatusPaid, []types.ReturnItemPayload{item(11, 2)}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := paidOrder()
+			order.Status = tt.status
+			if got, _ := checkReturnItems(order, tt.items); got != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReturnRefund(t *testing.T) {
+	order := paidOrder()
+	ret := &types.Return{Items: []*types.ReturnItem{
+		// a third of 30.00 is 10.00, within the 20.00 left
+		{OrderItemID: 10, Quantity: 1},
+		{OrderItemID: 11, Quantity: 1},
+	}}
+
+	items := returnRefund(order, ret)
+	if len(items) != 2 || items[0].Amount != testutil.USD(1000) || items[1].Amount != testutil.USD(799) {
+		t.Fatalf("expected 10.00 and 7.99, got %v", items)
+	}
+
+	// all 3 units are worth 30.00 but only 20.00 is left
+	ret.Items = []*types.ReturnItem{{OrderItemID: 10, Quantity: 3}}
+	items = returnRefund(order, ret)
+	if len(items) != 1 || items[0].Amount != testutil.USD(2000) {
+		t.Fatalf("expected 20.00, got %v", items)
+	}
+
+	order.Items[0].Refunded = order.Items[0].Total
+	if items := returnRefund(order, ret); len(items) != 0 {
+		t.Errorf("expected nothing left to refund, got %v", items)
+	}
+}
+
+func TestRefundItems(t *testing.T) {
+	line := func(id int, cents int64) types.RefundItemPayload {
+		return types.RefundItemPayload{OrderItemID: id, Amount: testutil.USD(cents)}
+	}
+
+	tests := []struct {
+		name    string
+		payload []types.RefundItemPayload
+		lines   []int
+		want    int
+		total   types.Money
+	}{
+		{"partial", []types.RefundItemPayload{line(10, 500), line(11, 799)}, nil, 0, testutil.USD(1299)},
+		{"all that is left", []types.RefundItemPayload{line(10, 2000)}, nil, 0, testutil.USD(2000)},
+		{"more than is left", []types.RefundItemPayload{line(10, 2001)}, nil, http.StatusUnprocessableEntity, testutil.USD(0)},
+		{"line listed twice", []types.RefundItemPayload{line(11, 100), line(11, 100)}, nil, http.StatusBadRequest, testutil.USD(0)},
+		{"line of another order", []types.RefundItemPayload{line(12, 100)}, nil, http.StatusBadRequest, testutil.USD(0)},
+		{"line outside the return", []types.RefundItemPayload{line(11, 100)}, []int{10}, http.StatusBadRequest, testutil.USD(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, got, _ := refundItems(paidOrder(), tt.payload, tt.lines)
+			if got != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, got)
+			}
+			if total := sumRefund(items, types.DefaultCurrency); total != tt.total {
+				t.Errorf("expected %s refunded, got %s", tt.total, total)
+			}
+		})
+	}
+}
+
+func TestGiftCardRefund(t *testing.T) {
+	cardID := 7
+	order := paidOrder()
+	order.GiftCard, order.GiftCardID = testutil.USD(1500), &cardID
+	earlier := []*types.Refund{{GiftCard: testutil.USD(1000)}, {GiftCard: testutil.USD(0)}}
+
+	tests := []struct {
+		name    string
+		order   *types.Order
+		refunds []*types.Refund
+		total   types.Money
+		want    types.Money
+	}{
+		{"no gift card", paidOrder(), nil, testutil.USD(1000), testutil.USD(0)},
+		{"within what the card paid", order, nil, testutil.USD(1000), testutil.USD(1000)},
+		{"more than the card paid", order, nil, testutil.USD(2500), testutil.USD(1500)},
+		{"after earlier refunds", order, earlier, testutil.USD(2500), testutil.USD(500)},
+		{"card credited back in full", order, append(earlier, &types.Refund{GiftCard: testutil.USD(500)}), testutil.USD(2500), testutil.USD(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := giftCardRefund(tt.order, tt.refunds, tt.total); got.Cmp(tt.want) != 0 {
+				t.Errorf("expected %s onto the gift card, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,456 @@
+package returns
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/service/payment"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// returnStatuses lists the statuses returns can be filtered by.
+var returnStatuses = []string{types.ReturnRequested, types.ReturnApproved, types.ReturnRejected, types.ReturnReceived, types.ReturnRefunded}
+
+// Handler serves return requests to customers and the return and refund
+// workflow to support.
+type Handler struct {
+	store         types.ReturnStore
+	refundStore   types.RefundStore
+	orderStore    types.OrderStore
+	paymentStore  types.PaymentStore
+	locationStore types.LocationStore
+	processor     *payment.Processor
+	userStore     types.UserStore
+}
+
+// NewHandler creates a new Handler giving money back through processor.
+// The user store authorizes admin-only operations.
+func NewHandler(store types.ReturnStore, refundStore types.RefundStore, orderStore types.OrderStore, paymentStore types.PaymentStore, locationStore types.LocationStore, processor *payment.Processor, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:         store,
+		refundStore:   refundStore,
+		orderStore:    orderStore,
+		paymentStore:  paymentStore,
+		locationStore: locationStore,
+		processor:     processor,
+		userStore:     userStore,
+	}
+}
+
+// RegisterRoutes attaches return and refund routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/orders/{id:[0-9]+}/returns", auth.RequireToken(h.handleListOrderReturns)).Methods("GET")
+	router.HandleFunc("/orders/{id:[0-9]+}/returns", auth.RequireToken(h.handleCreateReturn)).Methods("POST")
+	router.HandleFunc("/orders/{id:[0-9]+}/refunds", auth.RequireToken(h.handleListRefunds)).Methods("GET")
+	router.HandleFunc("/orders/{id:[0-9]+}/refunds", auth.RequireAdmin(h.handleCreateRefund, h.userStore)).Methods("POST")
+
+	router.HandleFunc("/returns", auth.RequireAdmin(h.handleListReturns, h.userStore)).Methods("GET")
+	router.HandleFunc("/returns/{id:[0-9]+}", auth.RequireAdmin(h.handleGetReturn, h.userStore)).Methods("GET")
+	router.HandleFunc("/returns/{id:[0-9]+}/approve", auth.RequireAdmin(h.handleResolveReturn(types.ReturnApproved), h.userStore)).Methods("POST")
+	router.HandleFunc("/returns/{id:[0-9]+}/reject", auth.RequireAdmin(h.handleResolveReturn(types.ReturnRejected), h.userStore)).Methods("POST")
+	router.HandleFunc("/returns/{id:[0-9]+}/receive", auth.RequireAdmin(h.handleReceiveReturn, h.userStore)).Methods("POST")
+	router.HandleFunc("/returns/{id:[0-9]+}/refund", auth.RequireAdmin(h.handleRefundReturn, h.userStore)).Methods("POST")
+}
+
+// orderFromPath returns the order in the path, writing the error response
+// when there is none.
+func (h *Handler) orderFromPath(w http.ResponseWriter, r *http.Request) (*types.Order, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	order, err := h.orderStore.GetOrderByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if order == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order not found"))
+		return nil, false
+	}
+	return order, true
+}
+
+// returnFromPath returns the return in the path, writing the error
+// response when there is none.
+func (h *Handler) returnFromPath(w http.ResponseWriter, r *http.Request) (*types.Return, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	ret, err := h.store.GetReturnByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if ret == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("return not found"))
+		return nil, false
+	}
+	return ret, true
+}
+
+func (h *Handler) handleListOrderReturns(w http.ResponseWriter, r *http.Request) {
+	order, ok := auth.OwnOrder(w, r, h.orderStore)
+	if !ok {
+		return
+	}
+
+	returns, err := h.store.ListReturnsByOrder(order.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list returns: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListReturnsResponse{
+		Message: "success",
+		Data:    returns,
+	})
+}
+
+// handleCreateReturn asks to send lines of one of the caller's paid orders
+// back. The request waits for support to approve it.
+func (h *Handler) handleCreateReturn(w http.ResponseWriter, r *http.Request) {
+	order, ok := auth.OwnOrder(w, r, h.orderStore)
+	if !ok {
+		return
+	}
+
+	var payload types.CreateReturnPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if status, err := checkReturnItems(order, payload.Items); err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	ret := &types.Return{OrderID: order.ID, UserID: order.UserID, Note: payload.Note}
+	for _, item := range payload.Items {
+		ret.Items = append(ret.Items, &types.ReturnItem{OrderItemID: item.OrderItemID, Quantity: item.Quantity, Reason: item.Reason})
+	}
+	if err := h.store.CreateReturn(ret); err != nil {
+		if errors.Is(err, types.ErrReturnQuantity) {
+			utils.WriteError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to create return: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.ReturnResponse{
+		Message: "return requested",
+		Data:    ret,
+	})
+}
+
+func (h *Handler) handleListRefunds(w http.ResponseWriter, r *http.Request) {
+	order, ok := auth.OwnOrder(w, r, h.orderStore)
+	if !ok {
+		return
+	}
+
+	refunds, err := h.refundStore.ListRefundsByOrder(order.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list refunds: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListRefundsResponse{
+		Message: "success",
+		Data:    refunds,
+	})
+}
+
+// handleListReturns lists returns for support, optionally only the ones
+// in ?status=.
+func (h *Handler) handleListReturns(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(returnStatuses, status) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid status %q", status))
+		return
+	}
+
+	returns, err := h.store.ListReturns(status)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list returns: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListReturnsResponse{
+		Message: "success",
+		Data:    returns,
+	})
+}
+
+func (h *Handler) handleGetReturn(w http.ResponseWriter, r *http.Request) {
+	ret, ok := h.returnFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ReturnResponse{
+		Message: "success",
+		Data:    ret,
+	})
+}
+
+// handleResolveReturn returns the handler approving or rejecting a
+// requested return, as status says.
+func (h *Handler) handleResolveReturn(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ret, ok := h.returnFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var payload types.ResolveReturnPayload
+		if err := utils.ParseJson(r, &payload); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := utils.Validate.Struct(payload); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := h.store.UpdateReturnStatus(ret.ID, []string{types.ReturnRequested}, status, payload.Resolution); err != nil {
+			if errors.Is(err, types.ErrReturnStatus) {
+				utils.WriteError(w, http.StatusConflict, fmt.Errorf("return is %s, not requested", ret.Status))
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		h.writeReturn(w, ret.ID, "return "+status)
+	}
+}
+
+// handleReceiveReturn records that the items of an approved return came
+// back and restocks the ones that can be sold again.
+func (h *Handler) handleReceiveReturn(w http.ResponseWriter, r *http.Request) {
+	ret, ok := h.returnFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.ReceiveReturnPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	for _, id := range payload.Damaged {
+		if !slices.ContainsFunc(ret.Items, func(item *types.ReturnItem) bool { return item.ID == id }) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("return item %d is not part of the return", id))
+			return
+		}
+	}
+
+	locationID := types.DefaultLocationID
+	if payload.LocationID != nil {
+		location, err := h.locationStore.GetLocationByID(*payload.LocationID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if location == nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("location %d not found", *payload.LocationID))
+			return
+		}
+		locationID = location.ID
+	}
+
+	actorID, _ := auth.UserIDFromContext(r.Context())
+	if err := h.store.ReceiveReturn(ret.ID, locationID, payload.Damaged, actorID); err != nil {
+		if errors.Is(err, types.ErrReturnStatus) {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("return is %s and cannot be received", ret.Status))
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to receive return: %v", err))
+		return
+	}
+
+	h.writeReturn(w, ret.ID, "return received")
+}
+
+// handleRefundReturn refunds an approved or received return, by default
+// what was paid for the units returned.
+func (h *Handler) handleRefundReturn(w http.ResponseWriter, r *http.Request) {
+	ret, ok := h.returnFromPath(w, r)
+	if !ok {
+		return
+	}
+	if ret.Status != types.ReturnApproved && ret.Status != types.ReturnReceived {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("return is %s and cannot be refunded", ret.Status))
+		return
+	}
+
+	var payload types.RefundReturnPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	order, err := h.orderStore.GetOrderByID(ret.OrderID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	items := returnRefund(order, ret)
+	if len(payload.Items) > 0 {
+		lines := make([]int, 0, len(ret.Items))
+		for _, item := range ret.Items {
+			lines = append(lines, item.OrderItemID)
+		}
+		var status int
+		if items, status, err = refundItems(order, payload.Items, lines); err != nil {
+			utils.WriteError(w, status, err)
+			return
+		}
+	}
+
+	reason := fmt.Sprintf("return %d", ret.ID)
+	h.refund(w, r, order, &ret.ID, reason, items)
+}
+
+// handleCreateRefund refunds lines of an order without a return.
+func (h *Handler) handleCreateRefund(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.orderFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.CreateRefundPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	items, status, err := refundItems(order, payload.Items, nil)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	h.refund(w, r, order, nil, payload.Reason, items)
+}
+
+// refund gives items back onto the gift card order was paid with, as far
+// as it goes, and the rest on its captured payment, and records the
+// refund, for the return returnID when it is set.
+func (h *Handler) refund(w http.ResponseWriter, r *http.Request, order *types.Order, returnID *int, reason string, items []*types.RefundItem) {
+	total := sumRefund(items, order.Currency)
+	if total.IsZero() {
+		utils.WriteError(w, http.StatusUnprocessableEntity, fmt.Errorf("nothing is left to refund"))
+		return
+	}
+
+	// the gift card the order was paid with is credited first
+	refunds, err := h.refundStore.ListRefundsByOrder(order.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	toCard := giftCardRefund(order, refunds, total)
+	toPayment := total.Sub(toCard)
+
+	var paid *types.Payment
+	if !toPayment.IsZero() {
+		payments, err := h.paymentStore.ListPaymentsByOrder(order.ID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if paid = capturedPayment(payments); paid == nil {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("order has no captured payment to refund"))
+			return
+		}
+	}
+
+	actorID, _ := auth.UserIDFromContext(r.Context())
+	refund := &types.Refund{
+		OrderID:  order.ID,
+		ReturnID: returnID,
+		Amount:   total,
+		GiftCard: toCard,
+		Currency: order.Currency,
+		Reason:   reason,
+		ActorID:  &actorID,
+		Items:    items,
+	}
+	if paid != nil {
+		refund.PaymentID = &paid.ID
+	}
+	err = h.refundStore.CreateRefund(refund, func() error {
+		if paid == nil {
+			return nil
+		}
+		_, err := h.processor.Refund(r.Context(), paid, toPayment)
+		return err
+	})
+	switch {
+	case errors.Is(err, types.ErrRefundTooLarge):
+		utils.WriteError(w, http.StatusUnprocessableEntity, err)
+		return
+	case errors.Is(err, types.ErrReturnStatus):
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("return can no longer be refunded"))
+		return
+	case errors.Is(err, payment.ErrNotCaptured):
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	case errors.Is(err, payment.ErrProvider):
+		utils.WriteError(w, http.StatusBadGateway, fmt.Errorf("failed to refund: %v", err))
+		return
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to record refund: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.RefundResponse{
+		Message: "refund issued",
+		Data:    refund,
+	})
+}
+
+// writeReturn responds with the current state of a return.
+func (h *Handler) writeReturn(w http.ResponseWriter, id int, message string) {
+	ret, err := h.store.GetReturnByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ReturnResponse{
+		Message: message,
+		Data:    ret,
+	})
+}
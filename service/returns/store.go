@@ -0,0 +1,440 @@
+package returns
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/service/giftcard"
+	"github.com/nandaiqbalh/go-backend-ecom/service/inventory"
+	"github.com/nandaiqbalh/go-backend-ecom/service/invoice"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// returnColumns lists the columns read by scanReturn, in scan order.
+const returnColumns = "id, orderId, userId, status, note, resolution, receivedAt, createdAt, updatedAt"
+
+// refundColumns lists the columns read by scanRefund, in scan order.
+const refundColumns = "id, orderId, returnId, paymentId, amount, giftCard, currency, reason, actorId, createdAt"
+
+// Store implements types.ReturnStore and types.RefundStore on top of
+// MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanReturn(row sqlutil.Scanner) (*types.Return, error) {
+	r := new(types.Return)
+	var note, resolution, receivedAt sql.NullString
+	if err := row.Scan(&r.ID, &r.OrderID, &r.UserID, &r.Status, &note, &resolution, &receivedAt, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	r.Note, r.Resolution = note.String, resolution.String
+	if receivedAt.Valid {
+		r.ReceivedAt = &receivedAt.String
+	}
+	return r, nil
+}
+
+func scanRefund(row sqlutil.Scanner) (*types.Refund, error) {
+	r := new(types.Refund)
+	var amount, giftCard string
+	var returnID, paymentID, actorID sql.NullInt64
+	if err := row.Scan(&r.ID, &r.OrderID, &returnID, &paymentID, &amount, &giftCard, &r.Currency, &r.Reason, &actorID, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if r.Amount, err = types.ParseMoney(amount, r.Currency); err != nil {
+		return nil, err
+	}
+	if r.GiftCard, err = types.ParseMoney(giftCard, r.Currency); err != nil {
+		return nil, err
+	}
+	r.PaymentID = sqlutil.NullableInt(paymentID)
+	r.ReturnID = sqlutil.NullableInt(returnID)
+	r.ActorID = sqlutil.NullableInt(actorID)
+	return r, nil
+}
+
+// CreateReturn inserts the return and its items and reloads it. The order
+// is locked while the quantities already being returned are checked, so
+// concurrent requests cannot together return more than was ordered.
+func (s *Store) CreateReturn(ret *types.Return) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var orderID int
+	if err := tx.QueryRow("SELECT id FROM orders WHERE id = ? FOR UPDATE", ret.OrderID).Scan(&orderID); err != nil {
+		return err
+	}
+	for _, item := range ret.Items {
+		var ordered, returned int
+		if err := tx.QueryRow(
+			`SELECT i.quantity, COALESCE(SUM(ri.quantity), 0)
+			 FROM order_items i
+			 LEFT JOIN return_items ri ON ri.orderItemId = i.id
+			     AND ri.returnId IN (SELECT id FROM returns WHERE status <> ?)
+			 WHERE i.id = ? AND i.orderId = ?
+			 GROUP BY i.id`,
+			types.ReturnRejected, item.OrderItemID, ret.OrderID,
+		).Scan(&ordered, &returned); err != nil {
+			return err
+		}
+		if returned+item.Quantity > ordered {
+			return fmt.Errorf("%w: %d of order item %d left to return", types.ErrReturnQuantity, ordered-returned, item.OrderItemID)
+		}
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO returns (orderId, userId, status, note) VALUES (?, ?, ?, ?)",
+		ret.OrderID, ret.UserID, types.ReturnRequested, sqlutil.NullString(ret.Note),
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, item := range ret.Items {
+		if _, err := tx.Exec(
+			"INSERT INTO return_items (returnId, orderItemId, quantity, reason) VALUES (?, ?, ?, ?)",
+			id, item.OrderItemID, item.Quantity, item.Reason,
+		); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	created, err := s.GetReturnByID(int(id))
+	if err != nil {
+		return err
+	}
+	*ret = *created
+	return nil
+}
+
+// GetReturnByID returns nil when there is no return with that ID.
+func (s *Store) GetReturnByID(id int) (*types.Return, error) {
+	returns, err := s.queryReturns("WHERE id = ?", id)
+	if err != nil || len(returns) == 0 {
+		return nil, err
+	}
+	return returns[0], nil
+}
+
+// ListReturns returns the returns in status, or every return when status
+// is empty, oldest first so support works through them in order.
+func (s *Store) ListReturns(status string) ([]*types.Return, error) {
+	if status == "" {
+		return s.queryReturns("ORDER BY id")
+	}
+	return s.queryReturns("WHERE status = ? ORDER BY id", status)
+}
+
+// ListReturnsByOrder returns the returns of an order, oldest first.
+func (s *Store) ListReturnsByOrder(orderID int) ([]*types.Return, error) {
+	return s.queryReturns("WHERE orderId = ? ORDER BY id", orderID)
+}
+
+// queryReturns loads the returns matching the clause with their items.
+func (s *Store) queryReturns(clause string, args ...any) ([]*types.Return, error) {
+	rows, err := s.db.Query("SELECT "+returnColumns+" FROM returns "+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	returns := []*types.Return{}
+	byID := make(map[int]*types.Return)
+	for rows.Next() {
+		r, err := scanReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		r.Items = []*types.ReturnItem{}
+		returns = append(returns, r)
+		byID[r.ID] = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(returns) == 0 {
+		return returns, nil
+	}
+
+	ids := make([]any, 0, len(returns))
+	for _, r := range returns {
+		ids = append(ids, r.ID)
+	}
+	itemRows, err := s.db.Query(
+		"SELECT id, returnId, orderItemId, quantity, reason, restocked FROM return_items WHERE returnId IN ("+sqlutil.Placeholders(len(ids))+") ORDER BY id",
+		ids...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		item := new(types.ReturnItem)
+		if err := itemRows.Scan(&item.ID, &item.ReturnID, &item.OrderItemID, &item.Quantity, &item.Reason, &item.Restocked); err != nil {
+			return nil, err
+		}
+		byID[item.ReturnID].Items = append(byID[item.ReturnID].Items, item)
+	}
+	return returns, itemRows.Err()
+}
+
+// UpdateReturnStatus moves a return from one of the statuses in from to
+// status, leaving resolution as the note to the customer.
+func (s *Store) UpdateReturnStatus(id int, from []string, status, resolution string) error {
+	args := []any{status, sqlutil.NullString(resolution), id}
+	for _, f := range from {
+		args = append(args, f)
+	}
+	result, err := s.db.Exec(
+		"UPDATE returns SET status = ?, resolution = ? WHERE id = ? AND status IN ("+sqlutil.Placeholders(len(from))+")",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return types.ErrReturnStatus
+	}
+	return nil
+}
+
+// ReceiveReturn records that the items of a return came back and returns
+// the ones not listed in damaged to the stock at locationID with return
+// movements referencing the return.
+func (s *Store) ReceiveReturn(id, locationID int, damaged []int, actorID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var receivedAt sql.NullString
+	if err := tx.QueryRow("SELECT status, receivedAt FROM returns WHERE id = ? FOR UPDATE", id).Scan(&status, &receivedAt); err != nil {
+		return err
+	}
+	if receivedAt.Valid || (status != types.ReturnApproved && status != types.ReturnRefunded) {
+		return types.ErrReturnStatus
+	}
+
+	rows, err := tx.Query(
+		`SELECT ri.id, i.productId, i.variantId, ri.quantity
+		 FROM return_items ri JOIN order_items i ON i.id = ri.orderItemId
+		 WHERE ri.returnId = ? ORDER BY ri.id`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	var movements []*types.StockMovement
+	var restocked []int
+	for rows.Next() {
+		var itemID int
+		m := &types.StockMovement{LocationID: locationID, Reason: types.MovementReturn, ReferenceID: &id, ActorID: inventory.Actor(actorID)}
+		var variantID sql.NullInt64
+		if err := rows.Scan(&itemID, &m.ProductID, &variantID, &m.Delta); err != nil {
+			rows.Close()
+			return err
+		}
+		m.VariantID = sqlutil.NullableInt(variantID)
+		if !slices.Contains(damaged, itemID) {
+			movements = append(movements, m)
+			restocked = append(restocked, itemID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	bumped := make(map[int]bool)
+	for _, m := range movements {
+		if err := inventory.Move(tx, m); err != nil {
+			return err
+		}
+		if !bumped[m.ProductID] {
+			bumped[m.ProductID] = true
+			if _, err := tx.Exec("UPDATE products SET version = version + 1 WHERE id = ?", m.ProductID); err != nil {
+				return err
+			}
+		}
+	}
+	for _, itemID := range restocked {
+		if _, err := tx.Exec("UPDATE return_items SET restocked = TRUE WHERE id = ?", itemID); err != nil {
+			return err
+		}
+	}
+
+	// a refunded return stays refunded
+	if _, err := tx.Exec(
+		"UPDATE returns SET receivedAt = CURRENT_TIMESTAMP, status = IF(status = ?, ?, status) WHERE id = ?",
+		types.ReturnApproved, types.ReturnReceived, id,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateRefund records a refund and its credit note once issue gave the
+// money back. The GiftCard part of the refund is credited to the gift card
+// in the same transaction; see giftcard.Refund. The order items stay
+// locked while issue runs, so concurrent refunds of a line are checked
+// against each other. Should the commit fail after issue succeeded, the
+// money is back with the customer but only the payment shows it.
+func (s *Store) CreateRefund(refund *types.Refund, issue func() error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if refund.ReturnID != nil {
+		var status string
+		if err := tx.QueryRow("SELECT status FROM returns WHERE id = ? FOR UPDATE", *refund.ReturnID).Scan(&status); err != nil {
+			return err
+		}
+		if status != types.ReturnApproved && status != types.ReturnReceived {
+			return types.ErrReturnStatus
+		}
+	}
+
+	for _, item := range refund.Items {
+		var total, refunded string
+		if err := tx.QueryRow(
+			"SELECT total, refunded FROM order_items WHERE id = ? AND orderId = ? FOR UPDATE",
+			item.OrderItemID, refund.OrderID,
+		).Scan(&total, &refunded); err != nil {
+			return err
+		}
+		paid, err := types.ParseMoney(total, refund.Currency)
+		if err != nil {
+			return err
+		}
+		given, err := types.ParseMoney(refunded, refund.Currency)
+		if err != nil {
+			return err
+		}
+		if left := paid.Sub(given); item.Amount.Cmp(left) > 0 {
+			return fmt.Errorf("%w: %s left to refund on order item %d", types.ErrRefundTooLarge, left, item.OrderItemID)
+		}
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO refunds (orderId, returnId, paymentId, amount, giftCard, currency, reason, actorId) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		refund.OrderID, refund.ReturnID, refund.PaymentID, refund.Amount, refund.GiftCard, refund.Currency, refund.Reason, refund.ActorID,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, item := range refund.Items {
+		if _, err := tx.Exec(
+			"INSERT INTO refund_items (refundId, orderItemId, amount) VALUES (?, ?, ?)",
+			id, item.OrderItemID, item.Amount,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE order_items SET refunded = refunded + ? WHERE id = ?", item.Amount, item.OrderItemID); err != nil {
+			return err
+		}
+	}
+	refund.ID = int(id)
+	if err := giftcard.Refund(tx, refund); err != nil {
+		return err
+	}
+
+	if err := issue(); err != nil {
+		return err
+	}
+
+	if refund.ReturnID != nil {
+		if _, err := tx.Exec("UPDATE returns SET status = ? WHERE id = ?", types.ReturnRefunded, *refund.ReturnID); err != nil {
+			return err
+		}
+	}
+	if err := invoice.IssueCreditNote(tx, refund); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return s.db.QueryRow("SELECT createdAt FROM refunds WHERE id = ?", id).Scan(&refund.CreatedAt)
+}
+
+// ListRefundsByOrder returns the refunds of an order with their items,
+// oldest first.
+func (s *Store) ListRefundsByOrder(orderID int) ([]*types.Refund, error) {
+	rows, err := s.db.Query("SELECT "+refundColumns+" FROM refunds WHERE orderId = ? ORDER BY id", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := []*types.Refund{}
+	byID := make(map[int]*types.Refund)
+	for rows.Next() {
+		r, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		r.Items = []*types.RefundItem{}
+		refunds = append(refunds, r)
+		byID[r.ID] = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	itemRows, err := s.db.Query(
+		`SELECT ri.refundId, ri.orderItemId, ri.amount
+		 FROM refund_items ri JOIN refunds r ON r.id = ri.refundId
+		 WHERE r.orderId = ? ORDER BY ri.id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var refundID int
+		var amount string
+		item := new(types.RefundItem)
+		if err := itemRows.Scan(&refundID, &item.OrderItemID, &amount); err != nil {
+			return nil, err
+		}
+		r := byID[refundID]
+		if item.Amount, err = types.ParseMoney(amount, r.Currency); err != nil {
+			return nil, err
+		}
+		r.Items = append(r.Items, item)
+	}
+	return refunds, itemRows.Err()
+}
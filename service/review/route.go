@@ -0,0 +1,222 @@
+package review
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves product reviews: approved reviews to everyone, posting
+// to signed-in customers and moderation to admins.
+type Handler struct {
+	store        types.ReviewStore
+	productStore types.ProductStore
+	userStore    types.UserStore
+}
+
+// NewHandler creates a new Handler. The product store resolves the
+// reviewed products; the user store authorizes admin-only operations.
+func NewHandler(store types.ReviewStore, productStore types.ProductStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:        store,
+		productStore: productStore,
+		userStore:    userStore,
+	}
+}
+
+// RegisterRoutes attaches review routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/products/{id}/reviews", h.handleListProductReviews).Methods("GET")
+	router.HandleFunc("/products/{id}/reviews", auth.RequireToken(h.handleCreateReview)).Methods("POST")
+	router.HandleFunc("/reviews", auth.RequireAdmin(h.handleListReviews, h.userStore)).Methods("GET")
+	router.HandleFunc("/reviews/{id}/approve", auth.RequireAdmin(h.handleModerate(types.ReviewStatusApproved), h.userStore)).Methods("POST")
+	router.HandleFunc("/reviews/{id}/hide", auth.RequireAdmin(h.handleModerate(types.ReviewStatusHidden), h.userStore)).Methods("POST")
+}
+
+// handleListProductReviews lists the approved reviews of a product.
+func (h *Handler) handleListProductReviews(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	params, err := parseListParams(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	params.ProductID = product.ID
+	params.Status = types.ReviewStatusApproved
+
+	h.writeReviews(w, params)
+}
+
+// handleListReviews lists reviews of every status for moderation,
+// optionally narrowed with ?status= and ?productId=.
+func (h *Handler) handleListReviews(w http.ResponseWriter, r *http.Request) {
+	params, err := parseListParams(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	q := r.URL.Query()
+	switch status := q.Get("status"); status {
+	case "", types.ReviewStatusPending, types.ReviewStatusApproved, types.ReviewStatusHidden:
+		params.Status = status
+	default:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid status %q", status))
+		return
+	}
+	if v := q.Get("productId"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid productId"))
+			return
+		}
+		params.ProductID = id
+	}
+
+	h.writeReviews(w, params)
+}
+
+func (h *Handler) writeReviews(w http.ResponseWriter, params types.ReviewListParams) {
+	reviews, total, err := h.store.ListReviews(params)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list reviews: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListReviewsResponse{
+		Message: "success",
+		Data:    reviews,
+		Meta:    utils.NewPageMeta(params.Pagination, total),
+	})
+}
+
+// handleCreateReview posts the caller's review of a product. Each customer
+// reviews a product once; the review waits for moderation before it is
+// shown.
+func (h *Handler) handleCreateReview(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.CreateReviewPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	existing, err := h.store.GetUserReview(product.ID, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if existing != nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("you have already reviewed this product"))
+		return
+	}
+
+	review := &types.Review{
+		ProductID: product.ID,
+		UserID:    userID,
+		Rating:    payload.Rating,
+		Body:      payload.Body,
+	}
+	if err := h.store.CreateReview(review); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to create review: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.ReviewResponse{
+		Message: "review submitted for moderation",
+		Data:    review,
+	})
+}
+
+// handleModerate returns a handler that moves a review to status.
+func (h *Handler) handleModerate(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+			return
+		}
+
+		review, err := h.store.GetReviewByID(id)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if review == nil {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("review not found"))
+			return
+		}
+
+		if review.Status != status {
+			if err := h.store.SetReviewStatus(id, status); err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to moderate review: %v", err))
+				return
+			}
+			review.Status = status
+		}
+
+		utils.WriteJson(w, http.StatusOK, types.ReviewResponse{
+			Message: "review " + status,
+			Data:    review,
+		})
+	}
+}
+
+// productFromPath loads the product named by the {id} path variable,
+// writing the error response when there is none.
+func (h *Handler) productFromPath(w http.ResponseWriter, r *http.Request) (*types.Product, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	product, err := h.productStore.GetProductByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if product == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+		return nil, false
+	}
+	return product, true
+}
+
+// parseListParams reads the page and the ?sort= order of a review
+// listing.
+func parseListParams(r *http.Request) (types.ReviewListParams, error) {
+	params := types.ReviewListParams{Pagination: utils.ParsePagination(r)}
+
+	switch sort := r.URL.Query().Get("sort"); sort {
+	case "", types.ReviewSortNewest, types.ReviewSortOldest, types.ReviewSortHighest, types.ReviewSortLowest:
+		params.Sort = sort
+	default:
+		return params, fmt.Errorf("invalid sort %q; use %s, %s, %s or %s", sort,
+			types.ReviewSortNewest, types.ReviewSortOldest, types.ReviewSortHighest, types.ReviewSortLowest)
+	}
+	return params, nil
+}
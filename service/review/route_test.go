@@ -0,0 +1,125 @@
+package review
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/config"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// reviewStore keeps reviews of product 1 in memory.
+type reviewStore struct {
+	types.ReviewStore
+	types.ProductStore
+	reviews []*types.Review
+}
+
+func (s *reviewStore) GetProductByID(id int) (*types.Product, error) {
+	if id != 1 {
+		return nil, nil
+	}
+	return &types.Product{ID: 1}, nil
+}
+
+func (s *reviewStore) GetUserReview(productID, userID int) (*types.Review, error) {
+	for _, r := range s.reviews {
+		if r.ProductID == productID && r.UserID == userID {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *reviewStore) CreateReview(review *types.Review) error {
+	review.ID = len(s.reviews) + 1
+	review.Status = types.ReviewStatusPending
+	s.reviews = append(s.reviews, review)
+	return nil
+}
+
+func (s *reviewStore) ListReviews(params types.ReviewListParams) ([]*types.Review, int, error) {
+	return s.reviews, len(s.reviews), nil
+}
+
+func TestHandleCreateReview(t *testing.T) {
+	token, err := auth.CreateJWT([]byte(config.Envs.JWTSecret), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"created", "/products/1/reviews", `{"rating":4,"body":"solid"}`, http.StatusCreated},
+		{"second review", "/products/1/reviews", `{"rating":5,"body":"even better"}`, http.StatusConflict},
+		{"rating too high", "/products/1/reviews", `{"rating":6,"body":"wow"}`, http.StatusBadRequest},
+		{"rating missing", "/products/1/reviews", `{"body":"meh"}`, http.StatusBadRequest},
+		{"empty body", "/products/1/reviews", `{"rating":3,"body":""}`, http.StatusBadRequest},
+		{"unknown product", "/products/2/reviews", `{"rating":3,"body":"ok"}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &reviewStore{}
+			if tt.status == http.StatusConflict {
+				store.reviews = []*types.Review{{ID: 1, ProductID: 1, UserID: 5}}
+			}
+			h := NewHandler(store, store, nil)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/products/{id}/reviews", auth.RequireToken(h.handleCreateReview))
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+			if tt.status == http.StatusCreated && (len(store.reviews) != 1 || store.reviews[0].UserID != 5) {
+				t.Errorf("expected the review of user 5 to be stored, got %+v", store.reviews)
+			}
+		})
+	}
+}
+
+func TestHandleListProductReviewsSort(t *testing.T) {
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"?sort=newest", http.StatusOK},
+		{"?sort=oldest", http.StatusOK},
+		{"?sort=highest", http.StatusOK},
+		{"?sort=lowest", http.StatusOK},
+		{"?sort=rating", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			store := &reviewStore{}
+			h := NewHandler(store, store, nil)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/products/{id}/reviews", h.handleListProductReviews)
+
+			req := httptest.NewRequest(http.MethodGet, "/products/1/reviews"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
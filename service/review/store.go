@@ -0,0 +1,183 @@
+package review
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// reviewColumns lists the columns read by scanReview, in scan order. The
+// review table is aliased r and joined with the author as u.
+const reviewColumns = "r.id, r.productId, r.userId, CONCAT(u.firstName, ' ', LEFT(u.lastName, 1), '.'), " +
+	"r.rating, r.body, r.verifiedPurchase, r.status, r.createdAt"
+
+const reviewFrom = " FROM product_reviews r JOIN users u ON u.id = r.userId"
+
+// reviewOrders maps the sort orders accepted by ListReviews to ORDER BY
+// clauses. The ID breaks ties so pages do not overlap.
+var reviewOrders = map[string]string{
+	"":                      "r.createdAt DESC, r.id DESC",
+	types.ReviewSortNewest:  "r.createdAt DESC, r.id DESC",
+	types.ReviewSortOldest:  "r.createdAt, r.id",
+	types.ReviewSortHighest: "r.rating DESC, r.createdAt DESC, r.id DESC",
+	types.ReviewSortLowest:  "r.rating, r.createdAt DESC, r.id DESC",
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanReview(row sqlutil.Scanner) (*types.Review, error) {
+	review := new(types.Review)
+	err := row.Scan(
+		&review.ID,
+		&review.ProductID,
+		&review.UserID,
+		&review.AuthorName,
+		&review.Rating,
+		&review.Body,
+		&review.VerifiedPurchase,
+		&review.Status,
+		&review.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// ListReviews returns one page of reviews matching params together with
+// the total number of matching rows.
+func (s *Store) ListReviews(params types.ReviewListParams) ([]*types.Review, int, error) {
+	var conds []string
+	var args []any
+	if params.ProductID != 0 {
+		conds = append(conds, "r.productId = ?")
+		args = append(args, params.ProductID)
+	}
+	if params.Status != "" {
+		conds = append(conds, "r.status = ?")
+		args = append(args, params.Status)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM product_reviews r"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(
+		"SELECT "+reviewColumns+reviewFrom+where+" ORDER BY "+reviewOrders[params.Sort]+" LIMIT ? OFFSET ?",
+		append(args, params.PageSize, params.Offset())...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	reviews := []*types.Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// GetReviewByID returns the review with the given ID, or nil if there is
+// none.
+func (s *Store) GetReviewByID(id int) (*types.Review, error) {
+	return s.getReview("r.id = ?", id)
+}
+
+// GetUserReview returns the user's review of the product, or nil if the
+// user has not reviewed it.
+func (s *Store) GetUserReview(productID, userID int) (*types.Review, error) {
+	return s.getReview("r.productId = ? AND r.userId = ?", productID, userID)
+}
+
+func (s *Store) getReview(where string, args ...any) (*types.Review, error) {
+	review, err := scanReview(s.db.QueryRow("SELECT "+reviewColumns+reviewFrom+" WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// CreateReview inserts a pending review and fills in the generated fields.
+// The review is a verified purchase when one of the user's completed or
+// delivered orders contains the product.
+func (s *Store) CreateReview(review *types.Review) error {
+	result, err := s.db.Exec(
+		`INSERT INTO product_reviews (productId, userId, rating, body, verifiedPurchase)
+		 SELECT ?, ?, ?, ?, EXISTS (
+		        SELECT 1 FROM orders o JOIN order_items oi ON oi.orderId = o.id
+		         WHERE o.userId = ? AND o.status IN (?, ?) AND oi.productId = ?)`,
+		review.ProductID, review.UserID, review.Rating, review.Body,
+		review.UserID, types.OrderStatusCompleted, types.OrderStatusDelivered, review.ProductID,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	created, err := s.GetReviewByID(int(id))
+	if err != nil {
+		return err
+	}
+	*review = *created
+	return nil
+}
+
+// SetReviewStatus moves a review to status and recomputes the rating of
+// its product from the approved reviews, in one transaction.
+func (s *Store) SetReviewStatus(id int, status string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var productID int
+	if err := tx.QueryRow("SELECT productId FROM product_reviews WHERE id = ? FOR UPDATE", id).Scan(&productID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE product_reviews SET status = ? WHERE id = ?", status, id); err != nil {
+		return err
+	}
+
+	// the rating is derived data, like the available stock, so it does
+	// not bump the product version
+	_, err = tx.Exec(
+		`UPDATE products p
+		    SET p.ratingAverage = (SELECT AVG(r.rating) FROM product_reviews r WHERE r.productId = p.id AND r.status = ?),
+		        p.ratingCount = (SELECT COUNT(*) FROM product_reviews r WHERE r.productId = p.id AND r.status = ?)
+		  WHERE p.id = ?`,
+		types.ReviewStatusApproved, types.ReviewStatusApproved, productID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
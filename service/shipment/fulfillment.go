@@ -0,0 +1,95 @@
+// Package shipment records the packages paid orders are sent in, with
+// their carrier, tracking and the units of each order line they hold, and
+// moves orders to shipped and delivered as their packages are.
+package shipment
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// unshipped returns the units of every line of order not in one of its
+// shipments yet, keyed by order item ID.
+func unshipped(order *types.Order, shipments []*types.Shipment) map[int]int {
+	left := make(map[int]int, len(order.Items))
+	for _, item := range order.Items {
+		left[item.ID] = item.Quantity
+	}
+	for _, s := range shipments {
+		for _, item := range s.Items {
+			left[item.OrderItemID] -= item.Quantity
+		}
+	}
+	return left
+}
+
+// shipmentItems checks the lines of a shipment of order against the units
+// left to ship and returns them as shipment items. Without lines the
+// shipment holds every unit left. It returns the status and error to
+// respond with when the shipment is not valid.
+func shipmentItems(order *types.Order, payload []types.ShipmentItemPayload, left map[int]int) ([]*types.ShipmentItem, int, error) {
+	if order.Status != types.OrderStatusPaid {
+		return nil, http.StatusConflict, fmt.Errorf("order %d is %s; only paid orders can be shipped", order.ID, order.Status)
+	}
+
+	var items []*types.ShipmentItem
+	if len(payload) == 0 {
+		for _, item := range order.Items {
+			if left[item.ID] > 0 {
+				items = append(items, &types.ShipmentItem{OrderItemID: item.ID, Quantity: left[item.ID]})
+			}
+		}
+		if len(items) == 0 {
+			return nil, http.StatusConflict, fmt.Errorf("every item of order %d was shipped", order.ID)
+		}
+		return items, 0, nil
+	}
+
+	seen := make(map[int]bool, len(payload))
+	for _, p := range payload {
+		if seen[p.OrderItemID] {
+			return nil, http.StatusBadRequest, fmt.Errorf("order item %d is listed twice", p.OrderItemID)
+		}
+		seen[p.OrderItemID] = true
+
+		remaining, ok := left[p.OrderItemID]
+		if !ok {
+			return nil, http.StatusBadRequest, fmt.Errorf("order item %d is not part of order %d", p.OrderItemID, order.ID)
+		}
+		if p.Quantity > remaining {
+			return nil, http.StatusUnprocessableEntity, fmt.Errorf("%w: %d of order item %d left to ship", types.ErrShipmentQuantity, remaining, p.OrderItemID)
+		}
+		items = append(items, &types.ShipmentItem{OrderItemID: p.OrderItemID, Quantity: p.Quantity})
+	}
+	return items, 0, nil
+}
+
+// coverage is how many units of an order line were ordered, shipped and
+// delivered.
+type coverage struct {
+	ordered, shipped, delivered int
+}
+
+// orderStatus returns the status an order in status current moves to
+// given the coverage of its lines: delivered once every unit was
+// delivered, shipped once every unit was shipped. Only paid and shipped
+// orders move.
+func orderStatus(current string, lines []coverage) string {
+	if current != types.OrderStatusPaid && current != types.OrderStatusShipped {
+		return current
+	}
+	shipped, delivered := len(lines) > 0, len(lines) > 0
+	for _, l := range lines {
+		shipped = shipped && l.shipped >= l.ordered
+		delivered = delivered && l.delivered >= l.ordered
+	}
+	switch {
+	case delivered:
+		return types.OrderStatusDelivered
+	case shipped:
+		return types.OrderStatusShipped
+	}
+	return current
+}
@@ -0,0 +1,80 @@
+package shipment
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestUnshipped(t *testing.T) {
+	order := &types.Order{Items: []*types.OrderItem{{ID: 10, Quantity: 3}, {ID: 11, Quantity: 1}}}
+	shipments := []*types.Shipment{
+		{Items: []*types.ShipmentItem{{OrderItemID: 10, Quantity: 1}}},
+		{Items: []*types.ShipmentItem{{OrderItemID: 10, Quantity: 1}, {OrderItemID: 11, Quantity: 1}}},
+	}
+
+	left := unshipped(order, shipments)
+	if len(left) != 2 || left[10] != 1 || left[11] != 0 {
+		t.Errorf("expected 1 unit of line 10 and none of line 11 left, got %v", left)
+	}
+}
+
+func TestShipmentItems(t *testing.T) {
+	order := &types.Order{ID: 1, Status: types.OrderStatusPaid, Items: []*types.OrderItem{{ID: 10, Quantity: 3}, {ID: 11, Quantity: 1}}}
+	left := map[int]int{10: 2, 11: 1}
+
+	items, status, err := shipmentItems(order, nil, left)
+	if err != nil || len(items) != 2 || items[0].Quantity != 2 || items[1].Quantity != 1 {
+		t.Fatalf("expected every unit left to be shipped, got %v with %d: %v", items, status, err)
+	}
+
+	items, _, err = shipmentItems(order, []types.ShipmentItemPayload{{OrderItemID: 10, Quantity: 2}}, left)
+	if err != nil || len(items) != 1 || items[0].OrderItemID != 10 || items[0].Quantity != 2 {
+		t.Fatalf("expected 2 units of line 10 to be shipped, got %v: %v", items, err)
+	}
+
+	if _, status, _ := shipmentItems(order, []types.ShipmentItemPayload{{OrderItemID: 10, Quantity: 3}}, left); status != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d shipping more than is left, got %d", http.StatusUnprocessableEntity, status)
+	}
+	if _, status, _ := shipmentItems(order, []types.ShipmentItemPayload{{OrderItemID: 12, Quantity: 1}}, left); status != http.StatusBadRequest {
+		t.Errorf("expected status %d for a line of another order, got %d", http.StatusBadRequest, status)
+	}
+	twice := []types.ShipmentItemPayload{{OrderItemID: 11, Quantity: 1}, {OrderItemID: 11, Quantity: 1}}
+	if _, status, _ := shipmentItems(order, twice, left); status != http.StatusBadRequest {
+		t.Errorf("expected status %d for a line listed twice, got %d", http.StatusBadRequest, status)
+	}
+	if _, status, _ := shipmentItems(order, nil, map[int]int{10: 0, 11: 0}); status != http.StatusConflict {
+		t.Errorf("expected status %d with nothing left to ship, got %d", http.StatusConflict, status)
+	}
+
+	order.Status = types.OrderStatusPending
+	if _, status, _ := shipmentItems(order, nil, left); status != http.StatusConflict {
+		t.Errorf("expected status %d for an unpaid order, got %d", http.StatusConflict, status)
+	}
+}
+
+func TestOrderStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		lines   []coverage
+		want    string
+	}{
+		{"partly shipped", types.OrderStatusPaid, []coverage{{3, 3, 0}, {1, 0, 0}}, types.OrderStatusPaid},
+		{"all shipped", types.OrderStatusPaid, []coverage{{3, 3, 1}, {1, 1, 0}}, types.OrderStatusShipped},
+		{"partly delivered", types.OrderStatusShipped, []coverage{{3, 3, 3}, {1, 1, 0}}, types.OrderStatusShipped},
+		{"all delivered", types.OrderStatusShipped, []coverage{{3, 3, 3}, {1, 1, 1}}, types.OrderStatusDelivered},
+		{"delivered in one go", types.OrderStatusPaid, []coverage{{2, 2, 2}}, types.OrderStatusDelivered},
+		{"not paid", types.OrderStatusPending, []coverage{{2, 2, 2}}, types.OrderStatusPending},
+		{"no lines", types.OrderStatusPaid, nil, types.OrderStatusPaid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderStatus(tt.current, tt.lines); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
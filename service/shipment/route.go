@@ -0,0 +1,219 @@
+package shipment
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves shipment tracking to customers and shipment records to
+// the warehouse.
+type Handler struct {
+	store      types.ShipmentStore
+	orderStore types.OrderStore
+	userStore  types.UserStore
+	clock      types.Clock
+}
+
+// NewHandler creates a new Handler. The user store authorizes admin-only
+// operations and clock dates shipments recorded without a time.
+func NewHandler(store types.ShipmentStore, orderStore types.OrderStore, userStore types.UserStore, clock types.Clock) *Handler {
+	return &Handler{store: store, orderStore: orderStore, userStore: userStore, clock: clock}
+}
+
+// RegisterRoutes attaches shipment routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/orders/{id:[0-9]+}/shipments", auth.RequireToken(h.handleListShipments)).Methods("GET")
+	router.HandleFunc("/orders/{id:[0-9]+}/shipments", auth.RequireAdmin(h.handleCreateShipment, h.userStore)).Methods("POST")
+
+	router.HandleFunc("/shipments/{id:[0-9]+}", auth.RequireAdmin(h.handleGetShipment, h.userStore)).Methods("GET")
+	router.HandleFunc("/shipments/{id:[0-9]+}/deliver", auth.RequireAdmin(h.handleDeliverShipment, h.userStore)).Methods("POST")
+}
+
+func (h *Handler) handleListShipments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+	order, ok := h.orderFromPath(w, r)
+	if !ok {
+		return
+	}
+	if order.UserID != userID {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order not found"))
+		return
+	}
+
+	shipments, err := h.store.ListShipmentsByOrder(order.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list shipments: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListShipmentsResponse{
+		Message: "success",
+		Data:    shipments,
+	})
+}
+
+// handleCreateShipment records a package sent for a paid order. Admins
+// only.
+func (h *Handler) handleCreateShipment(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.orderFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.CreateShipmentPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	shipped, err := h.store.ListShipmentsByOrder(order.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	items, status, err := shipmentItems(order, payload.Items, unshipped(order, shipped))
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	shipment := &types.Shipment{
+		OrderID:        order.ID,
+		Carrier:        payload.Carrier,
+		TrackingNumber: payload.TrackingNumber,
+		TrackingURL:    payload.TrackingURL,
+		ShippedAt:      h.clock.Now(),
+		Items:          items,
+	}
+	if payload.ShippedAt != nil {
+		shipment.ShippedAt = *payload.ShippedAt
+	}
+	if err := h.store.CreateShipment(shipment); err != nil {
+		if errors.Is(err, types.ErrShipmentQuantity) {
+			utils.WriteError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to create shipment: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.ShipmentResponse{
+		Message: "shipment recorded",
+		Data:    shipment,
+	})
+}
+
+func (h *Handler) handleGetShipment(w http.ResponseWriter, r *http.Request) {
+	shipment, ok := h.shipmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ShipmentResponse{
+		Message: "success",
+		Data:    shipment,
+	})
+}
+
+// handleDeliverShipment records that the carrier delivered a shipment.
+// Admins only.
+func (h *Handler) handleDeliverShipment(w http.ResponseWriter, r *http.Request) {
+	shipment, ok := h.shipmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.DeliverShipmentPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	deliveredAt := h.clock.Now()
+	if payload.DeliveredAt != nil {
+		deliveredAt = *payload.DeliveredAt
+	}
+	if deliveredAt.Before(shipment.ShippedAt) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("a shipment cannot be delivered before it was shipped"))
+		return
+	}
+
+	if err := h.store.DeliverShipment(shipment.ID, deliveredAt); err != nil {
+		switch {
+		case errors.Is(err, types.ErrShipmentDelivered):
+			utils.WriteError(w, http.StatusConflict, err)
+		case errors.Is(err, sql.ErrNoRows):
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("shipment not found"))
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	shipment, err := h.store.GetShipmentByID(shipment.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJson(w, http.StatusOK, types.ShipmentResponse{
+		Message: "shipment delivered",
+		Data:    shipment,
+	})
+}
+
+// orderFromPath returns the order in the path, writing the error response
+// when there is none.
+func (h *Handler) orderFromPath(w http.ResponseWriter, r *http.Request) (*types.Order, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	order, err := h.orderStore.GetOrderByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if order == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order not found"))
+		return nil, false
+	}
+	return order, true
+}
+
+// shipmentFromPath returns the shipment in the path, writing the error
+// response when there is none.
+func (h *Handler) shipmentFromPath(w http.ResponseWriter, r *http.Request) (*types.Shipment, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	shipment, err := h.store.GetShipmentByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if shipment == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("shipment not found"))
+		return nil, false
+	}
+	return shipment, true
+}
@@ -0,0 +1,244 @@
+package shipment
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// shipmentColumns lists the columns read by scanShipment, in scan order.
+const shipmentColumns = "id, orderId, carrier, trackingNumber, trackingUrl, shippedAt, deliveredAt, createdAt"
+
+// Store implements types.ShipmentStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanShipment(row sqlutil.Scanner) (*types.Shipment, error) {
+	s := new(types.Shipment)
+	var trackingURL sql.NullString
+	var deliveredAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.OrderID, &s.Carrier, &s.TrackingNumber, &trackingURL, &s.ShippedAt, &deliveredAt, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	s.TrackingURL = trackingURL.String
+	if deliveredAt.Valid {
+		s.DeliveredAt = &deliveredAt.Time
+	}
+	return s, nil
+}
+
+// CreateShipment inserts the shipment and its items, moves the order to
+// shipped when nothing is left to ship and reloads the shipment. The order
+// is locked while the quantities already shipped are checked, so
+// concurrent shipments cannot together send more than was ordered.
+func (s *Store) CreateShipment(shipment *types.Shipment) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var orderID int
+	if err := tx.QueryRow("SELECT id FROM orders WHERE id = ? FOR UPDATE", shipment.OrderID).Scan(&orderID); err != nil {
+		return err
+	}
+	for _, item := range shipment.Items {
+		var ordered, shipped int
+		if err := tx.QueryRow(
+			`SELECT i.quantity, COALESCE(SUM(si.quantity), 0)
+			 FROM order_items i
+			 LEFT JOIN shipment_items si ON si.orderItemId = i.id
+			 WHERE i.id = ? AND i.orderId = ?
+			 GROUP BY i.id`,
+			item.OrderItemID, shipment.OrderID,
+		).Scan(&ordered, &shipped); err != nil {
+			return err
+		}
+		if shipped+item.Quantity > ordered {
+			return fmt.Errorf("%w: %d of order item %d left to ship", types.ErrShipmentQuantity, ordered-shipped, item.OrderItemID)
+		}
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO shipments (orderId, carrier, trackingNumber, trackingUrl, shippedAt) VALUES (?, ?, ?, ?, ?)",
+		shipment.OrderID, shipment.Carrier, shipment.TrackingNumber, sqlutil.NullString(shipment.TrackingURL), shipment.ShippedAt,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, item := range shipment.Items {
+		if _, err := tx.Exec(
+			"INSERT INTO shipment_items (shipmentId, orderItemId, quantity) VALUES (?, ?, ?)",
+			id, item.OrderItemID, item.Quantity,
+		); err != nil {
+			return err
+		}
+	}
+	if err := advance(tx, shipment.OrderID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	created, err := s.GetShipmentByID(int(id))
+	if err != nil {
+		return err
+	}
+	*shipment = *created
+	return nil
+}
+
+// DeliverShipment records that the shipment was delivered at deliveredAt
+// and moves its order to delivered when every unit of it was. It returns
+// sql.ErrNoRows when there is no such shipment.
+func (s *Store) DeliverShipment(id int, deliveredAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the order is locked first, as CreateShipment does
+	var orderID int
+	if err := tx.QueryRow("SELECT orderId FROM shipments WHERE id = ?", id).Scan(&orderID); err != nil {
+		return err
+	}
+	if err := tx.QueryRow("SELECT id FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&orderID); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("UPDATE shipments SET deliveredAt = ? WHERE id = ? AND deliveredAt IS NULL", deliveredAt, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return types.ErrShipmentDelivered
+	}
+	if err := advance(tx, orderID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// advance moves the order, locked by tx, to the status the coverage of its
+// lines by shipments calls for.
+func advance(tx *sql.Tx, orderID int) error {
+	var status string
+	if err := tx.QueryRow("SELECT status FROM orders WHERE id = ?", orderID).Scan(&status); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(
+		`SELECT i.quantity, COALESCE(SUM(si.quantity), 0), COALESCE(SUM(IF(s.deliveredAt IS NULL, 0, si.quantity)), 0)
+		 FROM order_items i
+		 LEFT JOIN shipment_items si ON si.orderItemId = i.id
+		 LEFT JOIN shipments s ON s.id = si.shipmentId
+		 WHERE i.orderId = ?
+		 GROUP BY i.id, i.quantity`,
+		orderID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var lines []coverage
+	for rows.Next() {
+		var c coverage
+		if err := rows.Scan(&c.ordered, &c.shipped, &c.delivered); err != nil {
+			return err
+		}
+		lines = append(lines, c)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if next := orderStatus(status, lines); next != status {
+		if _, err := tx.Exec("UPDATE orders SET status = ? WHERE id = ?", next, orderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetShipmentByID returns nil when there is no shipment with that ID.
+func (s *Store) GetShipmentByID(id int) (*types.Shipment, error) {
+	shipments, err := s.queryShipments("WHERE id = ?", id)
+	if err != nil || len(shipments) == 0 {
+		return nil, err
+	}
+	return shipments[0], nil
+}
+
+// ListShipmentsByOrder returns the shipments of an order, oldest first.
+func (s *Store) ListShipmentsByOrder(orderID int) ([]*types.Shipment, error) {
+	return s.queryShipments("WHERE orderId = ? ORDER BY id", orderID)
+}
+
+// queryShipments loads the shipments matching the clause with their items.
+func (s *Store) queryShipments(clause string, args ...any) ([]*types.Shipment, error) {
+	rows, err := s.db.Query("SELECT "+shipmentColumns+" FROM shipments "+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shipments := []*types.Shipment{}
+	byID := make(map[int]*types.Shipment)
+	for rows.Next() {
+		sh, err := scanShipment(rows)
+		if err != nil {
+			return nil, err
+		}
+		sh.Items = []*types.ShipmentItem{}
+		shipments = append(shipments, sh)
+		byID[sh.ID] = sh
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(shipments) == 0 {
+		return shipments, nil
+	}
+
+	ids := make([]any, 0, len(shipments))
+	for _, sh := range shipments {
+		ids = append(ids, sh.ID)
+	}
+	itemRows, err := s.db.Query(
+		"SELECT id, shipmentId, orderItemId, quantity FROM shipment_items WHERE shipmentId IN ("+sqlutil.Placeholders(len(ids))+") ORDER BY id",
+		ids...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		item := new(types.ShipmentItem)
+		if err := itemRows.Scan(&item.ID, &item.ShipmentID, &item.OrderItemID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		byID[item.ShipmentID].Items = append(byID[item.ShipmentID].Items, item)
+	}
+	return shipments, itemRows.Err()
+}
@@ -0,0 +1,289 @@
+package shipment
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// memoryDB answers the statements of Store from memory, as much of MySQL
+// as the shipments of a single order need.
+type memoryDB struct {
+	status    map[int]string // order ID to status
+	lines     map[int][2]int // order item ID to its order ID and quantity
+	shipments []memoryShipment
+	items     []types.ShipmentItem
+
+	saved *memoryDB // the state to go back to on rollback
+}
+
+type memoryShipment struct {
+	id, orderID int
+	shippedAt   time.Time
+	deliveredAt *time.Time
+}
+
+// newStore returns a Store on an order 1 that is paid for 3 units of line
+// 10 and 1 unit of line 11.
+func newStore() (*Store, *memoryDB) {
+	m := &memoryDB{
+		status: map[int]string{1: types.OrderStatusPaid},
+		lines:  map[int][2]int{10: {1, 3}, 11: {1, 1}},
+	}
+	return NewStore(sql.OpenDB(m)), m
+}
+
+func (m *memoryDB) Connect(context.Context) (driver.Conn, error) { return m, nil }
+func (m *memoryDB) Driver() driver.Driver                        { return nil }
+func (m *memoryDB) Prepare(string) (driver.Stmt, error)          { return nil, driver.ErrSkip }
+func (m *memoryDB) Close() error                                 { return nil }
+
+func (m *memoryDB) Begin() (driver.Tx, error) {
+	saved := *m
+	saved.status = make(map[int]string, len(m.status))
+	for id, status := range m.status {
+		saved.status[id] = status
+	}
+	saved.shipments = append([]memoryShipment(nil), m.shipments...)
+	saved.items = append([]types.ShipmentItem(nil), m.items...)
+	m.saved = &saved
+	return m, nil
+}
+
+func (m *memoryDB) Commit() error {
+	m.saved = nil
+	return nil
+}
+
+func (m *memoryDB) Rollback() error {
+	if m.saved != nil {
+		*m = *m.saved
+	}
+	return nil
+}
+
+func (m *memoryDB) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO shipments "):
+		id := len(m.shipments) + 1
+		m.shipments = append(m.shipments, memoryShipment{id: id, orderID: arg(args, 0), shippedAt: args[4].Value.(time.Time)})
+		return memoryResult{id: int64(id), affected: 1}, nil
+	case strings.HasPrefix(query, "INSERT INTO shipment_items "):
+		m.items = append(m.items, types.ShipmentItem{ID: len(m.items) + 1, ShipmentID: arg(args, 0), OrderItemID: arg(args, 1), Quantity: arg(args, 2)})
+		return memoryResult{affected: 1}, nil
+	case strings.HasPrefix(query, "UPDATE shipments SET deliveredAt "):
+		deliveredAt := args[0].Value.(time.Time)
+		for i := range m.shipments {
+			if s := &m.shipments[i]; s.id == arg(args, 1) && s.deliveredAt == nil {
+				s.deliveredAt = &deliveredAt
+				return memoryResult{affected: 1}, nil
+			}
+		}
+		return memoryResult{}, nil
+	case strings.HasPrefix(query, "UPDATE orders SET status "):
+		m.status[arg(args, 1)] = args[0].Value.(string)
+		return memoryResult{affected: 1}, nil
+	}
+	return nil, errors.New("unexpected statement: " + query)
+}
+
+func (m *memoryDB) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows := &memoryRows{}
+	switch {
+	case strings.HasPrefix(query, "SELECT id FROM orders "):
+		if _, ok := m.status[arg(args, 0)]; ok {
+			rows.add(int64(arg(args, 0)))
+		}
+	case strings.HasPrefix(query, "SELECT status FROM orders "):
+		if status, ok := m.status[arg(args, 0)]; ok {
+			rows.add(status)
+		}
+	case strings.HasPrefix(query, "SELECT orderId FROM shipments "):
+		for _, s := range m.shipments {
+			if s.id == arg(args, 0) {
+				rows.add(int64(s.orderID))
+			}
+		}
+	case strings.Contains(query, "FROM order_items") && strings.Contains(query, "deliveredAt"):
+		for _, id := range m.lineIDs(arg(args, 0)) {
+			shipped, delivered := m.coverage(id)
+			rows.add(int64(m.lines[id][1]), int64(shipped), int64(delivered))
+		}
+	case strings.Contains(query, "FROM order_items"):
+		if line, ok := m.lines[arg(args, 0)]; ok && line[0] == arg(args, 1) {
+			shipped, _ := m.coverage(arg(args, 0))
+			rows.add(int64(line[1]), int64(shipped))
+		}
+	case strings.HasPrefix(query, "SELECT "+shipmentColumns+" FROM shipments WHERE id = ?"):
+		for _, s := range m.shipments {
+			if s.id == arg(args, 0) {
+				var deliveredAt driver.Value
+				if s.deliveredAt != nil {
+					deliveredAt = *s.deliveredAt
+				}
+				rows.add(int64(s.id), int64(s.orderID), "ups", "1Z", nil, s.shippedAt, deliveredAt, "")
+			}
+		}
+	case strings.Contains(query, "FROM shipment_items WHERE shipmentId IN"):
+		for _, item := range m.items {
+			for i := range args {
+				if item.ShipmentID == arg(args, i) {
+					rows.add(int64(item.ID), int64(item.ShipmentID), int64(item.OrderItemID), int64(item.Quantity))
+				}
+			}
+		}
+	default:
+		return nil, errors.New("unexpected query: " + query)
+	}
+	return rows, nil
+}
+
+// lineIDs returns the order item IDs of an order, in order.
+func (m *memoryDB) lineIDs(orderID int) []int {
+	var ids []int
+	for id, line := range m.lines {
+		if line[0] == orderID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// coverage returns the units of an order line shipped and delivered.
+func (m *memoryDB) coverage(orderItemID int) (shipped, delivered int) {
+	for _, item := range m.items {
+		if item.OrderItemID != orderItemID {
+			continue
+		}
+		shipped += item.Quantity
+		if m.shipments[item.ShipmentID-1].deliveredAt != nil {
+			delivered += item.Quantity
+		}
+	}
+	return shipped, delivered
+}
+
+func arg(args []driver.NamedValue, i int) int {
+	return int(args[i].Value.(int64))
+}
+
+type memoryResult struct {
+	id, affected int64
+}
+
+func (r memoryResult) LastInsertId() (int64, error) { return r.id, nil }
+func (r memoryResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+type memoryRows struct {
+	values [][]driver.Value
+}
+
+func (r *memoryRows) add(values ...driver.Value) { r.values = append(r.values, values) }
+
+// Columns only needs the right count; Store scans by position.
+func (r *memoryRows) Columns() []string {
+	if len(r.values) == 0 {
+		return make([]string, 8)
+	}
+	return make([]string, len(r.values[0]))
+}
+
+func (r *memoryRows) Close() error { return nil }
+
+func (r *memoryRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func ship(orderItemID, quantity int) *types.Shipment {
+	return &types.Shipment{
+		OrderID:   1,
+		ShippedAt: time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC),
+		Items:     []*types.ShipmentItem{{OrderItemID: orderItemID, Quantity: quantity}},
+	}
+}
+
+func TestCreateShipment(t *testing.T) {
+	store, m := newStore()
+
+	first := ship(10, 3)
+	if err := store.CreateShipment(first); err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != 1 || len(first.Items) != 1 || first.Items[0].Quantity != 3 {
+		t.Errorf("expected shipment 1 of 3 units to be reloaded, got %+v", first)
+	}
+	if m.status[1] != types.OrderStatusPaid {
+		t.Errorf("expected the order to stay paid with line 11 unshipped, got %s", m.status[1])
+	}
+
+	if err := store.CreateShipment(ship(11, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if m.status[1] != types.OrderStatusShipped {
+		t.Errorf("expected the order to be shipped with every unit shipped, got %s", m.status[1])
+	}
+}
+
+func TestCreateShipmentOverShipment(t *testing.T) {
+	store, m := newStore()
+	if err := store.CreateShipment(ship(10, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.CreateShipment(ship(10, 2))
+	if !errors.Is(err, types.ErrShipmentQuantity) {
+		t.Fatalf("expected ErrShipmentQuantity with 1 unit left to ship, got %v", err)
+	}
+	if len(m.shipments) != 1 || len(m.items) != 1 {
+		t.Errorf("expected nothing of the rejected shipment to be stored, got %d shipments", len(m.shipments))
+	}
+
+	if err := store.CreateShipment(ship(12, 1)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a line of another order, got %v", err)
+	}
+}
+
+func TestDeliverShipment(t *testing.T) {
+	store, m := newStore()
+	for _, s := range []*types.Shipment{ship(10, 3), ship(11, 1)} {
+		if err := store.CreateShipment(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deliveredAt := time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)
+
+	if err := store.DeliverShipment(1, deliveredAt); err != nil {
+		t.Fatal(err)
+	}
+	if m.status[1] != types.OrderStatusShipped {
+		t.Errorf("expected the order to stay shipped with line 11 on its way, got %s", m.status[1])
+	}
+	if err := store.DeliverShipment(1, deliveredAt); !errors.Is(err, types.ErrShipmentDelivered) {
+		t.Errorf("expected ErrShipmentDelivered delivering twice, got %v", err)
+	}
+
+	if err := store.DeliverShipment(2, deliveredAt); err != nil {
+		t.Fatal(err)
+	}
+	if m.status[1] != types.OrderStatusDelivered {
+		t.Errorf("expected the order to be delivered with every unit delivered, got %s", m.status[1])
+	}
+
+	if err := store.DeliverShipment(3, deliveredAt); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for an unknown shipment, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+// Package shipping prices shipping: it lets admins manage shipping zones
+// and their methods and works out what each method costs for a parcel.
+package shipping
+
+import (
+	"fmt"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// volumetricDivisor turns the volume of a product in cubic millimetres
+// into grams: the usual carrier divisor of 5000 cm³ per kilogram.
+const volumetricDivisor = 5000
+
+// Parcel is what a shipping method is priced on: the items it holds, at
+// Value once the coupon discount is taken off, and its chargeable weight
+// in grams.
+type Parcel struct {
+	Value types.Money
+	Grams int
+}
+
+// Weigh returns the chargeable weight of the order items in grams. Each
+// product counts for the larger of its weight and its volumetric weight,
+// so light but bulky products are not shipped for nothing. products holds
+// the products of the items by ID.
+func Weigh(items []*types.OrderItem, products map[int]*types.Product) int {
+	grams := 0
+	for _, item := range items {
+		p, ok := products[item.ProductID]
+		if !ok {
+			continue
+		}
+		weight := 0
+		if p.Weight != nil {
+			weight = *p.Weight
+		}
+		if d := p.Dimensions; d != nil {
+			weight = max(weight, d.Length*d.Width*d.Height/volumetricDivisor)
+		}
+		grams += weight * item.Quantity
+	}
+	return grams
+}
+
+// Quote returns what method m costs for parcel p, as described on
+// types.ShippingMethod. The amounts of m must be in the currency of the
+// parcel value.
+func Quote(m *types.ShippingMethod, p Parcel) types.Money {
+	switch m.Kind {
+	case types.ShippingWeightBased:
+		if m.PerKg == nil {
+			return m.Rate
+		}
+		kg := (p.Grams + 999) / 1000
+		return m.Rate.Add(m.PerKg.Mul(kg))
+	case types.ShippingFreeOver:
+		if m.FreeOver != nil && p.Value.Cmp(*m.FreeOver) >= 0 {
+			return types.NewMoney(0, m.Rate.Currency)
+		}
+	}
+	return m.Rate
+}
+
+// checkKind enforces the rules that depend on the method kind, which
+// struct tags cannot express.
+func checkKind(p types.CreateShippingMethodPayload) error {
+	if p.Kind == types.ShippingWeightBased && p.PerKg == nil {
+		return fmt.Errorf("a weight-based method needs perKg")
+	}
+	if p.Kind != types.ShippingWeightBased && p.PerKg != nil {
+		return fmt.Errorf("perKg only applies to weight-based methods")
+	}
+	if p.Kind == types.ShippingFreeOver && p.FreeOver == nil {
+		return fmt.Errorf("a free-over method needs freeOver")
+	}
+	if p.Kind != types.ShippingFreeOver && p.FreeOver != nil {
+		return fmt.Errorf("freeOver only applies to free-over methods")
+	}
+	return nil
+}
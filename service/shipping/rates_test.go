@@ -0,0 +1,58 @@
+package shipping
+
+import (
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/testutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestQuote(t *testing.T) {
+	ptr := func(m types.Money) *types.Money { return &m }
+
+	flat := &types.ShippingMethod{Kind: types.ShippingFlatRate, Rate: testutil.USD(499)}
+	byWeight := &types.ShippingMethod{Kind: types.ShippingWeightBased, Rate: testutil.USD(300), PerKg: ptr(testutil.USD(150))}
+	freeOver := &types.ShippingMethod{Kind: types.ShippingFreeOver, Rate: testutil.USD(599), FreeOver: ptr(testutil.USD(5000))}
+
+	tests := []struct {
+		name   string
+		method *types.ShippingMethod
+		parcel Parcel
+		want   types.Money
+	}{
+		{"flat rate", flat, Parcel{Value: testutil.USD(10000), Grams: 25000}, testutil.USD(499)},
+		{"weight without weight", byWeight, Parcel{Value: testutil.USD(1000)}, testutil.USD(300)},
+		{"weight rounds up to the kilogram", byWeight, Parcel{Value: testutil.USD(1000), Grams: 1001}, testutil.USD(600)},
+		{"weight of whole kilograms", byWeight, Parcel{Value: testutil.USD(1000), Grams: 3000}, testutil.USD(750)},
+		{"below the free threshold", freeOver, Parcel{Value: testutil.USD(4999)}, testutil.USD(599)},
+		{"at the free threshold", freeOver, Parcel{Value: testutil.USD(5000)}, testutil.USD(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Quote(tt.method, tt.parcel); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWeigh(t *testing.T) {
+	weight := func(g int) *int { return &g }
+
+	products := map[int]*types.Product{
+		1: {ID: 1, Weight: weight(800)},
+		// 300x200x100 mm weighs 1200 g by volume
+		2: {ID: 2, Weight: weight(500), Dimensions: &types.Dimensions{Length: 300, Width: 200, Height: 100}},
+		3: {ID: 3},
+	}
+	items := []*types.OrderItem{
+		{ProductID: 1, Quantity: 2},
+		{ProductID: 2, Quantity: 1},
+		{ProductID: 3, Quantity: 5},
+	}
+
+	if got := Weigh(items, products); got != 2800 {
+		t.Errorf("expected 2800 g, got %d", got)
+	}
+}
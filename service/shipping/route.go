@@ -0,0 +1,194 @@
+package shipping
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves shipping zone and method management to admins. Quotes
+// are served by the cart package, which prices the cart.
+type Handler struct {
+	store     types.ShippingStore
+	userStore types.UserStore
+}
+
+// NewHandler creates a new Handler. The user store authorizes admin-only
+// operations.
+func NewHandler(store types.ShippingStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+// RegisterRoutes attaches shipping routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/shipping/zones", auth.RequireAdmin(h.handleListZones, h.userStore)).Methods("GET")
+	router.HandleFunc("/shipping/zones", auth.RequireAdmin(h.handleCreateZone, h.userStore)).Methods("POST")
+	router.HandleFunc("/shipping/zones/{id:[0-9]+}/methods", auth.RequireAdmin(h.handleCreateMethod, h.userStore)).Methods("POST")
+	router.HandleFunc("/shipping/methods/{id:[0-9]+}", auth.RequireAdmin(h.handleDeleteMethod, h.userStore)).Methods("DELETE")
+}
+
+func (h *Handler) handleListZones(w http.ResponseWriter, r *http.Request) {
+	zones, err := h.store.ListShippingZones()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list shipping zones: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListShippingZonesResponse{
+		Message: "success",
+		Data:    zones,
+	})
+}
+
+func (h *Handler) handleCreateZone(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateShippingZonePayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	zones, err := h.store.ListShippingZones()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if status, err := checkCountries(payload.Countries, zones); err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	zone := &types.ShippingZone{Name: payload.Name, Countries: payload.Countries}
+	if err := h.store.CreateShippingZone(zone); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to create shipping zone: %v", err))
+		return
+	}
+
+	created, err := h.store.GetShippingZoneByID(zone.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.ShippingZoneResponse{
+		Message: "shipping zone created",
+		Data:    created,
+	})
+}
+
+func (h *Handler) handleCreateMethod(w http.ResponseWriter, r *http.Request) {
+	zoneID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	var payload types.CreateShippingMethodPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := checkKind(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	zone, err := h.store.GetShippingZoneByID(zoneID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if zone == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("shipping zone not found"))
+		return
+	}
+
+	m := &types.ShippingMethod{
+		ZoneID:   zone.ID,
+		Name:     payload.Name,
+		Kind:     payload.Kind,
+		Rate:     payload.Rate,
+		PerKg:    payload.PerKg,
+		FreeOver: payload.FreeOver,
+	}
+	if err := h.store.CreateShippingMethod(m); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to create shipping method: %v", err))
+		return
+	}
+
+	created, err := h.store.GetShippingMethodByID(m.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.ShippingMethodResponse{
+		Message: "shipping method created",
+		Data:    created,
+	})
+}
+
+func (h *Handler) handleDeleteMethod(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	m, err := h.store.GetShippingMethodByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if m == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("shipping method not found"))
+		return
+	}
+
+	if err := h.store.DeleteShippingMethod(id); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ShippingMethodResponse{
+		Message: "shipping method deleted",
+		Data:    m,
+	})
+}
+
+// checkCountries checks the countries of a new zone are listed once and
+// belong to no other zone, and that there is at most one zone without
+// countries. It returns the HTTP status to report alongside the error.
+func checkCountries(countries []string, zones []*types.ShippingZone) (int, error) {
+	seen := make(map[string]bool)
+	for _, c := range countries {
+		if seen[c] {
+			return http.StatusBadRequest, fmt.Errorf("country %s is listed twice", c)
+		}
+		seen[c] = true
+	}
+
+	for _, z := range zones {
+		if len(countries) == 0 && len(z.Countries) == 0 {
+			return http.StatusConflict, fmt.Errorf("shipping zone %s already covers the rest of the world", z.Name)
+		}
+		for _, c := range z.Countries {
+			if seen[c] {
+				return http.StatusConflict, fmt.Errorf("country %s already belongs to shipping zone %s", c, z.Name)
+			}
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,203 @@
+package shipping
+
+import (
+	"database/sql"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// methodColumns lists the columns read by scanMethod, in scan order.
+const methodColumns = "id, zoneId, name, kind, rate, perKg, freeOver, createdAt"
+
+// Store implements types.ShippingStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanMethod(row sqlutil.Scanner) (*types.ShippingMethod, error) {
+	m := new(types.ShippingMethod)
+	var rate string
+	var perKg, freeOver sql.NullString
+	err := row.Scan(&m.ID, &m.ZoneID, &m.Name, &m.Kind, &rate, &perKg, &freeOver, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if m.Rate, err = types.ParseMoney(rate, types.DefaultCurrency); err != nil {
+		return nil, err
+	}
+	if perKg.Valid {
+		v, err := types.ParseMoney(perKg.String, types.DefaultCurrency)
+		if err != nil {
+			return nil, err
+		}
+		m.PerKg = &v
+	}
+	if freeOver.Valid {
+		v, err := types.ParseMoney(freeOver.String, types.DefaultCurrency)
+		if err != nil {
+			return nil, err
+		}
+		m.FreeOver = &v
+	}
+	return m, nil
+}
+
+// ListShippingZones returns every zone with its countries and methods,
+// in the order they were created.
+func (s *Store) ListShippingZones() ([]*types.ShippingZone, error) {
+	return s.queryZones("")
+}
+
+// GetShippingZoneByID returns nil when there is no zone with that ID.
+func (s *Store) GetShippingZoneByID(id int) (*types.ShippingZone, error) {
+	zones, err := s.queryZones("WHERE id = ?", id)
+	if err != nil || len(zones) == 0 {
+		return nil, err
+	}
+	return zones[0], nil
+}
+
+// FindShippingZone returns the zone listing country, else the first zone
+// without countries, else nil.
+func (s *Store) FindShippingZone(country string) (*types.ShippingZone, error) {
+	zones, err := s.queryZones(
+		`WHERE id = COALESCE(
+		    (SELECT c.zoneId FROM shipping_zone_countries c WHERE c.country = ?),
+		    (SELECT MIN(z.id) FROM shipping_zones z WHERE NOT EXISTS (SELECT 1 FROM shipping_zone_countries c WHERE c.zoneId = z.id)))`,
+		country,
+	)
+	if err != nil || len(zones) == 0 {
+		return nil, err
+	}
+	return zones[0], nil
+}
+
+// queryZones loads the zones selected by where with their countries and
+// methods.
+func (s *Store) queryZones(where string, args ...any) ([]*types.ShippingZone, error) {
+	rows, err := s.db.Query("SELECT id, name, createdAt FROM shipping_zones "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []*types.ShippingZone{}
+	byID := make(map[int]*types.ShippingZone)
+	for rows.Next() {
+		z := &types.ShippingZone{Countries: []string{}, Methods: []*types.ShippingMethod{}}
+		if err := rows.Scan(&z.ID, &z.Name, &z.CreatedAt); err != nil {
+			return nil, err
+		}
+		zones = append(zones, z)
+		byID[z.ID] = z
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(zones) == 0 {
+		return zones, nil
+	}
+
+	in := "WHERE zoneId IN (SELECT id FROM shipping_zones " + where + ")"
+	countries, err := s.db.Query("SELECT zoneId, country FROM shipping_zone_countries "+in+" ORDER BY country", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer countries.Close()
+	for countries.Next() {
+		var zoneID int
+		var country string
+		if err := countries.Scan(&zoneID, &country); err != nil {
+			return nil, err
+		}
+		if z, ok := byID[zoneID]; ok {
+			z.Countries = append(z.Countries, country)
+		}
+	}
+	if err := countries.Err(); err != nil {
+		return nil, err
+	}
+
+	methods, err := s.db.Query("SELECT "+methodColumns+" FROM shipping_methods "+in+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer methods.Close()
+	for methods.Next() {
+		m, err := scanMethod(methods)
+		if err != nil {
+			return nil, err
+		}
+		if z, ok := byID[m.ZoneID]; ok {
+			z.Methods = append(z.Methods, m)
+		}
+	}
+	return zones, methods.Err()
+}
+
+// CreateShippingZone inserts the zone with its countries in one
+// transaction and sets its ID.
+func (s *Store) CreateShippingZone(zone *types.ShippingZone) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO shipping_zones (name) VALUES (?)", zone.Name)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	zone.ID = int(id)
+
+	for _, country := range zone.Countries {
+		if _, err := tx.Exec("INSERT INTO shipping_zone_countries (zoneId, country) VALUES (?, ?)", zone.ID, country); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CreateShippingMethod inserts the method and sets its ID.
+func (s *Store) CreateShippingMethod(m *types.ShippingMethod) error {
+	result, err := s.db.Exec(
+		"INSERT INTO shipping_methods (zoneId, name, kind, rate, perKg, freeOver) VALUES (?, ?, ?, ?, ?, ?)",
+		m.ZoneID, m.Name, m.Kind, m.Rate, m.PerKg, m.FreeOver,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	m.ID = int(id)
+	return nil
+}
+
+// GetShippingMethodByID returns nil when there is no method with that ID.
+func (s *Store) GetShippingMethodByID(id int) (*types.ShippingMethod, error) {
+	m, err := scanMethod(s.db.QueryRow("SELECT "+methodColumns+" FROM shipping_methods WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+// DeleteShippingMethod removes a method. Orders shipped with it keep its
+// name and cost.
+func (s *Store) DeleteShippingMethod(id int) error {
+	_, err := s.db.Exec("DELETE FROM shipping_methods WHERE id = ?", id)
+	return err
+}
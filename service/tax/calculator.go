@@ -0,0 +1,144 @@
+// Package tax works out the taxes of orders from a table of rates by
+// country, region and tax class, and lets admins manage that table.
+package tax
+
+import (
+	"math"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// rateScale is the denominator of a rate as an integer: a percentage with
+// four decimals is a fraction with six.
+const rateScale = 1_000_000
+
+// Calculator implements types.TaxCalculator with the rates table of store.
+type Calculator struct {
+	store types.TaxRateStore
+}
+
+// NewCalculator creates a Calculator reading rates from store.
+func NewCalculator(store types.TaxRateStore) *Calculator {
+	return &Calculator{store: store}
+}
+
+// CalculateTax loads the rates of the order's destination and applies
+// them. Orders without a Country are not taxed.
+func (c *Calculator) CalculateTax(order *types.Order) error {
+	var rates []*types.TaxRate
+	if order.Country != "" {
+		var err error
+		if rates, err = c.store.FindTaxRates(order.Country, order.Region); err != nil {
+			return err
+		}
+	}
+	Apply(order, rates)
+	return nil
+}
+
+// Apply taxes the items of order with rates, the rates of its destination,
+// and sets the tax lines and totals as described on types.TaxCalculator.
+//
+// The coupon discount is spread over the items in proportion to their
+// amounts, so every item is taxed on what the customer actually pays for
+// it. Each rate of an item's tax class gives one tax line, rounded half to
+// even on the item. Inclusive rates are taken out of the item amount
+// together: with 20% VAT, 120.00 holds 20.00 of tax. Exclusive rates are
+// charged on the amount left once the inclusive taxes are out. The order
+// lines sum the item lines with the same name, rate and inclusiveness, and
+// the total of an item is its share of the order total before shipping.
+func Apply(order *types.Order, rates []*types.TaxRate) {
+	order.Tax = types.NewMoney(0, order.Currency)
+	order.TaxLines = nil
+	exclusive := types.NewMoney(0, order.Currency)
+
+	amounts := allocateDiscount(order)
+	for i, item := range order.Items {
+		item.TaxLines = nil
+
+		class := item.TaxClass
+		if class == "" {
+			class = types.DefaultTaxClass
+		}
+		var matching []*types.TaxRate
+		var inclusive int64
+		for _, r := range rates {
+			if r.TaxClass != class {
+				continue
+			}
+			matching = append(matching, r)
+			if r.Inclusive {
+				inclusive += basisPoints(r.Rate)
+			}
+		}
+
+		net := amounts[i]
+		for _, r := range matching {
+			if r.Inclusive {
+				net = net.Sub(amounts[i].MulRatio(basisPoints(r.Rate), rateScale+inclusive))
+			}
+		}
+
+		item.Total = amounts[i]
+		for _, r := range matching {
+			line := &types.TaxLine{Name: r.Name, Rate: r.Rate, Inclusive: r.Inclusive}
+			if r.Inclusive {
+				line.Amount = amounts[i].MulRatio(basisPoints(r.Rate), rateScale+inclusive)
+			} else {
+				line.Amount = net.MulRatio(basisPoints(r.Rate), rateScale)
+				exclusive = exclusive.Add(line.Amount)
+				item.Total = item.Total.Add(line.Amount)
+			}
+			item.TaxLines = append(item.TaxLines, line)
+			order.Tax = order.Tax.Add(line.Amount)
+			order.TaxLines = addLine(order.TaxLines, line)
+		}
+	}
+
+	order.Total = order.Subtotal.Sub(order.Discount).Add(exclusive)
+}
+
+// basisPoints returns a percentage as a fraction of rateScale.
+func basisPoints(rate float64) int64 {
+	return int64(math.Round(rate * 10_000))
+}
+
+// allocateDiscount returns the amount of every item of order less its
+// share of the order discount. The shares are proportional to the item
+// amounts and rounded half to even; what rounding leaves over goes to the
+// largest item so the shares add up to the discount.
+func allocateDiscount(order *types.Order) []types.Money {
+	amounts := make([]types.Money, len(order.Items))
+	largest := -1
+	for i, item := range order.Items {
+		amounts[i] = item.Price.Mul(item.Quantity)
+		if largest < 0 || amounts[i].Cmp(amounts[largest]) > 0 {
+			largest = i
+		}
+	}
+	if order.Discount.IsZero() || order.Subtotal.IsZero() || largest < 0 {
+		return amounts
+	}
+
+	left := order.Discount
+	for i := range amounts {
+		share := amounts[i].MulRatio(order.Discount.Amount, order.Subtotal.Amount)
+		amounts[i] = amounts[i].Sub(share)
+		left = left.Sub(share)
+	}
+	amounts[largest] = amounts[largest].Sub(left)
+	return amounts
+}
+
+// addLine adds line to the order lines, summing it into the one with the
+// same name, rate and inclusiveness if there is one.
+func addLine(lines []*types.TaxLine, line *types.TaxLine) []*types.TaxLine {
+	for _, l := range lines {
+		if l.Name == line.Name && l.Rate == line.Rate && l.Inclusive == line.Inclusive {
+			l.Amount = l.Amount.Add(line.Amount)
+			return lines
+		}
+	}
+	sum := *line
+	return append(lines, &sum)
+}
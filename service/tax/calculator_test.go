@@ -0,0 +1,123 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/testutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestApply(t *testing.T) {
+	state := &types.TaxRate{Country: "US", TaxClass: types.DefaultTaxClass, Name: "State", Rate: 6.25}
+	county := &types.TaxRate{Country: "US", Region: "CA", TaxClass: types.DefaultTaxClass, Name: "County", Rate: 1}
+	vat := &types.TaxRate{Country: "GB", TaxClass: types.DefaultTaxClass, Name: "VAT", Rate: 20, Inclusive: true}
+	reducedVAT := &types.TaxRate{Country: "GB", TaxClass: "food", Name: "VAT", Rate: 5, Inclusive: true}
+	tenPercent := &types.TaxRate{Country: "US", TaxClass: types.DefaultTaxClass, Name: "Sales", Rate: 10}
+
+	tests := []struct {
+		name      string
+		items     []*types.OrderItem
+		discount  types.Money
+		rates     []*types.TaxRate
+		tax       types.Money
+		total     types.Money
+		lineCount int
+	}{
+		{
+			"no rates",
+			[]*types.OrderItem{{Price: testutil.USD(1999), Quantity: 2}},
+			testutil.USD(0), nil, testutil.USD(0), testutil.USD(3998), 0,
+		},
+		{
+			"exclusive on its class only",
+			[]*types.OrderItem{{Price: testutil.USD(1999), Quantity: 2}, {Price: testutil.USD(500), Quantity: 3, TaxClass: "food"}},
+			testutil.USD(0), []*types.TaxRate{state}, testutil.USD(250), testutil.USD(5748), 1,
+		},
+		{
+			"country and region add up",
+			[]*types.OrderItem{{Price: testutil.USD(1999), Quantity: 2}},
+			testutil.USD(0), []*types.TaxRate{state, county}, testutil.USD(290), testutil.USD(4288), 2,
+		},
+		{
+			"inclusive by class",
+			[]*types.OrderItem{{Price: testutil.USD(1999), Quantity: 2}, {Price: testutil.USD(500), Quantity: 3, TaxClass: "food"}},
+			testutil.USD(0), []*types.TaxRate{vat, reducedVAT}, testutil.USD(737), testutil.USD(5498), 2,
+		},
+		{
+			"discount spread over items",
+			[]*types.OrderItem{{Price: testutil.USD(1999), Quantity: 2}, {Price: testutil.USD(500), Quantity: 3, TaxClass: "food"}},
+			testutil.USD(1000), []*types.TaxRate{tenPercent}, testutil.USD(327), testutil.USD(4825), 1,
+		},
+		{
+			"exclusive on top of inclusive",
+			[]*types.OrderItem{{Price: testutil.USD(1100), Quantity: 1}},
+			testutil.USD(0), []*types.TaxRate{{TaxClass: types.DefaultTaxClass, Name: "VAT", Rate: 10, Inclusive: true}, {TaxClass: types.DefaultTaxClass, Name: "Levy", Rate: 5}},
+			testutil.USD(150), testutil.USD(1150), 2,
+		},
+		{
+			"same rate summed across items",
+			[]*types.OrderItem{{Price: testutil.USD(1000), Quantity: 1}, {Price: testutil.USD(250), Quantity: 2}},
+			testutil.USD(0), []*types.TaxRate{tenPercent}, testutil.USD(150), testutil.USD(1650), 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &types.Order{Currency: types.DefaultCurrency, Discount: tt.discount, Items: tt.items}
+			order.Subtotal = testutil.USD(0)
+			for _, item := range tt.items {
+				order.Subtotal = order.Subtotal.Add(item.Price.Mul(item.Quantity))
+			}
+
+			Apply(order, tt.rates)
+
+			if order.Tax != tt.tax || order.Total != tt.total {
+				t.Errorf("expected tax %s and total %s, got %s and %s", tt.tax, tt.total, order.Tax, order.Total)
+			}
+			if len(order.TaxLines) != tt.lineCount {
+				t.Errorf("expected %d order tax lines, got %d", tt.lineCount, len(order.TaxLines))
+			}
+
+			// the item lines add up to the order lines
+			sum := testutil.USD(0)
+			for _, item := range order.Items {
+				for _, line := range item.TaxLines {
+					sum = sum.Add(line.Amount)
+				}
+			}
+			if sum != order.Tax {
+				t.Errorf("item tax lines sum to %s, expected %s", sum, order.Tax)
+			}
+
+			// and the item totals to the order total
+			paid := testutil.USD(0)
+			for _, item := range order.Items {
+				paid = paid.Add(item.Total)
+			}
+			if paid != order.Total {
+				t.Errorf("item totals sum to %s, expected %s", paid, order.Total)
+			}
+		})
+	}
+}
+
+func TestAllocateDiscount(t *testing.T) {
+	order := &types.Order{
+		Subtotal: testutil.USD(300),
+		Discount: testutil.USD(100),
+		Items: []*types.OrderItem{
+			{Price: testutil.USD(100), Quantity: 1},
+			{Price: testutil.USD(100), Quantity: 1},
+			{Price: testutil.USD(100), Quantity: 1},
+		},
+	}
+
+	amounts := allocateDiscount(order)
+	total := testutil.USD(0)
+	for _, a := range amounts {
+		total = total.Add(a)
+	}
+	if total != testutil.USD(200) {
+		t.Errorf("expected the items to keep 2.00 in all, got %s (%v)", total, amounts)
+	}
+}
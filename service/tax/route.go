@@ -0,0 +1,139 @@
+package tax
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves the tax rates table to admins.
+type Handler struct {
+	store     types.TaxRateStore
+	userStore types.UserStore
+}
+
+// NewHandler creates a new Handler. The user store authorizes admin-only
+// operations.
+func NewHandler(store types.TaxRateStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+// RegisterRoutes attaches tax rate routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/tax-rates", auth.RequireAdmin(h.handleListRates, h.userStore)).Methods("GET")
+	router.HandleFunc("/tax-rates", auth.RequireAdmin(h.handleCreateRate, h.userStore)).Methods("POST")
+	router.HandleFunc("/tax-rates/{id:[0-9]+}", auth.RequireAdmin(h.handleDeleteRate, h.userStore)).Methods("DELETE")
+}
+
+func (h *Handler) handleListRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.store.ListTaxRates()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list tax rates: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListTaxRatesResponse{
+		Message: "success",
+		Data:    rates,
+	})
+}
+
+func (h *Handler) handleCreateRate(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateTaxRatePayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	// stored with four decimals
+	if basis := payload.Rate * 10_000; math.Abs(basis-math.Round(basis)) > 1e-6 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("rate takes at most four decimals"))
+		return
+	}
+
+	rate := &types.TaxRate{
+		Country:   payload.Country,
+		Region:    payload.Region,
+		TaxClass:  payload.TaxClass,
+		Name:      payload.Name,
+		Rate:      payload.Rate,
+		Inclusive: payload.Inclusive,
+	}
+	if rate.TaxClass == "" {
+		rate.TaxClass = types.DefaultTaxClass
+	}
+
+	existing, err := h.store.FindTaxRates(rate.Country, rate.Region)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, e := range existing {
+		if strings.EqualFold(e.Region, rate.Region) && e.TaxClass == rate.TaxClass && e.Name == rate.Name {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("tax rate %s already exists for %s", rate.Name, where(rate)))
+			return
+		}
+	}
+
+	if err := h.store.CreateTaxRate(rate); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to create tax rate: %v", err))
+		return
+	}
+
+	created, err := h.store.GetTaxRateByID(rate.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.TaxRateResponse{
+		Message: "tax rate created",
+		Data:    created,
+	})
+}
+
+func (h *Handler) handleDeleteRate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	rate, err := h.store.GetTaxRateByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if rate == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("tax rate not found"))
+		return
+	}
+
+	if err := h.store.DeleteTaxRate(id); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.TaxRateResponse{
+		Message: "tax rate deleted",
+		Data:    rate,
+	})
+}
+
+// where describes the place a rate applies to in error messages.
+func where(rate *types.TaxRate) string {
+	if rate.Region == "" {
+		return rate.Country
+	}
+	return rate.Country + "/" + rate.Region
+}
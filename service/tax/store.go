@@ -0,0 +1,126 @@
+package tax
+
+import (
+	"database/sql"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// taxRateColumns lists the columns read by scanTaxRate, in scan order.
+const taxRateColumns = "id, country, region, taxClass, name, rate, inclusive, createdAt"
+
+// Store implements types.TaxRateStore on top of MySQL.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store given a previously opened *sql.DB.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func scanTaxRate(row sqlutil.Scanner) (*types.TaxRate, error) {
+	r := new(types.TaxRate)
+	err := row.Scan(
+		&r.ID,
+		&r.Country,
+		&r.Region,
+		&r.TaxClass,
+		&r.Name,
+		&r.Rate,
+		&r.Inclusive,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *Store) queryTaxRates(where string, args ...any) ([]*types.TaxRate, error) {
+	rows, err := s.db.Query("SELECT "+taxRateColumns+" FROM tax_rates "+where+" ORDER BY country, region, taxClass, name", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*types.TaxRate{}
+	for rows.Next() {
+		r, err := scanTaxRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
+
+// ListTaxRates returns the whole tax rates table.
+func (s *Store) ListTaxRates() ([]*types.TaxRate, error) {
+	return s.queryTaxRates("")
+}
+
+// FindTaxRates returns the rates for the whole country and the ones for
+// region, which is matched ignoring case.
+func (s *Store) FindTaxRates(country, region string) ([]*types.TaxRate, error) {
+	return s.queryTaxRates("WHERE country = ? AND (region = '' OR region = ?)", country, region)
+}
+
+// GetTaxRateByID returns nil when there is no rate with that ID.
+func (s *Store) GetTaxRateByID(id int) (*types.TaxRate, error) {
+	rates, err := s.queryTaxRates("WHERE id = ?", id)
+	if err != nil || len(rates) == 0 {
+		return nil, err
+	}
+	return rates[0], nil
+}
+
+// CreateTaxRate inserts rate and sets its ID.
+func (s *Store) CreateTaxRate(rate *types.TaxRate) error {
+	res, err := s.db.Exec(
+		"INSERT INTO tax_rates (country, region, taxClass, name, rate, inclusive) VALUES (?, ?, ?, ?, ?, ?)",
+		rate.Country, rate.Region, rate.TaxClass, rate.Name, rate.Rate, rate.Inclusive,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	rate.ID = int(id)
+	return nil
+}
+
+// DeleteTaxRate removes a rate. Orders keep the tax lines worked out with
+// it.
+func (s *Store) DeleteTaxRate(id int) error {
+	_, err := s.db.Exec("DELETE FROM tax_rates WHERE id = ?", id)
+	return err
+}
+
+// RecordOrderTaxes stores the tax lines of an order and of its items
+// inside tx, the transaction creating the order, once the items have
+// their IDs.
+func RecordOrderTaxes(tx *sql.Tx, order *types.Order) error {
+	for _, line := range order.TaxLines {
+		if _, err := tx.Exec(
+			"INSERT INTO order_taxes (orderId, name, rate, inclusive, amount) VALUES (?, ?, ?, ?, ?)",
+			order.ID, line.Name, line.Rate, line.Inclusive, line.Amount,
+		); err != nil {
+			return err
+		}
+	}
+	for _, item := range order.Items {
+		for _, line := range item.TaxLines {
+			if _, err := tx.Exec(
+				"INSERT INTO order_item_taxes (orderItemId, name, rate, inclusive, amount) VALUES (?, ?, ?, ?, ?)",
+				item.ID, line.Name, line.Rate, line.Inclusive, line.Amount,
+			); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
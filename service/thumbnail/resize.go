@@ -0,0 +1,86 @@
+// Package thumbnail generates resized variants of uploaded product images
+// in a background worker. Resizing is done in pure Go so no image library
+// or external tool needs to be installed on the server.
+package thumbnail
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// Fit returns the dimensions of a w x h image scaled down to fit inside a
+// limit x limit box while keeping its aspect ratio. Images that already fit
+// are never scaled up.
+func Fit(w, h, limit int) (int, int) {
+	if w <= limit && h <= limit {
+		return w, h
+	}
+	if w >= h {
+		return limit, max(1, h*limit/w)
+	}
+	return max(1, w*limit/h), limit
+}
+
+// Resize scales src to width x height using area averaging: each output
+// pixel is the weighted mean of the source pixels it covers. This gives
+// good quality for the downscaling thumbnails need and has no external
+// dependencies.
+func Resize(src image.Image, width, height int) *image.RGBA {
+	b := src.Bounds()
+	in, ok := src.(*image.RGBA)
+	if !ok || in.Bounds().Min != (image.Point{}) {
+		in = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(in, in.Bounds(), src, b.Min, draw.Src)
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	sw, sh := float64(b.Dx()), float64(b.Dy())
+	sx, sy := sw/float64(width), sh/float64(height)
+
+	for y := 0; y < height; y++ {
+		y0, y1 := float64(y)*sy, float64(y+1)*sy
+		for x := 0; x < width; x++ {
+			x0, x1 := float64(x)*sx, float64(x+1)*sx
+
+			var r, g, bl, a, total float64
+			for iy := int(y0); iy < int(math.Ceil(y1)) && iy < b.Dy(); iy++ {
+				wy := overlap(y0, y1, float64(iy))
+				for ix := int(x0); ix < int(math.Ceil(x1)) && ix < b.Dx(); ix++ {
+					weight := wy * overlap(x0, x1, float64(ix))
+					i := in.PixOffset(ix, iy)
+					r += float64(in.Pix[i]) * weight
+					g += float64(in.Pix[i+1]) * weight
+					bl += float64(in.Pix[i+2]) * weight
+					a += float64(in.Pix[i+3]) * weight
+					total += weight
+				}
+			}
+
+			o := out.PixOffset(x, y)
+			if total > 0 {
+				out.Pix[o] = uint8(r/total + 0.5)
+				out.Pix[o+1] = uint8(g/total + 0.5)
+				out.Pix[o+2] = uint8(bl/total + 0.5)
+				out.Pix[o+3] = uint8(a/total + 0.5)
+			}
+		}
+	}
+
+	return out
+}
+
+// overlap returns how much of the unit interval [p, p+1) lies in [lo, hi).
+func overlap(lo, hi, p float64) float64 {
+	start, end := p, p+1
+	if lo > start {
+		start = lo
+	}
+	if hi < end {
+		end = hi
+	}
+	if end <= start {
+		return 0
+	}
+	return end - start
+}
@@ -0,0 +1,56 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	cases := []struct {
+		w, h, limit  int
+		wantW, wantH int
+	}{
+		{2000, 1000, 600, 600, 300},
+		{1000, 2000, 600, 300, 600},
+		{100, 50, 600, 100, 50}, // never upscaled
+		{5000, 1, 150, 150, 1},
+	}
+	for _, c := range cases {
+		w, h := Fit(c.w, c.h, c.limit)
+		if w != c.wantW || h != c.wantH {
+			t.Errorf("Fit(%d, %d, %d) = %dx%d, want %dx%d", c.w, c.h, c.limit, w, h, c.wantW, c.wantH)
+		}
+	}
+}
+
+func TestResizeAveragesPixels(t *testing.T) {
+	// a 4x2 image whose left half is black and right half is white
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{A: 255}
+			if x >= 2 {
+				c = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	out := Resize(src, 1, 1)
+	got := out.RGBAAt(0, 0)
+	if got.R < 127 || got.R > 128 || got.A != 255 {
+		t.Errorf("expected mid grey, got %v", got)
+	}
+
+	out = Resize(src, 2, 1)
+	if l, r := out.RGBAAt(0, 0), out.RGBAAt(1, 0); l.R != 0 || r.R != 255 {
+		t.Errorf("expected black and white halves, got %v and %v", l, r)
+	}
+}
+
+func TestSizeKey(t *testing.T) {
+	if got := SizeKey("products/1/ab12.jpeg", "thumbnail", ".jpg"); got != "products/1/ab12_thumbnail.jpg" {
+		t.Errorf("unexpected key %s", got)
+	}
+}
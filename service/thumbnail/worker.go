@@ -0,0 +1,219 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// Size is a named bounding box resized variants are scaled to fit in.
+type Size struct {
+	Name string
+	Max  int
+}
+
+// Sizes are the variants generated for every product image.
+var Sizes = []Size{
+	{Name: "thumbnail", Max: 150},
+	{Name: "medium", Max: 600},
+	{Name: "large", Max: 1200},
+}
+
+// decoders lists the formats that can be resized. WebP is accepted for
+// upload but the standard library cannot decode it, so such images keep
+// only their original.
+var decoders = map[string]func([]byte) (image.Image, error){
+	"image/jpeg": func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) },
+	"image/png":  func(b []byte) (image.Image, error) { return png.Decode(bytes.NewReader(b)) },
+	"image/gif":  func(b []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(b)) },
+}
+
+// decodeConfigs read the dimensions of the formats in decoders from their
+// headers, without decoding the pixels.
+var decodeConfigs = map[string]func(io.Reader) (image.Config, error){
+	"image/jpeg": jpeg.DecodeConfig,
+	"image/png":  png.DecodeConfig,
+	"image/gif":  gif.DecodeConfig,
+}
+
+// ErrTooManyPixels is returned for images declaring more pixels than
+// allowed: decoding them could exhaust the memory of the process.
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// CheckPixels reads the header of an image of contentType from r and
+// returns an error wrapping ErrTooManyPixels when it declares more than
+// maxPixels. Formats that cannot be resized are never decoded, so they are
+// not checked.
+func CheckPixels(r io.Reader, contentType string, maxPixels int64) error {
+	decodeConfig, ok := decodeConfigs[contentType]
+	if !ok {
+		return nil
+	}
+	cfg, err := decodeConfig(r)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return fmt.Errorf("%w: %dx%d is more than %d pixels", ErrTooManyPixels, cfg.Width, cfg.Height, maxPixels)
+	}
+	return nil
+}
+
+// Worker generates resized variants of product images. Uploads are queued
+// with Enqueue and processed one at a time by Run so that resizing never
+// delays the upload request.
+type Worker struct {
+	store     types.ProductStore
+	blobs     types.BlobStore
+	maxPixels int64
+	jobs      chan *types.ProductImage
+}
+
+// NewWorker creates a Worker reading and writing images through blobs and
+// recording the generated variants in store. Images of more than
+// maxPixels are not resized. queueSize bounds the number of images
+// waiting to be processed.
+func NewWorker(store types.ProductStore, blobs types.BlobStore, maxPixels int64, queueSize int) *Worker {
+	return &Worker{
+		store:     store,
+		blobs:     blobs,
+		maxPixels: maxPixels,
+		jobs:      make(chan *types.ProductImage, queueSize),
+	}
+}
+
+// Enqueue schedules img for processing. It never blocks: when the queue is
+// full the image is dropped and false is returned; its variants can be
+// rebuilt later with the regenerate command.
+func (w *Worker) Enqueue(img *types.ProductImage) bool {
+	select {
+	case w.jobs <- img:
+		return true
+	default:
+		log.Printf("thumbnail queue full, skipping image %d", img.ID)
+		return false
+	}
+}
+
+// Run processes queued images until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case img := <-w.jobs:
+			if err := w.Process(img); err != nil {
+				log.Printf("failed to generate sizes for image %d: %v", img.ID, err)
+			}
+		}
+	}
+}
+
+// Process generates every size of img, stores them and records them on
+// the image, replacing any variants generated before.
+func (w *Worker) Process(img *types.ProductImage) error {
+	decode, ok := decoders[img.ContentType]
+	if !ok {
+		log.Printf("skipping image %d: cannot resize %s", img.ID, img.ContentType)
+		return nil
+	}
+
+	rc, err := w.blobs.Get(img.Key)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+
+	err = CheckPixels(bytes.NewReader(buf.Bytes()), img.ContentType, w.maxPixels)
+	if errors.Is(err, ErrTooManyPixels) {
+		log.Printf("skipping image %d: %v", img.ID, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	src, err := decode(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	var sizes []*types.ProductImageSize
+	for _, size := range Sizes {
+		generated, err := w.generate(img, src, size)
+		if err != nil {
+			w.cleanup(sizes)
+			return err
+		}
+		sizes = append(sizes, generated)
+	}
+
+	if err := w.store.SetProductImageSizes(img.ID, sizes); err != nil {
+		// the image was probably deleted while it was being processed
+		w.cleanup(sizes)
+		return err
+	}
+
+	return nil
+}
+
+// generate resizes src to fit size and writes it next to the original.
+// PNG and GIF sources are written as PNG to keep transparency; everything
+// else becomes JPEG.
+func (w *Worker) generate(img *types.ProductImage, src image.Image, size Size) (*types.ProductImageSize, error) {
+	b := src.Bounds()
+	width, height := Fit(b.Dx(), b.Dy(), size.Max)
+	resized := Resize(src, width, height)
+
+	var out bytes.Buffer
+	contentType, ext := "image/jpeg", ".jpg"
+	if img.ContentType == "image/png" || img.ContentType == "image/gif" {
+		contentType, ext = "image/png", ".png"
+		if err := png.Encode(&out, resized); err != nil {
+			return nil, err
+		}
+	} else if err := jpeg.Encode(&out, resized, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+
+	key := SizeKey(img.Key, size.Name, ext)
+	if err := w.blobs.Put(key, &out, contentType); err != nil {
+		return nil, err
+	}
+
+	return &types.ProductImageSize{
+		Size:   size.Name,
+		Key:    key,
+		Width:  width,
+		Height: height,
+	}, nil
+}
+
+func (w *Worker) cleanup(sizes []*types.ProductImageSize) {
+	for _, size := range sizes {
+		w.blobs.Delete(size.Key)
+	}
+}
+
+// SizeKey derives the blob key of a resized variant from the original key,
+// e.g. "products/1/ab12.jpg" becomes "products/1/ab12_thumbnail.jpg".
+func SizeKey(key, size, ext string) string {
+	base := strings.TrimSuffix(key, path.Ext(key))
+	return base + "_" + size + ext
+}
@@ -0,0 +1,51 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// pngHeader returns the signature and IHDR chunk of a PNG declaring width
+// by height pixels, which is all DecodeConfig reads.
+func pngHeader(width, height uint32) []byte {
+	ihdr := []byte("IHDR")
+	ihdr = binary.BigEndian.AppendUint32(ihdr, width)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, height)
+	ihdr = append(ihdr, 8, 2, 0, 0, 0) // 8-bit RGB
+
+	b := []byte("\x89PNG\r\n\x1a\n")
+	b = binary.BigEndian.AppendUint32(b, uint32(len(ihdr)-4))
+	b = append(b, ihdr...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestCheckPixels(t *testing.T) {
+	var small bytes.Buffer
+	if err := png.Encode(&small, image.NewRGBA(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPixels(bytes.NewReader(small.Bytes()), "image/png", 1200); err != nil {
+		t.Errorf("expected 40x30 to fit in 1200 pixels, got %v", err)
+	}
+	if err := CheckPixels(bytes.NewReader(small.Bytes()), "image/png", 1199); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("expected ErrTooManyPixels for 40x30 over 1199 pixels, got %v", err)
+	}
+
+	// a header a few bytes long can declare pixels that would take gigabytes
+	// to decode
+	if err := CheckPixels(bytes.NewReader(pngHeader(30000, 30000)), "image/png", 25_000_000); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("expected ErrTooManyPixels for 30000x30000, got %v", err)
+	}
+
+	if err := CheckPixels(bytes.NewReader([]byte("RIFF")), "image/webp", 1); err != nil {
+		t.Errorf("expected formats that are not resized to pass, got %v", err)
+	}
+	if err := CheckPixels(bytes.NewReader([]byte("not a png")), "image/png", 1200); err == nil {
+		t.Error("expected an undecodable header to be rejected")
+	}
+}
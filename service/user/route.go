@@ -35,6 +35,7 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
     // Define your user-related routes here
     router.HandleFunc("/login", h.handleLogin).Methods("POST")
     router.HandleFunc("/register", h.handleRegister).Methods("POST")
+    router.HandleFunc("/me/preferences", auth.RequireToken(h.handleUpdatePreferences)).Methods("PUT")
 }
 
 
@@ -150,4 +151,41 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 
     // respond with the newly created user data
     utils.WriteJson(w, http.StatusCreated, user)
-} 
\ No newline at end of file
+} 
+
+// handleUpdatePreferences sets the caller's preferred currency, used to
+// show prices when a request does not ask for a currency.
+func (h *Handler) handleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	var payload types.UpdatePreferencesPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	u, err := h.store.GetUserByID(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("user not found"))
+		return
+	}
+
+	if err := h.store.SetUserCurrency(userID, payload.Currency); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	u.Currency = payload.Currency
+
+	utils.WriteJson(w, http.StatusOK, types.UserResponse{
+		Message: "preferences updated",
+		Data:    u,
+	})
+}
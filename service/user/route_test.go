@@ -89,4 +89,8 @@ func (m mockUserStore) GetUserByID(id int) (*types.User, error) {
 
 func (m mockUserStore) CreateUser(user *types.User) error {
     return nil
+}
+
+func (m mockUserStore) SetUserCurrency(id int, currency *string) error {
+    return nil
 } 
\ No newline at end of file
@@ -49,6 +49,7 @@ func (s *Store) GetUserByEmail(email string) (*types.User, error) {
 // methods.
 func ScanRowIntoUser(rows *sql.Rows) (*types.User, error) {
     user := new(types.User)
+    var currency sql.NullString
 
     err := rows.Scan(
         &user.ID,
@@ -57,11 +58,16 @@ func ScanRowIntoUser(rows *sql.Rows) (*types.User, error) {
         &user.Email,
         &user.Password,
         &user.CreatedAt,
+        &user.Role,
+        &currency,
     )
 
     if err != nil {
         return nil, err
     }
+    if currency.Valid {
+        user.Currency = &currency.String
+    }
 
     return user, nil
 }
@@ -100,4 +106,11 @@ func (s *Store) GetUserByID(id int) (*types.User, error) {
     }
 
     return u, nil
-} 
\ No newline at end of file
+} 
+
+// SetUserCurrency sets the currency the user wants prices shown in; nil
+// clears it.
+func (s *Store) SetUserCurrency(id int, currency *string) error {
+	_, err := s.db.Exec("UPDATE users SET currency = ? WHERE id = ?", currency, id)
+	return err
+}
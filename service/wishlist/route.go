@@ -0,0 +1,411 @@
+// Package wishlist lets customers save products for later in named lists,
+// share a list read-only through a token and move items to their cart.
+package wishlist
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+	"github.com/nandaiqbalh/go-backend-ecom/utils"
+)
+
+// Handler serves the wishlists of the signed-in customer and the shared
+// wishlists.
+type Handler struct {
+	store        types.WishlistStore
+	productStore types.ProductStore
+}
+
+// NewHandler creates a new Handler. The product store checks the products
+// saved to and moved from wishlists.
+func NewHandler(store types.WishlistStore, productStore types.ProductStore) *Handler {
+	return &Handler{
+		store:        store,
+		productStore: productStore,
+	}
+}
+
+// RegisterRoutes attaches wishlist routes to the provided router.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/wishlists", auth.RequireToken(h.handleListWishlists)).Methods("GET")
+	router.HandleFunc("/wishlists", auth.RequireToken(h.handleCreateWishlist)).Methods("POST")
+	router.HandleFunc("/wishlists/shared/{token}", h.handleGetSharedWishlist).Methods("GET")
+	router.HandleFunc("/wishlists/{id:[0-9]+}", auth.RequireToken(h.handleGetWishlist)).Methods("GET")
+	router.HandleFunc("/wishlists/{id:[0-9]+}", auth.RequireToken(h.handleDeleteWishlist)).Methods("DELETE")
+	router.HandleFunc("/wishlists/{id:[0-9]+}/share", auth.RequireToken(h.handleShareWishlist)).Methods("POST")
+	router.HandleFunc("/wishlists/{id:[0-9]+}/share", auth.RequireToken(h.handleUnshareWishlist)).Methods("DELETE")
+	router.HandleFunc("/wishlists/{id:[0-9]+}/items", auth.RequireToken(h.handleAddItem)).Methods("POST")
+	router.HandleFunc("/wishlists/{id:[0-9]+}/items/{itemId:[0-9]+}", auth.RequireToken(h.handleRemoveItem)).Methods("DELETE")
+	router.HandleFunc("/wishlists/{id:[0-9]+}/items/{itemId:[0-9]+}/move-to-cart", auth.RequireToken(h.handleMoveToCart)).Methods("POST")
+}
+
+func (h *Handler) handleListWishlists(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	wishlists, err := h.store.ListWishlists(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list wishlists: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.ListWishlistsResponse{
+		Message: "success",
+		Data:    wishlists,
+	})
+}
+
+func (h *Handler) handleCreateWishlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return
+	}
+
+	var payload types.CreateWishlistPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	existing, err := h.store.GetWishlistByName(userID, payload.Name)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if existing != nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("wishlist %s already exists", payload.Name))
+		return
+	}
+
+	wishlist := &types.Wishlist{UserID: userID, Name: payload.Name}
+	if err := h.store.CreateWishlist(wishlist); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to create wishlist: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.WishlistResponse{
+		Message: "wishlist created",
+		Data:    wishlist,
+	})
+}
+
+func (h *Handler) handleGetWishlist(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.WishlistResponse{
+		Message: "success",
+		Data:    wishlist,
+	})
+}
+
+// handleGetSharedWishlist shows a shared wishlist to anyone holding its
+// token, without revealing its owner.
+func (h *Handler) handleGetSharedWishlist(w http.ResponseWriter, r *http.Request) {
+	wishlist, err := h.store.GetWishlistByShareToken(mux.Vars(r)["token"])
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if wishlist == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("wishlist not found"))
+		return
+	}
+	wishlist.UserID = 0
+
+	utils.WriteJson(w, http.StatusOK, types.WishlistResponse{
+		Message: "success",
+		Data:    wishlist,
+	})
+}
+
+func (h *Handler) handleDeleteWishlist(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteWishlist(wishlist.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.WishlistResponse{
+		Message: "wishlist deleted",
+		Data:    &types.Wishlist{ID: wishlist.ID},
+	})
+}
+
+// handleShareWishlist gives the wishlist a share token, keeping the one it
+// already has so links handed out earlier keep working.
+func (h *Handler) handleShareWishlist(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	if wishlist.ShareToken == nil {
+		token, err := newShareToken()
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if err := h.store.SetWishlistShareToken(wishlist.ID, &token); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		wishlist.ShareToken = &token
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.WishlistResponse{
+		Message: "wishlist shared",
+		Data:    wishlist,
+	})
+}
+
+// handleUnshareWishlist revokes the share token; links handed out before
+// stop working.
+func (h *Handler) handleUnshareWishlist(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	if wishlist.ShareToken != nil {
+		if err := h.store.SetWishlistShareToken(wishlist.ID, nil); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		wishlist.ShareToken = nil
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.WishlistResponse{
+		Message: "wishlist no longer shared",
+		Data:    wishlist,
+	})
+}
+
+func (h *Handler) handleAddItem(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.AddWishlistItemPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	product, err := h.productStore.GetProductByID(payload.ProductID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if product == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+	variantID, err := resolveVariant(product, payload.VariantID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, it := range wishlist.Items {
+		if it.ProductID == payload.ProductID && sameVariant(it.VariantID, variantID) {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("product is already in the wishlist"))
+			return
+		}
+	}
+
+	item := &types.WishlistItem{
+		WishlistID: wishlist.ID,
+		ProductID:  product.ID,
+		VariantID:  variantID,
+	}
+	if err := h.store.AddWishlistItem(item); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to add item: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, types.WishlistItemResponse{
+		Message: "item added",
+		Data:    item,
+	})
+}
+
+func (h *Handler) handleRemoveItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.ownItem(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.RemoveWishlistItem(item.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.WishlistItemResponse{
+		Message: "item removed",
+		Data:    item,
+	})
+}
+
+// handleMoveToCart adds a wishlist item to the cart and removes it from
+// the wishlist. The cart may not end up holding more of the product, or
+// variant, than its current stock on hand.
+func (h *Handler) handleMoveToCart(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.ownItem(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.MoveToCartPayload
+	if err := utils.ParseJson(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	product, err := h.productStore.GetProductByID(item.ProductID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if product == nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("product %d is no longer available", item.ProductID))
+		return
+	}
+	// the product may have gained variants since it was saved
+	if item.VariantID == nil && len(product.Variants) > 0 {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("product %d requires a variant; save one of its variants instead", product.ID))
+		return
+	}
+
+	cartItem, err := h.store.MoveWishlistItemToCart(item.ID, payload.Quantity)
+	if err != nil {
+		if errors.Is(err, types.ErrInsufficientStock) {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to move item to cart: %v", err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, types.CartItemResponse{
+		Message: "item moved to cart",
+		Data:    cartItem,
+	})
+}
+
+// ownWishlist loads the wishlist named by the {id} path variable. Another
+// customer's wishlist is reported as not found.
+func (h *Handler) ownWishlist(w http.ResponseWriter, r *http.Request) (*types.Wishlist, bool) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token: missing user id"))
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return nil, false
+	}
+
+	wishlist, err := h.store.GetWishlistByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if wishlist == nil || wishlist.UserID != userID {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("wishlist not found"))
+		return nil, false
+	}
+	return wishlist, true
+}
+
+// ownItem loads the item named by the {itemId} path variable from the
+// caller's wishlist named by {id}.
+func (h *Handler) ownItem(w http.ResponseWriter, r *http.Request) (*types.WishlistItem, bool) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	itemID, err := strconv.Atoi(mux.Vars(r)["itemId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid item id"))
+		return nil, false
+	}
+
+	item := wishlist.Item(itemID)
+	if item == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("item not found"))
+		return nil, false
+	}
+	return item, true
+}
+
+// resolveVariant checks a saved product's variant the way checkout does:
+// products with variants are saved by variant, others without one.
+func resolveVariant(product *types.Product, variantID int) (*int, error) {
+	if len(product.Variants) == 0 {
+		if variantID != 0 {
+			return nil, fmt.Errorf("product %d has no variants", product.ID)
+		}
+		return nil, nil
+	}
+
+	if variantID == 0 {
+		return nil, fmt.Errorf("product %d requires a variantId", product.ID)
+	}
+	for _, v := range product.Variants {
+		if v.ID == variantID {
+			id := v.ID
+			return &id, nil
+		}
+	}
+	return nil, fmt.Errorf("variant %d not found for product %d", variantID, product.ID)
+}
+
+func sameVariant(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// newShareToken returns a random token for a share link.
+func newShareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
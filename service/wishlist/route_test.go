@@ -0,0 +1,138 @@
+package wishlist
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/nandaiqbalh/go-backend-ecom/config"
+	"github.com/nandaiqbalh/go-backend-ecom/service/auth"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// wishlistStore holds wishlist 1 of user 5 and wishlist 2 of user 6, and
+// the catalog: product 1 without variants, product 2 with variant 7.
+type wishlistStore struct {
+	types.WishlistStore
+	types.ProductStore
+	wishlists map[int]*types.Wishlist
+	stock     int
+	cart      int
+}
+
+func newWishlistStore() *wishlistStore {
+	return &wishlistStore{
+		wishlists: map[int]*types.Wishlist{
+			1: {ID: 1, UserID: 5, Items: []*types.WishlistItem{{ID: 10, WishlistID: 1, ProductID: 1}}},
+			2: {ID: 2, UserID: 6},
+		},
+		stock: 3,
+	}
+}
+
+func (s *wishlistStore) GetProductByID(id int) (*types.Product, error) {
+	switch id {
+	case 1:
+		return &types.Product{ID: 1}, nil
+	case 2:
+		return &types.Product{ID: 2, Variants: []*types.ProductVariant{{ID: 7}}}, nil
+	}
+	return nil, nil
+}
+
+func (s *wishlistStore) GetWishlistByID(id int) (*types.Wishlist, error) {
+	return s.wishlists[id], nil
+}
+
+func (s *wishlistStore) AddWishlistItem(item *types.WishlistItem) error {
+	w := s.wishlists[item.WishlistID]
+	item.ID = 10 + len(w.Items)
+	w.Items = append(w.Items, item)
+	return nil
+}
+
+func (s *wishlistStore) MoveWishlistItemToCart(id, quantity int) (*types.CartItem, error) {
+	if s.cart+quantity > s.stock {
+		return nil, fmt.Errorf("%w for product 1", types.ErrInsufficientStock)
+	}
+	s.cart += quantity
+	return &types.CartItem{ID: 1, UserID: 5, ProductID: 1, Quantity: s.cart}, nil
+}
+
+func TestHandleAddItem(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"product", "/wishlists/1/items", `{"productId":2,"variantId":7}`, http.StatusCreated},
+		{"already saved", "/wishlists/1/items", `{"productId":1}`, http.StatusConflict},
+		{"variant required", "/wishlists/1/items", `{"productId":2}`, http.StatusBadRequest},
+		{"unknown variant", "/wishlists/1/items", `{"productId":2,"variantId":8}`, http.StatusBadRequest},
+		{"unknown product", "/wishlists/1/items", `{"productId":3}`, http.StatusNotFound},
+		{"someone else's wishlist", "/wishlists/2/items", `{"productId":1}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newWishlistStore()
+			h := NewHandler(store, store)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/wishlists/{id}/items", auth.RequireToken(h.handleAddItem))
+
+			rr := serve(t, router, tt.path, tt.body)
+			if rr.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleMoveToCart(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"within stock", "/wishlists/1/items/10/move-to-cart", `{"quantity":3}`, http.StatusOK},
+		{"beyond stock", "/wishlists/1/items/10/move-to-cart", `{"quantity":4}`, http.StatusConflict},
+		{"no quantity", "/wishlists/1/items/10/move-to-cart", `{}`, http.StatusBadRequest},
+		{"unknown item", "/wishlists/1/items/11/move-to-cart", `{"quantity":1}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newWishlistStore()
+			h := NewHandler(store, store)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/wishlists/{id}/items/{itemId}/move-to-cart", auth.RequireToken(h.handleMoveToCart))
+
+			rr := serve(t, router, tt.path, tt.body)
+			if rr.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+// serve posts body to path as user 5.
+func serve(t *testing.T, router *mux.Router, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := auth.CreateJWT([]byte(config.Envs.JWTSecret), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
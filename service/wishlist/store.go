@@ -0,0 +1,276 @@
+package wishlist
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/nandaiqbalh/go-backend-ecom/internal/sqlutil"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const wishlistColumns = "id, userId, name, shareToken, createdAt"
+
+func scanWishlist(row sqlutil.Scanner) (*types.Wishlist, error) {
+	w := &types.Wishlist{Items: []*types.WishlistItem{}}
+	var token sql.NullString
+	if err := row.Scan(&w.ID, &w.UserID, &w.Name, &token, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	if token.Valid {
+		w.ShareToken = &token.String
+	}
+	return w, nil
+}
+
+// ListWishlists returns the user's wishlists by name, with their items.
+func (s *Store) ListWishlists(userID int) ([]*types.Wishlist, error) {
+	rows, err := s.db.Query("SELECT "+wishlistColumns+" FROM wishlists WHERE userId = ? ORDER BY name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wishlists := []*types.Wishlist{}
+	byID := make(map[int]*types.Wishlist)
+	for rows.Next() {
+		w, err := scanWishlist(rows)
+		if err != nil {
+			return nil, err
+		}
+		wishlists = append(wishlists, w)
+		byID[w.ID] = w
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items, err := s.db.Query(
+		`SELECT i.id, i.wishlistId, i.productId, i.variantId, i.createdAt
+		   FROM wishlist_items i
+		   JOIN wishlists w ON w.id = i.wishlistId
+		  WHERE w.userId = ?
+		  ORDER BY i.createdAt, i.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer items.Close()
+
+	for items.Next() {
+		item, err := scanItem(items)
+		if err != nil {
+			return nil, err
+		}
+		if w, ok := byID[item.WishlistID]; ok {
+			w.Items = append(w.Items, item)
+		}
+	}
+	return wishlists, items.Err()
+}
+
+// GetWishlistByID returns the wishlist with the given ID, or nil if there
+// is none.
+func (s *Store) GetWishlistByID(id int) (*types.Wishlist, error) {
+	return s.getWishlist("id = ?", id)
+}
+
+// GetWishlistByName returns the user's wishlist with the given name, or
+// nil if there is none.
+func (s *Store) GetWishlistByName(userID int, name string) (*types.Wishlist, error) {
+	return s.getWishlist("userId = ? AND name = ?", userID, name)
+}
+
+// GetWishlistByShareToken returns the wishlist shared with token, or nil
+// if no wishlist is shared with it.
+func (s *Store) GetWishlistByShareToken(token string) (*types.Wishlist, error) {
+	return s.getWishlist("shareToken = ?", token)
+}
+
+func (s *Store) getWishlist(where string, args ...any) (*types.Wishlist, error) {
+	w, err := scanWishlist(s.db.QueryRow("SELECT "+wishlistColumns+" FROM wishlists WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		"SELECT id, wishlistId, productId, variantId, createdAt FROM wishlist_items WHERE wishlistId = ? ORDER BY createdAt, id",
+		w.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		w.Items = append(w.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func scanItem(row sqlutil.Scanner) (*types.WishlistItem, error) {
+	item := new(types.WishlistItem)
+	var variantID sql.NullInt64
+	if err := row.Scan(&item.ID, &item.WishlistID, &item.ProductID, &variantID, &item.CreatedAt); err != nil {
+		return nil, err
+	}
+	item.VariantID = sqlutil.NullableInt(variantID)
+	return item, nil
+}
+
+// CreateWishlist inserts an empty wishlist and fills in the generated
+// fields.
+func (s *Store) CreateWishlist(w *types.Wishlist) error {
+	result, err := s.db.Exec("INSERT INTO wishlists (userId, name) VALUES (?, ?)", w.UserID, w.Name)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	created, err := s.GetWishlistByID(int(id))
+	if err != nil {
+		return err
+	}
+	*w = *created
+	return nil
+}
+
+// DeleteWishlist deletes a wishlist together with its items.
+func (s *Store) DeleteWishlist(id int) error {
+	_, err := s.db.Exec("DELETE FROM wishlists WHERE id = ?", id)
+	return err
+}
+
+// SetWishlistShareToken shares the wishlist with token, or stops sharing
+// it when token is nil.
+func (s *Store) SetWishlistShareToken(id int, token *string) error {
+	_, err := s.db.Exec("UPDATE wishlists SET shareToken = ? WHERE id = ?", token, id)
+	return err
+}
+
+// AddWishlistItem inserts an item and fills in the generated fields.
+func (s *Store) AddWishlistItem(item *types.WishlistItem) error {
+	result, err := s.db.Exec(
+		"INSERT INTO wishlist_items (wishlistId, productId, variantId) VALUES (?, ?, ?)",
+		item.WishlistID, item.ProductID, item.VariantID,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	created, err := scanItem(s.db.QueryRow(
+		"SELECT id, wishlistId, productId, variantId, createdAt FROM wishlist_items WHERE id = ?", id,
+	))
+	if err != nil {
+		return err
+	}
+	*item = *created
+	return nil
+}
+
+// RemoveWishlistItem deletes a wishlist item.
+func (s *Store) RemoveWishlistItem(id int) error {
+	_, err := s.db.Exec("DELETE FROM wishlist_items WHERE id = ?", id)
+	return err
+}
+
+// MoveWishlistItemToCart adds quantity of the item to the cart of the
+// wishlist's owner, merging it with a cart line of the same product or
+// variant, and removes the item from the wishlist. The stock row is locked
+// so the check against its quantity holds until the commit.
+func (s *Store) MoveWishlistItemToCart(id, quantity int) (*types.CartItem, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID, productID int
+	var variantID sql.NullInt64
+	err = tx.QueryRow(
+		`SELECT w.userId, i.productId, i.variantId
+		   FROM wishlist_items i
+		   JOIN wishlists w ON w.id = i.wishlistId
+		  WHERE i.id = ?
+		    FOR UPDATE`,
+		id,
+	).Scan(&userID, &productID, &variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var stock int
+	if variantID.Valid {
+		err = tx.QueryRow("SELECT quantity FROM product_variants WHERE id = ? FOR UPDATE", variantID.Int64).Scan(&stock)
+	} else {
+		err = tx.QueryRow("SELECT quantity FROM products WHERE id = ? FOR UPDATE", productID).Scan(&stock)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var inCart int
+	err = tx.QueryRow(
+		"SELECT quantity FROM cart_items WHERE userId = ? AND productId = ? AND variantKey = ? FOR UPDATE",
+		userID, productID, variantID.Int64,
+	).Scan(&inCart)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if inCart+quantity > stock {
+		return nil, fmt.Errorf("%w for product %d", types.ErrInsufficientStock, productID)
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO cart_items (userId, productId, variantId, quantity) VALUES (?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE quantity = quantity + VALUES(quantity)`,
+		userID, productID, variantID, quantity,
+	); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM wishlist_items WHERE id = ?", id); err != nil {
+		return nil, err
+	}
+
+	item := new(types.CartItem)
+	var cartVariantID sql.NullInt64
+	err = tx.QueryRow(
+		`SELECT id, userId, productId, variantId, quantity, createdAt
+		   FROM cart_items WHERE userId = ? AND productId = ? AND variantKey = ?`,
+		userID, productID, variantID.Int64,
+	).Scan(&item.ID, &item.UserID, &item.ProductID, &cartVariantID, &item.Quantity, &item.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	item.VariantID = sqlutil.NullableInt(cartVariantID)
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
@@ -0,0 +1,139 @@
+// Package storage provides BlobStore implementations used to keep uploaded
+// files. Only a local filesystem backend exists today; an object storage
+// backend can be added by implementing types.BlobStore.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+// LocalStore keeps blobs as files below a root directory and serves them
+// over HTTP under urlPrefix.
+type LocalStore struct {
+	root      string
+	urlPrefix string
+}
+
+// NewLocalStore creates a LocalStore rooted at dir, creating the directory
+// if needed. urlPrefix is the path the files are served under, e.g.
+// "/uploads".
+func NewLocalStore(dir, urlPrefix string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{
+		root:      dir,
+		urlPrefix: "/" + strings.Trim(urlPrefix, "/"),
+	}, nil
+}
+
+// path maps a key to a file below the root, rejecting keys that would
+// escape it.
+func (s *LocalStore) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+// Put writes the blob to a temporary file and renames it into place so
+// readers never observe a partially written file.
+func (s *LocalStore) Put(key string, r io.Reader, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+// Get opens the blob for reading. The caller must close it.
+func (s *LocalStore) Get(key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, types.ErrBlobNotFound
+	}
+	return f, err
+}
+
+// Delete removes the blob. Deleting a missing blob is not an error.
+func (s *LocalStore) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL returns the path the blob is served under.
+func (s *LocalStore) URL(key string) string {
+	return s.urlPrefix + "/" + key
+}
+
+// Prefix returns the URL path prefix the store's files are served under.
+func (s *LocalStore) Prefix() string {
+	return s.urlPrefix + "/"
+}
+
+// ServeHTTP serves a stored file. Keys are never reused for different
+// content, so responses may be cached by clients and proxies for a year.
+// The request path must already have the URL prefix stripped.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p, err := s.path(strings.TrimPrefix(r.URL.Path, "/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nandaiqbalh/go-backend-ecom/types"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("round trips a blob", func(t *testing.T) {
+		if err := store.Put("products/1/a.png", strings.NewReader("png"), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+
+		rc, err := store.Get("products/1/a.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		b, _ := io.ReadAll(rc)
+		if string(b) != "png" {
+			t.Errorf("expected png, got %q", b)
+		}
+
+		if got := store.URL("products/1/a.png"); got != "/uploads/products/1/a.png" {
+			t.Errorf("unexpected url %s", got)
+		}
+	})
+
+	t.Run("rejects keys escaping the root", func(t *testing.T) {
+		if err := store.Put("../evil", strings.NewReader("x"), "text/plain"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("reports missing blobs", func(t *testing.T) {
+		if _, err := store.Get("missing.png"); !errors.Is(err, types.ErrBlobNotFound) {
+			t.Errorf("expected ErrBlobNotFound, got %v", err)
+		}
+	})
+
+	t.Run("serves files with cache headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/products/1/a.png", nil)
+		rr := httptest.NewRecorder()
+		store.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		if cc := rr.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age=31536000") {
+			t.Errorf("unexpected Cache-Control %q", cc)
+		}
+	})
+}
@@ -0,0 +1,299 @@
+package types
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// DefaultCurrency is the currency of the catalog prices.
+const DefaultCurrency = "USD"
+
+// zeroDecimalCurrencies have no minor unit: their amounts are whole.
+var zeroDecimalCurrencies = map[string]bool{
+	"CLP": true, "ISK": true, "JPY": true, "KRW": true, "PYG": true,
+	"UGX": true, "VND": true, "XAF": true, "XOF": true,
+}
+
+// threeDecimalCurrencies have a minor unit of a thousandth.
+var threeDecimalCurrencies = map[string]bool{
+	"BHD": true, "IQD": true, "JOD": true, "KWD": true, "LYD": true,
+	"OMR": true, "TND": true,
+}
+
+// MinorUnits returns the number of decimal places of an amount of
+// currency: 0 for the yen, 3 for the Bahraini dinar and 2 for most
+// others, including an empty currency.
+func MinorUnits(currency string) int {
+	switch {
+	case zeroDecimalCurrencies[currency]:
+		return 0
+	case threeDecimalCurrencies[currency]:
+		return 3
+	}
+	return 2
+}
+
+// ValidCurrency reports whether code looks like an ISO 4217 code whose
+// amounts fit the DECIMAL(10, 2) columns that store prices, which rules
+// out the currencies with three decimals.
+func ValidCurrency(code string) bool {
+	if len(code) != 3 || MinorUnits(code) > 2 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Money is an exact amount of a currency, kept in integer minor units
+// (cents, or yen for the yen) so sums and products do not drift the way
+// floats do. It serializes to JSON as a decimal string such as "19.99" and
+// to SQL as a DECIMAL; the currency is not serialized, so types holding
+// prices carry it in a field of their own.
+//
+// Arithmetic on amounts of different currencies panics. The zero Money has
+// no currency and takes on the currency of the other operand, so it can
+// start a sum.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney returns amount minor units of currency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses a decimal amount such as "19.99" or "-5" of currency.
+// Digits beyond the minor units of currency are rounded half to even.
+func ParseMoney(s, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	digits := strings.TrimPrefix(s, "-")
+	neg := len(digits) < len(s)
+
+	whole, frac, hasDot := strings.Cut(digits, ".")
+	if !isDigits(whole) || (hasDot && !isDigits(frac)) {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+
+	n, _ := new(big.Int).SetString(whole+frac, 10)
+	n.Mul(n, pow10(MinorUnits(currency)))
+	amount := divHalfEven(n, pow10(len(frac)))
+	if !amount.IsInt64() {
+		return Money{}, fmt.Errorf("amount %q is out of range", s)
+	}
+
+	m := Money{Amount: amount.Int64(), Currency: currency}
+	if neg {
+		m.Amount = -m.Amount
+	}
+	return m, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// divHalfEven returns n / d rounded half to even, for n >= 0 and d > 0.
+func divHalfEven(n, d *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	switch r.Lsh(r, 1).Cmp(d) {
+	case 1:
+		q.Add(q, big.NewInt(1))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// String formats the amount as a decimal with the minor units of its
+// currency, such as "19.99" or, for the yen, "1500".
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	digits := MinorUnits(m.Currency)
+	if digits == 0 {
+		return fmt.Sprintf("%s%d", sign, amount)
+	}
+	unit := pow10(digits).Int64()
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/unit, digits, amount%unit)
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Cmp compares the amounts of m and o: -1 if m is less, 0 if they are
+// equal and +1 if m is more.
+func (m Money) Cmp(o Money) int {
+	m.currency(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Add returns m + o.
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currency(o)}
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currency(o)}
+}
+
+// Mul returns m times n, such as the price of n units.
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// MulRatio returns m times num/den rounded half to even to the minor
+// unit, such as a percentage of a price with num/den = 15/100.
+func (m Money) MulRatio(num, den int64) Money {
+	n := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(num))
+	d := big.NewInt(den)
+	neg := n.Sign()*d.Sign() < 0
+	q := divHalfEven(n.Abs(n), d.Abs(d))
+	if neg {
+		q.Neg(q)
+	}
+	return Money{Amount: q.Int64(), Currency: m.Currency}
+}
+
+// In returns the same decimal amount in currency, such as a price decoded
+// from JSON, which is read in DefaultCurrency, for a product priced in
+// another currency. It fails when the amount has more decimals than
+// currency allows.
+func (m Money) In(currency string) (Money, error) {
+	from, to := MinorUnits(m.Currency), MinorUnits(currency)
+	amount := new(big.Int).Mul(big.NewInt(m.Amount), pow10(to))
+	q, r := new(big.Int).QuoRem(amount, pow10(from), new(big.Int))
+	if r.Sign() != 0 {
+		return Money{}, fmt.Errorf("amount %s has more decimals than %s allows", m, currency)
+	}
+	if !q.IsInt64() {
+		return Money{}, fmt.Errorf("amount %s is out of range", m)
+	}
+	return Money{Amount: q.Int64(), Currency: currency}, nil
+}
+
+// Convert returns m in currency at rate, the number of currency units one
+// unit of m's currency buys, rounded half to even to the minor unit.
+func (m Money) Convert(currency string, rate *big.Rat) Money {
+	n := new(big.Int).Mul(big.NewInt(m.Amount), rate.Num())
+	n.Mul(n, pow10(MinorUnits(currency)))
+	d := new(big.Int).Mul(rate.Denom(), pow10(MinorUnits(m.Currency)))
+	neg := n.Sign() < 0
+	q := divHalfEven(n.Abs(n), d)
+	if neg {
+		q.Neg(q)
+	}
+	return Money{Amount: q.Int64(), Currency: currency}
+}
+
+// currency returns the currency of the result of an operation on m and o.
+func (m Money) currency(o Money) string {
+	switch {
+	case m.Currency == "":
+		return o.Currency
+	case o.Currency == "" || o.Currency == m.Currency:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: mixing %s and %s", m.Currency, o.Currency))
+}
+
+// MarshalJSON encodes the amount as a decimal string.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts a decimal string or a JSON number, read from its
+// text rather than through a float. The currency is kept when already
+// set and DefaultCurrency otherwise.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(bytes.TrimSpace(data))
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	if strings.ContainsAny(s, "eE") {
+		return fmt.Errorf("invalid amount %s: exponents are not supported", s)
+	}
+
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	parsed, err := ParseMoney(s, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as a decimal string, which MySQL converts to
+// DECIMAL exactly.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a DECIMAL column. The currency is kept when already set and
+// DefaultCurrency otherwise. Nullable columns are read into a
+// sql.NullString and parsed with ParseMoney instead.
+func (m *Money) Scan(src any) error {
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		*m = Money{Amount: v * pow10(MinorUnits(currency)).Int64(), Currency: currency}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+
+	parsed, err := ParseMoney(s, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,124 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		err  bool
+	}{
+		{"19.99", 1999, false},
+		{"5", 500, false},
+		{"-0.5", -50, false},
+		{"0.125", 12, false},
+		{"0.135", 14, false},
+		{"0.1251", 13, false},
+		{"1.", 0, true},
+		{".5", 0, true},
+		{"1e2", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in, DefaultCurrency)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil || got.Amount != tt.want || got.Currency != DefaultCurrency {
+				t.Errorf("expected %d, got %+v, %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestMoneyMulRatio(t *testing.T) {
+	tests := []struct {
+		amount, num, den, want int64
+	}{
+		{1000, 15, 100, 150},
+		{25, 1, 2, 12},
+		{35, 1, 2, 18},
+		{-25, 1, 2, -12},
+		{6748, 1000, 10000, 675},
+	}
+
+	for _, tt := range tests {
+		got := NewMoney(tt.amount, DefaultCurrency).MulRatio(tt.num, tt.den)
+		if got.Amount != tt.want {
+			t.Errorf("%d * %d/%d: expected %d, got %d", tt.amount, tt.num, tt.den, tt.want, got.Amount)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var v struct{ Price Money }
+	if err := json.Unmarshal([]byte(`{"Price": 0.1}`), &v); err != nil || v.Price.Amount != 10 {
+		t.Fatalf("expected 10 from a number, got %+v, %v", v.Price, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Price": "19.99"}`), &v); err != nil || v.Price.Amount != 1999 {
+		t.Fatalf("expected 1999 from a string, got %+v, %v", v.Price, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Price": 1e3}`), &v); err == nil {
+		t.Error("expected exponents to be rejected")
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil || string(out) != `{"Price":"19.99"}` {
+		t.Errorf("got %s, %v", out, err)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	var m Money
+	for _, src := range []any{[]byte("12.30"), "12.30", int64(12)} {
+		m = Money{}
+		if err := m.Scan(src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m.Amount != 1200 || m.Currency != DefaultCurrency {
+		t.Errorf("expected 1200 USD, got %+v", m)
+	}
+
+	if err := m.Scan([]byte("12.30")); err != nil || m.Amount != 1230 {
+		t.Errorf("expected 1230, got %+v, %v", m, err)
+	}
+}
+
+func TestMoneyMinorUnits(t *testing.T) {
+	yen, err := ParseMoney("1500.5", "JPY")
+	if err != nil || yen.Amount != 1500 || yen.String() != "1500" {
+		t.Errorf("expected 1500 yen, got %+v %s, %v", yen, yen, err)
+	}
+
+	in, err := NewMoney(150000, DefaultCurrency).In("JPY")
+	if err != nil || in.Amount != 1500 || in.Currency != "JPY" {
+		t.Errorf("expected 1500 yen, got %+v, %v", in, err)
+	}
+	if _, err := NewMoney(1999, DefaultCurrency).In("JPY"); err == nil {
+		t.Error("expected an error for decimals the yen does not have")
+	}
+
+	for code, want := range map[string]bool{"EUR": true, "JPY": true, "KWD": false, "eur": false, "EURO": false} {
+		if ValidCurrency(code) != want {
+			t.Errorf("ValidCurrency(%q): expected %v", code, want)
+		}
+	}
+}
+
+func TestMoneyMixedCurrencies(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	NewMoney(1, "USD").Add(NewMoney(1, "EUR"))
+}
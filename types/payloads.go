@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // RegisterUserPayload defines the expected JSON structure for
 // registration requests. Validation tags are used with the validator
 // package to enforce required fields.
@@ -17,11 +19,22 @@ type LoginUserPayload struct {
     Password string `json:"password" validate:"required"`
 }
 
+// CreateProductPayload creates a product. Prices are in Currency,
+// DefaultCurrency when it is empty.
 type CreateProductPayload struct {
-	Name        string  `json:"name" validate:"required"`
-	Description string  `json:"description" validate:"required"`
-	Price       float64 `json:"price" validate:"required,gt=0"`
-	Quantity    int     `json:"quantity" validate:"required,gte=0"`
+	SKU               string                  `json:"sku" validate:"omitempty,max=64"`
+	Name              string                  `json:"name" validate:"required"`
+	Description       string                  `json:"description" validate:"required"`
+	Price             Money                   `json:"price" validate:"required,gt=0"`
+	Currency          string                  `json:"currency" validate:"omitempty,currency"`
+	TaxClass          string                  `json:"taxClass" validate:"omitempty,max=32"`
+	Quantity          int                     `json:"quantity" validate:"gte=0"`
+	LowStockThreshold *int                    `json:"lowStockThreshold" validate:"omitempty,gte=0"`
+	Weight            *int                    `json:"weight" validate:"omitempty,gte=0"`
+	Dimensions        *Dimensions             `json:"dimensions" validate:"omitempty"`
+	CategoryIDs       []int                   `json:"categoryIds" validate:"omitempty,dive,gt=0"`
+	Options           []ProductOptionPayload  `json:"options" validate:"omitempty,dive"`
+	Variants          []ProductVariantPayload `json:"variants" validate:"omitempty,dive"`
 }
 
 type GetProductByIDPayload struct {
@@ -29,13 +42,287 @@ type GetProductByIDPayload struct {
 }
 
 type UpdateProductPayload struct {
-	ID          int     `json:"id" validate:"required"`
-	Name        string  `json:"name" validate:"required"`
-	Description string  `json:"description" validate:"required"`
-	Price       float64 `json:"price" validate:"required,gt=0"`
-	Quantity    int     `json:"quantity" validate:"required,gte=0"`
+	ID                int                     `json:"id" validate:"required"`
+	Name              string                  `json:"name" validate:"required"`
+	Description       string                  `json:"description" validate:"required"`
+	Price             Money                   `json:"price" validate:"required,gt=0"`
+	Currency          string                  `json:"currency" validate:"omitempty,currency"`
+	TaxClass          string                  `json:"taxClass" validate:"omitempty,max=32"`
+	Quantity          int                     `json:"quantity" validate:"gte=0"`
+	LowStockThreshold *int                    `json:"lowStockThreshold" validate:"omitempty,gte=0"`
+	Weight            *int                    `json:"weight" validate:"omitempty,gte=0"`
+	Dimensions        *Dimensions             `json:"dimensions" validate:"omitempty"`
+	CategoryIDs       []int                   `json:"categoryIds" validate:"omitempty,dive,gt=0"`
+	Options           []ProductOptionPayload  `json:"options" validate:"omitempty,dive"`
+	Variants          []ProductVariantPayload `json:"variants" validate:"omitempty,dive"`
 }
 
 type DeleteProductPayload struct {
 	ID int `json:"id" validate:"required"`
 }
+
+// CreateCategoryPayload is accepted by the admin category endpoint. When
+// Slug is empty it is derived from Name.
+type CreateCategoryPayload struct {
+	ParentID    *int   `json:"parentId" validate:"omitempty,gt=0"`
+	Name        string `json:"name" validate:"required"`
+	Slug        string `json:"slug" validate:"omitempty,max=255"`
+	Description string `json:"description"`
+}
+
+// UpdateCategoryPayload replaces every editable field of a category.
+type UpdateCategoryPayload struct {
+	ParentID    *int   `json:"parentId" validate:"omitempty,gt=0"`
+	Name        string `json:"name" validate:"required"`
+	Slug        string `json:"slug" validate:"required,max=255"`
+	Description string `json:"description"`
+}
+
+// ProductOptionPayload declares an option such as "size" and the values a
+// variant may take for it.
+type ProductOptionPayload struct {
+	Name   string   `json:"name" validate:"required,max=64"`
+	Values []string `json:"values" validate:"required,min=1,dive,required,max=64"`
+}
+
+// ProductVariantPayload describes one SKU. Options must name a value for
+// every option declared on the product. Quantity is the variant's stock and
+// Price, when set, overrides the product price.
+type ProductVariantPayload struct {
+	SKU      string            `json:"sku" validate:"required,max=64"`
+	Price    *Money            `json:"price" validate:"omitempty,gt=0"`
+	Quantity int               `json:"quantity" validate:"gte=0"`
+	Options  map[string]string `json:"options" validate:"required"`
+}
+
+// CartItemPayload is a single line submitted at checkout. VariantID is
+// required for products that have variants and must be omitted otherwise.
+type CartItemPayload struct {
+	ProductID int `json:"productId" validate:"required,gt=0"`
+	VariantID int `json:"variantId" validate:"omitempty,gt=0"`
+	Quantity  int `json:"quantity" validate:"required,gt=0"`
+}
+
+// CartCheckoutPayload is the body of the checkout endpoint. Items are the
+// lines to order; without them the user's server-side cart is checked
+// out, and emptied once the order is placed.
+// Latitude and Longitude of the delivery address are optional; when given
+// the order ships from the closest locations that have the stock.
+// CouponCode optionally applies a coupon. Country, an ISO 3166 alpha-2
+// code, and Region of the delivery address pick the tax rates; orders
+// without a Country are not taxed. ShippingMethodID is one of the methods
+// quoted for the Country. GiftCardCode pays as much of the order as the
+// balance of the gift card allows.
+type CartCheckoutPayload struct {
+	Items            []CartItemPayload `json:"items" validate:"omitempty,dive"`
+	Address          string            `json:"address" validate:"required,max=255"`
+	Country          string            `json:"country" validate:"required_with=ShippingMethodID,omitempty,iso3166_1_alpha2"`
+	Region           string            `json:"region" validate:"omitempty,max=64"`
+	Latitude         *float64          `json:"latitude" validate:"required_with=Longitude,omitempty,gte=-90,lte=90"`
+	Longitude        *float64          `json:"longitude" validate:"required_with=Latitude,omitempty,gte=-180,lte=180"`
+	CouponCode       string            `json:"couponCode" validate:"omitempty,max=32"`
+	ShippingMethodID *int              `json:"shippingMethodId" validate:"omitempty,gt=0"`
+	GiftCardCode     string            `json:"giftCardCode" validate:"omitempty,max=32"`
+}
+
+// ShippingQuotePayload asks what the shipping methods to Country cost for
+// Items, or the user's server-side cart without them; CouponCode counts
+// towards free-over thresholds and free-shipping coupons.
+type ShippingQuotePayload struct {
+	Items      []CartItemPayload `json:"items" validate:"omitempty,dive"`
+	Country    string            `json:"country" validate:"required,iso3166_1_alpha2"`
+	Region     string            `json:"region" validate:"omitempty,max=64"`
+	CouponCode string            `json:"couponCode" validate:"omitempty,max=32"`
+}
+
+// ReorderProductImagesPayload lists every image ID of a product in the
+// desired display order.
+type ReorderProductImagesPayload struct {
+	ImageIDs []int `json:"imageIds" validate:"required,min=1,dive,gt=0"`
+}
+
+// RecordMovementPayload records a manual stock movement, such as a
+// delivery from a supplier. VariantID is required for products with
+// variants. LocationID defaults to the default location.
+type RecordMovementPayload struct {
+	VariantID   *int   `json:"variantId" validate:"omitempty,gt=0"`
+	LocationID  int    `json:"locationId" validate:"omitempty,gt=0"`
+	Delta       int    `json:"delta" validate:"required,ne=0"`
+	Reason      string `json:"reason" validate:"required,oneof=restock adjustment return"`
+	ReferenceID *int   `json:"referenceId" validate:"omitempty,gt=0"`
+}
+
+// CreateLocationPayload adds a stock location. The coordinates are
+// optional but must be given together.
+type CreateLocationPayload struct {
+	Code      string   `json:"code" validate:"required,max=32"`
+	Name      string   `json:"name" validate:"required,max=255"`
+	Latitude  *float64 `json:"latitude" validate:"required_with=Longitude,omitempty,gte=-90,lte=90"`
+	Longitude *float64 `json:"longitude" validate:"required_with=Latitude,omitempty,gte=-180,lte=180"`
+}
+
+// CreateReviewPayload posts a review of a product.
+type CreateReviewPayload struct {
+	Rating int    `json:"rating" validate:"required,min=1,max=5"`
+	Body   string `json:"body" validate:"required,max=5000"`
+}
+
+// CreateWishlistPayload names a new wishlist.
+type CreateWishlistPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// AddWishlistItemPayload saves a product to a wishlist. VariantID follows
+// the same rules as at checkout.
+type AddWishlistItemPayload struct {
+	ProductID int `json:"productId" validate:"required,gt=0"`
+	VariantID int `json:"variantId" validate:"omitempty,gt=0"`
+}
+
+// MoveToCartPayload moves a wishlist item to the cart.
+type MoveToCartPayload struct {
+	Quantity int `json:"quantity" validate:"required,gt=0"`
+}
+
+// CreateCouponPayload defines a coupon. PercentOff, AmountOff, BuyQuantity
+// and GetQuantity are checked against Kind by the coupon service.
+type CreateCouponPayload struct {
+	Code         string     `json:"code" validate:"required,max=32"`
+	Kind         string     `json:"kind" validate:"required,oneof=percentage fixed_amount free_shipping buy_x_get_y"`
+	PercentOff   float64    `json:"percentOff" validate:"gte=0,lte=100"`
+	AmountOff    *Money     `json:"amountOff" validate:"omitempty,gt=0"`
+	BuyQuantity  int        `json:"buyQuantity" validate:"gte=0"`
+	GetQuantity  int        `json:"getQuantity" validate:"gte=0"`
+	MinSubtotal  Money      `json:"minSubtotal" validate:"gte=0"`
+	ProductIDs   []int      `json:"productIds" validate:"dive,gt=0"`
+	CategoryIDs  []int      `json:"categoryIds" validate:"dive,gt=0"`
+	StartsAt     *time.Time `json:"startsAt"`
+	EndsAt       *time.Time `json:"endsAt"`
+	UsageLimit   *int       `json:"usageLimit" validate:"omitempty,gt=0"`
+	PerUserLimit *int       `json:"perUserLimit" validate:"omitempty,gt=0"`
+}
+
+// UpdatePreferencesPayload sets the caller's preferences. A null currency
+// clears the preferred currency.
+type UpdatePreferencesPayload struct {
+	Currency *string `json:"currency" validate:"omitempty,currency"`
+}
+
+// CreateTaxRatePayload adds a row to the tax rates table. Rate is a
+// percentage with at most four decimals; an empty TaxClass means
+// DefaultTaxClass and an empty Region the whole country.
+type CreateTaxRatePayload struct {
+	Country   string  `json:"country" validate:"required,iso3166_1_alpha2"`
+	Region    string  `json:"region" validate:"omitempty,max=64"`
+	TaxClass  string  `json:"taxClass" validate:"omitempty,max=32"`
+	Name      string  `json:"name" validate:"required,max=64"`
+	Rate      float64 `json:"rate" validate:"gte=0,lte=100"`
+	Inclusive bool    `json:"inclusive"`
+}
+
+// CreateShippingZonePayload adds a shipping zone. A zone without Countries
+// covers every country no other zone lists.
+type CreateShippingZonePayload struct {
+	Name      string   `json:"name" validate:"required,max=64"`
+	Countries []string `json:"countries" validate:"dive,iso3166_1_alpha2"`
+}
+
+// CreateShippingMethodPayload adds a method to a shipping zone. PerKg and
+// FreeOver are checked against Kind by the shipping service. Amounts are
+// in DefaultCurrency.
+type CreateShippingMethodPayload struct {
+	Name     string `json:"name" validate:"required,max=64"`
+	Kind     string `json:"kind" validate:"required,oneof=flat_rate weight_based free_over"`
+	Rate     Money  `json:"rate" validate:"gte=0"`
+	PerKg    *Money `json:"perKg" validate:"omitempty,gt=0"`
+	FreeOver *Money `json:"freeOver" validate:"omitempty,gt=0"`
+}
+
+// ConfirmPaymentPayload confirms a payment with a payment method obtained
+// by the client from the provider, such as a card token.
+type ConfirmPaymentPayload struct {
+	PaymentMethod string `json:"paymentMethod" validate:"required,max=255"`
+}
+
+// CreateReturnPayload asks to send items of an order back.
+type CreateReturnPayload struct {
+	Items []ReturnItemPayload `json:"items" validate:"required,min=1,dive"`
+	Note  string              `json:"note" validate:"max=1000"`
+}
+
+// ReturnItemPayload is a line of a return request.
+type ReturnItemPayload struct {
+	OrderItemID int    `json:"orderItemId" validate:"required,gt=0"`
+	Quantity    int    `json:"quantity" validate:"required,gt=0"`
+	Reason      string `json:"reason" validate:"required,oneof=damaged wrong_item not_as_described no_longer_needed other"`
+}
+
+// ResolveReturnPayload approves or rejects a return with a note to the
+// customer.
+type ResolveReturnPayload struct {
+	Resolution string `json:"resolution" validate:"max=1000"`
+}
+
+// ReceiveReturnPayload records that the items of a return came back. They
+// are restocked at LocationID, or at the default location when it is nil,
+// except the return items listed in Damaged.
+type ReceiveReturnPayload struct {
+	LocationID *int  `json:"locationId" validate:"omitempty,gt=0"`
+	Damaged    []int `json:"damaged" validate:"dive,gt=0"`
+}
+
+// RefundReturnPayload refunds a return. Without Items every returned line
+// gets back what was paid for the units returned; Items overrides that,
+// such as to keep a restocking fee. Amounts are in the order currency.
+type RefundReturnPayload struct {
+	Items []RefundItemPayload `json:"items" validate:"dive"`
+}
+
+// CreateRefundPayload refunds lines of an order without a return, such as
+// for a price adjustment. Amounts are in the order currency.
+type CreateRefundPayload struct {
+	Items  []RefundItemPayload `json:"items" validate:"required,min=1,dive"`
+	Reason string              `json:"reason" validate:"required,max=255"`
+}
+
+// RefundItemPayload is the amount refunded for an order line.
+type RefundItemPayload struct {
+	OrderItemID int   `json:"orderItemId" validate:"required,gt=0"`
+	Amount      Money `json:"amount" validate:"gt=0"`
+}
+
+// CreateShipmentPayload records a package sent for an order. Without Items
+// it holds every unit not shipped yet. ShippedAt defaults to now.
+type CreateShipmentPayload struct {
+	Carrier        string                `json:"carrier" validate:"required,max=100"`
+	TrackingNumber string                `json:"trackingNumber" validate:"required,max=100"`
+	TrackingURL    string                `json:"trackingUrl" validate:"omitempty,url,max=2048"`
+	ShippedAt      *time.Time            `json:"shippedAt"`
+	Items          []ShipmentItemPayload `json:"items" validate:"dive"`
+}
+
+// ShipmentItemPayload is a line of a shipment.
+type ShipmentItemPayload struct {
+	OrderItemID int `json:"orderItemId" validate:"required,gt=0"`
+	Quantity    int `json:"quantity" validate:"required,gt=0"`
+}
+
+// DeliverShipmentPayload records when a shipment was delivered, now when
+// DeliveredAt is nil.
+type DeliverShipmentPayload struct {
+	DeliveredAt *time.Time `json:"deliveredAt"`
+}
+
+// IssueGiftCardPayload issues a gift card of Amount in Currency, which
+// defaults to DefaultCurrency.
+type IssueGiftCardPayload struct {
+	Amount    Money      `json:"amount" validate:"gt=0"`
+	Currency  string     `json:"currency" validate:"omitempty,len=3"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Note      string     `json:"note" validate:"max=255"`
+}
+
+// GiftCardBalancePayload looks a gift card up by its code.
+type GiftCardBalancePayload struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
@@ -7,50 +7,293 @@ type LoginResponse struct {
 }
 
 type CreateProductResponse struct {
-	Message string `json:"message"`
-	Data	struct {
-		ID int `json:"id"`
-		Name string `json:"name"`
-		Description string `json:"description"`
-		Price float64 `json:"price"`
-		Quantity int `json:"quantity"`
-		CreatedAt string `json:"createdAt"`
-	} `json:"data"`
+	Message string   `json:"message"`
+	Data    *Product `json:"data"`
 }
 
 type GetProductByIDResponse struct {
-	Message string `json:"message"`
-	Data	struct {
-		ID int `json:"id"`
-		Name string `json:"name"`	
-		Description string `json:"description"`
-		Price float64 `json:"price"`
-		Quantity int `json:"quantity"`
-		CreatedAt string `json:"createdAt"`
-	} `json:"data"`
+	Message string   `json:"message"`
+	Data    *Product `json:"data"`
 }
 
 type UpdateProductResponse struct {
-	Message string `json:"message"`
-	Data	struct {
-		ID int `json:"id"`
-		Name string `json:"name"`
-		Description string `json:"description"`
-		Price float64 `json:"price"`
-		Quantity int `json:"quantity"`
-		CreatedAt string `json:"createdAt"`
-	} `json:"data"`
+	Message string   `json:"message"`
+	Data    *Product `json:"data"`
 }
 
 type DeleteProductResponse struct {
+	Message string   `json:"message"`
+	Data    *Product `json:"data"`
+}
+
+// PageMeta accompanies paginated listings so clients can request the
+// following pages.
+type PageMeta struct {
+	Page       int `json:"page"`
+	PageSize   int `json:"pageSize"`
+	Total      int `json:"total"`
+	TotalPages int `json:"totalPages"`
+}
+
+// ListProductsResponse is returned by the product listing endpoints.
+type ListProductsResponse struct {
+	Message string     `json:"message"`
+	Data    []*Product `json:"data"`
+	Meta    PageMeta   `json:"meta"`
+}
+
+// CategoryResponse wraps a single category.
+type CategoryResponse struct {
+	Message string    `json:"message"`
+	Data    *Category `json:"data"`
+}
+
+// CategoryTreeResponse wraps the full category tree.
+type CategoryTreeResponse struct {
+	Message string      `json:"message"`
+	Data    []*Category `json:"data"`
+}
+
+// OrderResponse wraps a single order, including its items.
+type OrderResponse struct {
 	Message string `json:"message"`
-	Data	struct {
-		ID int `json:"id"`
-		Name string `json:"name"`
-		Description string `json:"description"`
-		Price float64 `json:"price"`
-		Quantity int `json:"quantity"`
-		CreatedAt string `json:"createdAt"`	
-	} `json:"data"`
+	Data    *Order `json:"data"`
+}
+
+// ListOrdersResponse wraps the orders of the authenticated user.
+type ListOrdersResponse struct {
+	Message string   `json:"message"`
+	Data    []*Order `json:"data"`
+}
+
+// ProductImagesResponse wraps the images of a product in display order.
+type ProductImagesResponse struct {
+	Message string          `json:"message"`
+	Data    []*ProductImage `json:"data"`
+}
+
+// ImportProductsResponse is returned by the bulk product import endpoint.
+type ImportProductsResponse struct {
+	Message string        `json:"message"`
+	Data    *ImportReport `json:"data"`
+}
+
+// ListMovementsResponse is one page of a product's inventory ledger.
+type ListMovementsResponse struct {
+	Message string           `json:"message"`
+	Data    []*StockMovement `json:"data"`
+	Meta    PageMeta         `json:"meta"`
+}
+
+// MovementResponse wraps a single inventory movement.
+type MovementResponse struct {
+	Message string         `json:"message"`
+	Data    *StockMovement `json:"data"`
 }
 
+// ReconciliationResponse lists the stock that does not match the ledger.
+type ReconciliationResponse struct {
+	Message string              `json:"message"`
+	Data    []*StockDiscrepancy `json:"data"`
+}
+
+// ListLocationsResponse lists every stock location.
+type ListLocationsResponse struct {
+	Message string           `json:"message"`
+	Data    []*StockLocation `json:"data"`
+}
+
+// LocationResponse wraps a single stock location.
+type LocationResponse struct {
+	Message string         `json:"message"`
+	Data    *StockLocation `json:"data"`
+}
+
+// ListStockLevelsResponse lists a product's stock per location.
+type ListStockLevelsResponse struct {
+	Message string        `json:"message"`
+	Data    []*StockLevel `json:"data"`
+}
+
+// SubscriptionResponse wraps a back-in-stock subscription.
+type SubscriptionResponse struct {
+	Message string             `json:"message"`
+	Data    *StockSubscription `json:"data"`
+}
+
+// ListReviewsResponse wraps one page of reviews.
+type ListReviewsResponse struct {
+	Message string    `json:"message"`
+	Data    []*Review `json:"data"`
+	Meta    PageMeta  `json:"meta"`
+}
+
+// ReviewResponse wraps a single review.
+type ReviewResponse struct {
+	Message string  `json:"message"`
+	Data    *Review `json:"data"`
+}
+
+// ListWishlistsResponse lists the caller's wishlists.
+type ListWishlistsResponse struct {
+	Message string      `json:"message"`
+	Data    []*Wishlist `json:"data"`
+}
+
+// WishlistResponse wraps a single wishlist.
+type WishlistResponse struct {
+	Message string    `json:"message"`
+	Data    *Wishlist `json:"data"`
+}
+
+// WishlistItemResponse wraps a single wishlist item.
+type WishlistItemResponse struct {
+	Message string        `json:"message"`
+	Data    *WishlistItem `json:"data"`
+}
+
+// CartResponse lists the lines of the caller's cart.
+type CartResponse struct {
+	Message string      `json:"message"`
+	Data    []*CartItem `json:"data"`
+}
+
+// CartItemResponse wraps a single cart line.
+type CartItemResponse struct {
+	Message string    `json:"message"`
+	Data    *CartItem `json:"data"`
+}
+
+// ListCouponsResponse wraps one page of coupons.
+type ListCouponsResponse struct {
+	Message string    `json:"message"`
+	Data    []*Coupon `json:"data"`
+	Meta    PageMeta  `json:"meta"`
+}
+
+// CouponResponse wraps a single coupon.
+type CouponResponse struct {
+	Message string  `json:"message"`
+	Data    *Coupon `json:"data"`
+}
+
+// UserResponse wraps the caller's own user record.
+type UserResponse struct {
+	Message string `json:"message"`
+	Data    *User  `json:"data"`
+}
+
+// ExchangeRatesResponse lists the exchange rates table.
+type ExchangeRatesResponse struct {
+	Message string          `json:"message"`
+	Data    []*ExchangeRate `json:"data"`
+}
+
+// ListTaxRatesResponse lists the tax rates table.
+type ListTaxRatesResponse struct {
+	Message string     `json:"message"`
+	Data    []*TaxRate `json:"data"`
+}
+
+// TaxRateResponse wraps a single tax rate.
+type TaxRateResponse struct {
+	Message string   `json:"message"`
+	Data    *TaxRate `json:"data"`
+}
+
+// ListShippingZonesResponse lists the shipping zones with their methods.
+type ListShippingZonesResponse struct {
+	Message string          `json:"message"`
+	Data    []*ShippingZone `json:"data"`
+}
+
+// ShippingZoneResponse wraps a single shipping zone.
+type ShippingZoneResponse struct {
+	Message string        `json:"message"`
+	Data    *ShippingZone `json:"data"`
+}
+
+// ShippingMethodResponse wraps a single shipping method.
+type ShippingMethodResponse struct {
+	Message string          `json:"message"`
+	Data    *ShippingMethod `json:"data"`
+}
+
+// ShippingQuotesResponse lists what each shipping method costs for a cart.
+type ShippingQuotesResponse struct {
+	Message string           `json:"message"`
+	Data    []*ShippingQuote `json:"data"`
+}
+
+// PaymentResponse wraps a single payment.
+type PaymentResponse struct {
+	Message string   `json:"message"`
+	Data    *Payment `json:"data"`
+}
+
+// ListPaymentsResponse lists the payments of an order.
+type ListPaymentsResponse struct {
+	Message string     `json:"message"`
+	Data    []*Payment `json:"data"`
+}
+
+// ReturnResponse wraps a single return with its items.
+type ReturnResponse struct {
+	Message string  `json:"message"`
+	Data    *Return `json:"data"`
+}
+
+// ListReturnsResponse lists returns with their items.
+type ListReturnsResponse struct {
+	Message string    `json:"message"`
+	Data    []*Return `json:"data"`
+}
+
+// RefundResponse wraps a single refund with its items.
+type RefundResponse struct {
+	Message string  `json:"message"`
+	Data    *Refund `json:"data"`
+}
+
+// ListRefundsResponse lists the refunds of an order.
+type ListRefundsResponse struct {
+	Message string    `json:"message"`
+	Data    []*Refund `json:"data"`
+}
+
+// ListInvoicesResponse lists the invoice and credit notes of an order.
+type ListInvoicesResponse struct {
+	Message string     `json:"message"`
+	Data    []*Invoice `json:"data"`
+}
+
+// ShipmentResponse wraps a single shipment with its items.
+type ShipmentResponse struct {
+	Message string    `json:"message"`
+	Data    *Shipment `json:"data"`
+}
+
+// ListShipmentsResponse lists the shipments of an order.
+type ListShipmentsResponse struct {
+	Message string      `json:"message"`
+	Data    []*Shipment `json:"data"`
+}
+
+// GiftCardResponse wraps a single gift card.
+type GiftCardResponse struct {
+	Message string    `json:"message"`
+	Data    *GiftCard `json:"data"`
+}
+
+// ListGiftCardsResponse wraps one page of gift cards.
+type ListGiftCardsResponse struct {
+	Message string      `json:"message"`
+	Data    []*GiftCard `json:"data"`
+	Meta    PageMeta    `json:"meta"`
+}
+
+// ListGiftCardTransactionsResponse lists the ledger of a gift card.
+type ListGiftCardTransactionsResponse struct {
+	Message string                 `json:"message"`
+	Data    []*GiftCardTransaction `json:"data"`
+}
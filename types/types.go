@@ -3,6 +3,70 @@
 // implementations.
 package types
 
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrInsufficientStock is returned by stores when a stock decrement would
+// take a product or variant below zero.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ErrVersionConflict is returned by conditional updates when the row was
+// changed by someone else since the caller read it.
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrUnknownCategory is returned when a product is linked to a category
+// that does not exist.
+var ErrUnknownCategory = errors.New("unknown category")
+
+// ErrOrderNotPending is returned when an order can no longer be paid or
+// cancelled because it already left the pending state.
+var ErrOrderNotPending = errors.New("order is not pending")
+
+// ErrVariantReserved is returned when removing a variant that pending
+// orders still hold stock of.
+var ErrVariantReserved = errors.New("variant is reserved by pending orders")
+
+// ErrCouponUnavailable is returned at checkout when the coupon was
+// deactivated, expired or reached one of its usage limits since it was
+// applied to the cart.
+var ErrCouponUnavailable = errors.New("coupon is no longer available")
+
+// ErrPaymentEventReplayed is returned by PaymentStore.ApplyPaymentEvent
+// for an event that was already recorded, such as a redelivered webhook.
+var ErrPaymentEventReplayed = errors.New("payment event already recorded")
+
+// ErrReturnStatus is returned when a return is not in a status that
+// allows the change asked for, such as approving a rejected return.
+var ErrReturnStatus = errors.New("return is not in a status that allows this")
+
+// ErrReturnQuantity is returned when the returns of an order line would
+// add up to more than was ordered.
+var ErrReturnQuantity = errors.New("return exceeds the quantity ordered")
+
+// ErrRefundTooLarge is returned for refunds of more than was paid and not
+// refunded yet, whether through a payment, an order line or a gift card.
+var ErrRefundTooLarge = errors.New("refund exceeds the amount left to refund")
+
+// ErrShipmentQuantity is returned when the shipments of an order line
+// would add up to more than was ordered.
+var ErrShipmentQuantity = errors.New("shipment exceeds the quantity ordered")
+
+// ErrShipmentDelivered is returned when delivering a shipment that was
+// delivered before.
+var ErrShipmentDelivered = errors.New("shipment already delivered")
+
+// ErrGiftCardUnavailable is returned at checkout when the gift card was
+// deactivated, expired or no longer holds the amount applied to the order.
+var ErrGiftCardUnavailable = errors.New("gift card is no longer available")
+
+// ErrBlobNotFound is returned by a BlobStore when no object exists for a key.
+var ErrBlobNotFound = errors.New("blob not found")
+
 // UserStore represents the minimum operations required by handlers and
 // services to manage user records. Implementations may talk to a database,
 // an in-memory store, or a remote service.
@@ -10,14 +74,309 @@ type UserStore interface {
     GetUserByEmail(email string) (*User, error)
     GetUserByID(id int) (*User, error)
     CreateUser(user *User) error 
+    SetUserCurrency(id int, currency *string) error
 }
 
 type ProductStore interface {
-	ListProducts() ([]*Product, error)
+	ListProducts(params ProductListParams) ([]*Product, int, error)
+	EachProduct(params ProductListParams, fn func(*Product) error) error
 	GetProductByID(id int) (*Product, error)
-	CreateProduct(product *Product) error
-	UpdateProduct(product *Product) error
+	GetProductByIDIncludingDeleted(id int) (*Product, error)
+	CreateProduct(product *Product, related ProductRelations, actorID int) error
+	UpdateProduct(product *Product, related ProductRelations, actorID int) error
+	PatchProduct(id, version int, changes map[string]any, related ProductRelations, actorID int) error
 	DeleteProduct(id int) error
+	RestoreProduct(id int) error
+	GetProductVariants(productID int) ([]*ProductOption, []*ProductVariant, error)
+	GetVariantBySKU(sku string) (*ProductVariant, error)
+	GetProductBySKU(sku string) (*Product, error)
+	UpsertProductsBySKU(products []*Product, dryRun bool, actorID int) ([]*ImportRowResult, error)
+	ListProductImages(productID int) ([]*ProductImage, error)
+	AddProductImage(image *ProductImage) error
+	DeleteProductImage(productID, imageID int) (*ProductImage, error)
+	ReorderProductImages(productID int, imageIDs []int) error
+	SetProductCoverImage(productID int, url string) error
+	SetProductImageSizes(imageID int, sizes []*ProductImageSize) error
+	ListAllProductImages() ([]*ProductImage, error)
+	ListCoverImages(productIDs []int) (map[int]*ProductImage, error)
+}
+
+// ThumbnailQueue accepts uploaded images whose resized variants should be
+// generated in the background. Enqueue must not block; it reports false
+// when the image could not be queued.
+type ThumbnailQueue interface {
+	Enqueue(image *ProductImage) bool
+}
+
+// BlobStore stores binary objects such as uploaded images under opaque
+// keys. Implementations decide where the bytes live; URL returns the
+// address clients should use to download an object.
+type BlobStore interface {
+	Put(key string, r io.Reader, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	URL(key string) string
+}
+
+// OrderStore persists orders and their line items. CreateOrder writes the
+// order, its items and an inventory reservation for every line in one
+// transaction; the reservations expire at holdUntil unless the order is
+// paid first. MarkOrderPaid turns the reservations into stock decrements
+// and CancelOrder releases them. An order its gift card pays in full
+// leaves CreateOrder already paid.
+type OrderStore interface {
+	CreateOrder(order *Order, holdUntil time.Time) error
+	GetOrderByID(id int) (*Order, error)
+	ListOrdersByUser(userID int) ([]*Order, error)
+	MarkOrderPaid(orderID int) error
+	CancelOrder(orderID int) error
+}
+
+// ReservationStore reads inventory reservations. ExpiredOrderIDs returns
+// the pending orders holding at least one active reservation that expired
+// at or before now.
+type ReservationStore interface {
+	ExpiredOrderIDs(now time.Time) ([]int, error)
+}
+
+// MovementStore reads and appends to the inventory ledger. RecordMovement
+// applies the movement to the stock on hand and records it atomically.
+// Reconcile returns every product, variant and stock level whose on-hand
+// quantity differs from the sum of its movements.
+type MovementStore interface {
+	RecordMovement(movement *StockMovement) error
+	ListMovements(productID int, page Pagination) ([]*StockMovement, int, error)
+	Reconcile() ([]*StockDiscrepancy, error)
+}
+
+// LocationStore manages the stock locations and the stock kept at each.
+// The lookups return nil without an error when nothing matches.
+type LocationStore interface {
+	ListLocations() ([]*StockLocation, error)
+	GetLocationByID(id int) (*StockLocation, error)
+	GetLocationByCode(code string) (*StockLocation, error)
+	CreateLocation(location *StockLocation) error
+	ListStockLevels(productID int) ([]*StockLevel, error)
+}
+
+// AlertStore reads the stock alert outbox and the back-in-stock
+// subscriptions. Subscribe returns the waiting subscription of the user
+// for the product, creating it when there is none; created reports which.
+type AlertStore interface {
+	PendingAlerts(limit int) ([]*StockAlert, error)
+	MarkAlertSent(id int) error
+	WaitingSubscriptions(productID int) ([]*StockSubscription, error)
+	MarkSubscriptionNotified(id int) error
+	Subscribe(productID, userID int) (sub *StockSubscription, created bool, err error)
+}
+
+// Notifier delivers a notification, for instance by logging it or by
+// posting it to a webhook.
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// Clock tells the current time. Code that schedules or expires work takes
+// a Clock so tests can control time.
+type Clock interface {
+	Now() time.Time
+}
+
+// PaymentProvider is a payment gateway. CreateIntent opens a payment of
+// amount for an order; the other calls act on the intent it returns and
+// report the outcome as a PaymentEvent, the same way the gateway reports
+// asynchronous outcomes to its webhook. ConfirmIntent authorizes the
+// intent with a payment method from the client, CaptureIntent takes
+// authorized funds and Refund gives captured funds back. VerifyWebhook
+// checks the signature of a webhook request and decodes its event.
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, orderID int, amount Money) (*PaymentIntent, error)
+	ConfirmIntent(ctx context.Context, intentID, paymentMethod string) (*PaymentEvent, error)
+	CaptureIntent(ctx context.Context, intentID string, amount Money) (*PaymentEvent, error)
+	Refund(ctx context.Context, intentID string, amount Money) (*PaymentEvent, error)
+	VerifyWebhook(body []byte, header http.Header) (*PaymentEvent, error)
+}
+
+// PaymentStore persists payments and the events that moved them.
+// ApplyPaymentEvent locks the payment the provider knows as the event's
+// intent, lets apply move it and stores the event with the new state of
+// the payment in one transaction. It returns the updated payment, nil
+// when there is no such payment, and ErrPaymentEventReplayed with the
+// payment as it stands, writing nothing, when the event was recorded
+// before.
+type PaymentStore interface {
+	CreatePayment(payment *Payment) error
+	GetPaymentByID(id int) (*Payment, error)
+	ListPaymentsByOrder(orderID int) ([]*Payment, error)
+	ApplyPaymentEvent(provider string, event *PaymentEvent, apply func(*Payment) error) (*Payment, error)
+}
+
+// ReturnStore persists return requests, loaded with their items.
+// CreateReturn fails with an error wrapping ErrReturnQuantity when the
+// returns of an order line that were not rejected would add up to more
+// than was ordered. UpdateReturnStatus moves a return that is in one of
+// from to status with the resolution note. ReceiveReturn records that the
+// items of an approved or refunded return came back, moving an approved
+// one to received, and puts them back in stock at locationID, except the
+// return items listed in damaged, in one transaction. Both fail with
+// ErrReturnStatus for returns in any other status.
+type ReturnStore interface {
+	CreateReturn(ret *Return) error
+	GetReturnByID(id int) (*Return, error)
+	ListReturns(status string) ([]*Return, error)
+	ListReturnsByOrder(orderID int) ([]*Return, error)
+	UpdateReturnStatus(id int, from []string, status, resolution string) error
+	ReceiveReturn(id, locationID int, damaged []int, actorID int) error
+}
+
+// RefundStore persists refunds, loaded with their items. CreateRefund
+// locks the order items of the refund and, in one transaction, checks no
+// item gets back more than its Total, credits the GiftCard part back onto
+// the gift card, calls issue to give the rest back and records the refund
+// with its credit note. The refund of a return also
+// moves the return to refunded, failing with ErrReturnStatus unless it was
+// approved or received.
+type RefundStore interface {
+	CreateRefund(refund *Refund, issue func() error) error
+	ListRefundsByOrder(orderID int) ([]*Refund, error)
+}
+
+// InvoiceStore reads issued invoices and credit notes, loaded with their
+// lines and tax lines. Documents are issued by the stores that pay orders
+// and record refunds; see the invoice package. GetOrderInvoice returns
+// the invoice of an order, or nil until it is paid.
+type InvoiceStore interface {
+	GetInvoiceByID(id int) (*Invoice, error)
+	GetOrderInvoice(orderID int) (*Invoice, error)
+	ListInvoicesByOrder(orderID int) ([]*Invoice, error)
+}
+
+// ShipmentStore persists the shipments of orders, loaded with their
+// items. CreateShipment fails with an error wrapping ErrShipmentQuantity
+// when the shipments of an order line would add up to more than was
+// ordered. DeliverShipment records when a shipment was delivered, failing
+// with ErrShipmentDelivered when it was before. Both move the order to
+// shipped or delivered once every unit of it is, in the same transaction.
+type ShipmentStore interface {
+	CreateShipment(shipment *Shipment) error
+	GetShipmentByID(id int) (*Shipment, error)
+	ListShipmentsByOrder(orderID int) ([]*Shipment, error)
+	DeliverShipment(id int, deliveredAt time.Time) error
+}
+
+// GiftCardStore persists gift cards and their ledger. CreateGiftCard
+// stores the card under the hash of its Code and records its issue in the
+// ledger. GetGiftCardByCode looks a card up by its code and returns nil
+// without an error when none matches. Orders redeem and release cards,
+// and refunds credit them, through the giftcard package.
+type GiftCardStore interface {
+	CreateGiftCard(card *GiftCard) error
+	GetGiftCardByID(id int) (*GiftCard, error)
+	GetGiftCardByCode(code string) (*GiftCard, error)
+	ListGiftCards(page Pagination) ([]*GiftCard, int, error)
+	SetGiftCardActive(id int, active bool) error
+	ListGiftCardTransactions(cardID int) ([]*GiftCardTransaction, error)
+}
+
+// ShippingStore persists shipping zones and their methods. Zones are
+// loaded with their countries and methods. FindShippingZone returns the
+// zone listing country, else the zone without countries that covers the
+// rest of the world, else nil.
+type ShippingStore interface {
+	ListShippingZones() ([]*ShippingZone, error)
+	GetShippingZoneByID(id int) (*ShippingZone, error)
+	FindShippingZone(country string) (*ShippingZone, error)
+	CreateShippingZone(zone *ShippingZone) error
+	CreateShippingMethod(method *ShippingMethod) error
+	GetShippingMethodByID(id int) (*ShippingMethod, error)
+	DeleteShippingMethod(id int) error
+}
+
+// TaxCalculator works out the taxes of an order from its destination
+// (Country and Region) and the TaxClass, Price and Quantity of its items,
+// on the amounts left once the coupon Discount is taken off. It sets Tax,
+// TaxLines, the TaxLines and Total of every item, and Total: the subtotal
+// less the discount plus the taxes not already included in the prices.
+type TaxCalculator interface {
+	CalculateTax(order *Order) error
+}
+
+// TaxRateStore persists the tax rates table. FindTaxRates returns the
+// rates that apply in a country and region: the ones for the whole
+// country and the ones for the region.
+type TaxRateStore interface {
+	ListTaxRates() ([]*TaxRate, error)
+	FindTaxRates(country, region string) ([]*TaxRate, error)
+	GetTaxRateByID(id int) (*TaxRate, error)
+	CreateTaxRate(rate *TaxRate) error
+	DeleteTaxRate(id int) error
+}
+
+// CategoryStore persists the category hierarchy. The tree itself is
+// assembled by the category service from the flat list returned here.
+type CategoryStore interface {
+	ListCategories() ([]*Category, error)
+	GetCategoryByID(id int) (*Category, error)
+	GetCategoryBySlug(slug string) (*Category, error)
+	CreateCategory(category *Category) error
+	UpdateCategory(category *Category) error
+	DeleteCategory(id int) error
+}
+
+// ReviewStore persists product reviews. CreateReview marks the review as a
+// verified purchase when the user has a completed or delivered order for
+// the product. SetReviewStatus also recomputes the product's rating from
+// its approved reviews.
+type ReviewStore interface {
+	ListReviews(params ReviewListParams) ([]*Review, int, error)
+	GetReviewByID(id int) (*Review, error)
+	GetUserReview(productID, userID int) (*Review, error)
+	CreateReview(review *Review) error
+	SetReviewStatus(id int, status string) error
+}
+
+// CouponStore persists coupons and their rules. Coupons are redeemed as
+// part of order creation, see coupon.Redeem.
+type CouponStore interface {
+	ListCoupons(page Pagination) ([]*Coupon, int, error)
+	GetCouponByID(id int) (*Coupon, error)
+	GetCouponByCode(code string) (*Coupon, error)
+	CreateCoupon(coupon *Coupon) error
+	SetCouponActive(id int, active bool) error
+}
+
+// WishlistStore persists wishlists and their items. The wishlist getters
+// load the items. MoveWishlistItemToCart adds quantity of the item to its
+// owner's cart and removes it from the wishlist in one transaction; it
+// fails with ErrInsufficientStock when the cart would then hold more than
+// the stock on hand.
+type WishlistStore interface {
+	ListWishlists(userID int) ([]*Wishlist, error)
+	GetWishlistByID(id int) (*Wishlist, error)
+	GetWishlistByName(userID int, name string) (*Wishlist, error)
+	GetWishlistByShareToken(token string) (*Wishlist, error)
+	CreateWishlist(wishlist *Wishlist) error
+	DeleteWishlist(id int) error
+	SetWishlistShareToken(id int, token *string) error
+	AddWishlistItem(item *WishlistItem) error
+	RemoveWishlistItem(id int) error
+	MoveWishlistItemToCart(id, quantity int) (*CartItem, error)
+}
+
+// ExchangeRateStore persists the exchange rates table. The rates are
+// replaced as a whole, as loaded from a rates file.
+type ExchangeRateStore interface {
+	ListExchangeRates() ([]*ExchangeRate, error)
+	ReplaceExchangeRates(rates []*ExchangeRate) error
+}
+
+// CartStore reads and trims the server-side cart. Items are added by
+// moving them from a wishlist and removed by checking the cart out.
+type CartStore interface {
+	ListCartItems(userID int) ([]*CartItem, error)
+	GetCartItemByID(id int) (*CartItem, error)
+	RemoveCartItem(id int) error
 }
 
 // User represents a persisted user entity. The Password field is omitted
@@ -29,14 +388,862 @@ type User struct {
     Email     string `json:"email"`
     Password  string `json:"-"`
     CreatedAt string `json:"createdAt"` 
+    Role      string `json:"role"`
+    Currency  *string `json:"currency,omitempty"` // preferred currency for prices, when chosen
 } 
 
+// Roles a user can hold. Customers are the default; admins may manage the
+// catalog through the admin-only endpoints.
+const (
+	RoleCustomer = "customer"
+	RoleAdmin    = "admin"
+)
+
 type Product struct {
-    ID          int     `json:"id"`
-    Name        string  `json:"name"`
-    Description string  `json:"description"`
-    Image       string  `json:"image,omitempty"` // URL or path to product image; may be empty
-    Price       float64 `json:"price"`
-    Quantity    int     `json:"quantity"`
-    CreatedAt   string  `json:"createdAt"`
+	ID          int     `json:"id"`
+	SKU         *string `json:"sku,omitempty"` // optional merchant identifier, unique across products
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Image       string  `json:"image,omitempty"` // URL or path to product image; may be empty
+	Price       Money   `json:"price"`
+	Currency    string  `json:"currency"` // currency of Price and of the variant prices
+	TaxClass    string  `json:"taxClass"` // picks the tax rates that apply, DefaultTaxClass unless set
+	Quantity    int     `json:"quantity"`
+	Available   int     `json:"available"` // quantity minus active reservations
+	// LowStockThreshold, when set, raises a low-stock alert as soon as
+	// Quantity drops below it.
+	LowStockThreshold *int `json:"lowStockThreshold,omitempty"`
+	// Weight, in grams, and Dimensions size the parcel at checkout;
+	// products without them weigh nothing.
+	Weight     *int        `json:"weight,omitempty"`
+	Dimensions *Dimensions `json:"dimensions,omitempty"`
+	// RatingAverage is the mean rating of the approved reviews, nil until
+	// one is approved; RatingCount is their number.
+	RatingAverage *float64          `json:"ratingAverage"`
+	RatingCount   int               `json:"ratingCount"`
+	CreatedAt     string            `json:"createdAt"`
+	Version       int               `json:"version"` // incremented on every write, exposed as the ETag
+	CategoryIDs   []int             `json:"categoryIds,omitempty"`
+	Options       []*ProductOption  `json:"options,omitempty"`
+	Variants      []*ProductVariant `json:"variants,omitempty"`
+	Images        []*ProductImage   `json:"images,omitempty"`
+	ImageSizes    map[string]string `json:"imageSizes,omitempty"` // resized variants of the first image by size name
+	DeletedAt     *string           `json:"deletedAt,omitempty"`  // set when the product has been soft deleted
+}
+
+// Dimensions are the length, width and height of a packed product in
+// millimetres.
+type Dimensions struct {
+	Length int `json:"length" validate:"gt=0"`
+	Width  int `json:"width" validate:"gt=0"`
+	Height int `json:"height" validate:"gt=0"`
+}
+
+// ProductImage is an uploaded image of a product. Images are shown in
+// ascending Position; the first one is mirrored into Product.Image.
+type ProductImage struct {
+	ID          int    `json:"id"`
+	ProductID   int    `json:"productId"`
+	Key         string `json:"-"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Position    int    `json:"position"`
+	CreatedAt   string `json:"createdAt"`
+
+	// SizeKeys maps a size name such as "thumbnail" to the blob key of the
+	// resized variant; Sizes holds the matching URLs for clients.
+	SizeKeys map[string]string `json:"-"`
+	Sizes    map[string]string `json:"sizes,omitempty"`
+}
+
+// ProductImageSize is a resized variant of a product image.
+type ProductImageSize struct {
+	Size   string
+	Key    string
+	Width  int
+	Height int
+}
+
+// Outcomes of a single row in a bulk product import.
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportFailed  = "failed"
+)
+
+// ImportRowResult reports what a bulk import did with one input row. Row is
+// the 1-based line number in the uploaded file.
+type ImportRowResult struct {
+	Row       int      `json:"row"`
+	SKU       string   `json:"sku,omitempty"`
+	Status    string   `json:"status"`
+	ProductID int      `json:"productId,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ImportReport summarizes a bulk product import. In a dry run nothing is
+// written, but the rows report what would have happened.
+type ImportReport struct {
+	DryRun  bool               `json:"dryRun"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Failed  int                `json:"failed"`
+	Rows    []*ImportRowResult `json:"rows"`
+}
+
+// ProductOption is a dimension a product varies along, such as size or
+// color, together with its allowed values in display order.
+type ProductOption struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// ProductVariant is a purchasable SKU of a product. Options maps each
+// option name to the value this variant has for it. A nil Price means the
+// variant sells at the product's base price.
+type ProductVariant struct {
+	ID        int               `json:"id"`
+	ProductID int               `json:"productId"`
+	SKU       string            `json:"sku"`
+	Price     *Money            `json:"price"`
+	Quantity  int               `json:"quantity"`
+	Available int               `json:"available"` // quantity minus active reservations
+	Options   map[string]string `json:"options"`
+	CreatedAt string            `json:"createdAt"`
+}
+
+// ProductRelations are the records written along with the columns of a
+// product, in the same transaction. Nil fields are left as stored.
+// Variants, with the Options they pick values from, replace the stored
+// ones; an empty slice removes them all. Quantity sets the stock of a
+// product without variants. CategoryIDs replace the linked categories.
+type ProductRelations struct {
+	Options     []*ProductOption
+	Variants    []*ProductVariant
+	Quantity    *int
+	CategoryIDs []int
+}
+
+// PriceOr returns the variant's price override, or base when it has none.
+func (v *ProductVariant) PriceOr(base Money) Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return base
+}
+
+// Order statuses as stored in the orders.status column. A paid order
+// moves to shipped once every unit of it was shipped and to delivered once
+// every unit was delivered.
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusDelivered = "delivered"
+	OrderStatusCompleted = "completed"
+	OrderStatusCancelled = "cancelled"
+)
+
+// Order is a placed order. Items is populated when the order is loaded by
+// ID or created through checkout.
+type Order struct {
+	ID     int `json:"id"`
+	UserID int `json:"userId"`
+	// Subtotal is the price of the items and Total what the customer
+	// pays once the coupon's Discount is taken off and Shipping and the
+	// taxes not included in the prices are added, all in Currency.
+	Subtotal Money  `json:"subtotal"`
+	Discount Money  `json:"discount"`
+	Total    Money  `json:"total"`
+	Currency string `json:"currency"`
+	// Shipping is the cost of ShippingMethod, the name of the method
+	// chosen at checkout, and is part of Total. Orders placed without a
+	// method ship for free.
+	Shipping         Money  `json:"shipping"`
+	ShippingMethodID *int   `json:"shippingMethodId,omitempty"`
+	ShippingMethod   string `json:"shippingMethod,omitempty"`
+	// Tax is the sum of the TaxLines, whether included in the prices or
+	// added to the total. Country and Region locate the delivery address
+	// for taxes; without a Country the order is not taxed.
+	Tax      Money      `json:"tax"`
+	TaxLines []*TaxLine `json:"taxLines,omitempty"`
+	Country  string     `json:"country,omitempty"`
+	Region   string     `json:"region,omitempty"`
+	// ExchangeRates are the rates used at checkout to convert catalog
+	// prices into Currency: the rate of Currency and of every currency the
+	// items are priced in. Only loaded with the order's items.
+	ExchangeRates []*ExchangeRate `json:"exchangeRates,omitempty"`
+	// CouponCode is the coupon the order was placed with, if any;
+	// FreeShipping is set by free-shipping coupons.
+	CouponCode   *string `json:"couponCode,omitempty"`
+	FreeShipping bool    `json:"freeShipping"`
+	Status       string  `json:"status"`
+	Address      string  `json:"address"`
+	// Latitude and Longitude locate the delivery address when the
+	// customer gave them; checkout ships from the closest locations.
+	Latitude  *float64     `json:"latitude,omitempty"`
+	Longitude *float64     `json:"longitude,omitempty"`
+	CreatedAt string       `json:"createdAt"`
+	Items     []*OrderItem `json:"items,omitempty"`
+	// Shipments are the packages the order was sent in, with their
+	// tracking. Only loaded with the order's items.
+	Shipments []*Shipment `json:"shipments,omitempty"`
+	// HoldExpiresAt is set at checkout: the order must be paid before then
+	// or its stock reservations are released and it is cancelled.
+	HoldExpiresAt *time.Time `json:"holdExpiresAt,omitempty"`
+	// GiftCard is the part of Total paid with the gift card GiftCardID;
+	// the rest, Due, is paid through the payment layer.
+	GiftCard   Money `json:"giftCard"`
+	GiftCardID *int  `json:"giftCardId,omitempty"`
+	// CartItemIDs are the lines of the server-side cart the order was
+	// checked out from, which CreateOrder removes from the cart.
+	CartItemIDs []int `json:"-"`
+}
+
+// Due returns what is left to pay for the order once its gift card is
+// taken off.
+func (o *Order) Due() Money {
+	return o.Total.Sub(o.GiftCard)
+}
+
+// Reservation statuses as stored in inventory_reservations.status. An
+// active reservation holds stock; committing it decrements the stock and
+// releasing it makes the stock available again.
+const (
+	ReservationActive    = "active"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+)
+
+// Reasons recorded on inventory movements.
+const (
+	MovementSale         = "sale"
+	MovementRestock      = "restock"
+	MovementAdjustment   = "adjustment"
+	MovementReturn       = "return"
+	MovementCancellation = "cancellation"
+)
+
+// StockMovement is one entry of the append-only inventory ledger. Delta is
+// signed and applies to the stock at LocationID; a movement of a variant
+// also moves its product, so the sum of a product's movements equals its
+// quantity. ReferenceID points at the
+// record that caused the movement, such as the order of a sale. ActorID is
+// nil for changes made by the system.
+type StockMovement struct {
+	ID          int    `json:"id"`
+	ProductID   int    `json:"productId"`
+	VariantID   *int   `json:"variantId"`
+	LocationID  int    `json:"locationId"`
+	Delta       int    `json:"delta"`
+	Reason      string `json:"reason"`
+	ReferenceID *int   `json:"referenceId"`
+	ActorID     *int   `json:"actorId"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// StockDiscrepancy reports a product, or a variant when VariantID is set,
+// whose on-hand quantity does not match its ledger. LocationID is set when
+// the stock at one location is off.
+type StockDiscrepancy struct {
+	ProductID  int  `json:"productId"`
+	VariantID  *int `json:"variantId"`
+	LocationID *int `json:"locationId"`
+	OnHand     int  `json:"onHand"`
+	Ledger     int  `json:"ledger"`
+}
+
+// DefaultLocationID is the stock location created by the migration that
+// introduced locations. Stock set without naming a location, such as the
+// quantity of a product form or an import, is kept there.
+const DefaultLocationID = 1
+
+// StockLocation is a warehouse that keeps stock and ships orders. The
+// coordinates are used to find the location closest to a delivery point;
+// locations without them are never considered closest.
+type StockLocation struct {
+	ID        int      `json:"id"`
+	Code      string   `json:"code"`
+	Name      string   `json:"name"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+// StockLevel is the stock of a product, or of one of its variants, at one
+// location. Available excludes the active reservations at the location.
+type StockLevel struct {
+	LocationID int  `json:"locationId"`
+	ProductID  int  `json:"productId"`
+	VariantID  *int `json:"variantId"`
+	Quantity   int  `json:"quantity"`
+	Available  int  `json:"available"`
+}
+
+// Allocation assigns part of an order line to the location it ships from.
+type Allocation struct {
+	LocationID int `json:"locationId"`
+	Quantity   int `json:"quantity"`
+}
+
+// Kinds of stock alerts and of the notifications sent for them.
+const (
+	AlertLowStock    = "low_stock"
+	AlertBackInStock = "back_in_stock"
+)
+
+// StockAlert is an entry of the stock alert outbox. Quantity is the
+// product's stock when the alert was raised.
+type StockAlert struct {
+	ID        int    `json:"id"`
+	ProductID int    `json:"productId"`
+	Kind      string `json:"kind"`
+	Quantity  int    `json:"quantity"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// StockSubscription asks for a back-in-stock notification. Email is the
+// subscriber's address; NotifiedAt is nil while the subscription waits.
+type StockSubscription struct {
+	ID         int        `json:"id"`
+	ProductID  int        `json:"productId"`
+	UserID     int        `json:"userId"`
+	Email      string     `json:"-"`
+	CreatedAt  string     `json:"createdAt"`
+	NotifiedAt *time.Time `json:"notifiedAt"`
+}
+
+// Notification is what a Notifier delivers. Low-stock notifications go to
+// the merchandisers and carry the threshold; back-in-stock notifications
+// go to one subscriber, identified by UserID and Email.
+type Notification struct {
+	Kind        string `json:"kind"`
+	ProductID   int    `json:"productId"`
+	ProductName string `json:"productName"`
+	Quantity    int    `json:"quantity"`
+	Available   int    `json:"available"`
+	Threshold   *int   `json:"threshold,omitempty"`
+	UserID      int    `json:"userId,omitempty"`
+	Email       string `json:"email,omitempty"`
+}
+
+// Reservation holds stock of a product, or of one of its variants, at one
+// location for a pending order until ExpiresAt.
+type Reservation struct {
+	ID         int       `json:"id"`
+	OrderID    int       `json:"orderId"`
+	ProductID  int       `json:"productId"`
+	VariantID  *int      `json:"variantId"`
+	LocationID int       `json:"locationId"`
+	Quantity   int       `json:"quantity"`
+	Status     string    `json:"status"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// OrderItem is a single order line. VariantID is nil for products sold
+// without variants; Price is the unit price charged at checkout.
+// Allocations tell which locations ship the line and sum to Quantity.
+// TaxClass is the product's tax class at checkout and TaxLines the taxes
+// of the whole line.
+type OrderItem struct {
+	ID          int           `json:"id"`
+	OrderID     int           `json:"orderId"`
+	ProductID   int           `json:"productId"`
+	VariantID   *int          `json:"variantId"`
+	Quantity    int           `json:"quantity"`
+	Price       Money         `json:"price"`
+	TaxClass    string        `json:"taxClass"`
+	TaxLines    []*TaxLine    `json:"taxLines,omitempty"`
+	Allocations []*Allocation `json:"allocations,omitempty"`
+	// Total is what the customer paid for the line: its amount less its
+	// share of the discount plus the taxes not included in the price.
+	// Refunded is the part of it given back so far.
+	Total    Money `json:"total"`
+	Refunded Money `json:"refunded"`
+}
+
+// DefaultTaxClass is the tax class of products that were not given one.
+const DefaultTaxClass = "standard"
+
+// TaxRate is a row of the tax rates table: Rate percent of the items of
+// TaxClass delivered to Country, or only to Region of it when Region is
+// set. Inclusive rates are already part of the catalog prices, as VAT
+// usually is; the others are added on top, like US sales tax. Every rate
+// that matches an item applies, so a country rate and a region rate add
+// up.
+type TaxRate struct {
+	ID        int     `json:"id"`
+	Country   string  `json:"country"`
+	Region    string  `json:"region,omitempty"`
+	TaxClass  string  `json:"taxClass"`
+	Name      string  `json:"name"`
+	Rate      float64 `json:"rate"`
+	Inclusive bool    `json:"inclusive"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+// TaxLine is the tax charged at one rate, on an order item or, summed,
+// on the whole order.
+type TaxLine struct {
+	Name      string  `json:"name"`
+	Rate      float64 `json:"rate"`
+	Inclusive bool    `json:"inclusive"`
+	Amount    Money   `json:"amount"`
+}
+
+// Category is a node in the product category tree. ParentID is nil for
+// top-level categories; Children is only populated when the tree is built.
+type Category struct {
+	ID          int         `json:"id"`
+	ParentID    *int        `json:"parentId"`
+	Name        string      `json:"name"`
+	Slug        string      `json:"slug"`
+	Description string      `json:"description"`
+	CreatedAt   string      `json:"createdAt"`
+	Children    []*Category `json:"children,omitempty"`
+}
+
+// Wishlist is a named list of products a customer saved for later.
+// ShareToken is set while the list is shared; UserID is left out of the
+// shared view.
+type Wishlist struct {
+	ID         int             `json:"id"`
+	UserID     int             `json:"userId,omitempty"`
+	Name       string          `json:"name"`
+	ShareToken *string         `json:"shareToken,omitempty"`
+	CreatedAt  string          `json:"createdAt"`
+	Items      []*WishlistItem `json:"items"`
+}
+
+// Item returns the wishlist item with the given ID, or nil.
+func (w *Wishlist) Item(id int) *WishlistItem {
+	for _, it := range w.Items {
+		if it.ID == id {
+			return it
+		}
+	}
+	return nil
+}
+
+// WishlistItem is a product, or one variant of it, saved in a wishlist.
+type WishlistItem struct {
+	ID         int    `json:"id"`
+	WishlistID int    `json:"wishlistId"`
+	ProductID  int    `json:"productId"`
+	VariantID  *int   `json:"variantId"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// CartItem is a line of a customer's server-side cart.
+type CartItem struct {
+	ID        int  `json:"id"`
+	UserID    int  `json:"userId"`
+	ProductID int  `json:"productId"`
+	VariantID *int `json:"variantId"`
+	Quantity  int  `json:"quantity"`
+	// Price is the current unit price in Currency, set when the cart is
+	// shown; it is absent when the product or variant is gone.
+	Price     *Money `json:"price,omitempty"`
+	Currency  string `json:"currency,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// ExchangeRate is the number of Currency units one unit of
+// DefaultCurrency buys, as an exact decimal string.
+type ExchangeRate struct {
+	Currency  string     `json:"currency"`
+	Rate      string     `json:"rate"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// Kinds of coupons. A percentage coupon takes PercentOff percent off the
+// eligible items and a fixed-amount coupon AmountOff off their price; a
+// buy-X-get-Y coupon makes GetQuantity of every BuyQuantity+GetQuantity
+// eligible units free, the cheapest first.
+const (
+	CouponPercentage   = "percentage"
+	CouponFixedAmount  = "fixed_amount"
+	CouponFreeShipping = "free_shipping"
+	CouponBuyXGetY     = "buy_x_get_y"
+)
+
+// Coupon is a promotion customers apply at checkout by its code. A coupon
+// with ProductIDs or CategoryIDs only discounts the items of those
+// products or directly in those categories. StartsAt and EndsAt bound the
+// window it can be used in; nil limits are unlimited. Redemptions counts
+// the orders placed with it that were not cancelled.
+type Coupon struct {
+	ID           int        `json:"id"`
+	Code         string     `json:"code"`
+	Kind         string     `json:"kind"`
+	PercentOff   float64    `json:"percentOff,omitempty"`
+	AmountOff    *Money     `json:"amountOff,omitempty"`
+	BuyQuantity  int        `json:"buyQuantity,omitempty"`
+	GetQuantity  int        `json:"getQuantity,omitempty"`
+	MinSubtotal  Money      `json:"minSubtotal"`
+	ProductIDs   []int      `json:"productIds,omitempty"`
+	CategoryIDs  []int      `json:"categoryIds,omitempty"`
+	StartsAt     *time.Time `json:"startsAt"`
+	EndsAt       *time.Time `json:"endsAt"`
+	UsageLimit   *int       `json:"usageLimit"`
+	PerUserLimit *int       `json:"perUserLimit"`
+	Redemptions  int        `json:"redemptions"`
+	Active       bool       `json:"active"`
+	CreatedAt    string     `json:"createdAt"`
+}
+
+// Review moderation statuses as stored in the product_reviews.status
+// column. New reviews are pending until an admin approves them; only
+// approved reviews are public and count towards the product's rating.
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusHidden   = "hidden"
+)
+
+// Review is a customer's rating of a product, from 1 to 5 stars, with a
+// text. AuthorName is the reviewer's first name and last initial.
+type Review struct {
+	ID               int    `json:"id"`
+	ProductID        int    `json:"productId"`
+	UserID           int    `json:"userId"`
+	AuthorName       string `json:"authorName"`
+	Rating           int    `json:"rating"`
+	Body             string `json:"body"`
+	VerifiedPurchase bool   `json:"verifiedPurchase"`
+	Status           string `json:"status"`
+	CreatedAt        string `json:"createdAt"`
+}
+
+// Pagination describes the page of results requested by a listing
+// endpoint. Page is 1-based.
+type Pagination struct {
+	Page     int
+	PageSize int
+}
+
+// Offset returns the number of rows to skip for the requested page.
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// ProductListParams narrows a product listing. An empty CategoryIDs slice
+// means no category filter is applied. Soft deleted products are only
+// returned when IncludeDeleted is set.
+type ProductListParams struct {
+	Pagination
+	CategoryIDs    []int
+	IncludeDeleted bool
+}
+
+// Orders in which reviews can be listed.
+const (
+	ReviewSortNewest  = "newest"
+	ReviewSortOldest  = "oldest"
+	ReviewSortHighest = "highest"
+	ReviewSortLowest  = "lowest"
+)
+
+// ReviewListParams narrows a review listing. Zero ProductID and empty
+// Status mean no filter on them; an empty Sort lists the newest first.
+type ReviewListParams struct {
+	Pagination
+	ProductID int
+	Status    string
+	Sort      string
+}
+
+// Shipping method kinds.
+const (
+	ShippingFlatRate    = "flat_rate"
+	ShippingWeightBased = "weight_based"
+	ShippingFreeOver    = "free_over"
+)
+
+// ShippingZone groups the countries that share shipping methods. A zone
+// without Countries covers every country no other zone lists.
+type ShippingZone struct {
+	ID        int               `json:"id"`
+	Name      string            `json:"name"`
+	Countries []string          `json:"countries"`
+	Methods   []*ShippingMethod `json:"methods"`
+	CreatedAt string            `json:"createdAt"`
+}
+
+// ShippingMethod is a way to ship to a zone, priced by Kind: flat-rate
+// methods cost Rate; weight-based ones Rate plus PerKg for every started
+// kilogram of the parcel; free-over ones Rate, or nothing once the items
+// cost FreeOver or more after the coupon discount. Amounts are in
+// DefaultCurrency.
+type ShippingMethod struct {
+	ID        int    `json:"id"`
+	ZoneID    int    `json:"zoneId"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Rate      Money  `json:"rate"`
+	PerKg     *Money `json:"perKg,omitempty"`
+	FreeOver  *Money `json:"freeOver,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// ShippingQuote is what a shipping method costs for a cart, in the
+// currency the cart was priced in.
+type ShippingQuote struct {
+	MethodID int    `json:"methodId"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Cost     Money  `json:"cost"`
+}
+
+// Payment statuses as stored in the payments.status column. A payment
+// only changes status on a PaymentEvent.
+const (
+	PaymentPending        = "pending"         // waiting for the customer to confirm
+	PaymentRequiresAction = "requires_action" // the customer must authenticate, as with 3-D Secure
+	PaymentAuthorized     = "authorized"      // funds held, waiting for capture
+	PaymentCaptured       = "captured"        // funds taken; the order is paid
+	PaymentFailed         = "failed"          // declined; the customer may confirm again
+	PaymentRefunded       = "refunded"        // the whole captured amount was given back
+)
+
+// Payment event types, named after the status they move a payment to.
+const (
+	PaymentEventRequiresAction = "payment.requires_action"
+	PaymentEventAuthorized     = "payment.authorized"
+	PaymentEventCaptured       = "payment.captured"
+	PaymentEventFailed         = "payment.failed"
+	PaymentEventRefunded       = "payment.refunded"
+)
+
+// Payment is an attempt to pay an order through a PaymentProvider, known
+// to the provider by IntentID. Refunded is the part of Amount given back
+// so far. NextActionURL is where the customer authenticates while the
+// payment requires action.
+type Payment struct {
+	ID            int    `json:"id"`
+	OrderID       int    `json:"orderId"`
+	Provider      string `json:"provider"`
+	IntentID      string `json:"intentId"`
+	Status        string `json:"status"`
+	Amount        Money  `json:"amount"`
+	Refunded      Money  `json:"refunded"`
+	Currency      string `json:"currency"`
+	NextActionURL string `json:"nextActionUrl,omitempty"`
+	FailureReason string `json:"failureReason,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+	UpdatedAt     string `json:"updatedAt"`
+}
+
+// PaymentIntent is a payment as opened with a PaymentProvider.
+type PaymentIntent struct {
+	ID     string
+	Amount Money
+}
+
+// PaymentEvent is something a PaymentProvider reports about an intent.
+// ID is unique per provider so redelivered events are recognised. Amount
+// is the amount captured or refunded by the event, FailureReason why a
+// payment failed and NextActionURL where the customer authenticates.
+type PaymentEvent struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	IntentID      string `json:"intentId"`
+	Amount        *Money `json:"amount,omitempty"`
+	Currency      string `json:"currency,omitempty"`
+	FailureReason string `json:"failureReason,omitempty"`
+	NextActionURL string `json:"nextActionUrl,omitempty"`
+}
+
+// Return statuses as stored in the returns.status column. Support approves
+// or rejects a requested return; an approved return is received when the
+// items come back and refunded once the money went back. Refunds may be
+// issued before the items arrive, so a refunded return can still be
+// received.
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+	ReturnReceived  = "received"
+	ReturnRefunded  = "refunded"
+)
+
+// Reasons customers give for returning an item.
+const (
+	ReturnReasonDamaged        = "damaged"
+	ReturnReasonWrongItem      = "wrong_item"
+	ReturnReasonNotAsDescribed = "not_as_described"
+	ReturnReasonNoLongerNeeded = "no_longer_needed"
+	ReturnReasonOther          = "other"
+)
+
+// Return is a customer's request to send items of an order back. Note is
+// the customer's and Resolution the note support left when approving or
+// rejecting it. ReceivedAt is set once the items came back.
+type Return struct {
+	ID         int           `json:"id"`
+	OrderID    int           `json:"orderId"`
+	UserID     int           `json:"userId"`
+	Status     string        `json:"status"`
+	Note       string        `json:"note,omitempty"`
+	Resolution string        `json:"resolution,omitempty"`
+	ReceivedAt *string       `json:"receivedAt,omitempty"`
+	Items      []*ReturnItem `json:"items"`
+	CreatedAt  string        `json:"createdAt"`
+	UpdatedAt  string        `json:"updatedAt"`
+}
+
+// ReturnItem is Quantity units of an order line sent back. Restocked is
+// set once they came back and were put back in stock.
+type ReturnItem struct {
+	ID          int    `json:"id"`
+	ReturnID    int    `json:"returnId"`
+	OrderItemID int    `json:"orderItemId"`
+	Quantity    int    `json:"quantity"`
+	Reason      string `json:"reason"`
+	Restocked   bool   `json:"restocked"`
+}
+
+// Refund is money given back for an order, spread over its lines by
+// Items. GiftCard is the part of Amount credited back onto the gift card
+// the order was paid with and the rest went back on the captured payment
+// PaymentID, nil when there was none. ReturnID is set for the refund of a
+// return. ActorID is the admin who issued it, nil when the system did.
+type Refund struct {
+	ID        int           `json:"id"`
+	OrderID   int           `json:"orderId"`
+	ReturnID  *int          `json:"returnId"`
+	PaymentID *int          `json:"paymentId"`
+	Amount    Money         `json:"amount"`
+	GiftCard  Money         `json:"giftCard"`
+	Currency  string        `json:"currency"`
+	Reason    string        `json:"reason"`
+	ActorID   *int          `json:"actorId"`
+	Items     []*RefundItem `json:"items"`
+	CreatedAt string        `json:"createdAt"`
+}
+
+// RefundItem is the part of a refund given back for an order line.
+type RefundItem struct {
+	OrderItemID int   `json:"orderItemId"`
+	Amount      Money `json:"amount"`
+}
+
+// Kinds of invoice documents as stored in the invoices.kind column.
+const (
+	InvoiceKindInvoice    = "invoice"
+	InvoiceKindCreditNote = "credit_note"
+)
+
+// Invoice is an invoice issued when an order was paid, or a credit note
+// issued for one of its refunds, with everything it shows copied from the
+// order so it never changes. Number is taken from a gap-free sequence per
+// kind. A credit note has its RefundID and CreditedNumber, the number of
+// the invoice it corrects when the order has one, and its amounts are what
+// was given back.
+type Invoice struct {
+	ID             int            `json:"id"`
+	Number         string         `json:"number"`
+	Kind           string         `json:"kind"`
+	OrderID        int            `json:"orderId"`
+	RefundID       *int           `json:"refundId,omitempty"`
+	CreditedNumber string         `json:"creditedNumber,omitempty"`
+	Customer       InvoiceParty   `json:"customer"`
+	Lines          []*InvoiceLine `json:"lines"`
+	Subtotal       Money          `json:"subtotal"`
+	Discount       Money          `json:"discount"`
+	Shipping       Money          `json:"shipping"`
+	Tax            Money          `json:"tax"`
+	TaxLines       []*TaxLine     `json:"taxLines"`
+	Total          Money          `json:"total"`
+	Currency       string         `json:"currency"`
+	IssuedAt       string         `json:"issuedAt"`
+}
+
+// InvoiceParty is the seller or the customer named on an invoice.
+type InvoiceParty struct {
+	Name    string `json:"name"`
+	Email   string `json:"email,omitempty"`
+	Address string `json:"address"`
+	Country string `json:"country,omitempty"`
+	Region  string `json:"region,omitempty"`
+	TaxID   string `json:"taxId,omitempty"`
+}
+
+// InvoiceLine is a line of an invoice: Quantity units of an order item at
+// UnitPrice, for Amount before the discount and the taxes not included in
+// the price. On credit notes Amount is what the line got back and
+// Quantity the units returned, 0 when no units were.
+type InvoiceLine struct {
+	OrderItemID *int   `json:"orderItemId"`
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	UnitPrice   Money  `json:"unitPrice"`
+	Amount      Money  `json:"amount"`
+}
+
+// Shipment is a package an order was sent in: Quantity units of each
+// order line in Items, handed to Carrier at ShippedAt under
+// TrackingNumber. DeliveredAt is set once the carrier delivered it.
+type Shipment struct {
+	ID             int             `json:"id"`
+	OrderID        int             `json:"orderId"`
+	Carrier        string          `json:"carrier"`
+	TrackingNumber string          `json:"trackingNumber"`
+	TrackingURL    string          `json:"trackingUrl,omitempty"`
+	ShippedAt      time.Time       `json:"shippedAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+	Items          []*ShipmentItem `json:"items"`
+	CreatedAt      string          `json:"createdAt"`
+}
+
+// ShipmentItem is Quantity units of an order line in a shipment.
+type ShipmentItem struct {
+	ID          int `json:"id"`
+	ShipmentID  int `json:"shipmentId"`
+	OrderItemID int `json:"orderItemId"`
+	Quantity    int `json:"quantity"`
+}
+
+// Kinds of gift card ledger entries as stored in
+// gift_card_transactions.kind.
+const (
+	GiftCardIssue   = "issue"   // the card was issued with its initial balance
+	GiftCardRedeem  = "redeem"  // an order was paid with the card
+	GiftCardRelease = "release" // a cancelled order gave back what it redeemed
+	GiftCardRefund  = "refund"  // a refund of an order was credited back
+)
+
+// GiftCard is a prepaid card customers pay orders with by its code. Only
+// a hash of the code is stored: Code is set once, when the card is issued,
+// and Last4 identifies the card afterwards. Balance is what is left of
+// InitialBalance, in Currency; a card past ExpiresAt or deactivated
+// cannot be redeemed.
+type GiftCard struct {
+	ID             int        `json:"id"`
+	Code           string     `json:"code,omitempty"`
+	Last4          string     `json:"last4"`
+	InitialBalance Money      `json:"initialBalance"`
+	Balance        Money      `json:"balance"`
+	Currency       string     `json:"currency"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+	Active         bool       `json:"active"`
+	Note           string     `json:"note,omitempty"`
+	CreatedAt      string     `json:"createdAt"`
+}
+
+// GiftCardTransaction is an entry of the ledger of a gift card. Amount is
+// signed, negative when the card paid for an order, and Balance is the
+// balance of the card after it. OrderID and RefundID point at the order
+// or refund that caused it.
+type GiftCardTransaction struct {
+	ID         int    `json:"id"`
+	GiftCardID int    `json:"giftCardId"`
+	Kind       string `json:"kind"`
+	Amount     Money  `json:"amount"`
+	Balance    Money  `json:"balance"`
+	OrderID    *int   `json:"orderId"`
+	RefundID   *int   `json:"refundId"`
+	CreatedAt  string `json:"createdAt"`
 }
@@ -0,0 +1,347 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types accepted by PATCH endpoints.
+const (
+	MergePatchMediaType = "application/merge-patch+json"
+	JSONPatchMediaType  = "application/json-patch+json"
+)
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to the JSON document doc
+// and returns the patched document. Members set to null in the patch are
+// removed; objects are merged recursively and every other value replaces
+// the target value.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target, patch any) any {
+	pm, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	tm, ok := target.(map[string]any)
+	if !ok {
+		tm = make(map[string]any)
+	}
+	for k, v := range pm {
+		if v == nil {
+			delete(tm, k)
+			continue
+		}
+		tm[k] = mergeValue(tm[k], v)
+	}
+	return tm
+}
+
+// jsonPatchOp is a single RFC 6902 operation. hasValue tells an operation
+// without a value member from one whose value is null.
+type jsonPatchOp struct {
+	Op       string          `json:"op"`
+	Path     string          `json:"path"`
+	From     string          `json:"from"`
+	Value    json.RawMessage `json:"value"`
+	hasValue bool
+}
+
+// UnmarshalJSON decodes the operation and records whether it has a value.
+func (op *jsonPatchOp) UnmarshalJSON(b []byte) error {
+	type fields jsonPatchOp
+	if err := json.Unmarshal(b, (*fields)(op)); err != nil {
+		return err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(b, &members); err != nil {
+		return err
+	}
+	op.Value, op.hasValue = members["value"]
+	return nil
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch to the JSON document doc.
+// Operations are applied in order and the patch is atomic: if any
+// operation fails (including a failed "test") an error is returned and no
+// result is produced.
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("invalid json patch: %w", err)
+	}
+
+	for i, op := range ops {
+		var err error
+		target, err = applyOp(target, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func applyOp(doc any, op jsonPatchOp) (any, error) {
+	value := func() (any, error) {
+		if !op.hasValue {
+			return nil, fmt.Errorf("missing value")
+		}
+		var v any
+		err := json.Unmarshal(op.Value, &v)
+		return v, err
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, op.Path, v)
+	case "remove":
+		doc, _, err := pointerRemove(doc, op.Path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		doc, _, err = pointerRemove(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, op.Path, v)
+	case "move":
+		if op.Path == op.From || strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("cannot move a value into itself")
+		}
+		doc, v, err := pointerRemove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, op.Path, v)
+	case "copy":
+		v, err := pointerGet(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		// round trip through JSON so the copy shares no maps or slices
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var clone any
+		if err := json.Unmarshal(b, &clone); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, op.Path, clone)
+	case "test":
+		want, err := value()
+		if err != nil {
+			return nil, err
+		}
+		got, err := pointerGet(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(got, want) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// splitPointer parses an RFC 6901 JSON Pointer into unescaped tokens.
+func splitPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex resolves an array token. When allowEnd is set the "-" token
+// and len(arr) address the position after the last element.
+func arrayIndex(arr []any, token string, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return len(arr), nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := len(arr) - 1
+	if allowEnd {
+		limit = len(arr)
+	}
+	if i > limit {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+func pointerGet(doc any, pointer string) (any, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	cur := doc
+	for _, t := range tokens {
+		switch node := cur.(type) {
+		case map[string]any:
+			v, ok := node[t]
+			if !ok {
+				return nil, fmt.Errorf("path %q not found", pointer)
+			}
+			cur = v
+		case []any:
+			i, err := arrayIndex(node, t, false)
+			if err != nil {
+				return nil, err
+			}
+			cur = node[i]
+		default:
+			return nil, fmt.Errorf("path %q not found", pointer)
+		}
+	}
+	return cur, nil
+}
+
+// pointerAdd inserts value at pointer and returns the updated document.
+// Arrays are replaced by copies because inserting may grow them.
+func pointerAdd(doc any, pointer string, value any) (any, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return addAt(doc, tokens, value)
+}
+
+func addAt(node any, tokens []string, value any) (any, error) {
+	t := tokens[0]
+	last := len(tokens) == 1
+
+	switch n := node.(type) {
+	case map[string]any:
+		if last {
+			n[t] = value
+			return n, nil
+		}
+		child, ok := n[t]
+		if !ok {
+			return nil, fmt.Errorf("path segment %q not found", t)
+		}
+		updated, err := addAt(child, tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[t] = updated
+		return n, nil
+	case []any:
+		if last {
+			i, err := arrayIndex(n, t, true)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]any, 0, len(n)+1)
+			out = append(out, n[:i]...)
+			out = append(out, value)
+			return append(out, n[i:]...), nil
+		}
+		i, err := arrayIndex(n, t, false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := addAt(n[i], tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = updated
+		return n, nil
+	default:
+		return nil, fmt.Errorf("path segment %q not found", t)
+	}
+}
+
+// pointerRemove deletes the value at pointer and returns the updated
+// document together with the removed value.
+func pointerRemove(doc any, pointer string) (any, any, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	return removeAt(doc, tokens)
+}
+
+func removeAt(node any, tokens []string) (any, any, error) {
+	t := tokens[0]
+	last := len(tokens) == 1
+
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[t]
+		if !ok {
+			return nil, nil, fmt.Errorf("path segment %q not found", t)
+		}
+		if last {
+			delete(n, t)
+			return n, child, nil
+		}
+		updated, removed, err := removeAt(child, tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[t] = updated
+		return n, removed, nil
+	case []any:
+		i, err := arrayIndex(n, t, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if last {
+			out := make([]any, 0, len(n)-1)
+			out = append(out, n[:i]...)
+			return append(out, n[i+1:]...), n[i], nil
+		}
+		updated, removed, err := removeAt(n[i], tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[i] = updated
+		return n, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("path segment %q not found", t)
+	}
+}
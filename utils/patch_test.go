@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// example from RFC 7396 section 3
+	doc := `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`
+	patch := `{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`
+
+	got, err := MergePatch([]byte(doc), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, got, `{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`)
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := `{"name":"Shirt","price":10,"tags":["a","b"],"meta":{"x":1}}`
+
+	t.Run("applies operations in order", func(t *testing.T) {
+		patch := `[
+			{"op":"test","path":"/name","value":"Shirt"},
+			{"op":"replace","path":"/price","value":12.5},
+			{"op":"add","path":"/tags/1","value":"c"},
+			{"op":"add","path":"/tags/-","value":"d"},
+			{"op":"remove","path":"/tags/0"},
+			{"op":"copy","from":"/meta/x","path":"/meta/y"},
+			{"op":"move","from":"/meta/x","path":"/count"}
+		]`
+		got, err := ApplyJSONPatch([]byte(doc), []byte(patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertJSONEqual(t, got, `{"name":"Shirt","price":12.5,"tags":["c","b","d"],"meta":{"y":1},"count":1}`)
+	})
+
+	t.Run("accepts null values", func(t *testing.T) {
+		patch := `[
+			{"op":"replace","path":"/name","value":null},
+			{"op":"add","path":"/note","value":null},
+			{"op":"test","path":"/note","value":null}
+		]`
+		got, err := ApplyJSONPatch([]byte(doc), []byte(patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertJSONEqual(t, got, `{"name":null,"price":10,"tags":["a","b"],"meta":{"x":1},"note":null}`)
+	})
+
+	failing := map[string]string{
+		"missing value":      `[{"op":"replace","path":"/name"}]`,
+		"failed test":        `[{"op":"test","path":"/name","value":"Pants"}]`,
+		"missing path":       `[{"op":"replace","path":"/missing","value":1}]`,
+		"index out of range": `[{"op":"add","path":"/tags/5","value":"x"}]`,
+		"unknown operation":  `[{"op":"frobnicate","path":"/name"}]`,
+		"move into child":    `[{"op":"move","from":"/meta","path":"/meta/inner"}]`,
+	}
+	for name, patch := range failing {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if _, err := ApplyJSONPatch([]byte(doc), []byte(patch)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
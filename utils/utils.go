@@ -7,12 +7,35 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"reflect"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/nandaiqbalh/go-backend-ecom/types"
 )
 
 // Validate is an instance of the validator used to check struct tags.
-var Validate = validator.New()
+// Money fields are validated by their amount in minor units, so tags such
+// as gt=0 work on prices, and the currency tag accepts the currency codes
+// allowed by types.ValidCurrency.
+var Validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+		return field.Interface().(types.Money).Amount
+	}, types.Money{})
+	v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return types.ValidCurrency(fl.Field().String())
+	})
+	return v
+}
+
+// Page size limits applied by ParsePagination.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
 
 // ParseJson reads JSON from the request body and unmarshals it into the
 // given payload structure. It returns an error if the body is empty or if
@@ -48,4 +71,38 @@ func WriteJson(w http.ResponseWriter, statusCode int, payload any) error {
 // form. It calls WriteJson internally.
 func WriteError(w http.ResponseWriter, statusCode int, err error) {
     WriteJson(w, statusCode, map[string]string{"error": err.Error()})
-} 
\ No newline at end of file
+} 
+// ParsePagination reads the `page` and `pageSize` query parameters. Missing
+// or invalid values fall back to the first page of DefaultPageSize items and
+// the page size is capped at MaxPageSize.
+func ParsePagination(r *http.Request) types.Pagination {
+	p := types.Pagination{Page: 1, PageSize: DefaultPageSize}
+
+	q := r.URL.Query()
+	if v, err := strconv.Atoi(q.Get("page")); err == nil && v > 0 {
+		p.Page = v
+	}
+	if v, err := strconv.Atoi(q.Get("pageSize")); err == nil && v > 0 {
+		p.PageSize = v
+	}
+	if p.PageSize > MaxPageSize {
+		p.PageSize = MaxPageSize
+	}
+
+	return p
+}
+
+// NewPageMeta builds the pagination metadata returned alongside a page of
+// results.
+func NewPageMeta(p types.Pagination, total int) types.PageMeta {
+	pages := 0
+	if p.PageSize > 0 {
+		pages = (total + p.PageSize - 1) / p.PageSize
+	}
+	return types.PageMeta{
+		Page:       p.Page,
+		PageSize:   p.PageSize,
+		Total:      total,
+		TotalPages: pages,
+	}
+}